@@ -52,6 +52,8 @@ type TenantInterface interface {
 	GetManyDeployVersion(w http.ResponseWriter, r *http.Request)
 	LimitTenantMemory(w http.ResponseWriter, r *http.Request)
 	TenantResourcesStatus(w http.ResponseWriter, r *http.Request)
+	UpdateTenantQuota(w http.ResponseWriter, r *http.Request)
+	GetTenantQuota(w http.ResponseWriter, r *http.Request)
 }
 
 //ServiceInterface ServiceInterface
@@ -106,6 +108,9 @@ type TenantInterfaceWithV1 interface {
 //LogInterface log interface
 type LogInterface interface {
 	HistoryLogs(w http.ResponseWriter, r *http.Request)
+	SearchLogs(w http.ResponseWriter, r *http.Request)
+	HTTPRuleAccessLogs(w http.ResponseWriter, r *http.Request)
+	RestoreLogArchives(w http.ResponseWriter, r *http.Request)
 	LogList(w http.ResponseWriter, r *http.Request)
 	LogFile(w http.ResponseWriter, r *http.Request)
 	LogSocket(w http.ResponseWriter, r *http.Request)
@@ -177,6 +182,7 @@ type Gatewayer interface {
 	GetAvailablePort(w http.ResponseWriter, r *http.Request)
 	RuleConfig(w http.ResponseWriter, r *http.Request)
 	Certificate(w http.ResponseWriter, r *http.Request)
+	ErrorPages(w http.ResponseWriter, r *http.Request)
 }
 
 // ThirdPartyServicer is an interface for defining methods for third-party service.
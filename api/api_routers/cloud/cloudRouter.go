@@ -30,6 +30,9 @@ func Routes() chi.Router {
 	r.Post("/auth", controller.GetCloudRouterManager().CreateToken)
 	r.Get("/auth/{eid}", controller.GetCloudRouterManager().GetTokenInfo)
 	r.Put("/auth/{eid}", controller.GetCloudRouterManager().UpdateToken)
+	r.Post("/auth/{eid}/tokens", controller.GetCloudRouterManager().CreateScopedToken)
+	r.Get("/auth/{eid}/tokens", controller.GetCloudRouterManager().ListScopedTokens)
+	r.Delete("/auth/{eid}/tokens/{token_id}", controller.GetCloudRouterManager().RevokeScopedToken)
 	r.Get("/api/manager", controller.GetCloudRouterManager().GetAPIManager)
 	r.Post("/api/manager", controller.GetCloudRouterManager().DeleteAPIManager)
 	return r
@@ -62,6 +62,8 @@ func (v2 *V2) Routes() chi.Router {
 	r.Put("/volume-options/{volume_type}", controller.UpdateVolumeType)
 	r.Mount("/enterprise/{enterprise_id}", v2.enterpriseRouter())
 	r.Mount("/monitor", v2.monitorRouter())
+	r.Get("/audit-logs", controller.ListAuditLogs)
+	r.Get("/audit-logs/export", controller.ExportAuditLogs)
 	return r
 }
 
@@ -154,11 +156,18 @@ func (v2 *V2) tenantNameRouter() chi.Router {
 	//团队资源限制
 	r.Post("/limit_memory", controller.GetManager().LimitTenantMemory)
 	r.Get("/limit_memory", controller.GetManager().TenantResourcesStatus)
+	r.Put("/quota", controller.GetManager().UpdateTenantQuota)
+	r.Get("/quota", controller.GetManager().GetTenantQuota)
+	r.Get("/log-retention", controller.GetLogRetentionPolicy)
+	r.Put("/log-retention", controller.UpdateLogRetentionPolicy)
+	r.Get("/log-archives", controller.ListLogArchives)
+	r.Post("/log-archives/restore", controller.GetManager().RestoreLogArchives)
 
 	// Gateway
 	r.Post("/http-rule", controller.GetManager().HTTPRule)
 	r.Delete("/http-rule", controller.GetManager().HTTPRule)
 	r.Put("/http-rule", controller.GetManager().HTTPRule)
+	r.Get("/http-rules/{rule_id}/access-logs", controller.GetManager().HTTPRuleAccessLogs)
 	r.Post("/tcp-rule", controller.GetManager().TCPRule)
 	r.Delete("/tcp-rule", controller.GetManager().TCPRule)
 	r.Put("/tcp-rule", controller.GetManager().TCPRule)
@@ -173,6 +182,8 @@ func (v2 *V2) tenantNameRouter() chi.Router {
 func (v2 *V2) gatewayRouter() chi.Router {
 	r := chi.NewRouter()
 	r.Put("/certificate", controller.GetManager().Certificate)
+	r.Put("/error-pages", controller.GetManager().ErrorPages)
+	r.Delete("/error-pages", controller.GetManager().ErrorPages)
 
 	return r
 }
@@ -210,6 +221,10 @@ func (v2 *V2) serviceRouter() chi.Router {
 	r.Post("/share", middleware.WrapEL(controller.GetManager().Share, dbmodel.TargetTypeService, "share-service", dbmodel.ASYNEVENTTYPE))
 	r.Get("/share/{share_id}", controller.GetManager().ShareResult)
 	r.Get("/logs", controller.GetManager().HistoryLogs)
+	r.Get("/logs/search", controller.GetManager().SearchLogs)
+	r.Get("/log-retention", controller.GetLogRetentionPolicy)
+	r.Put("/log-retention", controller.UpdateLogRetentionPolicy)
+	r.Delete("/log-retention", controller.DeleteLogRetentionPolicy)
 	r.Get("/log-file", controller.GetManager().LogList)
 	r.Get("/log-instance", controller.GetManager().LogSocket)
 	r.Post("/event-log", controller.GetManager().LogByAction)
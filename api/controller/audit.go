@@ -0,0 +1,118 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	httputil "github.com/goodrain/rainbond/util/http"
+	"github.com/sirupsen/logrus"
+)
+
+// the page size used when exporting audit logs
+const auditLogExportBatchSize = 500
+
+// ListAuditLogs lists the audit logs of mutating api calls.
+func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r)
+	if err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = 10
+	}
+	logs, total, err := db.GetManager().AuditLogDao().ListAuditLogs(query)
+	if err != nil {
+		logrus.Errorf("list audit logs: %v", err)
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnList(r, w, int(total), query.Page, logs)
+}
+
+// ExportAuditLogs exports all matched audit logs as json lines.
+func ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r)
+	if err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	query.PageSize = auditLogExportBatchSize
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=audit_log.jsonl")
+	encoder := json.NewEncoder(w)
+	for query.Page = 1; ; query.Page++ {
+		logs, _, err := db.GetManager().AuditLogDao().ListAuditLogs(query)
+		if err != nil {
+			// the response has been started, the client will get a truncated file
+			logrus.Errorf("export audit logs: %v", err)
+			return
+		}
+		for _, log := range logs {
+			if err := encoder.Encode(log); err != nil {
+				logrus.Warningf("write audit log: %v", err)
+				return
+			}
+		}
+		if len(logs) < auditLogExportBatchSize {
+			return
+		}
+	}
+}
+
+func parseAuditLogQuery(r *http.Request) (*dbmodel.AuditLogQuery, error) {
+	values := r.URL.Query()
+	query := &dbmodel.AuditLogQuery{
+		TenantName: values.Get("tenant_name"),
+		Actor:      values.Get("actor"),
+		TargetType: values.Get("target_type"),
+		TargetID:   values.Get("target_id"),
+		Method:     values.Get("method"),
+	}
+	if tokenID := values.Get("token_id"); tokenID != "" {
+		id, err := strconv.ParseUint(tokenID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		query.TokenID = uint(id)
+	}
+	var err error
+	if startTime := values.Get("start_time"); startTime != "" {
+		if query.StartTime, err = time.Parse(time.RFC3339, startTime); err != nil {
+			return nil, err
+		}
+	}
+	if endTime := values.Get("end_time"); endTime != "" {
+		if query.EndTime, err = time.Parse(time.RFC3339, endTime); err != nil {
+			return nil, err
+		}
+	}
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	query.PageSize, _ = strconv.Atoi(values.Get("page_size"))
+	return query, nil
+}
@@ -20,6 +20,7 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
@@ -239,3 +240,88 @@ func (c *CloudManager) DeleteAPIManager(w http.ResponseWriter, r *http.Request)
 	}
 	httputil.ReturnSuccess(r, w, nil)
 }
+
+//CreateScopedToken creates a token which is limited to a tenant or an application
+// swagger:operation POST /cloud/auth/{eid}/tokens cloud createScopedToken
+//
+// create scoped token
+//
+// ---
+// consumes:
+// - application/json
+// - application/x-protobuf
+//
+// produces:
+// - application/json
+// - application/xml
+//
+// responses:
+//   default:
+//     schema:
+//       "$ref": "#/responses/commandResponse"
+//     description: 统一返回格式
+func (c *CloudManager) CreateScopedToken(w http.ResponseWriter, r *http.Request) {
+	var st api_model.CreateScopedToken
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &st.Body, nil); !ok {
+		return
+	}
+	st.EID = chi.URLParam(r, "eid")
+	token, err := handler.GetCloudManager().CreateScopedToken(&st)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, token)
+}
+
+//ListScopedTokens lists the scoped tokens of the enterprise
+// swagger:operation GET /cloud/auth/{eid}/tokens cloud listScopedTokens
+//
+// list scoped tokens
+//
+// ---
+// produces:
+// - application/json
+// - application/xml
+//
+// responses:
+//   default:
+//     schema:
+//       "$ref": "#/responses/commandResponse"
+//     description: 统一返回格式
+func (c *CloudManager) ListScopedTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := handler.GetCloudManager().ListScopedTokens(chi.URLParam(r, "eid"))
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, tokens)
+}
+
+//RevokeScopedToken revokes the scoped token
+// swagger:operation DELETE /cloud/auth/{eid}/tokens/{token_id} cloud revokeScopedToken
+//
+// revoke scoped token
+//
+// ---
+// produces:
+// - application/json
+// - application/xml
+//
+// responses:
+//   default:
+//     schema:
+//       "$ref": "#/responses/commandResponse"
+//     description: 统一返回格式
+func (c *CloudManager) RevokeScopedToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "token_id"), 10, 64)
+	if err != nil {
+		httputil.ReturnError(r, w, 400, "token id should be a number")
+		return
+	}
+	if err := handler.GetCloudManager().RevokeScopedToken(chi.URLParam(r, "eid"), uint(id)); err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
@@ -20,10 +20,14 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	httputil "github.com/goodrain/rainbond/util/http"
 
@@ -34,6 +38,9 @@ import (
 	"github.com/goodrain/rainbond/api/middleware"
 	api_model "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/api/proxy"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	eventdb "github.com/goodrain/rainbond/eventlog/db"
 )
 
 //EventLogStruct eventlog struct
@@ -57,6 +64,150 @@ func (e *EventLogStruct) HistoryLogs(w http.ResponseWriter, r *http.Request) {
 	e.EventlogServerProxy.Proxy(w, r)
 }
 
+//SearchLogs searches the indexed component logs
+//proxy
+func (e *EventLogStruct) SearchLogs(w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v2/tenants/{tenant_name}/services/{service_alias}/logs/search v2 searchLogs
+	//
+	// 搜索应用历史日志
+	//
+	// search the component logs by the full-text query and time range,
+	// the query parameters are q, from, to, pod, page and page_size.
+	//
+	// ---
+	// produces:
+	// - application/json
+	// - application/xml
+	//
+	// responses:
+	//   default:
+	//     schema:
+	//       "$ref": "#/responses/commandResponse"
+	//     description: 统一返回格式
+	service := r.Context().Value(middleware.ContextKey("service")).(*dbmodel.TenantServices)
+	query := r.URL.Query()
+	if podName := query.Get("pod"); podName != "" {
+		containerIDs, err := handler.GetEventHandler().GetPodContainerIDs(service, podName)
+		if err != nil {
+			httputil.ReturnError(r, w, 404, fmt.Sprintf("get containers of pod %s: %v", podName, err))
+			return
+		}
+		if len(containerIDs) == 0 {
+			httputil.ReturnList(r, w, 0, 1, nil)
+			return
+		}
+		query.Del("pod")
+		query["container_id"] = containerIDs
+		r.URL.RawQuery = query.Encode()
+	}
+	name, _ := handler.GetEventHandler().GetLogInstance(service.ServiceID)
+	if name != "" {
+		r = r.WithContext(context.WithValue(r.Context(), proxy.ContextKey("host_id"), name))
+	}
+	serviceAlias := r.Context().Value(middleware.ContextKey("service_alias")).(string)
+	r.URL.Path = strings.Replace(r.URL.Path, serviceAlias, service.ServiceID, 1)
+	r.URL.Path = strings.Replace(r.URL.Path, "/v2/", "/", 1)
+	e.EventlogServerProxy.Proxy(w, r)
+}
+
+//HTTPRuleAccessLogs searches the gateway access logs of the http rule
+//proxy
+func (e *EventLogStruct) HTTPRuleAccessLogs(w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v2/tenants/{tenant_name}/http-rules/{rule_id}/access-logs v2 httpRuleAccessLogs
+	//
+	// 搜索网关访问日志
+	//
+	// search the gateway access logs of the http rule,
+	// the query parameters are status(such as 502 or 5xx), path, client_ip, from, to, page and page_size.
+	// the logs are sorted by time desc, and more is true if there are more logs after the page.
+	//
+	// ---
+	// produces:
+	// - application/json
+	// - application/xml
+	//
+	// responses:
+	//   default:
+	//     schema:
+	//       "$ref": "#/responses/commandResponse"
+	//     description: 统一返回格式
+	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
+	ruleID := chi.URLParam(r, "rule_id")
+	rule, err := db.GetManager().HTTPRuleDao().GetHTTPRuleByID(ruleID)
+	if err != nil {
+		httputil.ReturnError(r, w, 500, fmt.Sprintf("get http rule %s: %v", ruleID, err))
+		return
+	}
+	if rule == nil || rule.UUID == "" {
+		httputil.ReturnError(r, w, 404, fmt.Sprintf("http rule %s not found", ruleID))
+		return
+	}
+	service, err := db.GetManager().TenantServiceDao().GetServiceByID(rule.ServiceID)
+	if err != nil || service.TenantID != tenantID {
+		httputil.ReturnError(r, w, 404, fmt.Sprintf("http rule %s not found", ruleID))
+		return
+	}
+	query := r.URL.Query()
+	search := &eventdb.AccessLogQuery{TenantID: tenantID}
+	search.Page, _ = strconv.Atoi(query.Get("page"))
+	search.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+	if err := search.Normalize(); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	// the access logs are shipped to any eventlog instance,
+	// so the newest logs till the page are searched in all instances and merged
+	query.Set("rule_id", ruleID)
+	query.Set("page", "1")
+	query.Set("page_size", strconv.Itoa(search.Page*search.PageSize))
+	uri := fmt.Sprintf("/tenants/%s/access-logs?%s", tenantID, query.Encode())
+	results, err := searchAccessLogs(e.EventlogServerProxy.Endpoints(), uri)
+	if err != nil {
+		httputil.ReturnError(r, w, 502, fmt.Sprintf("search access logs: %v", err))
+		return
+	}
+	httputil.ReturnSuccess(r, w, eventdb.MergeAccessLogResults(search, results))
+}
+
+var accessLogClient = &http.Client{Timeout: 10 * time.Second}
+
+//searchAccessLogs searches the access logs in all eventlog instances, it fails if any instance fails
+func searchAccessLogs(endpoints []string, uri string) ([]*eventdb.AccessLogResult, error) {
+	results := make([]*eventdb.AccessLogResult, len(endpoints))
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			res, err := accessLogClient.Get(endpoint + uri)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer res.Body.Close()
+			result := &eventdb.AccessLogResult{}
+			body := httputil.ResponseBody{Bean: result}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				errs[i] = fmt.Errorf("decode the response of %s: %v", endpoint, err)
+				return
+			}
+			if res.StatusCode != http.StatusOK {
+				errs[i] = fmt.Errorf("%s responds %s: %s", endpoint, res.Status, body.Msg)
+				return
+			}
+			results[i] = result
+		}(i, endpoint)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
 //LogList GetLogList
 func (e *EventLogStruct) LogList(w http.ResponseWriter, r *http.Request) {
 	// swagger:operation GET  /v2/tenants/{tenant_name}/services/{service_alias}/log-file v2 logList
@@ -0,0 +1,58 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	eventdb "github.com/goodrain/rainbond/eventlog/db"
+	httputil "github.com/goodrain/rainbond/util/http"
+)
+
+func TestSearchAccessLogs(t *testing.T) {
+	newInstance := func(result *eventdb.AccessLogResult) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page_size") != "4" {
+				httputil.ReturnError(r, w, 400, "unexpected page size")
+				return
+			}
+			httputil.ReturnSuccess(r, w, result)
+		}))
+	}
+	now := time.Now()
+	a := newInstance(&eventdb.AccessLogResult{Logs: []*eventdb.AccessLog{{Time: now, Path: "/a"}}})
+	defer a.Close()
+	b := newInstance(&eventdb.AccessLogResult{Logs: []*eventdb.AccessLog{{Time: now.Add(time.Second), Path: "/b"}}, More: true})
+	defer b.Close()
+
+	results, err := searchAccessLogs([]string{a.URL, b.URL}, "/tenants/foo/access-logs?page=1&page_size=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := eventdb.MergeAccessLogResults(&eventdb.AccessLogQuery{Page: 1, PageSize: 4}, results)
+	if len(merged.Logs) != 2 || merged.Logs[0].Path != "/b" || !merged.More {
+		t.Errorf("expected the logs of all instances, got %+v", merged)
+	}
+	if _, err := searchAccessLogs([]string{a.URL, b.URL}, "/tenants/foo/access-logs?page=1&page_size=1"); err == nil {
+		t.Errorf("expected an error if any instance fails")
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/goodrain/rainbond/api/middleware"
 	api_model "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/cmd/api/option"
+	dbmodel "github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/mq/client"
 	httputil "github.com/goodrain/rainbond/util/http"
 )
@@ -68,6 +69,14 @@ func validateDomain(domain string) []string {
 	return errs
 }
 
+func validateBackendProtocol(protocol string) bool {
+	switch strings.ToUpper(protocol) {
+	case "", "HTTP", "HTTPS", "GRPC", "GRPCS":
+		return true
+	}
+	return false
+}
+
 func (g *GatewayStruct) addHTTPRule(w http.ResponseWriter, r *http.Request) {
 	var req api_model.AddHTTPRuleStruct
 	ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil)
@@ -93,11 +102,20 @@ func (g *GatewayStruct) addHTTPRule(w http.ResponseWriter, r *http.Request) {
 		logrus.Debugf("Invalid domain: %s", strings.Join(errs, ";"))
 		values["domain"] = []string{"The domain field is invalid"}
 	}
+	if !validateBackendProtocol(req.BackendProtocol) {
+		values["backend_protocol"] = []string{"The backend_protocol field should be one of HTTP, HTTPS, GRPC and GRPCS"}
+	}
 	if len(values) != 0 {
 		httputil.ReturnValidationError(r, w, values)
 		return
 	}
 
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{Domains: []string{req.Domain}}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
+
 	h := handler.GetGatewayHandler()
 	err := h.AddHTTPRule(&req)
 	if err != nil {
@@ -141,6 +159,9 @@ func (g *GatewayStruct) updateHTTPRule(w http.ResponseWriter, r *http.Request) {
 		logrus.Debugf("Invalid domain: %s", strings.Join(errs, ";"))
 		values["domain"] = []string{"The domain field is invalid"}
 	}
+	if !validateBackendProtocol(req.BackendProtocol) {
+		values["backend_protocol"] = []string{"The backend_protocol field should be one of HTTP, HTTPS, GRPC and GRPCS"}
+	}
 	if len(values) != 0 {
 		httputil.ReturnValidationError(r, w, values)
 		return
@@ -227,6 +248,11 @@ func (g *GatewayStruct) AddTCPRule(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnValidationError(r, w, values)
 		return
 	}
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{TCPPorts: 1}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 	err := h.AddTCPRule(&req)
 	if err != nil {
 		httputil.ReturnError(r, w, 500, fmt.Sprintf("Unexpected error occorred while "+
@@ -352,6 +378,56 @@ func (g *GatewayStruct) updCertificate(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, nil)
 }
 
+// ErrorPages is used to upload or delete the custom error pages of http rules.
+func (g *GatewayStruct) ErrorPages(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		g.updErrorPages(w, r)
+	case "DELETE":
+		g.deleteErrorPages(w, r)
+	}
+}
+
+func (g *GatewayStruct) updErrorPages(w http.ResponseWriter, r *http.Request) {
+	var req api_model.ErrorPagesStruct
+	ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil)
+	if !ok {
+		return
+	}
+	if len(req.Pages) == 0 {
+		httputil.ReturnError(r, w, 400, "pages can not be empty")
+		return
+	}
+	for key := range req.Pages {
+		switch key {
+		case "404", "502", "503", "504", "maintenance":
+		default:
+			httputil.ReturnError(r, w, 400, fmt.Sprintf("unsupported error page: %s", key))
+			return
+		}
+	}
+	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
+	if err := handler.GetGatewayHandler().UpdateErrorPages(tenantID, &req); err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, "success")
+}
+
+func (g *GatewayStruct) deleteErrorPages(w http.ResponseWriter, r *http.Request) {
+	var req api_model.ErrorPagesStruct
+	ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil)
+	if !ok {
+		return
+	}
+	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
+	if err := handler.GetGatewayHandler().DeleteErrorPages(tenantID, req.Name); err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, "success")
+}
+
 //GetGatewayIPs get gateway ips
 func GetGatewayIPs(w http.ResponseWriter, r *http.Request) {
 	ips := handler.GetGatewayHandler().GetGatewayIPs()
@@ -0,0 +1,233 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodrain/rainbond/api/handler"
+	"github.com/goodrain/rainbond/api/middleware"
+	api_model "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/proxy"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	httputil "github.com/goodrain/rainbond/util/http"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// GetLogRetentionPolicy returns the log retention policy of the tenant, or the component if it is a component route.
+func GetLogRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, serviceID := logRetentionTarget(r)
+	policy, err := db.GetManager().LogRetentionPolicyDao().GetPolicy(tenantID, serviceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// the default retention is used
+			httputil.ReturnSuccess(r, w, &dbmodel.LogRetentionPolicy{TenantID: tenantID, ServiceID: serviceID})
+			return
+		}
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, policy)
+}
+
+// UpdateLogRetentionPolicy creates or updates the log retention policy of the tenant or the component.
+func UpdateLogRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	var req api_model.LogRetentionPolicy
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	tenantID, serviceID := logRetentionTarget(r)
+	dao := db.GetManager().LogRetentionPolicyDao()
+	policy, err := dao.GetPolicy(tenantID, serviceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	if err == gorm.ErrRecordNotFound {
+		policy = &dbmodel.LogRetentionPolicy{TenantID: tenantID, ServiceID: serviceID}
+	}
+	policy.EventLogDays = req.EventLogDays
+	policy.ContainerLogDays = req.ContainerLogDays
+	policy.Archive = req.Archive
+	if policy.ID == 0 {
+		err = dao.AddModel(policy)
+	} else {
+		err = dao.UpdateModel(policy)
+	}
+	if err != nil {
+		logrus.Errorf("save log retention policy of %s/%s: %v", tenantID, serviceID, err)
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, policy)
+}
+
+// DeleteLogRetentionPolicy deletes the log retention policy, the policy of the tenant
+// or the default retention is used after that.
+func DeleteLogRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, serviceID := logRetentionTarget(r)
+	if err := db.GetManager().LogRetentionPolicyDao().DeletePolicy(tenantID, serviceID); err != nil {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+func logRetentionTarget(r *http.Request) (string, string) {
+	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
+	serviceID, _ := r.Context().Value(middleware.ContextKey("service_id")).(string)
+	return tenantID, serviceID
+}
+
+// ListLogArchives lists the archived logs of the tenant.
+func ListLogArchives(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	query := &dbmodel.LogArchiveQuery{
+		TenantID: r.Context().Value(middleware.ContextKey("tenant_id")).(string),
+		Kind:     values.Get("kind"),
+	}
+	if alias := values.Get("service_alias"); alias != "" {
+		service, err := db.GetManager().TenantServiceDao().GetServiceByTenantIDAndServiceAlias(query.TenantID, alias)
+		if err != nil {
+			httputil.ReturnError(r, w, 404, "component not found")
+			return
+		}
+		query.ServiceID = service.ServiceID
+	}
+	var err error
+	if startTime := values.Get("start_time"); startTime != "" {
+		if query.StartTime, err = time.Parse(time.RFC3339, startTime); err != nil {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
+	}
+	if endTime := values.Get("end_time"); endTime != "" {
+		if query.EndTime, err = time.Parse(time.RFC3339, endTime); err != nil {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
+	}
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	query.PageSize, _ = strconv.Atoi(values.Get("page_size"))
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = 10
+	}
+	archives, total, err := db.GetManager().LogArchiveDao().ListArchives(query)
+	if err != nil {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnList(r, w, int(total), query.Page, archives)
+}
+
+//RestoreLogArchives restores the archived logs in the time range for viewing, the restored logs
+//can be viewed by the log file and event log apis until they are cleaned again.
+//The archives are restored by the eventlog instances which made them, so the request is sent to each of them.
+func (e *EventLogStruct) RestoreLogArchives(w http.ResponseWriter, r *http.Request) {
+	var req api_model.RestoreLogArchivesReq
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+		return
+	}
+	query := &dbmodel.LogArchiveQuery{
+		TenantID:  r.Context().Value(middleware.ContextKey("tenant_id")).(string),
+		Kind:      req.Kind,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+	if req.ServiceAlias != "" {
+		service, err := db.GetManager().TenantServiceDao().GetServiceByTenantIDAndServiceAlias(query.TenantID, req.ServiceAlias)
+		if err != nil {
+			httputil.ReturnError(r, w, 404, "component not found")
+			return
+		}
+		query.ServiceID = service.ServiceID
+	}
+	archives, _, err := db.GetManager().LogArchiveDao().ListArchives(query)
+	if err != nil {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	hosts := make(map[string]struct{})
+	for _, archive := range archives {
+		hosts[archive.HostID] = struct{}{}
+	}
+	body := map[string]interface{}{
+		"tenant_id":  query.TenantID,
+		"service_id": query.ServiceID,
+		"kind":       req.Kind,
+		"start_time": req.StartTime,
+		"end_time":   req.EndTime,
+	}
+	var restored []*dbmodel.LogArchive
+	for hostID := range hosts {
+		target := hostID
+		if target == "" && query.ServiceID != "" {
+			// the archives made before the host id is recorded are restored by the eventlog instance
+			// which stores the logs of the component
+			target, _ = handler.GetEventHandler().GetLogInstance(query.ServiceID)
+		}
+		body["host_id"] = hostID
+		list, err := e.restoreLogArchives(r.Context(), target, body)
+		if err != nil {
+			logrus.Errorf("restore log archives of %s in eventlog instance %s: %v", query.TenantID, target, err)
+			httputil.ReturnError(r, w, 502, fmt.Sprintf("restore log archives: %v", err))
+			return
+		}
+		restored = append(restored, list...)
+	}
+	httputil.ReturnSuccess(r, w, restored)
+}
+
+//restoreLogArchives sends the restore request to the eventlog instance of the host id, any instance is used if it is empty
+func (e *EventLogStruct) restoreLogArchives(ctx context.Context, hostID string, body map[string]interface{}) ([]*dbmodel.LogArchive, error) {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, "/log-archives/restore", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hostID != "" {
+		ctx = context.WithValue(ctx, proxy.ContextKey("host_id"), hostID)
+	}
+	res, err := e.EventlogServerProxy.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var archives []*dbmodel.LogArchive
+	resBody := httputil.ResponseBody{List: &archives}
+	if err := json.NewDecoder(res.Body).Decode(&resBody); err != nil {
+		return nil, fmt.Errorf("decode the response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("eventlog responds %s: %s", res.Status, resBody.Msg)
+	}
+	return archives, nil
+}
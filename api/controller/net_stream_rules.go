@@ -20,7 +20,9 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi"
 	"github.com/goodrain/rainbond/api/handler"
 	"github.com/goodrain/rainbond/api/middleware"
 	api_model "github.com/goodrain/rainbond/api/model"
@@ -91,9 +93,9 @@ func (t *TenantStruct) SetDownStreamRule(w http.ResponseWriter, r *http.Request)
 //     description: 统一返回格式
 func (t *TenantStruct) GetDownStreamRule(w http.ResponseWriter, r *http.Request) {
 	serviceAlias := r.Context().Value(middleware.ContextKey("service_alias")).(string)
-	destServiceAlias := r.Context().Value(middleware.ContextKey("dest_service_alias")).(string)
+	destServiceAlias := chi.URLParam(r, "dest_service_alias")
 	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
-	port := r.Context().Value(middleware.ContextKey("port")).(string)
+	port := chi.URLParam(r, "port")
 
 	nrs, err := handler.GetRulesManager().GetDownStreamNetRule(
 		tenantID,
@@ -141,8 +143,12 @@ func (t *TenantStruct) UpdateDownStreamRule(w http.ResponseWriter, r *http.Reque
 	tenantName := r.Context().Value(middleware.ContextKey("tenant_name")).(string)
 	serviceAlias := r.Context().Value(middleware.ContextKey("service_alias")).(string)
 	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
-	destServiceAlias := r.Context().Value(middleware.ContextKey("dest_service_alias")).(string)
-	port := r.Context().Value(middleware.ContextKey("tenant_id")).(int)
+	destServiceAlias := chi.URLParam(r, "dest_service_alias")
+	port, err := strconv.Atoi(chi.URLParam(r, "port"))
+	if err != nil {
+		httputil.ReturnError(r, w, 400, "port must be a number")
+		return
+	}
 
 	urs.DestServiceAlias = destServiceAlias
 	urs.Port = port
@@ -42,6 +42,7 @@ import (
 	"github.com/goodrain/rainbond/util/fuzzy"
 	validator "github.com/goodrain/rainbond/util/govalidator"
 	httputil "github.com/goodrain/rainbond/util/http"
+	k8sutil "github.com/goodrain/rainbond/util/k8s"
 	"github.com/goodrain/rainbond/worker/client"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
@@ -677,6 +678,20 @@ func (t *TenantStruct) CreateService(w http.ResponseWriter, r *http.Request) {
 
 	tenantID := r.Context().Value(middleware.ContextKey("tenant_id")).(string)
 	ss.TenantID = tenantID
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	quotaReq := handler.QuotaRequest{
+		Components: 1,
+		Replicas:   ss.Replicas,
+		CPU:        ss.ContainerCPU * ss.Replicas,
+		Memory:     ss.ContainerMemory * ss.Replicas,
+	}
+	for _, v := range ss.VolumesInfo {
+		quotaReq.Storage += handler.VolumeStorageRequest(v.VolumeType, v.VolumeCapacity)
+	}
+	if err := handler.CheckTenantQuota(tenant, quotaReq); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 	if err := handler.GetServiceManager().ServiceCreate(&ss); err != nil {
 		if strings.Contains(err.Error(), "is exist in tenant") {
 			httputil.ReturnError(r, w, 400, fmt.Sprintf("create service error, %v", err))
@@ -878,6 +893,11 @@ func (t *TenantStruct) Label(w http.ResponseWriter, r *http.Request) {
 		if label.LabelValue == "" {
 			values["label_value"] = []string{"The label_value field is required"}
 		}
+		if label.LabelKey == dbmodel.LabelKeyTopologySpread && r.Method != "DELETE" {
+			if _, err := k8sutil.ParseTopologySpread(label.LabelValue); err != nil {
+				values["label_value"] = []string{err.Error()}
+			}
+		}
 	}
 	if len(values) != 0 {
 		httputil.ReturnValidationError(r, w, values)
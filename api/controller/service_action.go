@@ -222,6 +222,12 @@ func (t *TenantStruct) VerticalService(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnResNotEnough(r, w, err.Error())
 		return
 	}
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{
+		CPU: (cpu - service.ContainerCPU) * service.Replicas,
+	}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 
 	verticalTask := &model.VerticalScalingTaskBody{
 		TenantID:        tenantID,
@@ -277,6 +283,13 @@ func (t *TenantStruct) HorizontalService(w http.ResponseWriter, r *http.Request)
 		httputil.ReturnResNotEnough(r, w, err.Error())
 		return
 	}
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{
+		CPU:      service.ContainerCPU * (int(replicas) - service.Replicas),
+		Replicas: int(replicas) - service.Replicas,
+	}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 
 	horizontalTask := &model.HorizontalScalingTaskBody{
 		TenantID:  tenantID,
@@ -651,6 +664,31 @@ func (t *TenantStruct) LimitTenantMemory(w http.ResponseWriter, r *http.Request)
 
 }
 
+//UpdateTenantQuota updates the quotas of the tenant
+func (t *TenantStruct) UpdateTenantQuota(w http.ResponseWriter, r *http.Request) {
+	var quota api_model.TenantQuota
+	if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &quota, nil); !ok {
+		return
+	}
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	if err := handler.GetTenantManager().UpdateTenantQuota(tenant, &quota); err != nil {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+//GetTenantQuota returns the quotas and usages of the tenant
+func (t *TenantStruct) GetTenantQuota(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	status, err := handler.GetTenantManager().GetTenantQuotaStatus(tenant)
+	if err != nil {
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
 //SourcesInfo -
 type SourcesInfo struct {
 	TenantID        string `json:"tenant_id"`
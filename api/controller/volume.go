@@ -173,6 +173,13 @@ func (t *TenantStruct) AddVolume(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnError(r, w, 400, "volume path is invalid,must begin with /")
 		return
 	}
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{
+		Storage: handler.VolumeStorageRequest(tsv.VolumeType, tsv.VolumeCapacity),
+	}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 	if err := handler.GetServiceManager().VolumnVar(tsv, tenantID, "", "add"); err != nil {
 		err.Handle(r, w)
 		return
@@ -379,6 +386,13 @@ func AddVolume(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnError(r, w, 400, "volume path is invalid,must begin with /")
 		return
 	}
+	tenant := r.Context().Value(middleware.ContextKey("tenant")).(*dbmodel.Tenants)
+	if err := handler.CheckTenantQuota(tenant, handler.QuotaRequest{
+		Storage: handler.VolumeStorageRequest(tsv.VolumeType, tsv.VolumeCapacity),
+	}); err != nil {
+		httputil.ReturnResNotEnough(r, w, err.Error())
+		return
+	}
 	if err := handler.GetServiceManager().VolumnVar(tsv, tenantID, avs.Body.FileContent, "add"); err != nil {
 		err.Handle(r, w)
 		return
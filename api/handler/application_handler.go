@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"strconv"
 	"time"
 
@@ -117,12 +119,72 @@ func (a *ApplicationAction) UpdateApp(srcApp *dbmodel.Application, req model.Upd
 		}
 		srcApp.GovernanceMode = req.GovernanceMode
 	}
+	if req.MTLSMode != "" {
+		if !dbmodel.IsMTLSModeValid(req.MTLSMode) {
+			return nil, bcode.NewBadRequest(fmt.Sprintf("mtls mode '%s' is invalid", req.MTLSMode))
+		}
+		srcApp.MTLSMode = req.MTLSMode
+		if req.MTLSMode == dbmodel.MTLSModeDisable {
+			srcApp.MTLSMode = ""
+		}
+	}
+	if req.Tracing != nil {
+		if err := setAppTracing(srcApp, req.Tracing); err != nil {
+			return nil, err
+		}
+	}
+	var isolationChanged bool
+	if req.DisableNetworkIsolation != nil && *req.DisableNetworkIsolation != srcApp.DisableNetworkIsolation {
+		srcApp.DisableNetworkIsolation = *req.DisableNetworkIsolation
+		isolationChanged = true
+	}
 	if err := db.GetManager().ApplicationDao().UpdateModel(srcApp); err != nil {
 		return nil, err
 	}
+	if isolationChanged {
+		services, err := db.GetManager().TenantServiceDao().ListByAppID(srcApp.AppID)
+		if err != nil {
+			return nil, err
+		}
+		var serviceIDs []string
+		for _, svc := range services {
+			serviceIDs = append(serviceIDs, svc.ServiceID)
+		}
+		if err := GetServiceManager().RefreshNetworkPolicy(serviceIDs...); err != nil {
+			logrus.Warningf("refresh network policies of app %s: %v", srcApp.AppID, err)
+		}
+	}
 	return srcApp, nil
 }
 
+func setAppTracing(app *dbmodel.Application, tracing *dbmodel.TracingConfig) error {
+	if tracing.Collector == "" {
+		app.TracingProvider, app.TracingCollector, app.TracingSamplingRate, app.TracingTags = "", "", 0, ""
+		return nil
+	}
+	if tracing.Provider == "" {
+		tracing.Provider = dbmodel.TracingProviderZipkin
+	}
+	if tracing.Provider != dbmodel.TracingProviderZipkin && tracing.Provider != dbmodel.TracingProviderJaeger {
+		return bcode.NewBadRequest(fmt.Sprintf("tracing provider '%s' is invalid", tracing.Provider))
+	}
+	if _, port, err := net.SplitHostPort(tracing.Collector); err != nil || port == "" {
+		return bcode.NewBadRequest(fmt.Sprintf("tracing collector '%s' should be host:port", tracing.Collector))
+	}
+	if tracing.SamplingRate < 0 || tracing.SamplingRate > 100 {
+		return bcode.NewBadRequest("tracing sampling rate should be between 0 and 100")
+	}
+	var tags []byte
+	if len(tracing.Tags) > 0 {
+		tags, _ = json.Marshal(tracing.Tags)
+	}
+	app.TracingProvider = tracing.Provider
+	app.TracingCollector = tracing.Collector
+	app.TracingSamplingRate = tracing.SamplingRate
+	app.TracingTags = string(tags)
+	return nil
+}
+
 // ListApps -
 func (a *ApplicationAction) ListApps(tenantID, appName string, page, pageSize int) (*model.ListAppResponse, error) {
 	var resp model.ListAppResponse
@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -39,6 +40,7 @@ import (
 	"github.com/goodrain/rainbond/cmd/api/option"
 	"github.com/goodrain/rainbond/db"
 	dbmodel "github.com/goodrain/rainbond/db/model"
+	core_util "github.com/goodrain/rainbond/util"
 )
 
 //CloudAction  cloud action struct
@@ -141,6 +143,82 @@ func (c *CloudAction) UpdateTokenTime(eid string, vd int) *util.APIHandleError {
 	return nil
 }
 
+//CreateScopedToken creates a token which is limited to a tenant or an application
+func (c *CloudAction) CreateScopedToken(st *api_model.CreateScopedToken) (*dbmodel.RegionUserInfo, *util.APIHandleError) {
+	if st.Body.ValidityPeriod <= int(time.Now().Unix()) {
+		return nil, util.CreateAPIHandleError(400, fmt.Errorf("validity period should be later than now"))
+	}
+	var methods []string
+	for _, m := range st.Body.AllowedMethods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		switch m {
+		case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+			methods = append(methods, m)
+		default:
+			return nil, util.CreateAPIHandleError(400, fmt.Errorf("unsupported http method %s", m))
+		}
+	}
+	tenant, err := db.GetManager().TenantDao().GetTenantIDByName(st.Body.TenantName)
+	if err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("get tenant", err)
+	}
+	if st.Body.AppID != "" {
+		app, err := db.GetManager().ApplicationDao().GetAppByID(st.Body.AppID)
+		if err != nil {
+			return nil, util.CreateAPIHandleErrorFromDBError("get app", err)
+		}
+		if app.TenantID != tenant.UUID {
+			return nil, util.CreateAPIHandleError(400, fmt.Errorf("app %s does not belong to tenant %s", st.Body.AppID, st.Body.TenantName))
+		}
+	}
+	fullStr := fmt.Sprintf("%s-%s-%s-%s-%d-%s", st.EID, c.RegionTag, tenant.UUID, st.Body.AppID, st.Body.ValidityPeriod, core_util.NewUUID())
+	h := md5.New()
+	h.Write([]byte(fullStr))
+	rui := &dbmodel.RegionUserInfo{
+		EID:            st.EID,
+		RegionTag:      c.RegionTag,
+		APIRange:       dbmodel.SCOPED,
+		ValidityPeriod: st.Body.ValidityPeriod,
+		Token:          hex.EncodeToString(h.Sum(nil)),
+		Name:           st.Body.Name,
+		TenantID:       tenant.UUID,
+		TenantName:     tenant.Name,
+		AppID:          st.Body.AppID,
+		AllowedMethods: strings.Join(methods, ","),
+	}
+	if err := db.GetManager().RegionUserInfoDao().AddModel(rui); err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("create scoped token", err)
+	}
+	GetTokenIdenHandler().AddTokenIntoMap(rui)
+	return rui, nil
+}
+
+//ListScopedTokens lists the scoped tokens of the enterprise
+func (c *CloudAction) ListScopedTokens(eid string) ([]*dbmodel.RegionUserInfo, *util.APIHandleError) {
+	tokens, err := db.GetManager().RegionUserInfoDao().ListScopedTokens(eid)
+	if err != nil {
+		return nil, util.CreateAPIHandleErrorFromDBError("list scoped tokens", err)
+	}
+	// the token can only be seen when it is created
+	for _, t := range tokens {
+		t.Token = ""
+	}
+	return tokens, nil
+}
+
+//RevokeScopedToken revokes the scoped token, it can not be used any more
+func (c *CloudAction) RevokeScopedToken(eid string, id uint) *util.APIHandleError {
+	token, err := db.GetManager().RegionUserInfoDao().GetScopedToken(eid, id)
+	if err != nil {
+		return util.CreateAPIHandleErrorFromDBError("get scoped token", err)
+	}
+	if err := db.GetManager().RegionUserInfoDao().DeleteScopedToken(eid, id); err != nil {
+		return util.CreateAPIHandleErrorFromDBError("delete scoped token", err)
+	}
+	GetTokenIdenHandler().RemoveTokenFromMap(token.Token)
+	return nil
+}
+
 //CertDispatcher Cert
 func (c *CloudAction) CertDispatcher(gt *api_model.GetUserToken) ([]byte, []byte, error) {
 	cert, err := analystCaKey(c.CAPath, "ca")
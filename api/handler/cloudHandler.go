@@ -29,4 +29,7 @@ type CloudHandler interface {
 	TokenDispatcher(gt *api_model.GetUserToken) (*api_model.TokenInfo, *util.APIHandleError)
 	GetTokenInfo(eid string) (*dbmodel.RegionUserInfo, *util.APIHandleError)
 	UpdateTokenTime(eid string, vd int) *util.APIHandleError
+	CreateScopedToken(st *api_model.CreateScopedToken) (*dbmodel.RegionUserInfo, *util.APIHandleError)
+	ListScopedTokens(eid string) ([]*dbmodel.RegionUserInfo, *util.APIHandleError)
+	RevokeScopedToken(eid string, id uint) *util.APIHandleError
 }
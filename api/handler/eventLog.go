@@ -27,6 +27,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/goodrain/rainbond/api/model"
@@ -35,18 +36,22 @@ import (
 	dbmodel "github.com/goodrain/rainbond/db/model"
 	eventdb "github.com/goodrain/rainbond/eventlog/db"
 	"github.com/goodrain/rainbond/util/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 //LogAction  log action struct
 type LogAction struct {
-	EtcdCli *clientv3.Client
-	eventdb *eventdb.EventFilePlugin
+	EtcdCli    *clientv3.Client
+	kubeClient kubernetes.Interface
+	eventdb    *eventdb.EventFilePlugin
 }
 
 //CreateLogManager get log manager
-func CreateLogManager(cli *clientv3.Client) *LogAction {
+func CreateLogManager(cli *clientv3.Client, kubeClient kubernetes.Interface) *LogAction {
 	return &LogAction{
-		EtcdCli: cli,
+		EtcdCli:    cli,
+		kubeClient: kubeClient,
 		eventdb: &eventdb.EventFilePlugin{
 			HomePath: "/grdata/logs/",
 		},
@@ -75,6 +80,10 @@ func (l *LogAction) GetLogList(serviceAlias string) ([]*model.HistoryLogFile, er
 
 	var logFiles []*model.HistoryLogFile
 	for _, file := range fileList {
+		//skip the log index
+		if file.IsDir() {
+			continue
+		}
 		logfile := &model.HistoryLogFile{
 			Filename:     file.Name(),
 			RelativePath: path.Join("logs", serviceAlias, file.Name()),
@@ -108,6 +117,25 @@ func (l *LogAction) GetLogInstance(serviceID string) (string, error) {
 	return "", nil
 }
 
+//GetPodContainerIDs returns the ids of the containers of the component pod
+func (l *LogAction) GetPodContainerIDs(service *dbmodel.TenantServices, podName string) ([]string, error) {
+	pod, err := l.kubeClient.CoreV1().Pods(service.TenantID).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pod.Labels["service_id"] != service.ServiceID {
+		return nil, fmt.Errorf("pod %s does not belong to the component", podName)
+	}
+	var ids []string
+	for _, status := range pod.Status.ContainerStatuses {
+		//the container id is in the format of <runtime>://<id>
+		if index := strings.Index(status.ContainerID, "://"); index >= 0 {
+			ids = append(ids, status.ContainerID[index+3:])
+		}
+	}
+	return ids, nil
+}
+
 //GetLevelLog get event log
 func (l *LogAction) GetLevelLog(eventID string, level string) (*api_model.DataLog, error) {
 	re, err := l.eventdb.GetMessages(eventID, level, 0)
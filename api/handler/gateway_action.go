@@ -29,27 +29,34 @@ import (
 
 	"github.com/coreos/etcd/clientv3"
 	apimodel "github.com/goodrain/rainbond/api/model"
+	apiutil "github.com/goodrain/rainbond/api/util"
 	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/mq/client"
 	"github.com/goodrain/rainbond/util"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // GatewayAction -
 type GatewayAction struct {
-	dbmanager db.Manager
-	mqclient  client.MQClient
-	etcdCli   *clientv3.Client
+	dbmanager  db.Manager
+	mqclient   client.MQClient
+	etcdCli    *clientv3.Client
+	kubeClient kubernetes.Interface
 }
 
-//CreateGatewayManager creates gateway manager.
-func CreateGatewayManager(dbmanager db.Manager, mqclient client.MQClient, etcdCli *clientv3.Client) *GatewayAction {
+// CreateGatewayManager creates gateway manager.
+func CreateGatewayManager(dbmanager db.Manager, mqclient client.MQClient, etcdCli *clientv3.Client, kubeClient kubernetes.Interface) *GatewayAction {
 	return &GatewayAction{
-		dbmanager: dbmanager,
-		mqclient:  mqclient,
-		etcdCli:   etcdCli,
+		dbmanager:  dbmanager,
+		mqclient:   mqclient,
+		etcdCli:    etcdCli,
+		kubeClient: kubeClient,
 	}
 }
 
@@ -66,11 +73,16 @@ func (g *GatewayAction) AddHTTPRule(req *apimodel.AddHTTPRuleStruct) error {
 			}
 			return req.Path
 		}(),
-		Header:        req.Header,
-		Cookie:        req.Cookie,
-		Weight:        req.Weight,
-		IP:            req.IP,
-		CertificateID: req.CertificateID,
+		Header:                req.Header,
+		Cookie:                req.Cookie,
+		Weight:                req.Weight,
+		IP:                    req.IP,
+		CertificateID:         req.CertificateID,
+		BackendProtocol:       strings.ToUpper(req.BackendProtocol),
+		ProxySSLVerify:        req.ProxySSLVerify,
+		ErrorPages:            req.ErrorPages,
+		Maintenance:           req.Maintenance,
+		MaintenanceRetryAfter: req.MaintenanceRetryAfter,
 	}
 
 	// begin transaction
@@ -200,6 +212,11 @@ func (g *GatewayAction) UpdateHTTPRule(req *apimodel.UpdateHTTPRuleStruct) error
 	rule.Header = req.Header
 	rule.Cookie = req.Cookie
 	rule.Weight = req.Weight
+	rule.BackendProtocol = strings.ToUpper(req.BackendProtocol)
+	rule.ProxySSLVerify = req.ProxySSLVerify
+	rule.ErrorPages = req.ErrorPages
+	rule.Maintenance = req.Maintenance
+	rule.MaintenanceRetryAfter = req.MaintenanceRetryAfter
 	if req.IP != "" {
 		rule.IP = req.IP
 	}
@@ -801,3 +818,67 @@ func (g *GatewayAction) GetGatewayIPs() []IPAndAvailablePort {
 	}
 	return defaultIps
 }
+
+// UpdateErrorPages creates or updates the configmap which contains the custom error pages,
+// the existing configmap which is not error pages is refused.
+func (g *GatewayAction) UpdateErrorPages(namespace string, req *apimodel.ErrorPagesStruct) *apiutil.APIHandleError {
+	data := make(map[string]string, len(req.Pages))
+	for key, page := range req.Pages {
+		data[key+".html"] = page
+	}
+	cmClient := g.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := cmClient.Get(req.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return apiutil.CreateAPIHandleError(500, fmt.Errorf("get configmap %s/%s: %v", namespace, req.Name, err))
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      req.Name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"creator":             "Rainbond",
+					model.ErrorPagesLabel: "true",
+				},
+			},
+			Data: data,
+		}
+		if _, err := cmClient.Create(cm); err != nil {
+			return apiutil.CreateAPIHandleError(500, fmt.Errorf("create configmap %s/%s: %v", namespace, req.Name, err))
+		}
+		return nil
+	}
+	if !isErrorPages(cm) {
+		return apiutil.CreateAPIHandleError(403, fmt.Errorf("configmap %s is not error pages", req.Name))
+	}
+	cm.Data = data
+	if _, err := cmClient.Update(cm); err != nil {
+		return apiutil.CreateAPIHandleError(500, fmt.Errorf("update configmap %s/%s: %v", namespace, req.Name, err))
+	}
+	return nil
+}
+
+// DeleteErrorPages deletes the configmap which contains the custom error pages,
+// the configmap which is not error pages is refused.
+func (g *GatewayAction) DeleteErrorPages(namespace, name string) *apiutil.APIHandleError {
+	cmClient := g.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := cmClient.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return apiutil.CreateAPIHandleError(500, fmt.Errorf("get configmap %s/%s: %v", namespace, name, err))
+	}
+	if !isErrorPages(cm) {
+		return apiutil.CreateAPIHandleError(403, fmt.Errorf("configmap %s is not error pages", name))
+	}
+	err = cmClient.Delete(name, &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &cm.UID}})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return apiutil.CreateAPIHandleError(500, fmt.Errorf("delete configmap %s/%s: %v", namespace, name, err))
+	}
+	return nil
+}
+
+func isErrorPages(cm *corev1.ConfigMap) bool {
+	return cm.Labels[model.ErrorPagesLabel] == "true"
+}
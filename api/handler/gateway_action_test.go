@@ -29,6 +29,9 @@ import (
 	apimodel "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/util"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 var gm *GatewayAction
@@ -46,7 +49,7 @@ func init() {
 	//if err != nil {
 	//	fmt.Printf("create mq client error, %v", err)
 	//}
-	//gm = CreateGatewayManager(cdb.GetManager(), cli, nil)
+	//gm = CreateGatewayManager(cdb.GetManager(), cli, nil, nil)
 }
 func TestSelectAvailablePort(t *testing.T) {
 	t.Log(selectAvailablePort([]int{9000}))         // less than minport
@@ -118,3 +121,31 @@ func TestAddTCPRule(t *testing.T) {
 func TestDeleteHTTPRule(t *testing.T) {
 	gm.DeleteHTTPRule(&apimodel.DeleteHTTPRuleStruct{HTTPRuleID: ""})
 }
+
+func TestErrorPagesRefuseOtherConfigMaps(t *testing.T) {
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name: "config-group", Namespace: "tenant", Labels: map[string]string{"creator": "Rainbond"},
+	}}
+	g := &GatewayAction{kubeClient: fake.NewSimpleClientset(other)}
+	req := &apimodel.ErrorPagesStruct{Name: "config-group", Pages: map[string]string{"404": "not found"}}
+	if err := g.UpdateErrorPages("tenant", req); err == nil || err.Code != 403 {
+		t.Errorf("expected 403 when updating the configmap which is not error pages, got %v", err)
+	}
+	if err := g.DeleteErrorPages("tenant", "config-group"); err == nil || err.Code != 403 {
+		t.Errorf("expected 403 when deleting the configmap which is not error pages, got %v", err)
+	}
+	if _, err := g.kubeClient.CoreV1().ConfigMaps("tenant").Get("config-group", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the configmap is kept, got %v", err)
+	}
+
+	req.Name = "pages"
+	if err := g.UpdateErrorPages("tenant", req); err != nil {
+		t.Fatalf("create error pages: %v", err)
+	}
+	if err := g.UpdateErrorPages("tenant", req); err != nil {
+		t.Errorf("update error pages: %v", err)
+	}
+	if err := g.DeleteErrorPages("tenant", "pages"); err != nil {
+		t.Errorf("delete error pages: %v", err)
+	}
+}
@@ -20,6 +20,7 @@ package handler
 
 import (
 	apimodel "github.com/goodrain/rainbond/api/model"
+	apiutil "github.com/goodrain/rainbond/api/util"
 	dbmodel "github.com/goodrain/rainbond/db/model"
 	"github.com/jinzhu/gorm"
 )
@@ -46,4 +47,6 @@ type GatewayHandler interface {
 	UpdCertificate(req *apimodel.UpdCertificateReq) error
 	GetGatewayIPs() []IPAndAvailablePort
 	ListHTTPRulesByCertID(certID string) ([]*dbmodel.HTTPRule, error)
+	UpdateErrorPages(namespace string, req *apimodel.ErrorPagesStruct) *apiutil.APIHandleError
+	DeleteErrorPages(namespace, name string) *apiutil.APIHandleError
 }
@@ -21,6 +21,7 @@ package handler
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/pquerna/ffjson/ffjson"
@@ -28,6 +29,9 @@ import (
 	"github.com/coreos/etcd/clientv3"
 	api_model "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/api/util"
+	"github.com/goodrain/rainbond/db"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
 
@@ -56,6 +60,10 @@ func (n *NetRulesAction) CreateDownStreamNetRules(
 		Protocol:         rs.Body.Protocol,
 		Rules:            rs.Body.Rules,
 	}
+	specs, apiErr := downStreamNetRulesConfigs(rs)
+	if apiErr != nil {
+		return apiErr
+	}
 	v, err := ffjson.Marshal(sb)
 	if err != nil {
 		logrus.Errorf("mashal etcd value error, %v", err)
@@ -67,9 +75,67 @@ func (n *NetRulesAction) CreateDownStreamNetRules(
 		return util.CreateAPIHandleError(500, err)
 	}
 	//TODO: store mysql
+	for pluginID, spec := range specs {
+		if err := GetServiceManager().SavePluginConfig(rs.Body.Rules.ServiceID, pluginID, spec); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+//downStreamNetRulesConfigs writes the route policies of the rules into the options of the dependency
+//in the mesh plugin configs of the component, the worker applies them to the envoy of the component.
+//The default mesh of the component has no plugin config to keep the rules, so the rules are rejected.
+func downStreamNetRulesConfigs(rs *api_model.SetNetDownStreamRuleStruct) (map[string]*api_model.ResourceSpec, *util.APIHandleError) {
+	rules := rs.Body.Rules
+	if rules == nil {
+		return nil, nil
+	}
+	configs, err := db.GetManager().TenantPluginVersionConfigDao().GetPluginConfigs(rules.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, util.CreateAPIHandleErrorFromDBError("get plugin configs", err)
+	}
+	options := map[string]interface{}{
+		envoyv2.KeyRetryOn:           rules.RetryOn,
+		envoyv2.KeyNumRetries:        strconv.Itoa(rules.NumRetries),
+		envoyv2.KeyPerTryTimeoutMS:   strconv.Itoa(rules.PerTryTimeoutMS),
+		envoyv2.KeyTimeoutMS:         strconv.Itoa(rules.TimeoutMS),
+		envoyv2.KeyFaultDelayPercent: strconv.Itoa(rules.FaultDelayPercent),
+		envoyv2.KeyFaultDelayMS:      strconv.Itoa(rules.FaultDelayMS),
+		envoyv2.KeyFaultAbortPercent: strconv.Itoa(rules.FaultAbortPercent),
+		envoyv2.KeyFaultAbortStatus:  strconv.Itoa(rules.FaultAbortStatus),
+	}
+	specs := make(map[string]*api_model.ResourceSpec)
+	for _, config := range configs {
+		var spec api_model.ResourceSpec
+		if err := ffjson.Unmarshal([]byte(config.ConfigStr), &spec); err != nil {
+			logrus.Warningf("unmarshal config of plugin %s failure %s", config.PluginID, err.Error())
+			continue
+		}
+		var changed bool
+		for _, dep := range spec.BaseServices {
+			if dep.DependServiceAlias != rs.Body.DestServiceAlias || dep.Port != rs.Body.Port {
+				continue
+			}
+			if dep.Options == nil {
+				dep.Options = make(map[string]interface{}, len(options))
+			}
+			for k, v := range options {
+				dep.Options[k] = v
+			}
+			changed = true
+		}
+		if changed {
+			specs[config.PluginID] = &spec
+		}
+	}
+	if len(specs) == 0 {
+		return nil, util.CreateAPIHandleError(400, fmt.Errorf("no mesh plugin of the component depends on %s:%d, the rules only take effect with the mesh plugin",
+			rs.Body.DestServiceAlias, rs.Body.Port))
+	}
+	return specs, nil
+}
+
 //GetDownStreamNetRule GetDownStreamNetRule
 func (n *NetRulesAction) GetDownStreamNetRule(
 	tenantID,
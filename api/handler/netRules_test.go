@@ -24,7 +24,11 @@ import (
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/mock/gomock"
 	api_model "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	"github.com/jinzhu/gorm"
 
 	"testing"
 )
@@ -73,3 +77,21 @@ func TestStoreETCD(t *testing.T) {
 	}
 	fmt.Printf("v is %v\n", string(v.Kvs[0].Value))
 }
+
+func TestDownStreamNetRulesOfDefaultMesh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	manager := db.NewMockManager(ctrl)
+	db.SetTestManager(manager)
+	configDao := dao.NewMockTenantPluginVersionConfigDao(ctrl)
+	manager.EXPECT().TenantPluginVersionConfigDao().Return(configDao).AnyTimes()
+	configDao.EXPECT().GetPluginConfigs("serviceid").Return(nil, gorm.ErrRecordNotFound)
+
+	srs := &api_model.SetNetDownStreamRuleStruct{ServiceAlias: "grtest12"}
+	srs.Body.DestServiceAlias = "grtest34"
+	srs.Body.Port = 6379
+	srs.Body.Rules = &api_model.NetDownStreamRules{ServiceID: "serviceid", NumRetries: 3}
+	if _, err := downStreamNetRulesConfigs(srs); err == nil || err.Code != 400 {
+		t.Fatalf("expected the rules of the default mesh to be rejected, got %v", err)
+	}
+}
@@ -565,13 +565,14 @@ func (s *ServiceAction) ServiceCreate(sc *api_model.ServiceStruct) error {
 
 		for _, volumn := range volumns {
 			v := dbmodel.TenantServiceVolume{
-				ServiceID:  ts.ServiceID,
-				Category:   volumn.Category,
-				VolumeType: volumn.VolumeType,
-				VolumeName: volumn.VolumeName,
-				HostPath:   volumn.HostPath,
-				VolumePath: volumn.VolumePath,
-				IsReadOnly: volumn.IsReadOnly,
+				ServiceID:      ts.ServiceID,
+				Category:       volumn.Category,
+				VolumeType:     volumn.VolumeType,
+				VolumeName:     volumn.VolumeName,
+				HostPath:       volumn.HostPath,
+				VolumePath:     volumn.VolumePath,
+				IsReadOnly:     volumn.IsReadOnly,
+				VolumeCapacity: volumn.VolumeCapacity,
 			}
 			v.ServiceID = ts.ServiceID
 			if volumn.VolumeType == "" {
@@ -996,6 +997,25 @@ func (s *ServiceAction) ServiceDepend(action string, ds *api_model.DependService
 			return err
 		}
 	}
+	// the network policy of the dependency controls who can access it
+	if err := s.RefreshNetworkPolicy(ds.DepServiceID); err != nil {
+		logrus.Warningf("refresh network policy of service %s: %v", ds.DepServiceID, err)
+	}
+	return nil
+}
+
+//RefreshNetworkPolicy sends tasks to regenerate the network policies of the services
+func (s *ServiceAction) RefreshNetworkPolicy(serviceIDs ...string) error {
+	for _, serviceID := range serviceIDs {
+		if err := s.MQClient.SendBuilderTopic(gclient.TaskStruct{
+			TaskType: "refresh_network_policy",
+			TaskBody: map[string]interface{}{"service_id": serviceID},
+			Topic:    gclient.WorkerTopic,
+		}); err != nil {
+			logrus.Errorf("send 'refresh_network_policy' task: %v", err)
+			return err
+		}
+	}
 	return nil
 }
 
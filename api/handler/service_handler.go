@@ -46,6 +46,7 @@ type ServiceHandler interface {
 	GetTenantRes(uuid string) (*api_model.TenantResource, error)
 	CodeCheck(c *api_model.CheckCodeStruct) error
 	ServiceDepend(action string, ds *api_model.DependService) error
+	RefreshNetworkPolicy(serviceIDs ...string) error
 	EnvAttr(action string, at *dbmodel.TenantServiceEnvVar) error
 	PortVar(action string, tenantID, serviceID string, vp *api_model.ServicePorts, oldPort int) error
 	CreatePorts(tenantID, serviceID string, vps *api_model.ServicePorts) error
@@ -71,6 +72,7 @@ type ServiceHandler interface {
 	SetTenantServicePluginRelation(tenantID, serviceID string, pss *api_model.PluginSetStruct) (*dbmodel.TenantServicePluginRelation, *util.APIHandleError)
 	UpdateTenantServicePluginRelation(serviceID string, pss *api_model.PluginSetStruct) (*dbmodel.TenantServicePluginRelation, *util.APIHandleError)
 	UpdateVersionEnv(uve *api_model.SetVersionEnv) *util.APIHandleError
+	SavePluginConfig(serviceID, pluginID string, config *api_model.ResourceSpec) *util.APIHandleError
 	DeletePluginConfig(serviceID, pluginID string) *util.APIHandleError
 	ServiceCheck(*api_model.ServiceCheckStruct) (string, string, *util.APIHandleError)
 	GetServiceCheckInfo(uuid string) (*exector.ServiceCheckResult, *util.APIHandleError)
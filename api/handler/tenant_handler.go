@@ -46,4 +46,6 @@ type TenantHandler interface {
 	UpdateTenant(*dbmodel.Tenants) error
 	DeleteTenant(tenantID string) error
 	GetClusterResource() *ClusterResourceStats
+	UpdateTenantQuota(tenant *dbmodel.Tenants, quota *api_model.TenantQuota) error
+	GetTenantQuotaStatus(tenant *dbmodel.Tenants) (*api_model.TenantQuotaStatus, error)
 }
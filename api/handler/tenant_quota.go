@@ -0,0 +1,317 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"fmt"
+
+	api_model "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//TenantQuotaResourceName the name of the ResourceQuota created in the tenant namespace
+const TenantQuotaResourceName = "rbd-tenant-quota"
+
+//TenantLimitRangeResourceName the name of the LimitRange created in the tenant namespace
+const TenantLimitRangeResourceName = "rbd-tenant-limit-range"
+
+//defaultVolumeCapacity the capacity(GB) of the volume claim if the capacity is not specified,
+//it is set by the flag default-volume-capacity, which must be the same as the worker.
+var defaultVolumeCapacity = 500
+
+//tenantLimitRangeDefaults the default limits and requests of the containers which do not specify them,
+//so that they can be admitted by the ResourceQuota.
+var tenantLimitRangeDefaults = struct {
+	limits, requests corev1.ResourceList
+}{
+	limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1000m"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	},
+	requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+	},
+}
+
+//QuotaRequest the amount of resources that an operation is going to apply for
+type QuotaRequest struct {
+	// unit: MB
+	Memory int
+	// unit: m
+	CPU        int
+	Components int
+	Replicas   int
+	// unit: GB
+	Storage int
+	// the domains that will be bound, the ones which have been used by the tenant are not counted
+	Domains  []string
+	TCPPorts int
+}
+
+//tenantQuotaUsage the usage of the tenant for the quotas
+type tenantQuotaUsage struct {
+	Memory     int
+	CPU        int
+	Components int
+	Replicas   int
+	Storage    int
+	Domains    map[string]struct{}
+	TCPPorts   int
+}
+
+// CheckTenantQuota checks whether the tenant has enough quotas for the request.
+// The memory and cpu are checked against the resource limits of the running components, the same as the ResourceQuota,
+// the others are checked against the resources that has been allocated.
+func CheckTenantQuota(tenant *dbmodel.Tenants, req QuotaRequest) error {
+	usage, err := getTenantQuotaUsage(tenant.UUID)
+	if err != nil {
+		return err
+	}
+	var newDomains = make(map[string]struct{})
+	for _, domain := range req.Domains {
+		if _, ok := usage.Domains[domain]; !ok {
+			newDomains[domain] = struct{}{}
+		}
+	}
+	var checks = []struct {
+		limit, used, need int
+		reason            string
+	}{
+		{tenant.LimitMemory, usage.Memory, req.Memory, "tenant_lack_of_memory"},
+		{tenant.LimitCPU, usage.CPU, req.CPU, "tenant_lack_of_cpu"},
+		{tenant.LimitComponents, usage.Components, req.Components, "tenant_lack_of_components"},
+		{tenant.LimitReplicas, usage.Replicas, req.Replicas, "tenant_lack_of_replicas"},
+		{tenant.LimitStorage, usage.Storage, req.Storage, "tenant_lack_of_storage"},
+		{tenant.LimitDomains, len(usage.Domains), len(newDomains), "tenant_lack_of_domains"},
+		{tenant.LimitTCPPorts, usage.TCPPorts, req.TCPPorts, "tenant_lack_of_tcp_ports"},
+	}
+	for _, c := range checks {
+		if c.limit == 0 || c.need <= 0 {
+			continue
+		}
+		if c.used+c.need > c.limit {
+			logrus.Errorf("tenant %s: limit %d, used %d, to apply for %d; %s", tenant.UUID, c.limit, c.used, c.need, c.reason)
+			return errors.New(c.reason)
+		}
+	}
+	return nil
+}
+
+func getTenantQuotaUsage(tenantID string) (*tenantQuotaUsage, error) {
+	usage := tenantQuotaUsage{Domains: make(map[string]struct{})}
+	ts, err := GetTenantManager().GetTenantResource(tenantID)
+	if err != nil {
+		logrus.Warningf("get resources of tenant %s: %v", tenantID, err)
+	} else {
+		usage.Memory = int(ts.MemoryLimit)
+		usage.CPU = int(ts.CPULimit)
+	}
+	services, err := db.GetManager().TenantServiceDao().GetServicesByTenantID(tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list services")
+	}
+	for _, svc := range services {
+		usage.Components++
+		usage.Replicas += svc.Replicas
+		volumes, err := db.GetManager().TenantServiceVolumeDao().GetTenantServiceVolumesByServiceID(svc.ServiceID)
+		if err != nil {
+			return nil, errors.Wrap(err, "list volumes")
+		}
+		for _, v := range volumes {
+			usage.Storage += VolumeStorageRequest(v.VolumeType, v.VolumeCapacity)
+		}
+		httpRules, err := db.GetManager().HTTPRuleDao().ListByServiceID(svc.ServiceID)
+		if err != nil {
+			return nil, errors.Wrap(err, "list http rules")
+		}
+		for _, rule := range httpRules {
+			usage.Domains[rule.Domain] = struct{}{}
+		}
+		tcpRules, err := db.GetManager().TCPRuleDao().ListByServiceID(svc.ServiceID)
+		if err != nil {
+			return nil, errors.Wrap(err, "list tcp rules")
+		}
+		usage.TCPPorts += len(tcpRules)
+	}
+	return &usage, nil
+}
+
+// VolumeStorageRequest returns the persistent storage(GB) that the volume applies for.
+// The volumes which are not backed by volume claims do not occupy the storage quota.
+func VolumeStorageRequest(volumeType string, capacity int64) int {
+	switch volumeType {
+	case dbmodel.MemoryFSVolumeType.String(), dbmodel.ConfigFileVolumeType.String():
+		return 0
+	}
+	if capacity <= 0 {
+		return defaultVolumeCapacity
+	}
+	return int(capacity)
+}
+
+//UpdateTenantQuota updates the quotas of the tenant, and syncs the memory, cpu and storage
+//quotas to the ResourceQuota of the tenant namespace.
+func (t *TenantAction) UpdateTenantQuota(tenant *dbmodel.Tenants, quota *api_model.TenantQuota) error {
+	tenant.LimitMemory = quota.LimitMemory
+	tenant.LimitCPU = quota.LimitCPU
+	tenant.LimitComponents = quota.LimitComponents
+	tenant.LimitReplicas = quota.LimitReplicas
+	tenant.LimitStorage = quota.LimitStorage
+	tenant.LimitDomains = quota.LimitDomains
+	tenant.LimitTCPPorts = quota.LimitTCPPorts
+	tenant.LogRetentionDays = quota.LogRetentionDays
+	if err := db.GetManager().TenantDao().UpdateModel(tenant); err != nil {
+		return errors.Wrap(err, "update tenant quota")
+	}
+	if err := t.syncResourceQuota(tenant); err != nil {
+		return err
+	}
+	return t.syncLimitRange(tenant)
+}
+
+func (t *TenantAction) syncResourceQuota(tenant *dbmodel.Tenants) error {
+	if t.kubeClient == nil {
+		return nil
+	}
+	hard := corev1.ResourceList{}
+	if tenant.LimitMemory > 0 {
+		hard[corev1.ResourceLimitsMemory] = resource.MustParse(fmt.Sprintf("%dMi", tenant.LimitMemory))
+	}
+	if tenant.LimitCPU > 0 {
+		hard[corev1.ResourceLimitsCPU] = *resource.NewMilliQuantity(int64(tenant.LimitCPU), resource.DecimalSI)
+	}
+	if tenant.LimitStorage > 0 {
+		hard[corev1.ResourceRequestsStorage] = resource.MustParse(fmt.Sprintf("%dGi", tenant.LimitStorage))
+	}
+	quotas := t.kubeClient.CoreV1().ResourceQuotas(tenant.UUID)
+	old, err := quotas.Get(TenantQuotaResourceName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "get resource quota")
+	}
+	if len(hard) == 0 {
+		if err == nil {
+			if err := quotas.Delete(TenantQuotaResourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrap(err, "delete resource quota")
+			}
+		}
+		return nil
+	}
+	if err == nil {
+		old.Spec.Hard = hard
+		if _, err := quotas.Update(old); err != nil {
+			return errors.Wrap(err, "update resource quota")
+		}
+		return nil
+	}
+	rq := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TenantQuotaResourceName,
+			Namespace: tenant.UUID,
+			Labels: map[string]string{
+				"creator":   "Rainbond",
+				"tenant_id": tenant.UUID,
+			},
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	if _, err := quotas.Create(rq); err != nil {
+		return errors.Wrap(err, "create resource quota")
+	}
+	return nil
+}
+
+//syncLimitRange keeps a LimitRange with default requests and limits while the tenant namespace has a ResourceQuota,
+//a ResourceQuota on limits.memory or limits.cpu rejects the pods whose containers do not set them.
+func (t *TenantAction) syncLimitRange(tenant *dbmodel.Tenants) error {
+	if t.kubeClient == nil {
+		return nil
+	}
+	limitRanges := t.kubeClient.CoreV1().LimitRanges(tenant.UUID)
+	old, err := limitRanges.Get(TenantLimitRangeResourceName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "get limit range")
+	}
+	if tenant.LimitMemory <= 0 && tenant.LimitCPU <= 0 && tenant.LimitStorage <= 0 {
+		if err == nil {
+			if err := limitRanges.Delete(TenantLimitRangeResourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrap(err, "delete limit range")
+			}
+		}
+		return nil
+	}
+	spec := corev1.LimitRangeSpec{
+		Limits: []corev1.LimitRangeItem{
+			{
+				Type:           corev1.LimitTypeContainer,
+				Default:        tenantLimitRangeDefaults.limits.DeepCopy(),
+				DefaultRequest: tenantLimitRangeDefaults.requests.DeepCopy(),
+			},
+		},
+	}
+	if err == nil {
+		old.Spec = spec
+		if _, err := limitRanges.Update(old); err != nil {
+			return errors.Wrap(err, "update limit range")
+		}
+		return nil
+	}
+	lr := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TenantLimitRangeResourceName,
+			Namespace: tenant.UUID,
+			Labels: map[string]string{
+				"creator":   "Rainbond",
+				"tenant_id": tenant.UUID,
+			},
+		},
+		Spec: spec,
+	}
+	if _, err := limitRanges.Create(lr); err != nil {
+		return errors.Wrap(err, "create limit range")
+	}
+	return nil
+}
+
+//GetTenantQuotaStatus returns the quotas and usages of the tenant
+func (t *TenantAction) GetTenantQuotaStatus(tenant *dbmodel.Tenants) (*api_model.TenantQuotaStatus, error) {
+	usage, err := getTenantQuotaUsage(tenant.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return &api_model.TenantQuotaStatus{
+		TenantID:   tenant.UUID,
+		Memory:     api_model.NewTenantQuotaItem(tenant.LimitMemory, usage.Memory),
+		CPU:        api_model.NewTenantQuotaItem(tenant.LimitCPU, usage.CPU),
+		Components: api_model.NewTenantQuotaItem(tenant.LimitComponents, usage.Components),
+		Replicas:   api_model.NewTenantQuotaItem(tenant.LimitReplicas, usage.Replicas),
+		Storage:    api_model.NewTenantQuotaItem(tenant.LimitStorage, usage.Storage),
+		Domains:    api_model.NewTenantQuotaItem(tenant.LimitDomains, len(usage.Domains)),
+		TCPPorts:   api_model.NewTenantQuotaItem(tenant.LimitTCPPorts, usage.TCPPorts),
+
+		LogRetentionDays: tenant.LogRetentionDays,
+	}, nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/goodrain/rainbond/cmd/api/option"
 	"github.com/goodrain/rainbond/db"
 	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/sirupsen/logrus"
 )
 
 //TokenIdenAction  TokenIdenAction
@@ -40,15 +41,13 @@ func CreateTokenIdenManager(conf option.Config) (*TokenIdenAction, error) {
 
 //AddTokenIntoMap AddTokenIntoMap
 func (t *TokenIdenAction) AddTokenIntoMap(rui *dbmodel.RegionUserInfo) {
-	m := GetDefaultTokenMap()
-	m[rui.Token] = rui
+	SetTokenCache(rui)
 }
 
 //DeleteTokenFromMap DeleteTokenFromMap
 func (t *TokenIdenAction) DeleteTokenFromMap(oldtoken string, rui *dbmodel.RegionUserInfo) {
-	m := GetDefaultTokenMap()
 	t.AddTokenIntoMap(rui)
-	delete(m, oldtoken)
+	RemoveTokenCache(oldtoken)
 }
 
 //GetAPIManager GetAPIManager
@@ -105,53 +104,91 @@ func (t *TokenIdenAction) DeleteAPIManager(am *api_model.APIManager) *util.APIHa
 	return nil
 }
 
-//CheckToken CheckToken
-func (t *TokenIdenAction) CheckToken(token, uri string) bool {
-	m := GetDefaultTokenMap()
-	//logrus.Debugf("default token map is %v", m)
-	regionInfo, ok := m[token]
-	if !ok {
+//RemoveTokenFromMap removes the token from the token cache
+func (t *TokenIdenAction) RemoveTokenFromMap(token string) {
+	RemoveTokenCache(token)
+}
+
+//CheckToken checks whether the token can access the uri with the http method
+func (t *TokenIdenAction) CheckToken(token, method, uri string) error {
+	regionInfo, ok := GetTokenCache(token)
+	// the scoped tokens may be revoked through the other api replicas, so they are always checked in the db
+	if !ok || regionInfo.APIRange == dbmodel.SCOPED {
 		var err error
 		regionInfo, err = db.GetManager().RegionUserInfoDao().GetTokenByTokenID(token)
 		if err != nil {
-			return false
+			if ok {
+				t.RemoveTokenFromMap(token)
+			}
+			return fmt.Errorf("token not found")
 		}
 		SetTokenCache(regionInfo)
 	}
 	if regionInfo.ValidityPeriod < int(time.Now().Unix()) {
-		return false
+		return fmt.Errorf("token is expired")
 	}
 	switch regionInfo.APIRange {
 	case dbmodel.ALLPOWER:
-		return true
-	case dbmodel.SERVERSOURCE:
+		return nil
+	case dbmodel.SERVERSOURCE, dbmodel.NODEMANAGER:
 		sm := GetDefaultSourceURI()
-		smL, ok := sm[dbmodel.SERVERSOURCE]
-		if !ok {
-			return false
-		}
-		rc := false
-		for _, urinfo := range smL {
+		for _, urinfo := range sm[regionInfo.APIRange] {
 			if strings.HasPrefix(uri, urinfo.Prefix) {
-				rc = true
+				return nil
 			}
 		}
-		return rc
-	case dbmodel.NODEMANAGER:
-		sm := GetDefaultSourceURI()
-		smL, ok := sm[dbmodel.NODEMANAGER]
-		if !ok {
-			return false
+		return fmt.Errorf("uri is out of the api range %s", regionInfo.APIRange)
+	case dbmodel.SCOPED:
+		if err := t.checkScope(regionInfo, method, uri); err != nil {
+			return err
 		}
-		rc := false
-		for _, urinfo := range smL {
-			if strings.HasPrefix(uri, urinfo.Prefix) {
-				rc = true
+		t.updateLastUsedAt(regionInfo)
+		return nil
+	}
+	return fmt.Errorf("unsupported api range %s", regionInfo.APIRange)
+}
+
+// checkScope checks the http method and the tenant or application scope of the scoped token.
+func (t *TokenIdenAction) checkScope(regionInfo *dbmodel.RegionUserInfo, method, uri string) error {
+	if !regionInfo.MethodAllowed(method) {
+		return fmt.Errorf("method %s is not allowed", method)
+	}
+	path := strings.SplitN(uri, "?", 2)[0]
+	tenantPrefix := "/v2/tenants/" + regionInfo.TenantName
+	if regionInfo.TenantName == "" || (path != tenantPrefix && !strings.HasPrefix(path, tenantPrefix+"/")) {
+		return fmt.Errorf("uri is out of the tenant %s", regionInfo.TenantName)
+	}
+	if regionInfo.AppID == "" {
+		return nil
+	}
+	// /v2/tenants/{tenant_name}/apps/{app_id}/... or /v2/tenants/{tenant_name}/services/{service_alias}/...
+	segments := strings.Split(strings.TrimPrefix(path, tenantPrefix+"/"), "/")
+	if len(segments) >= 2 {
+		switch segments[0] {
+		case "apps":
+			if segments[1] == regionInfo.AppID {
+				return nil
+			}
+		case "services":
+			svc, err := db.GetManager().TenantServiceDao().GetServiceByTenantIDAndServiceAlias(regionInfo.TenantID, segments[1])
+			if err == nil && svc.AppID == regionInfo.AppID {
+				return nil
 			}
 		}
-		return rc
 	}
-	return false
+	return fmt.Errorf("uri is out of the application %s", regionInfo.AppID)
+}
+
+// updateLastUsedAt records the last used time of the token, at most once a minute
+func (t *TokenIdenAction) updateLastUsedAt(regionInfo *dbmodel.RegionUserInfo) {
+	now := int(time.Now().Unix())
+	if now-regionInfo.LastUsedAt < 60 {
+		return
+	}
+	regionInfo.LastUsedAt = now
+	if err := db.GetManager().RegionUserInfoDao().UpdateLastUsedAt(regionInfo.Token, now); err != nil {
+		logrus.Warningf("update last used time of token %d: %v", regionInfo.ID, err)
+	}
 }
 
 //InitTokenMap InitTokenMap
@@ -160,9 +197,8 @@ func (t *TokenIdenAction) InitTokenMap() error {
 	if err != nil {
 		return err
 	}
-	m := GetDefaultTokenMap()
 	for _, rui := range ruis {
-		m[rui.Token] = rui
+		SetTokenCache(rui)
 	}
 	return nil
 }
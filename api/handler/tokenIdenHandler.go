@@ -20,6 +20,7 @@ package handler
 
 import (
 	"os"
+	"sync"
 
 	api_model "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/api/util"
@@ -32,7 +33,8 @@ import (
 type TokenMapHandler interface {
 	AddTokenIntoMap(rui *dbmodel.RegionUserInfo)
 	DeleteTokenFromMap(oldtoken string, rui *dbmodel.RegionUserInfo)
-	CheckToken(token, uri string) bool
+	RemoveTokenFromMap(token string)
+	CheckToken(token, method, uri string) error
 	GetAPIManager() map[string][]*dbmodel.RegionAPIClass
 	AddAPIManager(am *api_model.APIManager) *util.APIHandleError
 	DeleteAPIManager(am *api_model.APIManager) *util.APIHandleError
@@ -46,6 +48,9 @@ type TokenMap map[string]*dbmodel.RegionUserInfo
 
 var defaultTokenMap map[string]*dbmodel.RegionUserInfo
 
+// tokenMapLock guards defaultTokenMap, the cached token infos are never modified in place
+var tokenMapLock sync.RWMutex
+
 var defaultSourceURI map[string][]*dbmodel.RegionAPIClass
 
 //CreateTokenIdenHandler create token identification handler
@@ -116,14 +121,31 @@ func GetTokenIdenHandler() TokenMapHandler {
 	return defaultTokenIdenHandler
 }
 
-//GetDefaultTokenMap GetDefaultTokenMap
-func GetDefaultTokenMap() map[string]*dbmodel.RegionUserInfo {
-	return defaultTokenMap
+//GetTokenCache returns a copy of the cached token info
+func GetTokenCache(token string) (*dbmodel.RegionUserInfo, bool) {
+	tokenMapLock.RLock()
+	defer tokenMapLock.RUnlock()
+	info, ok := defaultTokenMap[token]
+	if !ok {
+		return nil, false
+	}
+	copied := *info
+	return &copied, true
 }
 
-//SetTokenCache SetTokenCache
+//SetTokenCache caches a copy of the token info
 func SetTokenCache(info *dbmodel.RegionUserInfo) {
-	defaultTokenMap[info.Token] = info
+	copied := *info
+	tokenMapLock.Lock()
+	defer tokenMapLock.Unlock()
+	defaultTokenMap[info.Token] = &copied
+}
+
+//RemoveTokenCache removes the token from the cache
+func RemoveTokenCache(token string) {
+	tokenMapLock.Lock()
+	defer tokenMapLock.Unlock()
+	delete(defaultTokenMap, token)
 }
 
 //GetDefaultSourceURI GetDefaultSourceURI
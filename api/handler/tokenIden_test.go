@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+func TestCheckScope(t *testing.T) {
+	token := &dbmodel.RegionUserInfo{
+		APIRange:       dbmodel.SCOPED,
+		TenantName:     "abc",
+		AllowedMethods: "GET,PUT",
+	}
+	tests := []struct {
+		method, uri string
+		allowed     bool
+	}{
+		{"GET", "/v2/tenants/abc/services", true},
+		{"PUT", "/v2/tenants/abc/services/gr123456/horizontal?x=1", true},
+		{"GET", "/v2/tenants/abc", true},
+		{"DELETE", "/v2/tenants/abc/services/gr123456", false},
+		{"GET", "/v2/tenants/abcd/services", false},
+		{"GET", "/v2/show", false},
+		{"GET", "/cloud/auth/eid", false},
+	}
+	ta := &TokenIdenAction{}
+	for _, tc := range tests {
+		err := ta.checkScope(token, tc.method, tc.uri)
+		if (err == nil) != tc.allowed {
+			t.Errorf("%s %s: expected allowed %v, got error %v", tc.method, tc.uri, tc.allowed, err)
+		}
+	}
+}
+
+func TestCheckRevokedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := db.NewMockManager(ctrl)
+	db.SetTestManager(manager)
+	userInfoDao := dao.NewMockRegionUserInfoDao(ctrl)
+	manager.EXPECT().RegionUserInfoDao().Return(userInfoDao).AnyTimes()
+	// revoked through another api replica
+	userInfoDao.EXPECT().GetTokenByTokenID("scoped").Return(nil, gorm.ErrRecordNotFound)
+
+	defaultTokenMap = make(map[string]*dbmodel.RegionUserInfo)
+	SetTokenCache(&dbmodel.RegionUserInfo{
+		Token:          "scoped",
+		APIRange:       dbmodel.SCOPED,
+		TenantName:     "abc",
+		ValidityPeriod: int(time.Now().Add(time.Hour).Unix()),
+	})
+	ta := &TokenIdenAction{}
+	if err := ta.CheckToken("scoped", "GET", "/v2/tenants/abc/services"); err == nil {
+		t.Errorf("expected the revoked token to be rejected")
+	}
+	if _, ok := GetTokenCache("scoped"); ok {
+		t.Errorf("expected the revoked token to be removed from the cache")
+	}
+}
+
+func TestCheckTokenConcurrently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := db.NewMockManager(ctrl)
+	db.SetTestManager(manager)
+	userInfoDao := dao.NewMockRegionUserInfoDao(ctrl)
+	manager.EXPECT().RegionUserInfoDao().Return(userInfoDao).AnyTimes()
+	userInfoDao.EXPECT().GetTokenByTokenID("scoped").DoAndReturn(func(token string) (*dbmodel.RegionUserInfo, error) {
+		return &dbmodel.RegionUserInfo{
+			Token:          token,
+			APIRange:       dbmodel.SCOPED,
+			TenantName:     "abc",
+			ValidityPeriod: int(time.Now().Add(time.Hour).Unix()),
+		}, nil
+	}).AnyTimes()
+	userInfoDao.EXPECT().UpdateLastUsedAt("scoped", gomock.Any()).Return(nil).AnyTimes()
+
+	defaultTokenMap = make(map[string]*dbmodel.RegionUserInfo)
+	ta := &TokenIdenAction{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ta.CheckToken("scoped", "GET", "/v2/tenants/abc/services"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if info, ok := GetTokenCache("scoped"); !ok || info.LastUsedAt != 0 {
+		t.Errorf("expected the cached token not to be modified, got %+v", info)
+	}
+}
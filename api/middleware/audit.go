@@ -0,0 +1,370 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/goodrain/rainbond/api/handler"
+	"github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/sirupsen/logrus"
+)
+
+// the max length of the before and after json kept in the audit log
+const maxAuditContentLength = 64 * 1024
+
+// truncatedBody replaces the request body which is larger than maxAuditContentLength
+const truncatedBody = "<truncated>"
+
+// binaryBody replaces the request body which is multipart or binary
+const binaryBody = "<binary>"
+
+// redactedValue replaces the values of the request fields which are not in auditFields
+const redactedValue = "******"
+
+// auditFields the request fields whose values are kept in the audit log. The values of the other fields
+// are redacted, they may be secrets, such as the passwords in maven settings, certificate keys and tokens.
+var auditFields = map[string]bool{
+	"operator":         true,
+	"action":           true,
+	"kind":             true,
+	"status":           true,
+	"tenant_id":        true,
+	"tenant_name":      true,
+	"app_id":           true,
+	"app_name":         true,
+	"service_id":       true,
+	"service_ids":      true,
+	"service_alias":    true,
+	"event_id":         true,
+	"rule_id":          true,
+	"node_id":          true,
+	"domain":           true,
+	"domain_name":      true,
+	"port":             true,
+	"container_port":   true,
+	"protocol":         true,
+	"replicas":         true,
+	"container_cpu":    true,
+	"container_memory": true,
+	"deploy_version":   true,
+	"volume_type":      true,
+	"volume_name":      true,
+	"volume_capacity":  true,
+	"label_key":        true,
+	"label_value":      true,
+	"limit_memory":     true,
+	"limit_cpu":        true,
+}
+
+// trustedProxies the proxies whose X-Real-IP and X-Forwarded-For are used as the source ip
+var trustedProxies []*net.IPNet
+
+//SetTrustedProxies sets the proxies, ips or cidrs, whose X-Real-IP and X-Forwarded-For are trusted by the audit log
+func SetTrustedProxies(proxies []string) error {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %s: %v", proxy, err)
+		}
+		nets = append(nets, ipnet)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+//PeerAddr keeps the address of the peer in the context before it is replaced by
+//the forwarded headers, it must be used before middleware.RealIP
+func PeerAddr(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ContextKey("peer_addr"), r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// auditTarget the resource that a mutating api call works on
+type auditTarget struct {
+	TenantName string
+	Type       string
+	ID         string
+}
+
+// Audit records every mutating api call into the append-only audit log,
+// with the actor, the token, the source ip and the target resource before and after the call.
+// If the target resource is not supported, the request body is recorded with the values redacted except auditFields.
+func Audit(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, omitted := readAuditBody(r)
+
+		target := parseAuditTarget(r.URL.Path)
+		before := target.snapshot()
+		rw := &resWriter{origWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		after := target.snapshot()
+		if after == "" {
+			after = omitted
+		}
+		if after == "" {
+			after = redactBody(body)
+		}
+
+		log := &dbmodel.AuditLog{
+			SourceIP:   sourceIP(r),
+			Method:     r.Method,
+			URI:        truncate(r.RequestURI, 512),
+			TenantName: target.TenantName,
+			TargetType: target.Type,
+			TargetID:   truncate(target.ID, 64),
+			StatusCode: rw.statusCode,
+			Before:     truncate(before, maxAuditContentLength),
+			After:      truncate(after, maxAuditContentLength),
+		}
+		var reqData map[string]interface{}
+		if err := json.Unmarshal(body, &reqData); err == nil {
+			if operator, ok := reqData["operator"].(string); ok {
+				log.Actor = operator
+			}
+		}
+		if tt := strings.Split(r.Header.Get("Authorization"), " "); len(tt) == 2 {
+			if info, ok := handler.GetTokenCache(tt[1]); ok {
+				log.TokenID = info.ID
+				if log.Actor == "" {
+					log.Actor = info.EID
+				}
+			}
+		}
+		if err := db.GetManager().AuditLogDao().AddModel(log); err != nil {
+			logrus.Errorf("add audit log of %s %s: %v", r.Method, r.RequestURI, err)
+		}
+	}
+	return http.HandlerFunc(fn)
+}
+
+// parseAuditTarget parses the target resource from the request path, such as
+// /v2/tenants/{tenant_name}/services/{service_alias}/... or /v2/volume-options/{volume_type}
+func parseAuditTarget(path string) *auditTarget {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "v2" {
+		segments = segments[1:]
+	}
+	target := &auditTarget{}
+	if len(segments) == 0 {
+		return target
+	}
+	if segments[0] != "tenants" || len(segments) < 2 {
+		target.Type = segments[0]
+		if len(segments) > 1 {
+			target.ID = strings.Join(segments[1:], "/")
+		}
+		return target
+	}
+	target.TenantName = segments[1]
+	if len(segments) == 2 {
+		target.Type, target.ID = "tenant", segments[1]
+		return target
+	}
+	switch segments[2] {
+	case "services":
+		target.Type = "service"
+	case "apps":
+		target.Type = "application"
+	case "quota", "limit_memory":
+		target.Type, target.ID = "tenant", segments[1]
+		return target
+	default:
+		target.Type = segments[2]
+	}
+	if len(segments) > 3 {
+		target.ID = segments[3]
+	}
+	return target
+}
+
+// snapshot returns the json of the target resource, it returns empty if the target is not supported.
+func (a *auditTarget) snapshot() string {
+	var obj interface{}
+	var err error
+	switch a.Type {
+	case "tenant":
+		obj, err = db.GetManager().TenantDao().GetTenantIDByName(a.TenantName)
+	case "service":
+		var tenant *dbmodel.Tenants
+		if tenant, err = db.GetManager().TenantDao().GetTenantIDByName(a.TenantName); err == nil {
+			obj, err = db.GetManager().TenantServiceDao().GetServiceByTenantIDAndServiceAlias(tenant.UUID, a.ID)
+		}
+	case "application":
+		obj, err = db.GetManager().ApplicationDao().GetAppByID(a.ID)
+	case "volume-options":
+		obj, err = db.GetManager().VolumeTypeDao().GetVolumeTypeByType(a.ID)
+	default:
+		return ""
+	}
+	if err != nil || obj == nil {
+		return ""
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// redactBody returns the json of the request body in which the values of the fields out of auditFields are redacted,
+// it returns empty if the body is not json.
+func redactBody(body []byte) string {
+	var obj interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return ""
+	}
+	data, err := json.Marshal(redactValue("", obj))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func redactValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = redactValue(k, v[k])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(key, v[i])
+		}
+		return v
+	case nil:
+		return nil
+	}
+	if auditFields[key] {
+		return value
+	}
+	return redactedValue
+}
+
+// readAuditBody reads at most maxAuditContentLength bytes of the request body, and keeps the body
+// unchanged for the next handler. Multipart and binary bodies are not read.
+// It returns the marker instead of the body if the body is not kept in the audit log.
+func readAuditBody(r *http.Request) ([]byte, string) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, ""
+	}
+	if isBinaryContent(r.Header.Get("Content-Type")) {
+		return nil, binaryBody
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxAuditContentLength+1))
+	if err != nil {
+		logrus.Warningf("error reading request body: %v", err)
+	}
+	// set a new body, which will simulate the same data we read and the rest of the original body
+	r.Body = &auditBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if len(body) > maxAuditContentLength {
+		return nil, truncatedBody
+	}
+	return body, ""
+}
+
+type auditBody struct {
+	io.Reader
+	io.Closer
+}
+
+func isBinaryContent(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "json"):
+		return false
+	case mediaType == "application/x-www-form-urlencoded":
+		return false
+	}
+	return true
+}
+
+// sourceIP returns the ip of the peer, X-Real-IP and X-Forwarded-For are used only if the peer is a trusted proxy
+func sourceIP(r *http.Request) string {
+	peer, ok := r.Context().Value(ContextKey("peer_addr")).(string)
+	if !ok {
+		peer = r.RemoteAddr
+	}
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		host = peer
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	if ips := r.Header.Get("X-Forwarded-For"); ips != "" {
+		return strings.TrimSpace(strings.Split(ips, ",")[0])
+	}
+	return host
+}
+
+func isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range trustedProxies {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAuditTarget(t *testing.T) {
+	tests := []struct {
+		path string
+		want auditTarget
+	}{
+		{path: "/v2/tenants/abc", want: auditTarget{TenantName: "abc", Type: "tenant", ID: "abc"}},
+		{path: "/v2/tenants/abc/quota", want: auditTarget{TenantName: "abc", Type: "tenant", ID: "abc"}},
+		{path: "/v2/tenants/abc/services/gr123456/volumes", want: auditTarget{TenantName: "abc", Type: "service", ID: "gr123456"}},
+		{path: "/v2/tenants/abc/apps/app1", want: auditTarget{TenantName: "abc", Type: "application", ID: "app1"}},
+		{path: "/v2/tenants/abc/http-rule", want: auditTarget{TenantName: "abc", Type: "http-rule"}},
+		{path: "/v2/volume-options/ceph-rbd", want: auditTarget{Type: "volume-options", ID: "ceph-rbd"}},
+		{path: "/cloud/auth/eid/tokens", want: auditTarget{Type: "cloud", ID: "auth/eid/tokens"}},
+	}
+	for _, tc := range tests {
+		got := parseAuditTarget(tc.path)
+		if *got != tc.want {
+			t.Errorf("%s: expected %+v, but got %+v", tc.path, tc.want, *got)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	body := `{"operator":"admin","private_key":"-----BEGIN","certificate":"x","content":"<password>p</password>",` +
+		`"service_ids":["s1","s2"],"rules":[{"rule_id":"r1","token":"t"}],"replicas":2,"options":null}`
+	want := `{"certificate":"******","content":"******","operator":"admin","options":null,"private_key":"******",` +
+		`"replicas":2,"rules":[{"rule_id":"r1","token":"******"}],"service_ids":["s1","s2"]}`
+	if got := redactBody([]byte(body)); got != want {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+	if got := redactBody([]byte("token=abc")); got != "" {
+		t.Errorf("expected empty for the body which is not json, but got %s", got)
+	}
+}
+
+func TestReadAuditBody(t *testing.T) {
+	large := strings.Repeat("a", maxAuditContentLength+10)
+	tests := []struct {
+		contentType, body, want, omitted string
+	}{
+		{"application/json", `{"operator":"admin"}`, `{"operator":"admin"}`, ""},
+		{"application/json", large, "", truncatedBody},
+		{"multipart/form-data; boundary=x", "--x--", "", binaryBody},
+		{"application/octet-stream", "abc", "", binaryBody},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("POST", "/v2/tenants", strings.NewReader(tc.body))
+		r.Header.Set("Content-Type", tc.contentType)
+		body, omitted := readAuditBody(r)
+		if string(body) != tc.want || omitted != tc.omitted {
+			t.Errorf("%s: expected %q %q, but got %q %q", tc.contentType, tc.want, tc.omitted, body, omitted)
+		}
+		rest, _ := ioutil.ReadAll(r.Body)
+		if string(rest) != tc.body {
+			t.Errorf("%s: the body of the next handler is changed", tc.contentType)
+		}
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+	tests := []struct {
+		peer, realIP, want string
+	}{
+		{"1.2.3.4:5000", "5.6.7.8", "1.2.3.4"},
+		{"10.1.1.1:5000", "5.6.7.8", "5.6.7.8"},
+		{"192.168.1.1:5000", "5.6.7.8", "5.6.7.8"},
+		{"192.168.1.2:5000", "5.6.7.8", "192.168.1.2"},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("POST", "/v2/tenants", nil)
+		// RemoteAddr is replaced by middleware.RealIP
+		r.RemoteAddr = tc.realIP
+		r = r.WithContext(context.WithValue(r.Context(), ContextKey("peer_addr"), tc.peer))
+		r.Header.Set("X-Real-IP", tc.realIP)
+		if got := sourceIP(r); got != tc.want {
+			t.Errorf("%s: expected %s, but got %s", tc.peer, tc.want, got)
+		}
+	}
+	if err := SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Errorf("expected error for the invalid cidr")
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/goodrain/rainbond/api/handler"
 	"github.com/goodrain/rainbond/api/util"
+	"github.com/sirupsen/logrus"
 )
 
 //Token 简单token验证
@@ -130,10 +131,14 @@ func FullToken(next http.Handler) http.Handler {
 		//logrus.Debugf("request uri is %s", r.RequestURI)
 		t := r.Header.Get("Authorization")
 		if tt := strings.Split(t, " "); len(tt) == 2 {
-			if handler.GetTokenIdenHandler().CheckToken(tt[1], r.RequestURI) {
+			err := handler.GetTokenIdenHandler().CheckToken(tt[1], r.Method, r.RequestURI)
+			if err == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
+			logrus.Warningf("deny request %s %s from %s: %v", r.Method, r.RequestURI, r.RemoteAddr, err)
+		} else {
+			logrus.Warningf("deny request %s %s from %s: no token", r.Method, r.RequestURI, r.RemoteAddr)
 		}
 		util.CloseRequest(r)
 		w.WriteHeader(http.StatusUnauthorized)
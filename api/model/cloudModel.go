@@ -101,3 +101,34 @@ type APIManager struct {
 		Remark string `json:"remark" validate:"remark"`
 	}
 }
+
+//CreateScopedToken the request of creating a scoped token
+//swagger:parameters createScopedToken
+type CreateScopedToken struct {
+	// in: path
+	// required: true
+	EID string `json:"eid" validate:"eid|required"`
+	//in: body
+	Body struct {
+		// the name of the token
+		// in: body
+		// required: false
+		Name string `json:"name" validate:"name"`
+		// the tenant that the token is limited to
+		// in: body
+		// required: true
+		TenantName string `json:"tenant_name" validate:"tenant_name|required"`
+		// the application that the token is limited to, empty means the whole tenant
+		// in: body
+		// required: false
+		AppID string `json:"app_id" validate:"app_id"`
+		// the allowed http methods, empty means all methods are allowed
+		// in: body
+		// required: false
+		AllowedMethods []string `json:"allowed_methods" validate:"allowed_methods"`
+		// 有效期
+		// in: body
+		// required: true
+		ValidityPeriod int `json:"validity_period" validate:"validity_period|required"` //1549812345
+	}
+}
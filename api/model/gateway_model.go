@@ -33,6 +33,13 @@ type AddHTTPRuleStruct struct {
 	Certificate    string                 `json:"certificate"`
 	PrivateKey     string                 `json:"private_key"`
 	RuleExtensions []*RuleExtensionStruct `json:"rule_extensions"`
+	// HTTP, HTTPS, GRPC or GRPCS, the default is HTTP
+	BackendProtocol string `json:"backend_protocol"`
+	ProxySSLVerify  bool   `json:"proxy_ssl_verify"`
+	// the name of the configmap uploaded by PUT /gateway/error-pages
+	ErrorPages            string `json:"error_pages"`
+	Maintenance           bool   `json:"maintenance"`
+	MaintenanceRetryAfter int    `json:"maintenance_retry_after"`
 }
 
 //UpdateHTTPRuleStruct is used to update http rule, certificate and rule extensions
@@ -50,6 +57,21 @@ type UpdateHTTPRuleStruct struct {
 	Certificate    string                 `json:"certificate"`
 	PrivateKey     string                 `json:"private_key"`
 	RuleExtensions []*RuleExtensionStruct `json:"rule_extensions"`
+	// HTTP, HTTPS, GRPC or GRPCS, the default is HTTP
+	BackendProtocol string `json:"backend_protocol"`
+	ProxySSLVerify  bool   `json:"proxy_ssl_verify"`
+	// the name of the configmap uploaded by PUT /gateway/error-pages
+	ErrorPages            string `json:"error_pages"`
+	Maintenance           bool   `json:"maintenance"`
+	MaintenanceRetryAfter int    `json:"maintenance_retry_after"`
+}
+
+//ErrorPagesStruct is used to upload the custom error pages of http rules
+type ErrorPagesStruct struct {
+	// the name of the configmap, it is referenced by the error_pages of http rules
+	Name string `json:"name" validate:"name|required"`
+	// the html pages, the keys are the status codes 404, 502, 503, 504 or maintenance
+	Pages map[string]string `json:"pages"`
 }
 
 //DeleteHTTPRuleStruct contains the id of http rule that will be deleted
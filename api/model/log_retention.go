@@ -0,0 +1,41 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+//LogRetentionPolicy the log retention policy of a tenant or a component, 0 days means the default retention
+type LogRetentionPolicy struct {
+	// the days the event logs are kept, 0 means the default retention and -1 means forever
+	EventLogDays int `json:"event_log_days" validate:"event_log_days|numeric_between:-1,3650"`
+	// the days the container logs are kept, 0 means the default retention and -1 means forever
+	ContainerLogDays int `json:"container_log_days" validate:"container_log_days|numeric_between:-1,3650"`
+	// whether to archive the logs past the retention instead of deleting them
+	Archive bool `json:"archive" validate:"archive|bool"`
+}
+
+//RestoreLogArchivesReq the archived logs in the time range are restored for viewing
+type RestoreLogArchivesReq struct {
+	// restore the logs of the component, or all the logs of the tenant if it is empty
+	ServiceAlias string `json:"service_alias"`
+	// container or event, all kinds of logs are restored if it is empty
+	Kind      string    `json:"kind" validate:"kind|in:container,event"`
+	StartTime time.Time `json:"start_time" validate:"start_time|required"`
+	EndTime   time.Time `json:"end_time" validate:"end_time|required"`
+}
@@ -367,6 +367,8 @@ type TenantServiceVolumeStruct struct {
 	VolumePath string `json:"volume_path"`
 	//是否只读
 	IsReadOnly bool `json:"is_read_only"`
+	//存储大小(GB), 不设置则使用默认大小
+	VolumeCapacity int64 `json:"volume_capacity"`
 
 	FileContent string `json:"file_content"`
 }
@@ -1667,6 +1669,12 @@ type ListServiceResponse struct {
 type UpdateAppRequest struct {
 	AppName        string `json:"app_name"`
 	GovernanceMode string `json:"governance_mode"`
+	// allow all ingress traffic to the components of the application if true
+	DisableNetworkIsolation *bool `json:"disable_network_isolation"`
+	// the mutual TLS mode of the built-in service mesh, PERMISSIVE, STRICT or DISABLE
+	MTLSMode string `json:"mtls_mode"`
+	// the tracing config of the built-in service mesh, an empty collector disables tracing
+	Tracing *dbmodel.TracingConfig `json:"tracing"`
 }
 
 // BindServiceRequest -
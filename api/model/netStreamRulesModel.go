@@ -81,6 +81,38 @@ type NetDownStreamRules struct {
 	Prefix       string `json:"prefix" validate:"prefix"`
 	ServiceAlias string `json:"service_alias"`
 	ServiceID    string `json:"service_id" validate:"service_id"`
+	//重试条件, 例如 5xx,connect-failure
+	//in: body
+	//required: false
+	RetryOn string `json:"retry_on"`
+	//重试次数, 0 不重试
+	//in: body
+	//required: false
+	NumRetries int `json:"num_retries" validate:"num_retries|numeric_between:0,10"`
+	//每次尝试的超时时间(毫秒)
+	//in: body
+	//required: false
+	PerTryTimeoutMS int `json:"per_try_timeout_ms"`
+	//请求超时时间(毫秒), 包含所有重试
+	//in: body
+	//required: false
+	TimeoutMS int `json:"timeout_ms"`
+	//故障注入: 延迟请求的百分比
+	//in: body
+	//required: false
+	FaultDelayPercent int `json:"fault_delay_percent" validate:"fault_delay_percent|numeric_between:0,100"`
+	//故障注入: 延迟时间(毫秒)
+	//in: body
+	//required: false
+	FaultDelayMS int `json:"fault_delay_ms"`
+	//故障注入: 中断请求的百分比
+	//in: body
+	//required: false
+	FaultAbortPercent int `json:"fault_abort_percent" validate:"fault_abort_percent|numeric_between:0,100"`
+	//故障注入: 中断请求返回的状态码, 默认 503
+	//in: body
+	//required: false
+	FaultAbortStatus int `json:"fault_abort_status"`
 }
 
 //NetUpStreamRules NetUpStreamRules
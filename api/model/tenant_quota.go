@@ -0,0 +1,70 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+//TenantQuota the quotas of a tenant, 0 means unlimited
+type TenantQuota struct {
+	// memory limit, unit: MB
+	LimitMemory int `json:"limit_memory" validate:"limit_memory"`
+	// cpu limit, unit: m
+	LimitCPU int `json:"limit_cpu" validate:"limit_cpu"`
+	// the maximum number of components
+	LimitComponents int `json:"limit_components" validate:"limit_components"`
+	// the maximum number of replicas of all components
+	LimitReplicas int `json:"limit_replicas" validate:"limit_replicas"`
+	// persistent storage limit, unit: GB
+	LimitStorage int `json:"limit_storage" validate:"limit_storage"`
+	// the maximum number of gateway domains
+	LimitDomains int `json:"limit_domains" validate:"limit_domains"`
+	// the maximum number of gateway tcp ports
+	LimitTCPPorts int `json:"limit_tcp_ports" validate:"limit_tcp_ports"`
+	// the days the component logs are kept in the log index, 0 means the default retention
+	LogRetentionDays int `json:"log_retention_days" validate:"log_retention_days"`
+}
+
+//TenantQuotaItem the limit and usage of one kind of resource
+type TenantQuotaItem struct {
+	Limit int `json:"limit"`
+	Used  int `json:"used"`
+	// the percentage of used, it is always 0 if there is no limit
+	UsedPercent float64 `json:"used_percent"`
+}
+
+//NewTenantQuotaItem creates a new TenantQuotaItem
+func NewTenantQuotaItem(limit, used int) TenantQuotaItem {
+	item := TenantQuotaItem{Limit: limit, Used: used}
+	if limit > 0 {
+		item.UsedPercent = float64(used*10000/limit) / 100
+	}
+	return item
+}
+
+//TenantQuotaStatus the limits and usages of all kinds of resources of a tenant
+type TenantQuotaStatus struct {
+	TenantID   string          `json:"tenant_id"`
+	Memory     TenantQuotaItem `json:"memory"`
+	CPU        TenantQuotaItem `json:"cpu"`
+	Components TenantQuotaItem `json:"components"`
+	Replicas   TenantQuotaItem `json:"replicas"`
+	Storage    TenantQuotaItem `json:"storage"`
+	Domains    TenantQuotaItem `json:"domains"`
+	TCPPorts   TenantQuotaItem `json:"tcp_ports"`
+	// the days the component logs are kept in the log index, 0 means the default retention
+	LogRetentionDays int `json:"log_retention_days"`
+}
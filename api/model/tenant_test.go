@@ -59,3 +59,20 @@ func TestTenantList(t *testing.T) {
 		t.Logf("%+v", ten)
 	}
 }
+
+func TestNewTenantQuotaItem(t *testing.T) {
+	tests := []struct {
+		limit, used int
+		want        float64
+	}{
+		{0, 100, 0},
+		{300, 100, 33.33},
+		{100, 120, 120},
+	}
+	for _, tc := range tests {
+		item := NewTenantQuotaItem(tc.limit, tc.used)
+		if item.UsedPercent != tc.want {
+			t.Errorf("limit %d, used %d: expected %v, got %v", tc.limit, tc.used, tc.want, item.UsedPercent)
+		}
+	}
+}
@@ -68,6 +68,15 @@ func (h *HTTPProxy) UpdateEndpoints(endpoints ...string) {
 	h.endpoints = CreateEndpoints(ends)
 }
 
+//Endpoints returns the http addresses of all endpoints
+func (h *HTTPProxy) Endpoints() []string {
+	var addrs []string
+	for _, end := range h.endpoints {
+		addrs = append(addrs, end.GetHTTPAddr())
+	}
+	return addrs
+}
+
 //Do do proxy
 func (h *HTTPProxy) Do(r *http.Request) (*http.Response, error) {
 	endpoint := h.lb.Select(r, h.endpoints)
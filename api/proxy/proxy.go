@@ -25,6 +25,7 @@ type Proxy interface {
 	Proxy(w http.ResponseWriter, r *http.Request)
 	Do(r *http.Request) (*http.Response, error)
 	UpdateEndpoints(endpoints ...string) // format: ["name=>ip:port", ...]
+	Endpoints() []string
 }
 
 //CreateProxy create proxy
@@ -156,6 +156,15 @@ func (h *WebSocketProxy) UpdateEndpoints(endpoints ...string) {
 	h.endpoints = CreateEndpoints(endpoints)
 }
 
+//Endpoints returns the http addresses of all endpoints
+func (h *WebSocketProxy) Endpoints() []string {
+	var addrs []string
+	for _, end := range h.endpoints {
+		addrs = append(addrs, end.GetHTTPAddr())
+	}
+	return addrs
+}
+
 //Do do proxy
 func (h *WebSocketProxy) Do(r *http.Request) (*http.Response, error) {
 	return nil, fmt.Errorf("do not support")
@@ -280,6 +280,41 @@ func (n *node) ReSchedulable(nid string) *util.APIHandleError {
 	}
 	return handleAPIResult(code, res)
 }
+func (n *node) Drain(nid string, timeout int) (*model.DrainStatus, *util.APIHandleError) {
+	var res utilhttp.ResponseBody
+	var gc model.DrainStatus
+	res.Bean = &gc
+	req := fmt.Sprintf(`{"timeout":%d}`, timeout)
+	code, err := n.DoRequest(n.prefix+"/"+nid+"/drain", "POST", bytes.NewBuffer([]byte(req)), &res)
+	if err != nil {
+		return nil, util.CreateAPIHandleError(code, err)
+	}
+	if apierr := handleAPIResult(code, res); apierr != nil {
+		return nil, apierr
+	}
+	return &gc, nil
+}
+func (n *node) DrainStatus(nid string) (*model.DrainStatus, *util.APIHandleError) {
+	var res utilhttp.ResponseBody
+	var gc model.DrainStatus
+	res.Bean = &gc
+	code, err := n.DoRequest(n.prefix+"/"+nid+"/drain", "GET", nil, &res)
+	if err != nil {
+		return nil, util.CreateAPIHandleError(code, err)
+	}
+	if apierr := handleAPIResult(code, res); apierr != nil {
+		return nil, apierr
+	}
+	return &gc, nil
+}
+func (n *node) CancelDrain(nid string) *util.APIHandleError {
+	var res utilhttp.ResponseBody
+	code, err := n.DoRequest(n.prefix+"/"+nid+"/drain", "DELETE", nil, &res)
+	if err != nil {
+		return util.CreateAPIHandleError(code, err)
+	}
+	return handleAPIResult(code, res)
+}
 func (n *node) Install(nid string) *util.APIHandleError {
 	var res utilhttp.ResponseBody
 	code, err := n.DoRequest(n.prefix+"/"+nid+"/install", "POST", nil, &res)
@@ -346,6 +381,9 @@ type NodeInterface interface {
 	Label(nid string) NodeLabelInterface
 	Condition(nid string) NodeConditionInterface
 	Install(nid string) *util.APIHandleError
+	Drain(nid string, timeout int) (*model.DrainStatus, *util.APIHandleError)
+	DrainStatus(nid string) (*model.DrainStatus, *util.APIHandleError)
+	CancelDrain(nid string) *util.APIHandleError
 	UpdateNodeStatus(nid, status string) (*client.HostNode, *util.APIHandleError)
 }
 
@@ -89,6 +89,8 @@ func (m *Manager) SetMiddleware() {
 	r := m.r
 	r.Use(m.RequestMetric)
 	r.Use(middleware.RequestID)
+	//keep the address of the peer for the audit log before it is replaced by RealIP
+	r.Use(apimiddleware.PeerAddr)
 	//Sets a http.Request's RemoteAddr to either X-Forwarded-For or X-Real-IP
 	r.Use(middleware.RealIP)
 	//Logs the start and end of each request with the elapsed processing time
@@ -114,6 +116,8 @@ func (m *Manager) SetMiddleware() {
 	}
 	//simple api version
 	r.Use(apimiddleware.APIVersion)
+	//audit the mutating api calls, including the ones proxied to the other components
+	r.Use(apimiddleware.Audit)
 	r.Use(apimiddleware.Proxy)
 }
 
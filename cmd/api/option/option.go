@@ -61,6 +61,8 @@ type Config struct {
 	KubeConfigPath         string
 	PrometheusEndpoint     string
 	RbdNamespace           string
+	AuditTrustedProxies    []string
+	DefaultVolumeCapacity  int
 }
 
 //APIServer  apiserver server
@@ -113,6 +115,8 @@ func (a *APIServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&a.KuberentesDashboardAPI, "k8s-dashboard-api", "kubernetes-dashboard.rbd-system:443", "The service DNS name of Kubernetes dashboard. Default to kubernetes-dashboard.kubernetes-dashboard")
 	fs.StringVar(&a.PrometheusEndpoint, "prom-api", "rbd-monitor:9999", "The service DNS name of Prometheus api. Default to rbd-monitor:9999")
 	fs.StringVar(&a.RbdNamespace, "rbd-namespace", "rbd-system", "rbd component namespace")
+	fs.StringSliceVar(&a.AuditTrustedProxies, "audit-trusted-proxies", []string{}, "The ips or cidrs of the proxies whose X-Real-IP and X-Forwarded-For are trusted as the source ip of the audit log")
+	fs.IntVar(&a.DefaultVolumeCapacity, "default-volume-capacity", 500, "the capacity(GB) of the volume claim whose capacity is not specified, it must be the same as the worker")
 }
 
 //SetLog 设置log
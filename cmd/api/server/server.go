@@ -28,6 +28,7 @@ import (
 	"github.com/goodrain/rainbond/api/db"
 	"github.com/goodrain/rainbond/api/discover"
 	"github.com/goodrain/rainbond/api/handler"
+	apimiddleware "github.com/goodrain/rainbond/api/middleware"
 	"github.com/goodrain/rainbond/api/server"
 	"github.com/goodrain/rainbond/cmd/api/option"
 	"github.com/goodrain/rainbond/event"
@@ -108,6 +109,9 @@ func Run(s *option.APIServer) error {
 	if err := controller.CreateV2RouterManager(s.Config, cli); err != nil {
 		logrus.Errorf("create v2 route manager error, %v", err)
 	}
+	if err := apimiddleware.SetTrustedProxies(s.Config.AuditTrustedProxies); err != nil {
+		return err
+	}
 	// 启动api
 	apiManager := server.NewManager(s.Config, etcdcli)
 	if err := apiManager.Start(); err != nil {
@@ -24,7 +24,9 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/goodrain/rainbond/discover"
 	"github.com/goodrain/rainbond/eventlog/cluster"
@@ -106,6 +108,18 @@ func (s *LogServer) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&s.Conf.EventStore.DB.PoolSize, "db.pool.size", 3, "Data persistence db pool init size.")
 	fs.IntVar(&s.Conf.EventStore.DB.PoolMaxSize, "db.pool.maxsize", 10, "Data persistence db pool max size.")
 	fs.StringVar(&s.Conf.EventStore.DB.HomePath, "docker.log.homepath", "/grdata/logs/", "container log persistent home path")
+	fs.BoolVar(&s.Conf.EventStore.DB.LogIndex, "docker.log.index", true, "whether to index the container logs for searching")
+	fs.IntVar(&s.Conf.EventStore.DB.LogIndexRetention, "docker.log.index.retention", 7, "the default days the container logs are kept in the log index, it can be overridden by the tenant")
+	fs.IntVar(&s.Conf.EventStore.DB.ContainerLogRetention, "docker.log.retention", 7, "the default days the container logs are kept, it can be overridden by the log retention policies. env DOCKER_LOG_SAVE_DAY takes precedence")
+	fs.IntVar(&s.Conf.EventStore.DB.EventLogRetention, "event.log.retention", 0, "the default days the event logs are kept, 0 means forever, it can be overridden by the log retention policies")
+	fs.StringVar(&s.Conf.EventStore.DB.Archive.Provider, "log.archive.provider", "", "the object storage which the logs past the retention are archived to, s3 or alioss. the archives are kept in local if it is empty")
+	fs.StringVar(&s.Conf.EventStore.DB.Archive.Endpoint, "log.archive.endpoint", "", "the endpoint of the log archive object storage")
+	fs.StringVar(&s.Conf.EventStore.DB.Archive.AccessKey, "log.archive.accesskey", "", "the access key of the log archive object storage")
+	fs.StringVar(&s.Conf.EventStore.DB.Archive.SecretKey, "log.archive.secretkey", "", "the secret key of the log archive object storage")
+	fs.StringVar(&s.Conf.EventStore.DB.Archive.BucketName, "log.archive.bucket", "rainbond-logs", "the bucket of the log archive object storage")
+	fs.IntVar(&s.Conf.EventStore.DB.AccessLogRetention, "access.log.retention", 7, "the days the gateway access logs are kept")
+	fs.IntVar(&s.Conf.EventStore.DB.AccessLogMaxDaySize, "access.log.max.day.size", 256, "the max megabytes of the gateway access logs of a tenant in one day, the logs beyond it are dropped")
+	fs.DurationVar(&s.Conf.EventStore.DB.Archive.RestoreTTL, "log.archive.restore.ttl", 72*time.Hour, "how long the restored logs are kept for viewing")
 	fs.StringVar(&s.Conf.Entry.NewMonitorMessageServerConf.ListenerHost, "monitor.udp.host", "0.0.0.0", "receive new monitor udp server host")
 	fs.IntVar(&s.Conf.Entry.NewMonitorMessageServerConf.ListenerPort, "monitor.udp.port", 6166, "receive new monitor udp server port")
 	fs.StringVar(&s.Conf.Cluster.Discover.NodeID, "node-id", "", "the unique ID for this node.")
@@ -158,10 +172,14 @@ func (s *LogServer) InitConf() {
 	s.Conf.EventStore.ClusterMode = s.Conf.ClusterMode
 	s.Conf.Cluster.Discover.DockerLogPort = s.Conf.Entry.DockerLogServer.BindPort
 	s.Conf.Cluster.Discover.WebPort = s.Conf.WebSocket.BindPort
+	s.Conf.EventStore.DB.Archive.NodeID = s.Conf.Cluster.Discover.NodeID
 	if os.Getenv("MYSQL_HOST") != "" && os.Getenv("MYSQL_USER") != "" && os.Getenv("MYSQL_PASSWORD") != "" {
 		s.Conf.EventStore.DB.URL = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", os.Getenv("MYSQL_USER"), os.Getenv("MYSQL_PASSWORD"),
 			os.Getenv("MYSQL_HOST"), os.Getenv("MYSQL_PORT"), os.Getenv("MYSQL_DATABASE"))
 	}
+	if saveDay, _ := strconv.Atoi(os.Getenv("DOCKER_LOG_SAVE_DAY")); saveDay > 0 {
+		s.Conf.EventStore.DB.ContainerLogRetention = saveDay
+	}
 	if os.Getenv("CLUSTER_BIND_IP") != "" {
 		s.Conf.Cluster.PubSub.PubBindIP = os.Getenv("CLUSTER_BIND_IP")
 	}
@@ -29,6 +29,13 @@ import (
 	"github.com/spf13/pflag"
 )
 
+const (
+	//DataPlaneOpenresty the gateway proxies the traffic with openresty
+	DataPlaneOpenresty = "openresty"
+	//DataPlaneEnvoy the gateway proxies the traffic with envoy, which is configured by xds
+	DataPlaneEnvoy = "envoy"
+)
+
 // GWServer contains Config and LogLevel
 type GWServer struct {
 	Config
@@ -77,6 +84,18 @@ type Config struct {
 	ShareMemory       uint64
 	SyncRateLimit     float32
 	EnableSSLStapling bool
+	// DataPlane the proxy which serves the traffic, openresty or envoy
+	DataPlane string
+	EnvoyBin  string
+	// AccessLogShipTo the eventlog endpoint which the access logs of the http rules are shipped to
+	AccessLogShipTo string
+
+	// VIPs the floating virtual ips, each of them is held by one of the healthy gateway nodes
+	VIPs              []string
+	VIPInterface      string
+	VIPLeaseTTL       int
+	VIPHealthInterval time.Duration
+	VIPHealthFailures int
 }
 
 // ListenPorts describe the ports required to run the gateway controller
@@ -86,6 +105,9 @@ type ListenPorts struct {
 	Status int
 	Stream int
 	Health int
+	XDS    int
+	// AccessLog the syslog port which receives the access logs to be shipped
+	AccessLog int
 }
 
 // AddFlags adds flags
@@ -95,6 +117,8 @@ func (g *GWServer) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&g.ListenPorts.Status, "status-port", 18080, `Port to use for the lua HTTP endpoint configuration.`)
 	fs.IntVar(&g.ListenPorts.Stream, "stream-port", 18081, `Port to use for the lua TCP/UDP endpoint configuration.`)
 	fs.IntVar(&g.ListenPorts.Health, "healthz-port", 10254, `Port to use for the healthz endpoint.`)
+	fs.IntVar(&g.ListenPorts.XDS, "xds-port", 18082, `Port to use for the xds server which configures the envoy data plane.`)
+	fs.IntVar(&g.ListenPorts.AccessLog, "access-log-port", 18083, `Port to use for the syslog endpoint which receives the access logs to be shipped.`)
 	fs.IntVar(&g.ListenPorts.HTTP, "service-http-port", 80, `Port to use for the http service rule`)
 	fs.IntVar(&g.ListenPorts.HTTPS, "service-https-port", 443, `Port to use for the https service rule`)
 	fs.IntVar(&g.WorkerProcesses, "worker-processes", 0, "Default get current compute cpu core number.This number should be, at maximum, the number of CPU cores on your system.")
@@ -105,6 +129,7 @@ func (g *GWServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&g.ErrorLog, "error-log", "/dev/stderr", "nginx log file, stderr or syslog")
 	fs.StringVar(&g.ErrorLogLevel, "errlog-level", "crit", "log level")
 	fs.StringVar(&g.AccessLogPath, "access-log", "", "Set the access log store path, each server generates a file. Access logs are not generated by default.")
+	fs.StringVar(&g.AccessLogShipTo, "access-log-ship-to", "", "The eventlog endpoint which the access logs of the http rules are shipped to, such as http://rbd-eventlog:6363. It works with the openresty data plane, the access logs are not shipped by default.")
 	fs.StringVar(&g.NginxUser, "nginx-user", "root", "n ginx user name")
 	fs.IntVar(&g.KeepaliveRequests, "keepalive-requests", 10000, "Number of requests a client can make over the keep-alive connection. ")
 	fs.IntVar(&g.KeepaliveTimeout, "keepalive-timeout", 30, "Timeout for keep-alive connections. Server will close connections after this time.")
@@ -125,7 +150,15 @@ func (g *GWServer) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&g.EnableSSLStapling, "enable-ssl-stapling", false, "enable ssl stapling")
 	fs.Uint64Var(&g.ShareMemory, "max-config-share-memory", 128, "Nginx maximum Shared memory size, which should be increased for larger clusters.")
 	fs.Float32Var(&g.SyncRateLimit, "sync-rate-limit", 0.3, "Define the sync frequency upper limit")
+	fs.StringVar(&g.DataPlane, "data-plane", DataPlaneOpenresty, "The data plane of the gateway, openresty or envoy")
+	fs.StringVar(&g.EnvoyBin, "envoy-bin", "/usr/local/bin/envoy", "The envoy binary, it works with the envoy data plane")
 	fs.StringArrayVar(&g.IgnoreInterface, "ignore-interface", []string{"docker0", "tunl0", "cni0", "kube-ipvs0", "flannel"}, "The network interface name that ignore by gateway")
+	// floating virtual ip
+	fs.StringArrayVar(&g.VIPs, "vip", nil, "The floating virtual ip such as 192.168.0.100/24, it is held by one of the healthy gateway nodes and moved to another one when the node fails.")
+	fs.StringVar(&g.VIPInterface, "vip-interface", "", "The network interface which the virtual ips are added to, it is the interface in the same subnet as the virtual ip by default.")
+	fs.IntVar(&g.VIPLeaseTTL, "vip-lease-ttl", 5, "The ttl seconds of the etcd lease which elects the holder of the virtual ip, the virtual ip is moved in the ttl when the holder is gone.")
+	fs.DurationVar(&g.VIPHealthInterval, "vip-health-interval", 2*time.Second, "The interval of the health checks which drive the failover of the virtual ips.")
+	fs.IntVar(&g.VIPHealthFailures, "vip-health-failures", 3, "The number of the consecutive health check failures after which the virtual ips are released.")
 }
 
 // SetLog sets log
@@ -151,6 +184,9 @@ func (g *GWServer) CheckConfig() error {
 		}
 		g.HostIP = ip.String()
 	}
+	if g.DataPlane != DataPlaneOpenresty && g.DataPlane != DataPlaneEnvoy {
+		return fmt.Errorf("unsupported data plane %s", g.DataPlane)
+	}
 	if os.Getenv("ACCESS_LOG_FORMAT") != "" {
 		g.Config.AccessLogFormat = os.Getenv("ACCESS_LOG_FORMAT")
 	}
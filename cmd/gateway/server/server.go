@@ -27,6 +27,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/coreos/etcd/clientv3"
 	"github.com/go-chi/chi"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -37,6 +38,8 @@ import (
 
 	"github.com/goodrain/rainbond/cmd/gateway/option"
 	"github.com/goodrain/rainbond/discover"
+	"github.com/goodrain/rainbond/gateway/accesslog"
+	"github.com/goodrain/rainbond/gateway/acme"
 	"github.com/goodrain/rainbond/gateway/cluster"
 	"github.com/goodrain/rainbond/gateway/controller"
 	"github.com/goodrain/rainbond/gateway/metric"
@@ -97,6 +100,14 @@ func Run(s *option.GWServer) error {
 	}
 	mc.Start()
 
+	if s.Config.AccessLogShipTo != "" {
+		shipper := accesslog.NewShipper(fmt.Sprintf("127.0.0.1:%d", s.ListenPorts.AccessLog), s.Config.AccessLogShipTo)
+		if err := shipper.Start(); err != nil {
+			return err
+		}
+		defer shipper.Stop()
+	}
+
 	gwc, err := controller.NewGWController(ctx, clientset, &s.Config, mc, node)
 	if err != nil {
 		return err
@@ -110,9 +121,21 @@ func Run(s *option.GWServer) error {
 	}
 	defer gwc.Close()
 
+	if len(s.Config.VIPs) > 0 {
+		vipManager, err := cluster.CreateVIPManager(ctx, s.Config, etcdCli, func() error {
+			return gwc.Check(nil)
+		})
+		if err != nil {
+			return fmt.Errorf("create vip manager: %v", err)
+		}
+		vipManager.Start()
+		defer vipManager.Stop()
+	}
+
 	mux := chi.NewMux()
 	registerHealthz(gwc, mux)
 	registerMetrics(reg, mux)
+	registerACMEChallenge(etcdCli, mux)
 	if s.Debug {
 		util.ProfilerSetup(mux)
 	}
@@ -159,6 +182,14 @@ func registerMetrics(reg *prometheus.Registry, mux *chi.Mux) {
 	)
 }
 
+// the http-01 challenges of the acme client are proxied here by all the http servers
+func registerACMEChallenge(etcdCli *clientv3.Client, mux *chi.Mux) {
+	mux.Handle(
+		acme.HTTP01ChallengePath+"*",
+		acme.NewHTTP01Handler(acme.NewEtcdChallengeStore(etcdCli)),
+	)
+}
+
 func startHTTPServer(port int, mux *chi.Mux) {
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%v", port),
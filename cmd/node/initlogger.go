@@ -19,6 +19,10 @@
 package main
 
 import (
+	_ "github.com/goodrain/rainbond/node/nodem/logger/elasticsearch"
+	_ "github.com/goodrain/rainbond/node/nodem/logger/gelf"
+	_ "github.com/goodrain/rainbond/node/nodem/logger/loki"
 	_ "github.com/goodrain/rainbond/node/nodem/logger/streamlog"
+	_ "github.com/goodrain/rainbond/node/nodem/logger/syslog"
 	_ "github.com/goodrain/rainbond/node/nodem/logger/testlog"
 )
@@ -33,6 +33,14 @@ import (
 	etcdutil "github.com/goodrain/rainbond/util/etcd"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	//ContainerRuntimeDocker the logs are collected by docker api
+	ContainerRuntimeDocker = "docker"
+	//ContainerRuntimeCRI the logs are collected from the pod log dir of kubelet
+	ContainerRuntimeCRI = "cri"
 )
 
 var (
@@ -102,6 +110,12 @@ type Conf struct {
 	EnableCollectLog bool
 	DockerCli        *dockercli.Client
 	EtcdCli          *client.Client
+	KubeClient       kubernetes.Interface
+
+	// the container runtime of the node, docker or cri
+	ContainerRuntime string
+	// the pod log dir of kubelet, the logs are collected from it for cri runtime
+	PodLogPath string
 
 	LicPath   string
 	LicSoPath string
@@ -127,6 +141,11 @@ type Conf struct {
 	ImageRepositoryHost string
 	GatewayVIP          string
 	HostsFile           string
+
+	// MeshTrustDomain is the trust domain of the SPIFFE ids of the built-in service mesh
+	MeshTrustDomain string
+	// MeshCertTTL is the lifetime of the workload certificates issued by the platform ca
+	MeshCertTTL time.Duration
 }
 
 //StatsdConfig StatsdConfig
@@ -179,6 +198,8 @@ func (a *Conf) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&a.AutoRegistNode, "auto-registnode", true, "Whether auto regist node info to cluster where node is not found")
 	fs.BoolVar(&a.AutoScheduler, "auto-scheduler", true, "Whether auto set node unscheduler where current node is unhealth")
 	fs.BoolVar(&a.EnableCollectLog, "enabel-collect-log", true, "Whether to collect container logs")
+	fs.StringVar(&a.ContainerRuntime, "container-runtime", ContainerRuntimeDocker, "The container runtime of the node, docker or cri. Set cri for containerd and CRI-O")
+	fs.StringVar(&a.PodLogPath, "pod-log-path", "/var/log/pods", "The pod log dir of kubelet, the container logs are collected from it for cri runtime")
 	fs.DurationVar(&a.AutoUnschedulerUnHealthDuration, "autounscheduler-unhealthy-dura", 5*time.Minute, "Node unhealthy duration, after the automatic offline,if set 0,disable auto handle unscheduler.default is 5 Minute")
 	fs.StringVar(&a.LicPath, "lic-path", "/opt/rainbond/etc/license/license.yb", "the license path of the enterprise version.")
 	fs.StringVar(&a.LicSoPath, "lic-so-path", "/opt/rainbond/etc/license/license.so", "Dynamic library file path for parsing the license.")
@@ -191,6 +212,8 @@ func (a *Conf) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&a.ImageRepositoryHost, "image-repo-host", "goodrain.me", "The host of image repository")
 	fs.StringVar(&a.GatewayVIP, "gateway-vip", "", "The vip of gateway")
 	fs.StringVar(&a.HostsFile, "hostsfile", "/newetc/hosts", "/etc/hosts mapped path in the container. eg. /etc/hosts:/tmp/hosts. Do not set hostsfile to /etc/hosts")
+	fs.StringVar(&a.MeshTrustDomain, "mesh-trust-domain", "rainbond", "The trust domain of the SPIFFE ids issued to the components of the built-in service mesh")
+	fs.DurationVar(&a.MeshCertTTL, "mesh-cert-ttl", 24*time.Hour, "The lifetime of the workload certificates of the built-in service mesh, they are renewed automatically")
 }
 
 //SetLog 设置log
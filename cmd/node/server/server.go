@@ -75,6 +75,7 @@ func Run(cfg *option.Conf) error {
 		if err != nil {
 			return err
 		}
+		cfg.KubeClient = clientset
 
 		k8sDiscover := discover.NewK8sDiscover(ctx, clientset, cfg)
 		defer k8sDiscover.Stop()
@@ -21,6 +21,7 @@ package option
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -52,6 +53,12 @@ type Config struct {
 	LeaderElectionIdentity  string
 	RBDNamespace            string
 	GrdataPVCName           string
+	DefaultVolumeCapacity   int64
+	ACMEDirectoryURL        string
+	ACMEEmail               string
+	ACMERenewBefore         time.Duration
+	ACMECheckInterval       time.Duration
+	ACMEDNSPropagationWait  time.Duration
 }
 
 //Worker  worker server
@@ -91,6 +98,12 @@ func (a *Worker) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&a.LeaderElectionIdentity, "leader-election-identity", "", "Unique idenity of this attcher. Typically name of the pod where the attacher runs.")
 	fs.StringVar(&a.RBDNamespace, "rbd-system-namespace", "rbd-system", "rbd components kubernetes namespace")
 	fs.StringVar(&a.GrdataPVCName, "grdata-pvc-name", "rbd-cpt-grdata", "The name of grdata persistent volume claim")
+	fs.Int64Var(&a.DefaultVolumeCapacity, "default-volume-capacity", 500, "the capacity(GB) of the volume claim whose capacity is not specified")
+	fs.StringVar(&a.ACMEDirectoryURL, "acme-directory-url", "", "the acme directory url, such as https://acme-v02.api.letsencrypt.org/directory, acme certificates are disabled if it is empty")
+	fs.StringVar(&a.ACMEEmail, "acme-email", "", "the contact email of acme account")
+	fs.DurationVar(&a.ACMERenewBefore, "acme-renew-before", 30*24*time.Hour, "renew acme certificates that expire within this duration")
+	fs.DurationVar(&a.ACMECheckInterval, "acme-check-interval", time.Hour, "the interval of checking acme certificates")
+	fs.DurationVar(&a.ACMEDNSPropagationWait, "acme-dns-propagation-wait", time.Minute, "the time waiting for dns-01 challenge records to propagate")
 }
 
 //SetLog 设置log
@@ -33,6 +33,7 @@ import (
 	"github.com/goodrain/rainbond/worker/appm"
 	"github.com/goodrain/rainbond/worker/appm/controller"
 	"github.com/goodrain/rainbond/worker/appm/store"
+	"github.com/goodrain/rainbond/worker/appm/volume"
 	"github.com/goodrain/rainbond/worker/discover"
 	"github.com/goodrain/rainbond/worker/gc"
 	"github.com/goodrain/rainbond/worker/master"
@@ -45,6 +46,7 @@ import (
 //Run start run
 func Run(s *option.Worker) error {
 	errChan := make(chan error, 2)
+	volume.DefaultVolumeCapacity = s.Config.DefaultVolumeCapacity
 	dbconfig := config.Config{
 		DBType:              s.Config.DBType,
 		MysqlConnectionInfo: s.Config.MysqlConnectionInfo,
@@ -76,6 +76,27 @@ type ApplicationDao interface {
 	GetByServiceID(sid string) (*model.Application, error)
 }
 
+//AuditLogDao audit log dao, the audit logs are append-only
+type AuditLogDao interface {
+	Dao
+	ListAuditLogs(query *model.AuditLogQuery) ([]*model.AuditLog, int64, error)
+}
+
+//LogRetentionPolicyDao log retention policy dao
+type LogRetentionPolicyDao interface {
+	Dao
+	GetPolicy(tenantID, serviceID string) (*model.LogRetentionPolicy, error)
+	ListPolicies() ([]*model.LogRetentionPolicy, error)
+	DeletePolicy(tenantID, serviceID string) error
+}
+
+//LogArchiveDao log archive dao
+type LogArchiveDao interface {
+	Dao
+	GetArchive(kind, tenantID, serviceID, fileName string) (*model.LogArchive, error)
+	ListArchives(query *model.LogArchiveQuery) ([]*model.LogArchive, int64, error)
+}
+
 //AppConfigGroupDao Application config group Dao
 type AppConfigGroupDao interface {
 	Dao
@@ -411,6 +432,10 @@ type RegionUserInfoDao interface {
 	GetALLTokenInValidityPeriod() ([]*model.RegionUserInfo, error)
 	GetTokenByEid(eid string) (*model.RegionUserInfo, error)
 	GetTokenByTokenID(token string) (*model.RegionUserInfo, error)
+	ListScopedTokens(eid string) ([]*model.RegionUserInfo, error)
+	GetScopedToken(eid string, id uint) (*model.RegionUserInfo, error)
+	DeleteScopedToken(eid string, id uint) error
+	UpdateLastUsedAt(token string, lastUsedAt int) error
 }
 
 //RegionAPIClassDao RegionAPIClassDao
@@ -465,6 +490,7 @@ type RuleExtensionDao interface {
 	Dao
 	GetRuleExtensionByRuleID(ruleID string) ([]*model.RuleExtension, error)
 	DeleteRuleExtensionByRuleID(ruleID string) error
+	ListByKey(key string) ([]*model.RuleExtension, error)
 }
 
 // HTTPRuleDao -
@@ -503,6 +503,250 @@ func (mr *MockApplicationDaoMockRecorder) DeleteApp(appID interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApp", reflect.TypeOf((*MockApplicationDao)(nil).DeleteApp), appID)
 }
 
+// MockAuditLogDao is a mock of AuditLogDao interface.
+type MockAuditLogDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogDaoMockRecorder
+}
+
+// MockAuditLogDaoMockRecorder is the mock recorder for MockAuditLogDao.
+type MockAuditLogDaoMockRecorder struct {
+	mock *MockAuditLogDao
+}
+
+// NewMockAuditLogDao creates a new mock instance.
+func NewMockAuditLogDao(ctrl *gomock.Controller) *MockAuditLogDao {
+	mock := &MockAuditLogDao{ctrl: ctrl}
+	mock.recorder = &MockAuditLogDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLogDao) EXPECT() *MockAuditLogDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method.
+func (m *MockAuditLogDao) AddModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel.
+func (mr *MockAuditLogDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockAuditLogDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method.
+func (m *MockAuditLogDao) UpdateModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel.
+func (mr *MockAuditLogDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockAuditLogDao)(nil).UpdateModel), arg0)
+}
+
+// ListAuditLogs mocks base method.
+func (m *MockAuditLogDao) ListAuditLogs(query *model.AuditLogQuery) ([]*model.AuditLog, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLogs", query)
+	ret0, _ := ret[0].([]*model.AuditLog)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAuditLogs indicates an expected call of ListAuditLogs.
+func (mr *MockAuditLogDaoMockRecorder) ListAuditLogs(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLogs", reflect.TypeOf((*MockAuditLogDao)(nil).ListAuditLogs), query)
+}
+
+// MockLogRetentionPolicyDao is a mock of LogRetentionPolicyDao interface.
+type MockLogRetentionPolicyDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockLogRetentionPolicyDaoMockRecorder
+}
+
+// MockLogRetentionPolicyDaoMockRecorder is the mock recorder for MockLogRetentionPolicyDao.
+type MockLogRetentionPolicyDaoMockRecorder struct {
+	mock *MockLogRetentionPolicyDao
+}
+
+// NewMockLogRetentionPolicyDao creates a new mock instance.
+func NewMockLogRetentionPolicyDao(ctrl *gomock.Controller) *MockLogRetentionPolicyDao {
+	mock := &MockLogRetentionPolicyDao{ctrl: ctrl}
+	mock.recorder = &MockLogRetentionPolicyDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLogRetentionPolicyDao) EXPECT() *MockLogRetentionPolicyDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method.
+func (m *MockLogRetentionPolicyDao) AddModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel.
+func (mr *MockLogRetentionPolicyDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockLogRetentionPolicyDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method.
+func (m *MockLogRetentionPolicyDao) UpdateModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel.
+func (mr *MockLogRetentionPolicyDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockLogRetentionPolicyDao)(nil).UpdateModel), arg0)
+}
+
+// GetPolicy mocks base method.
+func (m *MockLogRetentionPolicyDao) GetPolicy(tenantID string, serviceID string) (*model.LogRetentionPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPolicy", tenantID, serviceID)
+	ret0, _ := ret[0].(*model.LogRetentionPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicy indicates an expected call of GetPolicy.
+func (mr *MockLogRetentionPolicyDaoMockRecorder) GetPolicy(tenantID interface{}, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicy", reflect.TypeOf((*MockLogRetentionPolicyDao)(nil).GetPolicy), tenantID, serviceID)
+}
+
+// ListPolicies mocks base method.
+func (m *MockLogRetentionPolicyDao) ListPolicies() ([]*model.LogRetentionPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPolicies")
+	ret0, _ := ret[0].([]*model.LogRetentionPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPolicies indicates an expected call of ListPolicies.
+func (mr *MockLogRetentionPolicyDaoMockRecorder) ListPolicies() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPolicies", reflect.TypeOf((*MockLogRetentionPolicyDao)(nil).ListPolicies))
+}
+
+// DeletePolicy mocks base method.
+func (m *MockLogRetentionPolicyDao) DeletePolicy(tenantID string, serviceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePolicy", tenantID, serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePolicy indicates an expected call of DeletePolicy.
+func (mr *MockLogRetentionPolicyDaoMockRecorder) DeletePolicy(tenantID interface{}, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePolicy", reflect.TypeOf((*MockLogRetentionPolicyDao)(nil).DeletePolicy), tenantID, serviceID)
+}
+
+// MockLogArchiveDao is a mock of LogArchiveDao interface.
+type MockLogArchiveDao struct {
+	ctrl     *gomock.Controller
+	recorder *MockLogArchiveDaoMockRecorder
+}
+
+// MockLogArchiveDaoMockRecorder is the mock recorder for MockLogArchiveDao.
+type MockLogArchiveDaoMockRecorder struct {
+	mock *MockLogArchiveDao
+}
+
+// NewMockLogArchiveDao creates a new mock instance.
+func NewMockLogArchiveDao(ctrl *gomock.Controller) *MockLogArchiveDao {
+	mock := &MockLogArchiveDao{ctrl: ctrl}
+	mock.recorder = &MockLogArchiveDaoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLogArchiveDao) EXPECT() *MockLogArchiveDaoMockRecorder {
+	return m.recorder
+}
+
+// AddModel mocks base method.
+func (m *MockLogArchiveDao) AddModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddModel indicates an expected call of AddModel.
+func (mr *MockLogArchiveDaoMockRecorder) AddModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddModel", reflect.TypeOf((*MockLogArchiveDao)(nil).AddModel), arg0)
+}
+
+// UpdateModel mocks base method.
+func (m *MockLogArchiveDao) UpdateModel(arg0 model.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel.
+func (mr *MockLogArchiveDaoMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockLogArchiveDao)(nil).UpdateModel), arg0)
+}
+
+// GetArchive mocks base method.
+func (m *MockLogArchiveDao) GetArchive(kind string, tenantID string, serviceID string, fileName string) (*model.LogArchive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchive", kind, tenantID, serviceID, fileName)
+	ret0, _ := ret[0].(*model.LogArchive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchive indicates an expected call of GetArchive.
+func (mr *MockLogArchiveDaoMockRecorder) GetArchive(kind interface{}, tenantID interface{}, serviceID interface{}, fileName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchive", reflect.TypeOf((*MockLogArchiveDao)(nil).GetArchive), kind, tenantID, serviceID, fileName)
+}
+
+// ListArchives mocks base method.
+func (m *MockLogArchiveDao) ListArchives(query *model.LogArchiveQuery) ([]*model.LogArchive, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListArchives", query)
+	ret0, _ := ret[0].([]*model.LogArchive)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListArchives indicates an expected call of ListArchives.
+func (mr *MockLogArchiveDaoMockRecorder) ListArchives(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListArchives", reflect.TypeOf((*MockLogArchiveDao)(nil).ListArchives), query)
+}
+
 // MockAppConfigGroupDao is a mock of AppConfigGroupDao interface.
 type MockAppConfigGroupDao struct {
 	ctrl     *gomock.Controller
@@ -4504,6 +4748,64 @@ func (mr *MockRegionUserInfoDaoMockRecorder) GetTokenByTokenID(token interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenByTokenID", reflect.TypeOf((*MockRegionUserInfoDao)(nil).GetTokenByTokenID), token)
 }
 
+// ListScopedTokens mocks base method.
+func (m *MockRegionUserInfoDao) ListScopedTokens(eid string) ([]*model.RegionUserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScopedTokens", eid)
+	ret0, _ := ret[0].([]*model.RegionUserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScopedTokens indicates an expected call of ListScopedTokens.
+func (mr *MockRegionUserInfoDaoMockRecorder) ListScopedTokens(eid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScopedTokens", reflect.TypeOf((*MockRegionUserInfoDao)(nil).ListScopedTokens), eid)
+}
+
+// GetScopedToken mocks base method.
+func (m *MockRegionUserInfoDao) GetScopedToken(eid string, id uint) (*model.RegionUserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScopedToken", eid, id)
+	ret0, _ := ret[0].(*model.RegionUserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScopedToken indicates an expected call of GetScopedToken.
+func (mr *MockRegionUserInfoDaoMockRecorder) GetScopedToken(eid, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScopedToken", reflect.TypeOf((*MockRegionUserInfoDao)(nil).GetScopedToken), eid, id)
+}
+
+// DeleteScopedToken mocks base method.
+func (m *MockRegionUserInfoDao) DeleteScopedToken(eid string, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteScopedToken", eid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteScopedToken indicates an expected call of DeleteScopedToken.
+func (mr *MockRegionUserInfoDaoMockRecorder) DeleteScopedToken(eid, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteScopedToken", reflect.TypeOf((*MockRegionUserInfoDao)(nil).DeleteScopedToken), eid, id)
+}
+
+// UpdateLastUsedAt mocks base method.
+func (m *MockRegionUserInfoDao) UpdateLastUsedAt(token string, lastUsedAt int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsedAt", token, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsedAt indicates an expected call of UpdateLastUsedAt.
+func (mr *MockRegionUserInfoDaoMockRecorder) UpdateLastUsedAt(token, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsedAt", reflect.TypeOf((*MockRegionUserInfoDao)(nil).UpdateLastUsedAt), token, lastUsedAt)
+}
+
 // MockRegionAPIClassDao is a mock of RegionAPIClassDao interface.
 type MockRegionAPIClassDao struct {
 	ctrl     *gomock.Controller
@@ -5141,6 +5443,21 @@ func (mr *MockRuleExtensionDaoMockRecorder) DeleteRuleExtensionByRuleID(ruleID i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRuleExtensionByRuleID", reflect.TypeOf((*MockRuleExtensionDao)(nil).DeleteRuleExtensionByRuleID), ruleID)
 }
 
+// ListByKey mocks base method.
+func (m *MockRuleExtensionDao) ListByKey(key string) ([]*model.RuleExtension, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByKey", key)
+	ret0, _ := ret[0].([]*model.RuleExtension)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByKey indicates an expected call of ListByKey.
+func (mr *MockRuleExtensionDaoMockRecorder) ListByKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByKey", reflect.TypeOf((*MockRuleExtensionDao)(nil).ListByKey), key)
+}
+
 // MockHTTPRuleDao is a mock of HTTPRuleDao interface.
 type MockHTTPRuleDao struct {
 	ctrl     *gomock.Controller
@@ -39,6 +39,9 @@ type Manager interface {
 	LicenseDao() dao.LicenseDao
 	AppDao() dao.AppDao
 	ApplicationDao() dao.ApplicationDao
+	AuditLogDao() dao.AuditLogDao
+	LogRetentionPolicyDao() dao.LogRetentionPolicyDao
+	LogArchiveDao() dao.LogArchiveDao
 	AppConfigGroupDao() dao.AppConfigGroupDao
 	AppConfigGroupDaoTransactions(db *gorm.DB) dao.AppConfigGroupDao
 	AppConfigGroupServiceDao() dao.AppConfigGroupServiceDao
@@ -132,6 +132,48 @@ func (mr *MockManagerMockRecorder) ApplicationDao() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationDao", reflect.TypeOf((*MockManager)(nil).ApplicationDao))
 }
 
+// AuditLogDao mocks base method
+func (m *MockManager) AuditLogDao() dao.AuditLogDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLogDao")
+	ret0, _ := ret[0].(dao.AuditLogDao)
+	return ret0
+}
+
+// AuditLogDao indicates an expected call of AuditLogDao
+func (mr *MockManagerMockRecorder) AuditLogDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLogDao", reflect.TypeOf((*MockManager)(nil).AuditLogDao))
+}
+
+// LogRetentionPolicyDao mocks base method
+func (m *MockManager) LogRetentionPolicyDao() dao.LogRetentionPolicyDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogRetentionPolicyDao")
+	ret0, _ := ret[0].(dao.LogRetentionPolicyDao)
+	return ret0
+}
+
+// LogRetentionPolicyDao indicates an expected call of LogRetentionPolicyDao
+func (mr *MockManagerMockRecorder) LogRetentionPolicyDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogRetentionPolicyDao", reflect.TypeOf((*MockManager)(nil).LogRetentionPolicyDao))
+}
+
+// LogArchiveDao mocks base method
+func (m *MockManager) LogArchiveDao() dao.LogArchiveDao {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogArchiveDao")
+	ret0, _ := ret[0].(dao.LogArchiveDao)
+	return ret0
+}
+
+// LogArchiveDao indicates an expected call of LogArchiveDao
+func (mr *MockManagerMockRecorder) LogArchiveDao() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogArchiveDao", reflect.TypeOf((*MockManager)(nil).LogArchiveDao))
+}
+
 // AppConfigGroupDao mocks base method
 func (m *MockManager) AppConfigGroupDao() dao.AppConfigGroupDao {
 	m.ctrl.T.Helper()
@@ -41,3 +41,6 @@ var SERVERSOURCE = "server_source"
 
 //ALLPOWER ALLPOWER
 var ALLPOWER = "all_power"
+
+//SCOPED the token is limited to a tenant or an application
+var SCOPED = "scoped"
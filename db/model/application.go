@@ -1,15 +1,52 @@
 package model
 
+import "encoding/json"
+
 const (
 	// GovernanceModeBuildInServiceMesh means the governance mode is BUILD_IN_SERVICE_MESH
 	GovernanceModeBuildInServiceMesh = "BUILD_IN_SERVICE_MESH"
 	// GovernanceModeKubernetesNativeService means the governance mode is KUBERNETES_NATIVE_SERVICE
 	GovernanceModeKubernetesNativeService = "KUBERNETES_NATIVE_SERVICE"
+	// GovernanceModeIstioServiceMesh means the governance mode is ISTIO_SERVICE_MESH
+	GovernanceModeIstioServiceMesh = "ISTIO_SERVICE_MESH"
 )
 
 // IsGovernanceModeValid checks if the governanceMode is valid.
 func IsGovernanceModeValid(governanceMode string) bool {
-	return governanceMode == GovernanceModeBuildInServiceMesh || governanceMode == GovernanceModeKubernetesNativeService
+	return governanceMode == GovernanceModeBuildInServiceMesh ||
+		governanceMode == GovernanceModeKubernetesNativeService ||
+		governanceMode == GovernanceModeIstioServiceMesh
+}
+
+const (
+	// MTLSModePermissive means the components of the application accept both mutual TLS and plaintext traffic
+	MTLSModePermissive = "PERMISSIVE"
+	// MTLSModeStrict means the components of the application only accept mutual TLS traffic
+	MTLSModeStrict = "STRICT"
+	// MTLSModeDisable disables mutual TLS of the application, it is stored as the empty mode
+	MTLSModeDisable = "DISABLE"
+)
+
+// IsMTLSModeValid checks if the mtlsMode is valid.
+func IsMTLSModeValid(mtlsMode string) bool {
+	return mtlsMode == MTLSModePermissive || mtlsMode == MTLSModeStrict || mtlsMode == MTLSModeDisable
+}
+
+const (
+	// TracingProviderZipkin sends the spans to a zipkin collector
+	TracingProviderZipkin = "zipkin"
+	// TracingProviderJaeger sends the spans to the zipkin compatible endpoint of a jaeger collector
+	TracingProviderJaeger = "jaeger"
+)
+
+// TracingConfig is the tracing config of the built-in service mesh
+type TracingConfig struct {
+	Provider string `json:"provider"`
+	// Collector is the address of the collector, host:port
+	Collector string `json:"collector"`
+	// SamplingRate is the percentage of the sampled requests, 0-100
+	SamplingRate float64           `json:"sampling_rate"`
+	Tags         map[string]string `json:"tags"`
 }
 
 // Application -
@@ -19,6 +56,34 @@ type Application struct {
 	AppID          string `gorm:"column:app_id" json:"app_id"`
 	TenantID       string `gorm:"column:tenant_id" json:"tenant_id"`
 	GovernanceMode string `gorm:"column:governance_mode;default:'BUILD_IN_SERVICE_MESH'" json:"governance_mode"`
+	// DisableNetworkIsolation allows all ingress traffic to the components of the application
+	DisableNetworkIsolation bool `gorm:"column:disable_network_isolation;default:false" json:"disable_network_isolation"`
+	// MTLSMode is the mutual TLS mode of the built-in service mesh.
+	// In the strict mode the plaintext traffic, including the traffic from the gateway, is rejected.
+	// Mutual TLS is disabled if it is empty, it is only enabled by an explicit mode.
+	MTLSMode string `gorm:"column:mtls_mode" json:"mtls_mode"`
+	// the tracing config of the built-in service mesh, tracing is disabled if the collector is empty
+	TracingProvider     string  `gorm:"column:tracing_provider" json:"tracing_provider"`
+	TracingCollector    string  `gorm:"column:tracing_collector" json:"tracing_collector"`
+	TracingSamplingRate float64 `gorm:"column:tracing_sampling_rate" json:"tracing_sampling_rate"`
+	// TracingTags is the json encoded custom tags added to the spans
+	TracingTags string `gorm:"column:tracing_tags;type:text" json:"tracing_tags"`
+}
+
+// GetTracingConfig returns the tracing config of the application, nil if tracing is disabled.
+func (t *Application) GetTracingConfig() *TracingConfig {
+	if t.TracingCollector == "" {
+		return nil
+	}
+	config := &TracingConfig{
+		Provider:     t.TracingProvider,
+		Collector:    t.TracingCollector,
+		SamplingRate: t.TracingSamplingRate,
+	}
+	if t.TracingTags != "" {
+		_ = json.Unmarshal([]byte(t.TracingTags), &config.Tags)
+	}
+	return config
 }
 
 // TableName return tableName "application"
@@ -0,0 +1,60 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+//AuditLog the record of a mutating api call, it can not be modified once it is created
+type AuditLog struct {
+	Model
+	// the operator of the call
+	Actor string `gorm:"column:actor;size:64" json:"actor"`
+	// the id of the region token used by the call
+	TokenID    uint   `gorm:"column:token_id" json:"token_id"`
+	SourceIP   string `gorm:"column:source_ip;size:64" json:"source_ip"`
+	Method     string `gorm:"column:method;size:8" json:"method"`
+	URI        string `gorm:"column:uri;size:512" json:"uri"`
+	TenantName string `gorm:"column:tenant_name;size:64;index" json:"tenant_name"`
+	// the kind of the target resource, such as tenant, service, application, http-rule
+	TargetType string `gorm:"column:target_type;size:64" json:"target_type"`
+	TargetID   string `gorm:"column:target_id;size:64" json:"target_id"`
+	StatusCode int    `gorm:"column:status_code" json:"status_code"`
+	// the json of the target resource before and after the call
+	Before string `gorm:"column:before;type:text" json:"before"`
+	After  string `gorm:"column:after;type:text" json:"after"`
+}
+
+//TableName returns table name of AuditLog
+func (a *AuditLog) TableName() string {
+	return "audit_log"
+}
+
+//AuditLogQuery the filters of audit logs, the empty fields are ignored
+type AuditLogQuery struct {
+	TenantName string
+	Actor      string
+	TokenID    uint
+	TargetType string
+	TargetID   string
+	Method     string
+	StartTime  time.Time
+	EndTime    time.Time
+	Page       int
+	PageSize   int
+}
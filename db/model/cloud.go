@@ -18,6 +18,8 @@
 
 package model
 
+import "strings"
+
 //TableName 表名
 func (t *RegionUserInfo) TableName() string {
 	return "user_region_info"
@@ -33,4 +35,30 @@ type RegionUserInfo struct {
 	Token          string `gorm:"column:token;size:32" json:"token"`
 	CA             string `gorm:"column:ca;size:4096" json:"ca"`
 	Key            string `gorm:"column:key;size:4096" json:"key"`
+	// the following fields only work for the scoped token
+	Name       string `gorm:"column:name;size:64" json:"name"`
+	TenantID   string `gorm:"column:tenant_id;size:32" json:"tenant_id"`
+	TenantName string `gorm:"column:tenant_name;size:64" json:"tenant_name"`
+	AppID      string `gorm:"column:app_id;size:32" json:"app_id"`
+	// the allowed http methods, separated by comma, empty means all methods are allowed
+	AllowedMethods string `gorm:"column:allowed_methods;size:64" json:"allowed_methods"`
+	LastUsedAt     int    `gorm:"column:last_used_at;size:10" json:"last_used_at"`
+}
+
+//IsScoped whether the token is limited to a tenant or an application
+func (t *RegionUserInfo) IsScoped() bool {
+	return t.APIRange == SCOPED
+}
+
+//MethodAllowed whether the http method is allowed by the token
+func (t *RegionUserInfo) MethodAllowed(method string) bool {
+	if t.AllowedMethods == "" {
+		return true
+	}
+	for _, m := range strings.Split(t.AllowedMethods, ",") {
+		if strings.EqualFold(strings.TrimSpace(m), method) {
+			return true
+		}
+	}
+	return false
 }
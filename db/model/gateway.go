@@ -18,11 +18,20 @@
 
 package model
 
+import "time"
+
 // TableName returns table name of Certificate
 func (Certificate) TableName() string {
 	return "gateway_certificate"
 }
 
+// CertificateIssuerACME the certificate is obtained and renewed by the acme client of the worker
+var CertificateIssuerACME = "acme"
+
+//ErrorPagesLabel the label of the configmaps which contain the custom error pages of http rules,
+//the other configmaps of the tenant are never updated, deleted or served as error pages
+var ErrorPagesLabel = "error_pages"
+
 // Certificate contains TLS information
 type Certificate struct {
 	Model
@@ -30,6 +39,9 @@ type Certificate struct {
 	CertificateName string `gorm:"column:certificate_name;size:128"`
 	Certificate     string `gorm:"column:certificate;size:65535"`
 	PrivateKey      string `gorm:"column:private_key;size:65535"`
+	// Issuer is empty if the certificate is uploaded by user
+	Issuer    string     `gorm:"column:issuer;size:32"`
+	ExpiresAt *time.Time `gorm:"column:expires_at"`
 }
 
 // TableName returns table name of RuleExtension
@@ -46,6 +58,39 @@ var HTTPToHTTPS RuleExtensionKey = "httptohttps"
 // LBType load balancer type
 var LBType RuleExtensionKey = "lb-type"
 
+// ACME obtains the certificate of the http rule automatically,
+// the value is the challenge type, http-01 or dns-01:<dns provider>
+var ACME RuleExtensionKey = "acme"
+
+// CORS enables the cross-origin resource sharing of the http rule, the value is true or false
+var CORS RuleExtensionKey = "cors"
+
+// CORSAllowOrigin the comma separated origins allowed by the http rule, eg: https://*.example.com
+var CORSAllowOrigin RuleExtensionKey = "cors-allow-origin"
+
+// CORSAllowMethods the comma separated methods allowed by the http rule
+var CORSAllowMethods RuleExtensionKey = "cors-allow-methods"
+
+// CORSAllowHeaders the comma separated request headers allowed by the http rule
+var CORSAllowHeaders RuleExtensionKey = "cors-allow-headers"
+
+// CORSExposeHeaders the comma separated response headers exposed to the browsers
+var CORSExposeHeaders RuleExtensionKey = "cors-expose-headers"
+
+// CORSAllowCredentials whether the credentials are allowed, the value is true or false,
+// defaults to false and only takes effect with an explicit origin list
+var CORSAllowCredentials RuleExtensionKey = "cors-allow-credentials"
+
+// CORSMaxAge the seconds that the preflight responses are cached
+var CORSMaxAge RuleExtensionKey = "cors-max-age"
+
+// SetResponseHeader sets a header of the responses, the value is <name>:<value>,
+// eg: Strict-Transport-Security:max-age=31536000
+var SetResponseHeader RuleExtensionKey = "set-response-header"
+
+// RemoveResponseHeader removes a header from the responses, the value is the header name
+var RemoveResponseHeader RuleExtensionKey = "remove-response-header"
+
 // RuleExtension contains rule extensions for http rule or tcp rule
 type RuleExtension struct {
 	Model
@@ -82,6 +127,17 @@ type HTTPRule struct {
 	Weight        int    `gorm:"column:weight"`
 	IP            string `gorm:"column:ip"`
 	CertificateID string `gorm:"column:certificate_id"`
+	// BackendProtocol the protocol used to communicate with the service, HTTP, HTTPS, GRPC or GRPCS
+	BackendProtocol string `gorm:"column:backend_protocol;size:8"`
+	// ProxySSLVerify whether the certificate of the service is verified, it works with HTTPS and GRPCS
+	ProxySSLVerify bool `gorm:"column:proxy_ssl_verify"`
+	// ErrorPages the name of the configmap which contains the custom error pages of the domain,
+	// the configmap is in the namespace of the tenant, and the keys are <status code>.html or maintenance.html
+	ErrorPages string `gorm:"column:error_pages"`
+	// Maintenance the gateway answers the requests with the maintenance page instead of passing them to the service
+	Maintenance bool `gorm:"column:maintenance"`
+	// MaintenanceRetryAfter the seconds of the Retry-After header of the maintenance page
+	MaintenanceRetryAfter int `gorm:"column:maintenance_retry_after"`
 }
 
 // TableName returns table name of TCPRule
@@ -0,0 +1,91 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+//LogRetentionPolicy the retention policy of the logs of a tenant or a component.
+//The policy of a component overrides the policy of its tenant, 0 days means the default retention,
+//LogRetentionForever means the logs are kept forever.
+type LogRetentionPolicy struct {
+	Model
+	TenantID string `gorm:"column:tenant_id;size:32;unique_index:tenant_service_id" json:"tenant_id"`
+	// it is empty for the policy of the tenant
+	ServiceID string `gorm:"column:service_id;size:32;unique_index:tenant_service_id" json:"service_id"`
+	// the days the event logs are kept
+	EventLogDays int `gorm:"column:event_log_days" json:"event_log_days"`
+	// the days the container logs are kept
+	ContainerLogDays int `gorm:"column:container_log_days" json:"container_log_days"`
+	// whether to archive the logs past the retention instead of deleting them
+	Archive bool `gorm:"column:archive" json:"archive"`
+}
+
+//LogRetentionForever the days of the log retention policy which keeps the logs forever
+const LogRetentionForever = -1
+
+//TableName returns table name of LogRetentionPolicy
+func (l *LogRetentionPolicy) TableName() string {
+	return "log_retention_policy"
+}
+
+//the kinds of the archived logs
+const (
+	LogArchiveKindContainer = "container"
+	LogArchiveKindEvent     = "event"
+)
+
+//LogArchive the compressed log file which is past the retention
+type LogArchive struct {
+	Model
+	TenantID  string `gorm:"column:tenant_id;size:32;index" json:"tenant_id"`
+	ServiceID string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	// container or event
+	Kind string `gorm:"column:kind;size:16" json:"kind"`
+	// the name of the log file, such as 2020-1-2.log.gz or <event id>.log
+	FileName string `gorm:"column:file_name;size:64" json:"file_name"`
+	// the time range of the logs in the archive
+	StartTime time.Time `gorm:"column:start_time" json:"start_time"`
+	EndTime   time.Time `gorm:"column:end_time" json:"end_time"`
+	// the size of the compressed file
+	Size int64 `gorm:"column:size" json:"size"`
+	// the key in the object storage, it is empty if the archive is kept in local
+	ObjectKey string `gorm:"column:object_key;size:255" json:"object_key"`
+	LocalPath string `gorm:"column:local_path;size:255" json:"-"`
+	// the eventlog instance which made the archive, the archive is restored by it
+	HostID string `gorm:"column:host_id;size:64" json:"host_id"`
+	// the last time the logs were restored for viewing
+	RestoredAt *time.Time `gorm:"column:restored_at" json:"restored_at,omitempty"`
+}
+
+//TableName returns table name of LogArchive
+func (l *LogArchive) TableName() string {
+	return "log_archive"
+}
+
+//LogArchiveQuery the filters of log archives, the empty fields are ignored
+type LogArchiveQuery struct {
+	TenantID  string
+	ServiceID string
+	Kind      string
+	// the archives which overlap the time range
+	StartTime time.Time
+	EndTime   time.Time
+	Page      int
+	PageSize  int
+}
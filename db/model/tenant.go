@@ -65,6 +65,21 @@ type Tenants struct {
 	EID         string `gorm:"column:eid"`
 	LimitMemory int    `gorm:"column:limit_memory"`
 	Status      string `gorm:"column:status;default:'normal'"`
+	//the quotas of the tenant, 0 means unlimited
+	//cpu limit, unit: m
+	LimitCPU int `gorm:"column:limit_cpu"`
+	//the maximum number of components
+	LimitComponents int `gorm:"column:limit_components"`
+	//the maximum number of replicas of all components
+	LimitReplicas int `gorm:"column:limit_replicas"`
+	//persistent storage limit, unit: GB
+	LimitStorage int `gorm:"column:limit_storage"`
+	//the maximum number of gateway domains
+	LimitDomains int `gorm:"column:limit_domains"`
+	//the maximum number of gateway tcp ports
+	LimitTCPPorts int `gorm:"column:limit_tcp_ports"`
+	//the days the component logs are kept in the log index, 0 means the default retention of eventlog
+	LogRetentionDays int `gorm:"column:log_retention_days"`
 }
 
 //TableName 返回租户表名称
@@ -504,9 +519,18 @@ var LabelKeyServiceAffinity = "service-affinity"
 //LabelKeyServiceAntyAffinity 应用反亲和标签
 var LabelKeyServiceAntyAffinity = "service-anti-affinity"
 
+//LabelKeyServiceZoneAffinity 应用同可用区亲和标签, 值为依赖应用的别名, 尽量调度到依赖应用所在的可用区
+var LabelKeyServiceZoneAffinity = "service-zone-affinity"
+
+//LabelKeyTopologySpread 应用拓扑分布标签, 例如 zone, node:2 或 zone:1:DoNotSchedule
+var LabelKeyTopologySpread = "topology-spread"
+
 // LabelKeyServicePrivileged -
 var LabelKeyServicePrivileged = "privileged"
 
+//LabelKeyPodDisruptionBudget 应用中断预算标签, 例如 min-available=2, max-unavailable=25% 或 disabled
+var LabelKeyPodDisruptionBudget = "pod-disruption-budget"
+
 //TenantServiceProbe 应用探针信息
 type TenantServiceProbe struct {
 	Model
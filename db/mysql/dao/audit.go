@@ -0,0 +1,88 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+//AuditLogDaoImpl audit log dao
+type AuditLogDaoImpl struct {
+	DB *gorm.DB
+}
+
+//AddModel adds an audit log
+func (a *AuditLogDaoImpl) AddModel(mo model.Interface) error {
+	log := mo.(*model.AuditLog)
+	return a.DB.Create(log).Error
+}
+
+//UpdateModel audit logs are append-only
+func (a *AuditLogDaoImpl) UpdateModel(mo model.Interface) error {
+	return fmt.Errorf("audit log can not be modified")
+}
+
+//ListAuditLogs lists the audit logs that match the query, the newest first
+func (a *AuditLogDaoImpl) ListAuditLogs(query *model.AuditLogQuery) ([]*model.AuditLog, int64, error) {
+	db := a.DB.Model(&model.AuditLog{})
+	if query.TenantName != "" {
+		db = db.Where("tenant_name=?", query.TenantName)
+	}
+	if query.Actor != "" {
+		db = db.Where("actor=?", query.Actor)
+	}
+	if query.TokenID != 0 {
+		db = db.Where("token_id=?", query.TokenID)
+	}
+	if query.TargetType != "" {
+		db = db.Where("target_type=?", query.TargetType)
+	}
+	if query.TargetID != "" {
+		db = db.Where("target_id=?", query.TargetID)
+	}
+	if query.Method != "" {
+		db = db.Where("method=?", query.Method)
+	}
+	if !query.StartTime.IsZero() {
+		db = db.Where("create_time>=?", query.StartTime)
+	}
+	if !query.EndTime.IsZero() {
+		db = db.Where("create_time<?", query.EndTime)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	db = db.Order("create_time desc")
+	if query.PageSize > 0 {
+		page := query.Page
+		if page < 1 {
+			page = 1
+		}
+		db = db.Limit(query.PageSize).Offset((page - 1) * query.PageSize)
+	}
+	var logs []*model.AuditLog
+	if err := db.Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
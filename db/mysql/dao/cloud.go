@@ -34,8 +34,11 @@ type RegionUserInfoDaoImpl struct {
 //AddModel 添加cloud信息
 func (t *RegionUserInfoDaoImpl) AddModel(mo model.Interface) error {
 	info := mo.(*model.RegionUserInfo)
+	if info.IsScoped() {
+		return t.DB.Create(info).Error
+	}
 	var oldInfo model.RegionUserInfo
-	if ok := t.DB.Where("eid = ?", info.EID).Find(&oldInfo).RecordNotFound(); ok {
+	if ok := t.DB.Where("eid = ? and api_range <> ?", info.EID, model.SCOPED).Find(&oldInfo).RecordNotFound(); ok {
 		if err := t.DB.Create(info).Error; err != nil {
 			return err
 		}
@@ -60,7 +63,7 @@ func (t *RegionUserInfoDaoImpl) UpdateModel(mo model.Interface) error {
 //GetTokenByEid GetTokenByEid
 func (t *RegionUserInfoDaoImpl) GetTokenByEid(eid string) (*model.RegionUserInfo, error) {
 	var rui model.RegionUserInfo
-	if err := t.DB.Where("eid=?", eid).Find(&rui).Error; err != nil {
+	if err := t.DB.Where("eid=? and api_range <> ?", eid, model.SCOPED).Find(&rui).Error; err != nil {
 		return nil, err
 	}
 	return &rui, nil
@@ -79,8 +82,36 @@ func (t *RegionUserInfoDaoImpl) GetTokenByTokenID(token string) (*model.RegionUs
 func (t *RegionUserInfoDaoImpl) GetALLTokenInValidityPeriod() ([]*model.RegionUserInfo, error) {
 	var ruis []*model.RegionUserInfo
 	timestamp := int(time.Now().Unix())
-	if err := t.DB.Select("api_range, validity_period, token").Where("validity_period > ?", timestamp).Find(&ruis).Error; err != nil {
+	if err := t.DB.Select("id, eid, api_range, validity_period, token, tenant_id, tenant_name, app_id, allowed_methods, last_used_at").Where("validity_period > ?", timestamp).Find(&ruis).Error; err != nil {
 		return nil, err
 	}
 	return ruis, nil
 }
+
+//ListScopedTokens lists the scoped tokens of the enterprise
+func (t *RegionUserInfoDaoImpl) ListScopedTokens(eid string) ([]*model.RegionUserInfo, error) {
+	var ruis []*model.RegionUserInfo
+	if err := t.DB.Where("eid=? and api_range=?", eid, model.SCOPED).Find(&ruis).Error; err != nil {
+		return nil, err
+	}
+	return ruis, nil
+}
+
+//GetScopedToken gets the scoped token of the enterprise by id
+func (t *RegionUserInfoDaoImpl) GetScopedToken(eid string, id uint) (*model.RegionUserInfo, error) {
+	var rui model.RegionUserInfo
+	if err := t.DB.Where("eid=? and api_range=? and id=?", eid, model.SCOPED, id).Find(&rui).Error; err != nil {
+		return nil, err
+	}
+	return &rui, nil
+}
+
+//DeleteScopedToken deletes the scoped token of the enterprise
+func (t *RegionUserInfoDaoImpl) DeleteScopedToken(eid string, id uint) error {
+	return t.DB.Where("eid=? and api_range=? and id=?", eid, model.SCOPED, id).Delete(&model.RegionUserInfo{}).Error
+}
+
+//UpdateLastUsedAt updates the last used time of the token
+func (t *RegionUserInfoDaoImpl) UpdateLastUsedAt(token string, lastUsedAt int) error {
+	return t.DB.Model(&model.RegionUserInfo{}).Where("token=?", token).UpdateColumn("last_used_at", lastUsedAt).Error
+}
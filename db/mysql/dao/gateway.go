@@ -79,6 +79,8 @@ func (c *CertificateDaoImpl) AddOrUpdate(mo model.Interface) error {
 	// update certificate
 	old.Certificate = cert.Certificate
 	old.PrivateKey = cert.PrivateKey
+	old.Issuer = cert.Issuer
+	old.ExpiresAt = cert.ExpiresAt
 	return c.DB.Table(cert.TableName()).Where("uuid = ?", cert.UUID).Save(&old).Error
 }
 
@@ -131,6 +133,18 @@ func (c *RuleExtensionDaoImpl) GetRuleExtensionByRuleID(ruleID string) ([]*model
 	return ruleExtension, nil
 }
 
+// ListByKey lists the rule extensions of all the rules by the key
+func (c *RuleExtensionDaoImpl) ListByKey(key string) ([]*model.RuleExtension, error) {
+	var ruleExtension []*model.RuleExtension
+	if err := c.DB.Where("`key` = ?", key).Find(&ruleExtension).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ruleExtension, nil
+		}
+		return nil, err
+	}
+	return ruleExtension, nil
+}
+
 // DeleteRuleExtensionByRuleID delete rule extensions by ruleID
 func (c *RuleExtensionDaoImpl) DeleteRuleExtensionByRuleID(ruleID string) error {
 	re := &model.RuleExtension{
@@ -0,0 +1,127 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package dao
+
+import (
+	"github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+//LogRetentionPolicyDaoImpl log retention policy dao
+type LogRetentionPolicyDaoImpl struct {
+	DB *gorm.DB
+}
+
+//AddModel adds a log retention policy
+func (l *LogRetentionPolicyDaoImpl) AddModel(mo model.Interface) error {
+	policy := mo.(*model.LogRetentionPolicy)
+	return l.DB.Create(policy).Error
+}
+
+//UpdateModel updates the log retention policy
+func (l *LogRetentionPolicyDaoImpl) UpdateModel(mo model.Interface) error {
+	policy := mo.(*model.LogRetentionPolicy)
+	return l.DB.Save(policy).Error
+}
+
+//GetPolicy returns the policy of the component, or the policy of the tenant if the service id is empty
+func (l *LogRetentionPolicyDaoImpl) GetPolicy(tenantID, serviceID string) (*model.LogRetentionPolicy, error) {
+	var policy model.LogRetentionPolicy
+	if err := l.DB.Where("tenant_id=? and service_id=?", tenantID, serviceID).Find(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+//ListPolicies lists all the log retention policies
+func (l *LogRetentionPolicyDaoImpl) ListPolicies() ([]*model.LogRetentionPolicy, error) {
+	var policies []*model.LogRetentionPolicy
+	if err := l.DB.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+//DeletePolicy deletes the log retention policy
+func (l *LogRetentionPolicyDaoImpl) DeletePolicy(tenantID, serviceID string) error {
+	return l.DB.Where("tenant_id=? and service_id=?", tenantID, serviceID).Delete(&model.LogRetentionPolicy{}).Error
+}
+
+//LogArchiveDaoImpl log archive dao
+type LogArchiveDaoImpl struct {
+	DB *gorm.DB
+}
+
+//AddModel adds a log archive
+func (l *LogArchiveDaoImpl) AddModel(mo model.Interface) error {
+	archive := mo.(*model.LogArchive)
+	return l.DB.Create(archive).Error
+}
+
+//UpdateModel updates the log archive
+func (l *LogArchiveDaoImpl) UpdateModel(mo model.Interface) error {
+	archive := mo.(*model.LogArchive)
+	return l.DB.Save(archive).Error
+}
+
+//GetArchive returns the archive of the log file
+func (l *LogArchiveDaoImpl) GetArchive(kind, tenantID, serviceID, fileName string) (*model.LogArchive, error) {
+	var archive model.LogArchive
+	if err := l.DB.Where("kind=? and tenant_id=? and service_id=? and file_name=?", kind, tenantID, serviceID, fileName).Find(&archive).Error; err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+//ListArchives lists the log archives that match the query, the newest first
+func (l *LogArchiveDaoImpl) ListArchives(query *model.LogArchiveQuery) ([]*model.LogArchive, int64, error) {
+	db := l.DB.Model(&model.LogArchive{})
+	if query.TenantID != "" {
+		db = db.Where("tenant_id=?", query.TenantID)
+	}
+	if query.ServiceID != "" {
+		db = db.Where("service_id=?", query.ServiceID)
+	}
+	if query.Kind != "" {
+		db = db.Where("kind=?", query.Kind)
+	}
+	if !query.StartTime.IsZero() {
+		db = db.Where("end_time>=?", query.StartTime)
+	}
+	if !query.EndTime.IsZero() {
+		db = db.Where("start_time<?", query.EndTime)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	db = db.Order("start_time desc")
+	if query.PageSize > 0 {
+		page := query.Page
+		if page < 1 {
+			page = 1
+		}
+		db = db.Limit(query.PageSize).Offset((page - 1) * query.PageSize)
+	}
+	var archives []*model.LogArchive
+	if err := db.Find(&archives).Error; err != nil {
+		return nil, 0, err
+	}
+	return archives, total, nil
+}
@@ -1533,7 +1533,7 @@ func (t *ServiceLabelDaoImpl) GetTenantNodeAffinityLabel(serviceID string) (*mod
 func (t *ServiceLabelDaoImpl) GetTenantServiceAffinityLabel(serviceID string) ([]*model.TenantServiceLable, error) {
 	var labels []*model.TenantServiceLable
 	if err := t.DB.Where("service_id=? and label_key in (?)", serviceID, []string{model.LabelKeyNodeSelector, model.LabelKeyNodeAffinity,
-		model.LabelKeyServiceAffinity, model.LabelKeyServiceAntyAffinity}).Find(&labels).Error; err != nil {
+		model.LabelKeyServiceAffinity, model.LabelKeyServiceAntyAffinity, model.LabelKeyServiceZoneAffinity, model.LabelKeyTopologySpread}).Find(&labels).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return labels, nil
 		}
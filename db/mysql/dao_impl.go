@@ -424,6 +424,27 @@ func (m *Manager) ApplicationDao() dao.ApplicationDao {
 	}
 }
 
+// AuditLogDao -
+func (m *Manager) AuditLogDao() dao.AuditLogDao {
+	return &mysqldao.AuditLogDaoImpl{
+		DB: m.db,
+	}
+}
+
+// LogRetentionPolicyDao -
+func (m *Manager) LogRetentionPolicyDao() dao.LogRetentionPolicyDao {
+	return &mysqldao.LogRetentionPolicyDaoImpl{
+		DB: m.db,
+	}
+}
+
+// LogArchiveDao -
+func (m *Manager) LogArchiveDao() dao.LogArchiveDao {
+	return &mysqldao.LogArchiveDaoImpl{
+		DB: m.db,
+	}
+}
+
 // AppConfigGroupDao -
 func (m *Manager) AppConfigGroupDao() dao.AppConfigGroupDao {
 	return &mysqldao.AppConfigGroupDaoImpl{
@@ -126,6 +126,9 @@ func (m *Manager) RegisterTableModel() {
 	m.models = append(m.models, &model.AppBackup{})
 	m.models = append(m.models, &model.ServiceSourceConfig{})
 	m.models = append(m.models, &model.Application{})
+	m.models = append(m.models, &model.AuditLog{})
+	m.models = append(m.models, &model.LogRetentionPolicy{})
+	m.models = append(m.models, &model.LogArchive{})
 	m.models = append(m.models, &model.ApplicationConfigGroup{})
 	m.models = append(m.models, &model.ConfigGroupService{})
 	m.models = append(m.models, &model.ConfigGroupItem{})
@@ -18,6 +18,8 @@
 
 package conf
 
+import "time"
+
 // Conf conf
 type Conf struct {
 	Entry       EntryConf
@@ -43,6 +45,31 @@ type DBConf struct {
 	PoolSize    int
 	PoolMaxSize int
 	HomePath    string
+	//whether to index the container logs for searching
+	LogIndex bool
+	//the default days the container logs are kept in the log index
+	LogIndexRetention int
+	//the default days the event logs and container logs are kept, 0 means forever
+	EventLogRetention     int
+	ContainerLogRetention int
+	Archive               ArchiveConf
+	//the days the gateway access logs are kept, and the max megabytes of the access logs of a tenant in one day
+	AccessLogRetention  int
+	AccessLogMaxDaySize int
+}
+
+// ArchiveConf the object storage which the logs past the retention are archived to,
+// the archives are kept in local if the provider is empty.
+type ArchiveConf struct {
+	Provider   string
+	Endpoint   string
+	AccessKey  string
+	SecretKey  string
+	BucketName string
+	// how long the restored logs are kept for viewing
+	RestoreTTL time.Duration
+	// the node id of this instance, the archives are restored by the instance which made them
+	NodeID string
 }
 
 // WebSocketConf websocket conf
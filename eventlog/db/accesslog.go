@@ -0,0 +1,383 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	//AccessLogDirName the dir of the gateway access logs in the log home path
+	AccessLogDirName = "accesslog"
+	//the access logs of a tenant are written into one file per day
+	accessLogFileLayout  = "20060102"
+	maxAccessLogLineSize = 1024 * 1024
+	//MaxAccessLogWindow the max number of the newest matched logs which can be paged
+	MaxAccessLogWindow = 10000
+)
+
+var tenantIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+//AccessLog the access log of a http rule, it is emitted by the gateway
+type AccessLog struct {
+	Time         time.Time `json:"time"`
+	TenantID     string    `json:"tenant_id"`
+	ServiceID    string    `json:"service_id"`
+	RuleID       string    `json:"rule_id"`
+	ClientIP     string    `json:"client_ip"`
+	Host         string    `json:"host"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	BytesSent    int64     `json:"bytes_sent"`
+	RequestTime  float64   `json:"request_time"`
+	UpstreamAddr string    `json:"upstream_addr"`
+	Referer      string    `json:"referer"`
+	UserAgent    string    `json:"user_agent"`
+}
+
+//AccessLogQuery the query of the access logs of a tenant
+type AccessLogQuery struct {
+	TenantID string
+	RuleID   string
+	//the exact status code such as 502, or the status class such as 5xx
+	Status string
+	//the logs whose path has the prefix are matched
+	Path     string
+	ClientIP string
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+//AccessLogResult the result of the access log query, the logs are sorted by time desc
+type AccessLogResult struct {
+	Logs []*AccessLog `json:"logs"`
+	//there are more logs after the page
+	More bool `json:"more"`
+}
+
+func (q *AccessLogQuery) match(l *AccessLog) bool {
+	if q.RuleID != "" && l.RuleID != q.RuleID {
+		return false
+	}
+	if q.Status != "" {
+		status := strconv.Itoa(l.Status)
+		if strings.HasSuffix(q.Status, "xx") {
+			if !strings.HasPrefix(status, strings.TrimSuffix(q.Status, "xx")) {
+				return false
+			}
+		} else if status != q.Status {
+			return false
+		}
+	}
+	if q.Path != "" && !strings.HasPrefix(l.Path, q.Path) {
+		return false
+	}
+	if q.ClientIP != "" && l.ClientIP != q.ClientIP {
+		return false
+	}
+	if !q.From.IsZero() && l.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && l.Time.After(q.To) {
+		return false
+	}
+	return true
+}
+
+//AccessLogStore stores the gateway access logs of the tenants in daily files,
+//such as <home>/accesslog/<tenant id>/20200102.log, one json record per line.
+//The size of the daily file is limited, the logs beyond the limit are dropped.
+type AccessLogStore struct {
+	homePath   string
+	maxDaySize int64
+	lock       sync.Mutex
+}
+
+//NewAccessLogStore creates the access log store, maxDaySize is the max bytes of the logs of a tenant in one day
+func NewAccessLogStore(homePath string, maxDaySize int64) *AccessLogStore {
+	return &AccessLogStore{
+		homePath:   path.Join(homePath, AccessLogDirName),
+		maxDaySize: maxDaySize,
+	}
+}
+
+func (s *AccessLogStore) tenantDir(tenantID string) string {
+	return path.Join(s.homePath, tenantID)
+}
+
+//Write appends the access logs to the daily files of their tenants, the logs without a tenant are ignored
+func (s *AccessLogStore) Write(logs []*AccessLog) error {
+	files := make(map[string][]byte)
+	for _, l := range logs {
+		if !tenantIDRegex.MatchString(l.TenantID) {
+			continue
+		}
+		if l.Time.IsZero() {
+			l.Time = time.Now()
+		}
+		record, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		name := path.Join(s.tenantDir(l.TenantID), l.Time.Local().Format(accessLogFileLayout)+".log")
+		files[name] = append(append(files[name], record...), '\n')
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name, data := range files {
+		if err := s.appendFile(name, data); err != nil {
+			return fmt.Errorf("write access logs to %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *AccessLogStore) appendFile(name string, data []byte) error {
+	if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(name); err == nil && s.maxDaySize > 0 && info.Size()+int64(len(data)) > s.maxDaySize {
+		logrus.Warningf("the access logs of %s exceed the daily limit, %d bytes are dropped", name, len(data))
+		return nil
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+//Normalize validates the query and sets the default page and page size
+func (q *AccessLogQuery) Normalize() error {
+	if !tenantIDRegex.MatchString(q.TenantID) {
+		return fmt.Errorf("invalid tenant id %s", q.TenantID)
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 100
+	}
+	if q.PageSize > MaxAccessLogWindow {
+		q.PageSize = MaxAccessLogWindow
+	}
+	if q.Page*q.PageSize > MaxAccessLogWindow {
+		return fmt.Errorf("only the newest %d access logs can be paged, narrow the time range", MaxAccessLogWindow)
+	}
+	return nil
+}
+
+//Search returns the access logs of the tenant which match the query.
+//The daily files are read backward from the newest one, and the search stops once the page is filled.
+func (s *AccessLogStore) Search(query *AccessLogQuery) (*AccessLogResult, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(s.tenantDir(query.TenantID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &AccessLogResult{}, nil
+		}
+		return nil, err
+	}
+	result := &AccessLogResult{}
+	skip := (query.Page - 1) * query.PageSize
+	collect := func(l *AccessLog) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if len(result.Logs) == query.PageSize {
+			result.More = true
+			return false
+		}
+		result.Logs = append(result.Logs, l)
+		return true
+	}
+	//the files are sorted by name, which is the day
+	for i := len(files) - 1; i >= 0 && !result.More; i-- {
+		day, err := time.ParseInLocation(accessLogFileLayout, strings.TrimSuffix(files[i].Name(), ".log"), time.Local)
+		if err != nil {
+			continue
+		}
+		if (!query.From.IsZero() && !day.AddDate(0, 0, 1).After(query.From)) || (!query.To.IsZero() && day.After(query.To)) {
+			continue
+		}
+		if err := s.searchFile(path.Join(s.tenantDir(query.TenantID), files[i].Name()), query, collect); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+//searchFile reads the file from the end, the matched logs are passed to fn until it returns false
+func (s *AccessLogStore) searchFile(name string, query *AccessLogQuery, fn func(l *AccessLog) bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			//the file is cleaned when searching
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return readLinesBackward(f, func(line []byte) bool {
+		var l AccessLog
+		if err := json.Unmarshal(line, &l); err != nil {
+			return true
+		}
+		if !query.match(&l) {
+			return true
+		}
+		return fn(&l)
+	})
+}
+
+//readLinesBackward passes the lines of the file to fn from the last one until it returns false,
+//the lines longer than maxAccessLogLineSize are skipped
+func readLinesBackward(f *os.File, fn func(line []byte) bool) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+	buf := make([]byte, 64*1024)
+	var rest []byte
+	for offset > 0 {
+		n := int64(len(buf))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(buf[:n], offset); err != nil {
+			return err
+		}
+		chunk := append(append(make([]byte, 0, int(n)+len(rest)), buf[:n]...), rest...)
+		for {
+			i := bytes.LastIndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			if line := chunk[i+1:]; len(line) > 0 && len(line) <= maxAccessLogLineSize && !fn(line) {
+				return nil
+			}
+			chunk = chunk[:i]
+		}
+		rest = chunk
+		if len(rest) > maxAccessLogLineSize {
+			//the line is too long, skip the rest of it
+			rest = nil
+			for offset > 0 {
+				n := int64(len(buf))
+				if offset < n {
+					n = offset
+				}
+				if _, err := f.ReadAt(buf[:n], offset-n); err != nil {
+					return err
+				}
+				if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+					offset = offset - n + int64(i) + 1
+					break
+				}
+				offset -= n
+			}
+		}
+	}
+	if len(rest) > 0 && len(rest) <= maxAccessLogLineSize {
+		fn(rest)
+	}
+	return nil
+}
+
+//MergeAccessLogResults merges the first pages of the eventlog instances into the page of the query,
+//each result contains the newest page*page_size logs of an instance
+func MergeAccessLogResults(query *AccessLogQuery, results []*AccessLogResult) *AccessLogResult {
+	var logs []*AccessLog
+	merged := &AccessLogResult{}
+	for _, result := range results {
+		logs = append(logs, result.Logs...)
+		merged.More = merged.More || result.More
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Time.After(logs[j].Time)
+	})
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(logs) {
+		return merged
+	}
+	end := start + query.PageSize
+	if end < len(logs) {
+		merged.More = true
+	} else {
+		end = len(logs)
+	}
+	merged.Logs = logs[start:end]
+	return merged
+}
+
+//Clean removes the daily files older than the retention days
+func (s *AccessLogStore) Clean(now time.Time, days int) {
+	if days <= 0 {
+		return
+	}
+	tenants, err := ioutil.ReadDir(s.homePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("list access log dir error, %s", err.Error())
+		}
+		return
+	}
+	for _, tenant := range tenants {
+		if !tenant.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(s.tenantDir(tenant.Name()))
+		if err != nil {
+			logrus.Errorf("list access log dir error, %s", err.Error())
+			continue
+		}
+		for _, file := range files {
+			day, err := time.ParseInLocation(accessLogFileLayout, strings.TrimSuffix(file.Name(), ".log"), time.Local)
+			if err != nil || !now.After(day.AddDate(0, 0, days+1)) {
+				continue
+			}
+			if err := os.Remove(path.Join(s.tenantDir(tenant.Name()), file.Name())); err != nil {
+				logrus.Errorf("remove access log %s/%s error, %s", tenant.Name(), file.Name(), err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogStore(t *testing.T) {
+	homePath, err := ioutil.TempDir("", "accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	store := NewAccessLogStore(homePath, 0)
+	tenantID := "e8539a9c33fd418db11cce26d2bca431"
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	logs := []*AccessLog{
+		{Time: yesterday, TenantID: tenantID, RuleID: "rule1", ClientIP: "10.0.0.1", Path: "/api/users", Status: 200},
+		{Time: yesterday.Add(time.Minute), TenantID: tenantID, RuleID: "rule1", ClientIP: "10.0.0.2", Path: "/api/orders", Status: 502},
+		{Time: now, TenantID: tenantID, RuleID: "rule1", ClientIP: "10.0.0.1", Path: "/static/app.js", Status: 404},
+		{Time: now, TenantID: tenantID, RuleID: "rule2", ClientIP: "10.0.0.1", Path: "/api/users", Status: 503},
+		{Time: now, TenantID: "../other", RuleID: "rule1", Status: 200},
+	}
+	if err := store.Write(logs); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query *AccessLogQuery) *AccessLogResult {
+		query.TenantID = tenantID
+		result, err := store.Search(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if result := search(&AccessLogQuery{RuleID: "rule1"}); len(result.Logs) != 3 || result.Logs[0].Path != "/static/app.js" {
+		t.Errorf("expected 3 logs sorted by time desc, got %+v", result)
+	}
+	if result := search(&AccessLogQuery{Status: "5xx"}); len(result.Logs) != 2 {
+		t.Errorf("expected 2 logs of 5xx, got %d", len(result.Logs))
+	}
+	if result := search(&AccessLogQuery{RuleID: "rule1", Status: "502"}); len(result.Logs) != 1 || result.Logs[0].ClientIP != "10.0.0.2" {
+		t.Errorf("expected the log of 502, got %+v", result)
+	}
+	if result := search(&AccessLogQuery{Path: "/api", ClientIP: "10.0.0.1"}); len(result.Logs) != 2 {
+		t.Errorf("expected 2 logs of /api from 10.0.0.1, got %d", len(result.Logs))
+	}
+	if result := search(&AccessLogQuery{From: now.Add(-time.Second)}); len(result.Logs) != 2 {
+		t.Errorf("expected 2 logs of today, got %d", len(result.Logs))
+	}
+	// the logs are read backward from the newest file
+	if result := search(&AccessLogQuery{PageSize: 1, Page: 2}); len(result.Logs) != 1 || result.Logs[0].Path != "/static/app.js" || !result.More {
+		t.Errorf("expected the second page of 4 logs, got %+v", result)
+	}
+	if result := search(&AccessLogQuery{PageSize: 2, Page: 2}); len(result.Logs) != 2 || result.More {
+		t.Errorf("expected the last page of 4 logs, got %+v", result)
+	}
+	if _, err := store.Search(&AccessLogQuery{TenantID: tenantID, PageSize: 100, Page: 101}); err == nil {
+		t.Errorf("expected an error of the page beyond the window")
+	}
+	if _, err := store.Search(&AccessLogQuery{TenantID: "../other"}); err == nil {
+		t.Errorf("expected an error of the invalid tenant id")
+	}
+
+	store.Clean(now.AddDate(0, 0, 1), 1)
+	if result := search(&AccessLogQuery{}); len(result.Logs) != 2 {
+		t.Errorf("expected the logs of yesterday are cleaned, got %d", len(result.Logs))
+	}
+	if _, err := os.Stat(path.Join(homePath, AccessLogDirName, tenantID)); err != nil {
+		t.Errorf("expected the tenant dir is kept, %v", err)
+	}
+}
+
+func TestAccessLogStoreDaySize(t *testing.T) {
+	homePath, err := ioutil.TempDir("", "accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	store := NewAccessLogStore(homePath, 200)
+	tenantID := "e8539a9c33fd418db11cce26d2bca431"
+	for i := 0; i < 5; i++ {
+		if err := store.Write([]*AccessLog{{Time: time.Now(), TenantID: tenantID, Path: "/", Status: 200}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := store.Search(&AccessLogQuery{TenantID: tenantID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Logs) == 0 || len(result.Logs) == 5 {
+		t.Errorf("expected the logs beyond the daily limit are dropped, got %d", len(result.Logs))
+	}
+}
+
+func TestReadLinesBackward(t *testing.T) {
+	f, err := ioutil.TempFile("", "accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	long := strings.Repeat("x", maxAccessLogLineSize+1)
+	lines := []string{"first", long, strings.Repeat("y", 100*1024), "last"}
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	var read []string
+	if err := readLinesBackward(f, func(line []byte) bool {
+		read = append(read, string(line))
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || read[0] != "last" || read[1] != lines[2] || read[2] != "first" {
+		t.Errorf("expected the lines in reverse order without the long one, got %d lines", len(read))
+	}
+}
+
+func TestMergeAccessLogResults(t *testing.T) {
+	now := time.Now()
+	results := []*AccessLogResult{
+		{Logs: []*AccessLog{{Time: now, Path: "/a1"}, {Time: now.Add(-2 * time.Second), Path: "/a2"}}, More: true},
+		{Logs: []*AccessLog{{Time: now.Add(-time.Second), Path: "/b1"}, {Time: now.Add(-3 * time.Second), Path: "/b2"}}},
+	}
+	merged := MergeAccessLogResults(&AccessLogQuery{Page: 2, PageSize: 2}, results)
+	if len(merged.Logs) != 2 || merged.Logs[0].Path != "/a2" || merged.Logs[1].Path != "/b2" || !merged.More {
+		t.Errorf("expected the second page of the merged logs, got %+v", merged)
+	}
+	merged = MergeAccessLogResults(&AccessLogQuery{Page: 1, PageSize: 3}, results[1:])
+	if len(merged.Logs) != 2 || merged.More {
+		t.Errorf("expected all the logs without more, got %+v", merged)
+	}
+}
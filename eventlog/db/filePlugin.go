@@ -23,6 +23,7 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -35,8 +36,12 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+//attrsFileName the file stores the level and fields of the structured logs in stdout.log
+const attrsFileName = "stdout.attrs"
+
 type filePlugin struct {
 	homePath string
+	index    *LogIndex
 }
 
 func (m *filePlugin) getStdFilePath(serviceID string) (string, error) {
@@ -82,6 +87,10 @@ func (m *filePlugin) SaveMessage(events []*EventLogMessage) error {
 			if err != nil {
 				return err
 			}
+			//the offsets of the attrs refer to the moved log file
+			if err := os.Remove(path.Join(filePathDir, attrsFileName)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
 		}
 	}
 	if logfile == nil {
@@ -93,14 +102,37 @@ func (m *filePlugin) SaveMessage(events []*EventLogMessage) error {
 	} else {
 		defer logfile.Close()
 	}
-	var contant [][]byte
+	offset, err := logfile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	var contant, attrs [][]byte
 	for _, e := range events {
 		contant = append(contant, e.Content)
+		if e.Level != "" || len(e.Fields) > 0 {
+			record, err := json.Marshal(&LogAttrsRecord{Offset: offset, Level: e.Level, Fields: e.Fields})
+			if err == nil {
+				attrs = append(attrs, record)
+			}
+		}
+		offset += int64(len(e.Content)) + 1
 	}
 	body := bytes.Join(contant, []byte("\n"))
 	body = append(body, []byte("\n")...)
-	_, err = logfile.Write(body)
-	return err
+	if _, err := logfile.Write(body); err != nil {
+		return err
+	}
+	if len(attrs) > 0 {
+		if err := appendFile(path.Join(filePathDir, attrsFileName), append(bytes.Join(attrs, []byte("\n")), '\n')); err != nil {
+			logrus.Errorf("save the attrs of container logs of %s error. %s", key, err.Error())
+		}
+	}
+	if m.index != nil {
+		if err := m.index.Index(events); err != nil {
+			logrus.Errorf("index container logs of %s error. %s", key, err.Error())
+		}
+	}
+	return nil
 }
 func (m *filePlugin) GetMessages(serviceID, level string, length int) (interface{}, error) {
 	if length <= 0 {
@@ -136,7 +168,83 @@ func (m *filePlugin) GetMessages(serviceID, level string, length int) (interface
 	return lines, nil
 }
 
+//FilterMessages returns the last length logs which match the filter.
+//The whole log file of today is scanned, so it is slower than GetMessages.
+func (m *filePlugin) FilterMessages(serviceID string, filter *LogFilter, length int) ([]string, error) {
+	if length <= 0 {
+		return nil, nil
+	}
+	filePathDir, err := m.getStdFilePath(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(map[int64]*LogAttrsRecord)
+	if !filter.Empty() {
+		af, err := os.Open(path.Join(filePathDir, attrsFileName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			attrs, err = ReadLogAttrs(af)
+			af.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	f, err := os.Open(path.Join(filePathDir, "stdout.log"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	var offset int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		lineOffset := offset
+		offset += int64(len(line))
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && filter.MatchAttrs(attrs[lineOffset]) {
+			lines = append(lines, string(line))
+			if len(lines) > length {
+				lines = lines[1:]
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return lines, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+func appendFile(filePath string, body []byte) error {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(body)
+	return err
+}
+
+//SearchMessages searches the indexed container logs
+func (m *filePlugin) SearchMessages(query *LogSearchQuery) (*LogSearchResult, error) {
+	if m.index == nil {
+		return nil, fmt.Errorf("container log index is disabled")
+	}
+	return m.index.Search(query)
+}
+
 func (m *filePlugin) Close() error {
+	if m.index != nil {
+		m.index.Stop()
+	}
 	return nil
 }
 
@@ -0,0 +1,123 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+)
+
+//StructuredLogMarker the marker before the structured log record sent by the node streamlog driver
+const StructuredLogMarker = 0x1e
+
+//fieldQueryPrefix the query prefix of the field filters, such as field.path=/api
+const fieldQueryPrefix = "field."
+
+//DockerLogRecord the structured container log parsed by the node
+type DockerLogRecord struct {
+	Time   string            `json:"time"`
+	Level  string            `json:"level,omitempty"`
+	Msg    string            `json:"msg"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+//LogFilter filters the container logs by level and field values
+type LogFilter struct {
+	Level  string
+	Fields map[string]string
+}
+
+//NewLogFilter creates a log filter from the query, such as level=error&field.path=/api
+func NewLogFilter(query url.Values) *LogFilter {
+	filter := &LogFilter{
+		Level:  strings.ToLower(query.Get("level")),
+		Fields: make(map[string]string),
+	}
+	for key, values := range query {
+		if strings.HasPrefix(key, fieldQueryPrefix) && len(values) > 0 {
+			filter.Fields[strings.TrimPrefix(key, fieldQueryPrefix)] = values[0]
+		}
+	}
+	return filter
+}
+
+//Empty returns whether there is no condition in the filter
+func (f *LogFilter) Empty() bool {
+	return f == nil || (f.Level == "" && len(f.Fields) == 0)
+}
+
+//Match returns whether the level and fields match the filter
+func (f *LogFilter) Match(level string, fields map[string]string) bool {
+	if f.Empty() {
+		return true
+	}
+	if f.Level != "" && !strings.EqualFold(f.Level, level) {
+		return false
+	}
+	for k, v := range f.Fields {
+		if value, ok := fields[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+//MatchMessage returns whether the container log message matches the filter
+func (f *LogFilter) MatchMessage(m *EventLogMessage) bool {
+	return f.Match(m.Level, m.Fields)
+}
+
+//MatchAttrs returns whether the attrs of the stored container log match the filter,
+//the attrs of the plain log are nil, so only the structured logs can match a non-empty filter.
+func (f *LogFilter) MatchAttrs(attrs *LogAttrsRecord) bool {
+	if f.Empty() {
+		return true
+	}
+	if attrs == nil {
+		return false
+	}
+	return f.Match(attrs.Level, attrs.Fields)
+}
+
+//LogAttrsRecord the level and fields of the structured container log, it is stored apart from the log line,
+//and refers to the line by its offset in the log file.
+type LogAttrsRecord struct {
+	Offset int64             `json:"offset"`
+	Level  string            `json:"level,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+//ReadLogAttrs reads the attrs records, and indexes them by the offset of the log line
+func ReadLogAttrs(r io.Reader) (map[int64]*LogAttrsRecord, error) {
+	attrs := make(map[int64]*LogAttrsRecord)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record LogAttrsRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			//skip the record broken by a crash
+			continue
+		}
+		attrs[record.Offset] = &record
+	}
+	return attrs, scanner.Err()
+}
@@ -0,0 +1,85 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLogFilterMatchAttrs(t *testing.T) {
+	query, _ := url.ParseQuery("level=ERROR&field.path=/api&rows=100")
+	filter := NewLogFilter(query)
+	if filter.Level != "error" || len(filter.Fields) != 1 || filter.Fields["path"] != "/api" {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+	tests := []struct {
+		attrs *LogAttrsRecord
+		match bool
+	}{
+		{attrs: &LogAttrsRecord{Level: "error", Fields: map[string]string{"path": "/api"}}, match: true},
+		{attrs: &LogAttrsRecord{Level: "info", Fields: map[string]string{"path": "/api"}}, match: false},
+		{attrs: &LogAttrsRecord{Level: "error", Fields: map[string]string{"path": "/"}}, match: false},
+		{attrs: nil, match: false},
+	}
+	for _, test := range tests {
+		if match := filter.MatchAttrs(test.attrs); match != test.match {
+			t.Errorf("attrs %v: expected match %v, got %v", test.attrs, test.match, match)
+		}
+	}
+	if !NewLogFilter(url.Values{}).MatchAttrs(nil) {
+		t.Error("empty filter should match all logs")
+	}
+}
+
+func TestFilterMessages(t *testing.T) {
+	home, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	m := &filePlugin{homePath: home}
+	serviceID := "d810c314735e3ddd8bb0fd3b1a5d2d5f"
+	events := []*EventLogMessage{
+		{EventID: serviceID, Content: []byte("abcdef123456:plain text log")},
+		{EventID: serviceID, Content: []byte("abcdef123456:failed"), Level: "error", Fields: map[string]string{"path": "/api"}},
+		{EventID: serviceID, Content: []byte("abcdef123456:ok"), Level: "info", Fields: map[string]string{"path": "/api"}},
+	}
+	if err := m.SaveMessage(events[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveMessage(events[2:]); err != nil {
+		t.Fatal(err)
+	}
+	query, _ := url.ParseQuery("level=error&field.path=/api")
+	lines, err := m.FilterMessages(serviceID, NewLogFilter(query), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"abcdef123456:failed"}) {
+		t.Errorf("unexpected filtered logs %v", lines)
+	}
+	lines, _ = m.FilterMessages(serviceID, NewLogFilter(url.Values{"field.path": {"/api"}}), 1)
+	if !reflect.DeepEqual(lines, []string{"abcdef123456:ok"}) {
+		t.Errorf("unexpected last filtered log %v", lines)
+	}
+}
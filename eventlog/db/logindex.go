@@ -0,0 +1,827 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	indexDirName     = "index"
+	indexDataFile    = "data"
+	indexFileName    = "index"
+	indexServiceFile = "service"
+	//the layouts of the partition names, the logs of one hour are written into one partition,
+	//and the sealed partitions of one day are compacted into a daily partition.
+	hourPartitionLayout = "2006010215"
+	dayPartitionLayout  = "20060102"
+	//the logs are persisted every minute, so a partition is sealed some minutes after it ends.
+	sealDelay         = 5 * time.Minute
+	maintainInterval  = 10 * time.Minute
+	maxTermLength     = 64
+	maxSearchPageSize = 1000
+	//the number of the partition locks, the partitions share the locks by the hash of their paths
+	partitionLockCount = 64
+	//the record longer than it is corrupted, the container log is split by the docker into 16K lines,
+	//and the multiline log is merged by the node.
+	maxRecordLength = 4 * 1024 * 1024
+)
+
+//CorruptRecordError the record of a partition can not be decoded
+type CorruptRecordError struct {
+	Offset int64
+	Reason string
+}
+
+func (e *CorruptRecordError) Error() string {
+	return fmt.Sprintf("corrupt log record at offset %d: %s", e.Offset, e.Reason)
+}
+
+//RetentionFunc returns how long the indexed logs of the component are kept
+type RetentionFunc func(serviceID string) time.Duration
+
+//LogSearchQuery the query of searching the indexed container logs
+type LogSearchQuery struct {
+	ServiceID string
+	//full-text query, the logs which contain all the terms of the query are matched
+	Query string
+	From  time.Time
+	To    time.Time
+	//only the logs of these containers are matched if it is not empty
+	ContainerIDs []string
+	Page         int
+	PageSize     int
+}
+
+//LogSearchItem the container log matched by the search
+type LogSearchItem struct {
+	Time        time.Time `json:"time"`
+	ContainerID string    `json:"container_id"`
+	Message     string    `json:"message"`
+}
+
+//LogSearchResult the result of searching the indexed container logs, the logs are sorted by time desc
+type LogSearchResult struct {
+	Total int              `json:"total"`
+	Logs  []*LogSearchItem `json:"logs"`
+}
+
+//LogIndex the time partitioned full-text index of the container logs.
+//The logs of a component are stored in the partitions under <home>/<alias id>/index,
+//the logs of the current partitions are scanned when searching, and the inverted index
+//of a partition is built when it is sealed.
+type LogIndex struct {
+	homePath  string
+	retention RetentionFunc
+	//lock guards the creation and the removal of the component directories
+	lock sync.Mutex
+	//the searching holds the read lock of one partition at a time, so that the partition is not written
+	//or compacted meanwhile, and the other partitions are not blocked by the slow searching.
+	partitionLocks [partitionLockCount]sync.RWMutex
+	stop           chan struct{}
+}
+
+//NewLogIndex creates the log index
+func NewLogIndex(homePath string, retention RetentionFunc) *LogIndex {
+	return &LogIndex{
+		homePath:  homePath,
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+}
+
+//Start compacts the partitions and cleans the expired logs in background
+func (l *LogIndex) Start() {
+	go func() {
+		ticker := time.NewTicker(maintainInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.Maintain(time.Now())
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+//Stop stops the background maintenance
+func (l *LogIndex) Stop() {
+	close(l.stop)
+}
+
+func (l *LogIndex) serviceDir(serviceID string) string {
+	return path.Join(l.homePath, GetServiceAliasID(serviceID), indexDirName)
+}
+
+func partitionLockIndex(dir string) int {
+	h := fnv.New32a()
+	h.Write([]byte(dir))
+	return int(h.Sum32() % partitionLockCount)
+}
+
+func (l *LogIndex) partitionLock(dir string) *sync.RWMutex {
+	return &l.partitionLocks[partitionLockIndex(dir)]
+}
+
+//lockPartitions locks the partitions in the order of the locks, it returns the function to unlock them
+func (l *LogIndex) lockPartitions(dirs ...string) func() {
+	locked := make(map[int]bool)
+	var locks []int
+	for _, dir := range dirs {
+		if i := partitionLockIndex(dir); !locked[i] {
+			locked[i] = true
+			locks = append(locks, i)
+		}
+	}
+	sort.Ints(locks)
+	for _, i := range locks {
+		l.partitionLocks[i].Lock()
+	}
+	return func() {
+		for _, i := range locks {
+			l.partitionLocks[i].Unlock()
+		}
+	}
+}
+
+//Index writes the logs of one component into the partitions by log time
+func (l *LogIndex) Index(events []*EventLogMessage) error {
+	if len(events) == 0 {
+		return nil
+	}
+	serviceID := events[0].EventID
+	dir := l.serviceDir(serviceID)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	serviceFile := path.Join(dir, indexServiceFile)
+	if _, err := os.Stat(serviceFile); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(serviceFile, []byte(serviceID), 0644); err != nil {
+			return err
+		}
+	}
+	partitions := make(map[string][]*EventLogMessage)
+	var names []string
+	for _, e := range events {
+		if len(e.Content) == 0 {
+			continue
+		}
+		name := parseEventTime(e.Time).Format(hourPartitionLayout)
+		if _, ok := partitions[name]; !ok {
+			names = append(names, name)
+		}
+		partitions[name] = append(partitions[name], e)
+	}
+	for _, name := range names {
+		if err := l.writePartition(path.Join(dir, name), partitions[name]); err != nil {
+			return fmt.Errorf("write log index partition %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+//writePartition appends the logs into the partition while holding the lock of the partition
+func (l *LogIndex) writePartition(dir string, events []*EventLogMessage) error {
+	lock := l.partitionLock(dir)
+	lock.Lock()
+	defer lock.Unlock()
+	f, err := openPartitionData(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, e := range events {
+		if err := writeRecord(w, parseEventTime(e.Time).UnixNano(), e.Content); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+//openPartitionData opens the data file for appending, the partition is unsealed if it has been sealed
+func openPartitionData(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path.Join(dir, indexFileName)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return os.OpenFile(path.Join(dir, indexDataFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+func parseEventTime(value string) time.Time {
+	if value != "" {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t.Local()
+		}
+	}
+	return time.Now()
+}
+
+//writeRecord writes a record: varint(time) uvarint(length) content
+func writeRecord(w io.Writer, logTime int64, content []byte) error {
+	var head [2 * binary.MaxVarintLen64]byte
+	n := binary.PutVarint(head[:], logTime)
+	n += binary.PutUvarint(head[n:], uint64(len(content)))
+	if _, err := w.Write(head[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(content)
+	return err
+}
+
+//readRecord reads the record at the offset, it returns the size of the record.
+//io.EOF is returned only if there is no more record, otherwise a CorruptRecordError is returned.
+func readRecord(r *bufio.Reader, offset int64) (int64, []byte, int, error) {
+	logTime, err := binary.ReadVarint(r)
+	if err == io.EOF {
+		return 0, nil, 0, err
+	}
+	if err != nil {
+		return 0, nil, 0, &CorruptRecordError{Offset: offset, Reason: "read time: " + err.Error()}
+	}
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, 0, &CorruptRecordError{Offset: offset, Reason: "read length: " + err.Error()}
+	}
+	if length > maxRecordLength {
+		return 0, nil, 0, &CorruptRecordError{Offset: offset, Reason: fmt.Sprintf("length %d exceeds the limit", length)}
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, 0, &CorruptRecordError{Offset: offset, Reason: "read content: " + err.Error()}
+	}
+	return logTime, content, varintLen(logTime) + uvarintLen(length) + int(length), nil
+}
+
+func varintLen(v int64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutVarint(buf[:], v)
+}
+
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+//splitLogContent splits the stored log(<container id>:<log>)
+func splitLogContent(content []byte) (string, []byte) {
+	if index := bytes.IndexByte(content, ':'); index > 0 {
+		return string(content[:index]), content[index+1:]
+	}
+	return "", content
+}
+
+//tokenize splits the log into the lower case terms
+func tokenize(content []byte) []string {
+	var terms []string
+	seen := make(map[string]struct{})
+	for _, field := range bytes.FieldsFunc(content, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(field) > maxTermLength {
+			continue
+		}
+		term := strings.ToLower(string(field))
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+//partitionIndex the inverted index of a sealed partition
+type partitionIndex struct {
+	Offsets []int64
+	Times   []int64
+	//the container of every record, it is the position in ContainerIDs
+	Containers   []uint32
+	ContainerIDs []string
+	Terms        map[string][]uint32
+}
+
+type logPartition struct {
+	dir        string
+	start, end time.Time
+	daily      bool
+}
+
+func (p *logPartition) sealed() bool {
+	_, err := os.Stat(path.Join(p.dir, indexFileName))
+	return err == nil
+}
+
+//listPartitions returns the partitions of the component sorted by time
+func listPartitions(dir string) ([]*logPartition, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var partitions []*logPartition
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		p := &logPartition{dir: path.Join(dir, info.Name())}
+		switch len(info.Name()) {
+		case len(hourPartitionLayout):
+			if p.start, err = time.ParseInLocation(hourPartitionLayout, info.Name(), time.Local); err != nil {
+				continue
+			}
+			p.end = p.start.Add(time.Hour)
+		case len(dayPartitionLayout):
+			if p.start, err = time.ParseInLocation(dayPartitionLayout, info.Name(), time.Local); err != nil {
+				continue
+			}
+			p.end = p.start.AddDate(0, 0, 1)
+			p.daily = true
+		default:
+			continue
+		}
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].start.Before(partitions[j].start)
+	})
+	return partitions, nil
+}
+
+//scan reads all the records of the partition, it stops at the first corrupt record
+func (p *logPartition) scan(handle func(offset, logTime int64, content []byte)) error {
+	f, err := os.Open(path.Join(p.dir, indexDataFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		logTime, content, size, err := readRecord(reader, offset)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		handle(offset, logTime, content)
+		offset += int64(size)
+	}
+}
+
+//seal builds the inverted index of the partition
+func (p *logPartition) seal() error {
+	index := &partitionIndex{Terms: make(map[string][]uint32)}
+	containers := make(map[string]uint32)
+	err := p.scan(func(offset, logTime int64, content []byte) {
+		record := uint32(len(index.Offsets))
+		containerID, log := splitLogContent(content)
+		c, ok := containers[containerID]
+		if !ok {
+			c = uint32(len(index.ContainerIDs))
+			containers[containerID] = c
+			index.ContainerIDs = append(index.ContainerIDs, containerID)
+		}
+		index.Offsets = append(index.Offsets, offset)
+		index.Times = append(index.Times, logTime)
+		index.Containers = append(index.Containers, c)
+		for _, term := range tokenize(log) {
+			index.Terms[term] = append(index.Terms[term], record)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(index); err != nil {
+		return err
+	}
+	tmp := path.Join(p.dir, indexFileName+".tmp")
+	if err := ioutil.WriteFile(tmp, buffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(p.dir, indexFileName))
+}
+
+func (p *logPartition) loadIndex() (*partitionIndex, error) {
+	f, err := os.Open(path.Join(p.dir, indexFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var index partitionIndex
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+type logHit struct {
+	partition *logPartition
+	offset    int64
+	time      int64
+}
+
+//newerThan sorts the hits by time desc, the hits of the same time are kept in the order of the records
+func (h logHit) newerThan(o logHit) bool {
+	if h.time != o.time {
+		return h.time > o.time
+	}
+	return h.offset < o.offset
+}
+
+//hitHeap keeps the newest hits of a partition, the oldest one is at the top
+type hitHeap []logHit
+
+func (h hitHeap) Len() int            { return len(h) }
+func (h hitHeap) Less(i, j int) bool  { return h[j].newerThan(h[i]) }
+func (h hitHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x interface{}) { *h = append(*h, x.(logHit)) }
+func (h *hitHeap) Pop() interface{} {
+	old := *h
+	hit := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return hit
+}
+
+//keep keeps the hit if it is one of the newest limit hits
+func (h *hitHeap) keep(hit logHit, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, hit)
+		return
+	}
+	if limit > 0 && hit.newerThan((*h)[0]) {
+		(*h)[0] = hit
+		heap.Fix(h, 0)
+	}
+}
+
+func (q *LogSearchQuery) matchTime(logTime int64) bool {
+	if !q.From.IsZero() && logTime < q.From.UnixNano() {
+		return false
+	}
+	if !q.To.IsZero() && logTime > q.To.UnixNano() {
+		return false
+	}
+	return true
+}
+
+func (q *LogSearchQuery) matchContainer(containerID string) bool {
+	if len(q.ContainerIDs) == 0 {
+		return true
+	}
+	for _, id := range q.ContainerIDs {
+		if containerID != "" && strings.HasPrefix(id, containerID) {
+			return true
+		}
+	}
+	return false
+}
+
+//search calls handle with the records of the partition which match the query,
+//the partition removed by the maintenance has no records.
+func (p *logPartition) search(query *LogSearchQuery, terms []string, handle func(hit logHit)) error {
+	if _, err := os.Stat(p.dir); os.IsNotExist(err) {
+		return nil
+	}
+	if !p.sealed() {
+		return p.scan(func(offset, logTime int64, content []byte) {
+			if !query.matchTime(logTime) {
+				return
+			}
+			containerID, log := splitLogContent(content)
+			if !query.matchContainer(containerID) || !containsTerms(tokenize(log), terms) {
+				return
+			}
+			handle(logHit{partition: p, offset: offset, time: logTime})
+		})
+	}
+	index, err := p.loadIndex()
+	if err != nil {
+		return err
+	}
+	for _, record := range index.match(terms) {
+		if !query.matchTime(index.Times[record]) || !query.matchContainer(index.ContainerIDs[index.Containers[record]]) {
+			continue
+		}
+		handle(logHit{partition: p, offset: index.Offsets[record], time: index.Times[record]})
+	}
+	return nil
+}
+
+//match returns the records which contain all the terms
+func (i *partitionIndex) match(terms []string) []uint32 {
+	if len(terms) == 0 {
+		records := make([]uint32, len(i.Offsets))
+		for r := range records {
+			records[r] = uint32(r)
+		}
+		return records
+	}
+	var records []uint32
+	for n, term := range terms {
+		postings := i.Terms[term]
+		if n == 0 {
+			records = postings
+			continue
+		}
+		records = intersect(records, postings)
+		if len(records) == 0 {
+			break
+		}
+	}
+	return records
+}
+
+//intersect returns the intersection of two sorted postings
+func intersect(a, b []uint32) []uint32 {
+	var result []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+func containsTerms(terms, query []string) bool {
+	for _, q := range query {
+		found := false
+		for _, t := range terms {
+			if t == q {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//Search searches the indexed logs of the component
+func (l *LogIndex) Search(query *LogSearchQuery) (*LogSearchResult, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = 100
+	}
+	if query.PageSize > maxSearchPageSize {
+		query.PageSize = maxSearchPageSize
+	}
+	partitions, err := listPartitions(l.serviceDir(query.ServiceID))
+	if err != nil {
+		return nil, err
+	}
+	terms := tokenize([]byte(query.Query))
+	start := (query.Page - 1) * query.PageSize
+	end := start + query.PageSize
+	result := &LogSearchResult{}
+	//the partitions do not overlap in time, so the hits are streamed from the newest partition,
+	//only the newest hits which may be in the page are kept, the others are only counted.
+	for i := len(partitions) - 1; i >= 0; i-- {
+		p := partitions[i]
+		if (!query.From.IsZero() && !p.end.After(query.From)) || (!query.To.IsZero() && p.start.After(query.To)) {
+			continue
+		}
+		if err := l.searchPartition(p, query, terms, start, end, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+//searchPartition counts the hits of the partition and reads the ones in the page [start, end),
+//it holds the read lock of the partition only.
+func (l *LogIndex) searchPartition(p *logPartition, query *LogSearchQuery, terms []string, start, end int, result *LogSearchResult) error {
+	lock := l.partitionLock(p.dir)
+	lock.RLock()
+	defer lock.RUnlock()
+	limit := end - result.Total
+	newest := &hitHeap{}
+	count := 0
+	err := p.search(query, terms, func(hit logHit) {
+		count++
+		newest.keep(hit, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("search partition %s: %v", p.dir, err)
+	}
+	hits := []logHit(*newest)
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].newerThan(hits[j])
+	})
+	for n, hit := range hits {
+		if position := result.Total + n; position < start {
+			continue
+		}
+		item, err := hit.read()
+		if err != nil {
+			return fmt.Errorf("read indexed log from %s: %v", p.dir, err)
+		}
+		result.Logs = append(result.Logs, item)
+	}
+	result.Total += count
+	return nil
+}
+
+func (h logHit) read() (*LogSearchItem, error) {
+	f, err := os.Open(path.Join(h.partition.dir, indexDataFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(h.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	logTime, content, _, err := readRecord(bufio.NewReader(f), h.offset)
+	if err == io.EOF {
+		return nil, &CorruptRecordError{Offset: h.offset, Reason: "unexpected end of partition"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	containerID, log := splitLogContent(content)
+	return &LogSearchItem{
+		Time:        time.Unix(0, logTime),
+		ContainerID: containerID,
+		Message:     string(log),
+	}, nil
+}
+
+//Maintain seals the finished partitions, compacts the hourly partitions of the past days
+//into daily partitions, and removes the partitions out of the retention.
+func (l *LogIndex) Maintain(now time.Time) {
+	infos, err := ioutil.ReadDir(l.homePath)
+	if err != nil {
+		logrus.Errorf("list log home path %s failure %s", l.homePath, err.Error())
+		return
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		dir := path.Join(l.homePath, info.Name(), indexDirName)
+		serviceID, err := ioutil.ReadFile(path.Join(dir, indexServiceFile))
+		if err != nil {
+			continue
+		}
+		if err := l.maintainService(string(serviceID), dir, now); err != nil {
+			logrus.Errorf("maintain log index of %s failure %s", serviceID, err.Error())
+		}
+	}
+}
+
+//maintainService maintains the partitions of the component, it holds the locks of the partitions being maintained
+func (l *LogIndex) maintainService(serviceID, dir string, now time.Time) error {
+	partitions, err := listPartitions(dir)
+	if err != nil {
+		return err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	days := make(map[string][]*logPartition)
+	for _, p := range partitions {
+		if !p.daily && !p.end.After(today) {
+			day := p.start.Format(dayPartitionLayout)
+			days[day] = append(days[day], p)
+		}
+	}
+	if len(days) > 0 {
+		for day, hourly := range days {
+			if err := l.compact(path.Join(dir, day), hourly); err != nil {
+				return fmt.Errorf("compact log index partition %s: %v", day, err)
+			}
+		}
+		if partitions, err = listPartitions(dir); err != nil {
+			return err
+		}
+	}
+	var retention time.Duration
+	if l.retention != nil {
+		retention = l.retention(serviceID)
+	}
+	remain := 0
+	for _, p := range partitions {
+		if retention > 0 && p.end.Before(now.Add(-retention)) {
+			unlock := l.lockPartitions(p.dir)
+			err := os.RemoveAll(p.dir)
+			unlock()
+			if err != nil {
+				return err
+			}
+			logrus.Debugf("clean expired log index partition %s", p.dir)
+			continue
+		}
+		remain++
+		if err := l.seal(p, now); err != nil {
+			return fmt.Errorf("seal log index partition %s: %v", p.dir, err)
+		}
+	}
+	if remain == 0 {
+		//the logs may be indexed since the partitions are listed
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		if partitions, err := listPartitions(dir); err != nil || len(partitions) > 0 {
+			return err
+		}
+		return os.RemoveAll(dir)
+	}
+	return nil
+}
+
+//seal seals the partition if it is finished
+func (l *LogIndex) seal(p *logPartition, now time.Time) error {
+	if !p.end.Add(sealDelay).Before(now) {
+		return nil
+	}
+	unlock := l.lockPartitions(p.dir)
+	defer unlock()
+	if _, err := os.Stat(p.dir); os.IsNotExist(err) || p.sealed() {
+		return nil
+	}
+	return p.seal()
+}
+
+//compact appends the records of the hourly partitions into the daily partition
+func (l *LogIndex) compact(dir string, hourly []*logPartition) error {
+	dirs := []string{dir}
+	for _, p := range hourly {
+		dirs = append(dirs, p.dir)
+	}
+	unlock := l.lockPartitions(dirs...)
+	defer unlock()
+	dst, err := openPartitionData(dir)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	for _, p := range hourly {
+		src, err := os.Open(path.Join(p.dir, indexDataFile))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		_, err = io.Copy(dst, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(p.dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
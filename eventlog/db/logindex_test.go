@@ -0,0 +1,161 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogIndex(t *testing.T) {
+	homePath, err := ioutil.TempDir("", "logindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	serviceID := "4d5d1a2d4e4c4b3e9f1a2b3c4d5e6f70"
+	retention := 3 * 24 * time.Hour
+	index := NewLogIndex(homePath, func(string) time.Duration { return retention })
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	var events []*EventLogMessage
+	add := func(logTime time.Time, containerID, log string) {
+		events = append(events, &EventLogMessage{
+			EventID: serviceID,
+			Time:    logTime.Format(time.RFC3339Nano),
+			Content: []byte(containerID + ":" + log),
+		})
+	}
+	for i := 0; i < 10; i++ {
+		add(yesterday.Add(time.Duration(i)*time.Minute), "aaaaaaaaaaaa", fmt.Sprintf("GET /api/users %d", i))
+	}
+	add(yesterday.Add(time.Hour), "bbbbbbbbbbbb", "ERROR connect to mysql: connection refused")
+	add(now, "aaaaaaaaaaaa", "error: connection refused\nretry later")
+	if err := index.Index(events); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query *LogSearchQuery) *LogSearchResult {
+		query.ServiceID = serviceID
+		result, err := index.Search(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	check := func() {
+		result := search(&LogSearchQuery{Query: "Connection refused"})
+		if result.Total != 2 || result.Logs[0].Message != "error: connection refused\nretry later" || result.Logs[1].ContainerID != "bbbbbbbbbbbb" {
+			t.Fatalf("unexpected result %+v", result)
+		}
+		result = search(&LogSearchQuery{Query: "refused", ContainerIDs: []string{"aaaaaaaaaaaa5a3c"}})
+		if result.Total != 1 {
+			t.Fatalf("expected 1 log of the container, got %d", result.Total)
+		}
+		result = search(&LogSearchQuery{Query: "api", From: yesterday.Add(2 * time.Minute), To: yesterday.Add(5 * time.Minute), PageSize: 3, Page: 2})
+		if result.Total != 4 || len(result.Logs) != 1 || result.Logs[0].Message != "GET /api/users 2" {
+			t.Fatalf("unexpected page %+v", result)
+		}
+	}
+	check()
+	//compact the partitions of yesterday, and seal them
+	index.Maintain(now)
+	partitions, err := listPartitions(index.serviceDir(serviceID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !partitions[0].daily || !partitions[0].sealed() {
+		t.Fatalf("the partition of yesterday should be compacted and sealed")
+	}
+	check()
+	//clean the expired partitions
+	retention = 2 * time.Hour
+	index.Maintain(now.Add(2 * time.Hour))
+	if result := search(&LogSearchQuery{}); result.Total != 1 {
+		t.Fatalf("expected 1 log after cleaning, got %d", result.Total)
+	}
+	if _, err := os.Stat(path.Join(index.serviceDir(serviceID), indexServiceFile)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogIndexCorruptRecord(t *testing.T) {
+	homePath, err := ioutil.TempDir("", "logindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	serviceID := "4d5d1a2d4e4c4b3e9f1a2b3c4d5e6f70"
+	index := NewLogIndex(homePath, nil)
+	now := time.Now()
+	err = index.Index([]*EventLogMessage{{EventID: serviceID, Time: now.Format(time.RFC3339Nano), Content: []byte("aaaaaaaaaaaa:started")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a record with a huge length
+	f, err := openPartitionData(path.Join(index.serviceDir(serviceID), now.Format(hourPartitionLayout)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0x02, 0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Close()
+	_, err = index.Search(&LogSearchQuery{ServiceID: serviceID})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("expected the corrupt record error, got %v", err)
+	}
+}
+
+func TestLogIndexSlowSearch(t *testing.T) {
+	homePath, err := ioutil.TempDir("", "logindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	serviceID := "4d5d1a2d4e4c4b3e9f1a2b3c4d5e6f70"
+	index := NewLogIndex(homePath, nil)
+	dir := index.serviceDir(serviceID)
+	now := time.Now()
+	//find an hour whose partition does not share the lock with the current one
+	searching := now.Add(-time.Hour)
+	for partitionLockIndex(path.Join(dir, searching.Format(hourPartitionLayout))) == partitionLockIndex(path.Join(dir, now.Format(hourPartitionLayout))) {
+		searching = searching.Add(-time.Hour)
+	}
+	//a slow search holds the read lock of its partition
+	lock := index.partitionLock(path.Join(dir, searching.Format(hourPartitionLayout)))
+	lock.RLock()
+	defer lock.RUnlock()
+	done := make(chan error, 1)
+	go func() {
+		done <- index.Index([]*EventLogMessage{{EventID: serviceID, Time: now.Format(time.RFC3339Nano), Content: []byte("aaaaaaaaaaaa:hello")}})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("indexing is blocked by the search of the other partition")
+	}
+}
@@ -31,13 +31,28 @@ type Manager interface {
 	GetMessages(id, level string, length int) (interface{}, error)
 }
 
+//SearchManager the storage which supports searching the indexed container logs
+type SearchManager interface {
+	SearchMessages(query *LogSearchQuery) (*LogSearchResult, error)
+}
+
+//FilterManager the storage which supports filtering the container logs
+type FilterManager interface {
+	FilterMessages(id string, filter *LogFilter, length int) ([]string, error)
+}
+
 //NewManager 创建存储管理器
 func NewManager(conf conf.DBConf, log *logrus.Entry) (Manager, error) {
 	switch conf.Type {
 	case "file":
-		return &filePlugin{
+		plugin := &filePlugin{
 			homePath: conf.HomePath,
-		}, nil
+		}
+		if conf.LogIndex {
+			plugin.index = NewLogIndex(conf.HomePath, NewLogRetention(conf.EventLogRetention, conf.ContainerLogRetention, conf.LogIndexRetention).IndexRetention)
+			plugin.index.Start()
+		}
+		return plugin, nil
 	case "eventfile":
 		return &EventFilePlugin{
 			HomePath: conf.HomePath,
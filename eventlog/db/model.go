@@ -27,6 +27,8 @@ type EventLogMessage struct {
 	Level   string `json:"level"`
 	Time    string `json:"time"`
 	Content []byte `json:"-"`
+	//the fields of the structured container log
+	Fields map[string]string `json:"fields,omitempty"`
 	//monitor消息使用
 	MonitorData []byte `json:"monitorData,omitempty"`
 }
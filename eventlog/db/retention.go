@@ -0,0 +1,142 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"sync"
+	"time"
+
+	cdb "github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+const retentionCacheTime = 10 * time.Minute
+
+//RetentionPolicy the effective log retention policy of a component, 0 days means the logs are kept forever
+type RetentionPolicy struct {
+	TenantID         string
+	EventLogDays     int
+	ContainerLogDays int
+	//the days the container logs are kept in the log index
+	IndexDays int
+	//whether to archive the logs past the retention instead of deleting them
+	Archive bool
+}
+
+type retentionCache struct {
+	policy *RetentionPolicy
+	expire time.Time
+}
+
+//LogRetention resolves the log retention policies of the tenants and components.
+//The policy of a component overrides the policy of its tenant, and the default retention is used
+//if neither of them sets it.
+type LogRetention struct {
+	defaults RetentionPolicy
+	lock     sync.Mutex
+	cache    map[string]retentionCache
+}
+
+//NewLogRetention creates the log retention with the default days
+func NewLogRetention(eventLogDays, containerLogDays, indexDays int) *LogRetention {
+	return &LogRetention{
+		defaults: RetentionPolicy{
+			EventLogDays:     eventLogDays,
+			ContainerLogDays: containerLogDays,
+			IndexDays:        indexDays,
+		},
+		cache: make(map[string]retentionCache),
+	}
+}
+
+//Policy returns the policy of the component, the tenant of the component is looked up if the tenant id is empty.
+//The policy of the tenant is returned if the service id is empty.
+func (r *LogRetention) Policy(tenantID, serviceID string) *RetentionPolicy {
+	key := tenantID + "/" + serviceID
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if c, ok := r.cache[key]; ok && c.expire.After(time.Now()) {
+		return c.policy
+	}
+	policy := r.resolve(tenantID, serviceID)
+	r.cache[key] = retentionCache{policy: policy, expire: time.Now().Add(retentionCacheTime)}
+	return policy
+}
+
+func (r *LogRetention) resolve(tenantID, serviceID string) *RetentionPolicy {
+	policy := r.defaults
+	if tenantID == "" && serviceID != "" {
+		service, err := cdb.GetManager().TenantServiceDao().GetServiceByID(serviceID)
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				logrus.Warningf("get the tenant of component %s failure %s", serviceID, err.Error())
+			}
+			return &policy
+		}
+		tenantID = service.TenantID
+	}
+	if tenantID == "" {
+		return &policy
+	}
+	policy.TenantID = tenantID
+	if tenant, err := cdb.GetManager().TenantDao().GetTenantByUUID(tenantID); err == nil && tenant.LogRetentionDays > 0 {
+		policy.IndexDays = tenant.LogRetentionDays
+	}
+	//the policy of the tenant is applied first, then the policy of the component
+	ids := []string{""}
+	if serviceID != "" {
+		ids = append(ids, serviceID)
+	}
+	for _, id := range ids {
+		p, err := cdb.GetManager().LogRetentionPolicyDao().GetPolicy(tenantID, id)
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				logrus.Warningf("get log retention policy of %s/%s failure %s", tenantID, id, err.Error())
+			}
+			continue
+		}
+		policy.EventLogDays = overrideRetentionDays(policy.EventLogDays, p.EventLogDays)
+		policy.ContainerLogDays = overrideRetentionDays(policy.ContainerLogDays, p.ContainerLogDays)
+		policy.Archive = p.Archive
+	}
+	//the logs should not be kept in the index longer than the log files
+	if policy.ContainerLogDays > 0 && (policy.IndexDays <= 0 || policy.IndexDays > policy.ContainerLogDays) {
+		policy.IndexDays = policy.ContainerLogDays
+	}
+	return &policy
+}
+
+//overrideRetentionDays returns the days of the policy if it sets them, LogRetentionForever overrides
+//the days with 0 which keeps the logs forever.
+func overrideRetentionDays(days, policyDays int) int {
+	switch {
+	case policyDays == dbmodel.LogRetentionForever:
+		return 0
+	case policyDays > 0:
+		return policyDays
+	}
+	return days
+}
+
+//IndexRetention returns how long the container logs of the component are kept in the log index
+func (r *LogRetention) IndexRetention(serviceID string) time.Duration {
+	return time.Duration(r.Policy("", serviceID).IndexDays) * 24 * time.Hour
+}
@@ -0,0 +1,41 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+
+	dbmodel "github.com/goodrain/rainbond/db/model"
+)
+
+func TestOverrideRetentionDays(t *testing.T) {
+	testCases := []struct {
+		days, policyDays, expected int
+	}{
+		{7, 0, 7},
+		{7, 30, 30},
+		{7, dbmodel.LogRetentionForever, 0},
+		{0, 3, 3},
+	}
+	for _, tc := range testCases {
+		if days := overrideRetentionDays(tc.days, tc.policyDays); days != tc.expected {
+			t.Errorf("override %d days with %d: expected %d, but returned %d", tc.days, tc.policyDays, tc.expected, days)
+		}
+	}
+}
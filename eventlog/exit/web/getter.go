@@ -19,10 +19,15 @@
 package web
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/eventlog/db"
 	httputil "github.com/goodrain/rainbond/util/http"
 )
 
@@ -33,6 +38,137 @@ func (s *SocketServer) getDockerLogs(w http.ResponseWriter, r *http.Request) {
 	if rows == 0 {
 		rows = 100
 	}
-	loglist := s.storemanager.GetDockerLogs(serviceID, rows)
+	// filter the structured logs by level and fields, such as level=error&field.path=/api
+	filter := db.NewLogFilter(r.URL.Query())
+	loglist := s.storemanager.GetDockerLogs(serviceID, rows, filter)
 	httputil.ReturnSuccess(r, w, loglist)
 }
+
+//searchDockerLogs searches the indexed container logs
+//query: q=&from=&to=&container_id=&page=&page_size=, the time is RFC3339 or unix seconds
+func (s *SocketServer) searchDockerLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := &db.LogSearchQuery{
+		ServiceID:    chi.URLParam(r, "serviceID"),
+		Query:        query.Get("q"),
+		ContainerIDs: query["container_id"],
+	}
+	search.Page, _ = strconv.Atoi(query.Get("page"))
+	search.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+	var err error
+	if search.From, err = parseSearchTime(query.Get("from")); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	if search.To, err = parseSearchTime(query.Get("to")); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	result, err := s.storemanager.SearchDockerLogs(search)
+	if err != nil {
+		s.log.Errorf("search container logs of %s failure %s", search.ServiceID, err.Error())
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnList(r, w, result.Total, search.Page, result.Logs)
+}
+
+//the max bytes of the access logs shipped at one time
+const maxAccessLogBodySize = 16 * 1024 * 1024
+
+//receiveAccessLogs receives the access logs shipped by the gateway, the body is a json array of the logs
+func (s *SocketServer) receiveAccessLogs(w http.ResponseWriter, r *http.Request) {
+	var logs []*db.AccessLog
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAccessLogBodySize)).Decode(&logs); err != nil {
+		httputil.ReturnError(r, w, 400, fmt.Sprintf("decode access logs: %v", err))
+		return
+	}
+	if err := s.storemanager.WriteAccessLogs(logs); err != nil {
+		s.log.Errorf("write access logs failure %s", err.Error())
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+//searchAccessLogs searches the access logs of the tenant
+//query: rule_id=&status=&path=&client_ip=&from=&to=&page=&page_size=, the status is such as 502 or 5xx
+func (s *SocketServer) searchAccessLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := &db.AccessLogQuery{
+		TenantID: chi.URLParam(r, "tenantID"),
+		RuleID:   query.Get("rule_id"),
+		Status:   query.Get("status"),
+		Path:     query.Get("path"),
+		ClientIP: query.Get("client_ip"),
+	}
+	search.Page, _ = strconv.Atoi(query.Get("page"))
+	search.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+	var err error
+	if search.From, err = parseSearchTime(query.Get("from")); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	if search.To, err = parseSearchTime(query.Get("to")); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	result, err := s.storemanager.SearchAccessLogs(search)
+	if err != nil {
+		s.log.Errorf("search access logs of %s failure %s", search.TenantID, err.Error())
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, result)
+}
+
+func parseSearchTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s, it should be RFC3339 or unix seconds", value)
+	}
+	return t, nil
+}
+
+//restoreArchiveRequest the archived logs in the time range are restored
+type restoreArchiveRequest struct {
+	TenantID  string    `json:"tenant_id"`
+	ServiceID string    `json:"service_id"`
+	Kind      string    `json:"kind"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// only the archives made by the eventlog instance of the host id are restored
+	HostID string `json:"host_id"`
+}
+
+//restoreLogArchives restores the archived logs for viewing
+func (s *SocketServer) restoreLogArchives(w http.ResponseWriter, r *http.Request) {
+	var req restoreArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.ReturnError(r, w, 400, fmt.Sprintf("decode request body: %v", err))
+		return
+	}
+	if req.TenantID == "" {
+		httputil.ReturnError(r, w, 400, "tenant id can not be empty")
+		return
+	}
+	archives, err := s.storemanager.RestoreLogArchives(&dbmodel.LogArchiveQuery{
+		TenantID:  req.TenantID,
+		ServiceID: req.ServiceID,
+		Kind:      req.Kind,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}, req.HostID)
+	if err != nil {
+		s.log.Errorf("restore log archives of %s failure %s", req.TenantID, err.Error())
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	httputil.ReturnSuccess(r, w, archives)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/goodrain/rainbond/eventlog/cluster"
 	"github.com/goodrain/rainbond/eventlog/cluster/discover"
 	"github.com/goodrain/rainbond/eventlog/conf"
+	"github.com/goodrain/rainbond/eventlog/db"
 	"github.com/goodrain/rainbond/eventlog/exit/monitor"
 	"github.com/goodrain/rainbond/eventlog/store"
 	"github.com/goodrain/rainbond/util"
@@ -206,6 +207,7 @@ func (s *SocketServer) pushDockerLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.log.Infof("Begin push docker message of service (%s)", ServiceID)
+	filter := db.NewLogFilter(r.URL.Query())
 	SubID := uuid.NewV4().String()
 	ch := s.storemanager.WebSocketMessageChan("docker", ServiceID, SubID)
 	if ch == nil {
@@ -229,7 +231,7 @@ func (s *SocketServer) pushDockerLog(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			if message != nil {
+			if message != nil && filter.MatchMessage(message) {
 				s.log.Debugf("websocket push a message: %v", message)
 				err := conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 				if err != nil {
@@ -488,6 +490,10 @@ func (s *SocketServer) listen() {
 	// new websocket pubsub
 	r.Get("/services/{serviceID}/pubsub", s.pubsub)
 	r.Get("/tenants/{tenantName}/services/{serviceID}/logs", s.getDockerLogs)
+	r.Get("/tenants/{tenantName}/services/{serviceID}/logs/search", s.searchDockerLogs)
+	r.Post("/log-archives/restore", s.restoreLogArchives)
+	r.Post("/access-logs", s.receiveAccessLogs)
+	r.Get("/tenants/{tenantID}/access-logs", s.searchAccessLogs)
 	//monitor setting
 	s.prometheus(r)
 	//pprof debug
@@ -278,3 +278,29 @@ func (h *dockerLogStore) GetHistoryMessage(eventID string, length int) (re []str
 	re = append(result.([]string), re...)
 	return re
 }
+
+//GetFilteredHistoryMessage returns the last length logs which match the filter
+func (h *dockerLogStore) GetFilteredHistoryMessage(eventID string, length int, filter *db.LogFilter) (re []string) {
+	h.rwLock.RLock()
+	if ba, ok := h.barrels[eventID]; ok {
+		for _, m := range ba.barrel {
+			if len(m.Content) > 0 && filter.MatchMessage(m) {
+				re = append(re, string(m.Content))
+			}
+		}
+	}
+	h.rwLock.RUnlock()
+	if len(re) >= length {
+		return re[len(re)-length:]
+	}
+	fm, ok := h.filePlugin.(db.FilterManager)
+	if !ok {
+		return re
+	}
+	result, err := fm.FilterMessages(eventID, filter, length-len(re))
+	if err != nil {
+		h.log.Errorf("filter history container logs of %s error. %s", eventID, err.Error())
+		return re
+	}
+	return append(result, re...)
+}
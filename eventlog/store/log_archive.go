@@ -0,0 +1,394 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/builder/cloudos"
+	cdb "github.com/goodrain/rainbond/db"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/goodrain/rainbond/eventlog/conf"
+	"github.com/goodrain/rainbond/eventlog/db"
+	eventutil "github.com/goodrain/rainbond/eventlog/util"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+//the dir of the local archives in the log home path
+const archiveDirName = "archive"
+
+//the max number of the archives restored at one time
+const maxRestoreArchives = 100
+
+//logArchiver cleans the logs past the retention, and archives them if the policy requires
+type logArchiver struct {
+	homePath   string
+	retention  *db.LogRetention
+	oss        cloudos.CloudOSer
+	restoreTTL time.Duration
+	hostID     string
+}
+
+func newLogArchiver(conf conf.DBConf) (*logArchiver, error) {
+	archiver := &logArchiver{
+		homePath:   conf.HomePath,
+		retention:  db.NewLogRetention(conf.EventLogRetention, conf.ContainerLogRetention, conf.LogIndexRetention),
+		restoreTTL: conf.Archive.RestoreTTL,
+		hostID:     conf.Archive.NodeID,
+	}
+	if conf.Archive.Provider != "" {
+		provider, err := cloudos.Str2S3Provider(conf.Archive.Provider)
+		if err != nil {
+			return nil, err
+		}
+		archiver.oss, err = cloudos.New(&cloudos.Config{
+			ProviderType: provider,
+			Endpoint:     conf.Archive.Endpoint,
+			AccessKey:    conf.Archive.AccessKey,
+			SecretKey:    conf.Archive.SecretKey,
+			BucketName:   conf.Archive.BucketName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create log archive object storage client: %v", err)
+		}
+	}
+	return archiver, nil
+}
+
+//cleanContainerLogs cleans the daily container log files, such as <home>/<alias id>/2020-1-2.log.gz
+func (a *logArchiver) cleanContainerLogs(now time.Time) {
+	services, err := cdb.GetManager().TenantServiceDao().GetAllServicesID()
+	if err != nil {
+		logrus.Errorf("list components failure %s", err.Error())
+		return
+	}
+	aliases := make(map[string]*dbmodel.TenantServices, len(services))
+	for _, service := range services {
+		aliases[db.GetServiceAliasID(service.ServiceID)] = service
+	}
+	dirs, err := ioutil.ReadDir(a.homePath)
+	if err != nil {
+		logrus.Errorf("list log dir error, %s", err.Error())
+		return
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || dir.Name() == "eventlog" || dir.Name() == archiveDirName || dir.Name() == db.AccessLogDirName {
+			continue
+		}
+		//the component may have been deleted
+		var tenantID, serviceID string
+		if service, ok := aliases[dir.Name()]; ok {
+			tenantID, serviceID = service.TenantID, service.ServiceID
+		}
+		policy := a.retention.Policy(tenantID, serviceID)
+		if policy.ContainerLogDays <= 0 {
+			continue
+		}
+		files, err := ioutil.ReadDir(path.Join(a.homePath, dir.Name()))
+		if err != nil {
+			logrus.Errorf("list log dir error, %s", err.Error())
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || file.Name() == "stdout.log" {
+				continue
+			}
+			date, err := time.ParseInLocation("2006-1-2", strings.Split(file.Name(), ".")[0], time.Local)
+			if err != nil {
+				logrus.Warningf("unexpected container log file %s", file.Name())
+				continue
+			}
+			if !now.After(date.AddDate(0, 0, policy.ContainerLogDays)) {
+				continue
+			}
+			archive := &dbmodel.LogArchive{
+				TenantID:  policy.TenantID,
+				ServiceID: serviceID,
+				Kind:      dbmodel.LogArchiveKindContainer,
+				FileName:  file.Name(),
+				StartTime: date,
+				EndTime:   date.AddDate(0, 0, 1),
+			}
+			//the logs of the deleted components are not archived
+			archived := policy.Archive && serviceID != ""
+			if err := a.expire(archive, path.Join(a.homePath, dir.Name(), file.Name()), archived, true); err != nil {
+				logrus.Errorf("clean container log %s/%s error. %s", dir.Name(), file.Name(), err.Error())
+			}
+		}
+	}
+}
+
+//cleanEventLogs cleans the event log files, such as <home>/eventlog/<event id>.log
+func (a *logArchiver) cleanEventLogs(now time.Time) {
+	minDays := a.minEventLogDays()
+	if minDays <= 0 {
+		//the event logs are kept forever
+		return
+	}
+	dir := eventutil.EventLogFilePath(a.homePath)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("list event log dir error, %s", err.Error())
+		}
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".log") {
+			continue
+		}
+		//the file is not expired in any policy
+		if !now.After(file.ModTime().AddDate(0, 0, minDays)) {
+			continue
+		}
+		eventID := strings.TrimSuffix(file.Name(), ".log")
+		archive := &dbmodel.LogArchive{
+			Kind:      dbmodel.LogArchiveKindEvent,
+			FileName:  file.Name(),
+			StartTime: file.ModTime(),
+			EndTime:   file.ModTime(),
+		}
+		event, err := cdb.GetManager().ServiceEventDao().GetEventByEventID(eventID)
+		if err == nil {
+			archive.TenantID, archive.ServiceID = event.TenantID, event.ServiceID
+			archive.StartTime = event.CreatedAt
+		} else if err != gorm.ErrRecordNotFound {
+			logrus.Errorf("get event %s failure %s", eventID, err.Error())
+			continue
+		}
+		policy := a.retention.Policy(archive.TenantID, archive.ServiceID)
+		if policy.EventLogDays <= 0 || !now.After(file.ModTime().AddDate(0, 0, policy.EventLogDays)) {
+			continue
+		}
+		archive.TenantID = policy.TenantID
+		if err := a.expire(archive, path.Join(dir, file.Name()), policy.Archive && archive.TenantID != "", false); err != nil {
+			logrus.Errorf("clean event log %s error. %s", file.Name(), err.Error())
+		}
+	}
+}
+
+//minEventLogDays returns the min retention days of the event logs in all the policies
+func (a *logArchiver) minEventLogDays() int {
+	minDays := a.retention.Policy("", "").EventLogDays
+	policies, err := cdb.GetManager().LogRetentionPolicyDao().ListPolicies()
+	if err != nil {
+		logrus.Errorf("list log retention policies failure %s", err.Error())
+		return 0
+	}
+	for _, policy := range policies {
+		if policy.EventLogDays > 0 && (minDays <= 0 || policy.EventLogDays < minDays) {
+			minDays = policy.EventLogDays
+		}
+	}
+	return minDays
+}
+
+//expire removes the log file past the retention, it is archived first if it is required.
+//The restored files are kept for viewing until the restore ttl.
+func (a *logArchiver) expire(archive *dbmodel.LogArchive, filePath string, archived, compressed bool) error {
+	old, err := cdb.GetManager().LogArchiveDao().GetArchive(archive.Kind, archive.TenantID, archive.ServiceID, archive.FileName)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == nil {
+		if old.RestoredAt != nil && old.RestoredAt.Add(a.restoreTTL).After(time.Now()) {
+			return nil
+		}
+		return removeLogFile(filePath)
+	}
+	if !archived {
+		logrus.Debugf("clean log file %s", filePath)
+		return removeLogFile(filePath)
+	}
+	serviceID := archive.ServiceID
+	if serviceID == "" {
+		serviceID = "tenant"
+	}
+	key := path.Join(archive.TenantID, serviceID, archive.Kind, archive.FileName)
+	if !compressed {
+		key += ".gz"
+	}
+	archivePath := path.Join(a.homePath, archiveDirName, key)
+	if err := os.MkdirAll(path.Dir(archivePath), 0755); err != nil {
+		return err
+	}
+	if compressed {
+		err = os.Rename(filePath, archivePath)
+	} else {
+		err = gzipFile(filePath, archivePath)
+	}
+	if err != nil {
+		return fmt.Errorf("compress log file: %v", err)
+	}
+	if info, err := os.Stat(archivePath); err == nil {
+		archive.Size = info.Size()
+	}
+	archive.LocalPath = archivePath
+	archive.HostID = a.hostID
+	if a.oss != nil {
+		if err := a.oss.PutObject(path.Join("logs", key), archivePath); err != nil {
+			//the archive is kept in local if it fails to be uploaded
+			logrus.Errorf("upload log archive %s failure %s", key, err.Error())
+		} else {
+			archive.ObjectKey = path.Join("logs", key)
+			archive.LocalPath = ""
+		}
+	}
+	if err := cdb.GetManager().LogArchiveDao().AddModel(archive); err != nil {
+		return err
+	}
+	if archive.LocalPath == "" {
+		removeLogFile(archivePath)
+	}
+	return removeLogFile(filePath)
+}
+
+//restore restores the archived logs to the original path for viewing
+func (a *logArchiver) restore(archive *dbmodel.LogArchive) error {
+	var target string
+	switch archive.Kind {
+	case dbmodel.LogArchiveKindContainer:
+		target = path.Join(a.homePath, db.GetServiceAliasID(archive.ServiceID), archive.FileName)
+	case dbmodel.LogArchiveKindEvent:
+		target = path.Join(eventutil.EventLogFilePath(a.homePath), archive.FileName)
+	default:
+		return fmt.Errorf("unknown log archive kind %s", archive.Kind)
+	}
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return err
+	}
+	source := archive.LocalPath
+	if archive.ObjectKey == "" && archive.HostID != "" && archive.HostID != a.hostID {
+		return fmt.Errorf("the archive is kept in the local of eventlog instance %s", archive.HostID)
+	}
+	if archive.ObjectKey != "" {
+		if a.oss == nil {
+			return fmt.Errorf("log archive object storage is not configured")
+		}
+		source = target + ".archive"
+		if err := a.oss.GetObject(archive.ObjectKey, source); err != nil {
+			return fmt.Errorf("download log archive %s: %v", archive.ObjectKey, err)
+		}
+		defer os.Remove(source)
+	}
+	var err error
+	if archive.Kind == dbmodel.LogArchiveKindEvent {
+		err = gunzipFile(source, target)
+	} else {
+		err = copyFile(source, target)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	archive.RestoredAt = &now
+	return cdb.GetManager().LogArchiveDao().UpdateModel(archive)
+}
+
+//restoreArchives restores the archives which match the query and are made by the instance of the host id,
+//the archives made before the host id is recorded have an empty host id.
+func (a *logArchiver) restoreArchives(query *dbmodel.LogArchiveQuery, hostID string) ([]*dbmodel.LogArchive, error) {
+	query.Page, query.PageSize = 1, maxRestoreArchives
+	archives, total, err := cdb.GetManager().LogArchiveDao().ListArchives(query)
+	if err != nil {
+		return nil, err
+	}
+	if total > maxRestoreArchives {
+		return nil, fmt.Errorf("too many archives(%d) in the range, at most %d archives can be restored at one time", total, maxRestoreArchives)
+	}
+	var restored []*dbmodel.LogArchive
+	for _, archive := range archives {
+		if archive.HostID != hostID {
+			continue
+		}
+		if err := a.restore(archive); err != nil {
+			return nil, fmt.Errorf("restore log archive %s: %v", archive.FileName, err)
+		}
+		restored = append(restored, archive)
+	}
+	return restored, nil
+}
+
+func removeLogFile(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := gzip.NewWriter(out)
+	if _, err := io.Copy(writer, in); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	reader, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, reader)
+	return err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,113 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	cdb "github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	dbmodel "github.com/goodrain/rainbond/db/model"
+	"github.com/jinzhu/gorm"
+)
+
+func TestLogArchiveExpireAndRestore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	archiveDao := dao.NewMockLogArchiveDao(ctrl)
+	manager := cdb.NewMockManager(ctrl)
+	manager.EXPECT().LogArchiveDao().AnyTimes().Return(archiveDao)
+	cdb.SetTestManager(manager)
+
+	homePath, err := ioutil.TempDir("", "logarchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homePath)
+	if err := os.MkdirAll(path.Join(homePath, "eventlog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logFile := path.Join(homePath, "eventlog", "event1.log")
+	content := "1 1600000000 build success\n"
+	if err := ioutil.WriteFile(logFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archiver := &logArchiver{homePath: homePath, restoreTTL: time.Hour, hostID: "node1"}
+	archive := &dbmodel.LogArchive{
+		TenantID:  "tenant1",
+		ServiceID: "service1",
+		Kind:      dbmodel.LogArchiveKindEvent,
+		FileName:  "event1.log",
+	}
+
+	//archive the event log in local
+	archiveDao.EXPECT().GetArchive(archive.Kind, archive.TenantID, archive.ServiceID, archive.FileName).Return(nil, gorm.ErrRecordNotFound)
+	archiveDao.EXPECT().AddModel(archive).Return(nil)
+	if err := archiver.expire(archive, logFile, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Fatalf("the expired log file should be removed")
+	}
+	if archive.LocalPath != path.Join(homePath, archiveDirName, "tenant1/service1/event/event1.log.gz") || archive.Size == 0 {
+		t.Fatalf("unexpected archive %+v", archive)
+	}
+	if archive.HostID != "node1" {
+		t.Fatalf("the archive should be made by node1, but %s", archive.HostID)
+	}
+
+	//the archive kept in the local of another instance can not be restored
+	other := *archive
+	other.HostID = "node2"
+	if err := archiver.restore(&other); err == nil {
+		t.Fatalf("the archive of node2 should not be restored by node1")
+	}
+
+	//restore the archive for viewing
+	archiveDao.EXPECT().UpdateModel(archive).Return(nil)
+	if err := archiver.restore(archive); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ioutil.ReadFile(logFile)
+	if err != nil || string(restored) != content || archive.RestoredAt == nil {
+		t.Fatalf("unexpected restored log %q, %v", restored, err)
+	}
+
+	//the restored log is kept until the restore ttl
+	archiveDao.EXPECT().GetArchive(archive.Kind, archive.TenantID, archive.ServiceID, archive.FileName).Return(archive, nil).Times(2)
+	if err := archiver.expire(archive, logFile, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(logFile); err != nil {
+		t.Fatalf("the restored log file should be kept: %v", err)
+	}
+	restoredAt := time.Now().Add(-2 * time.Hour)
+	archive.RestoredAt = &restoredAt
+	if err := archiver.expire(archive, logFile, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+		t.Fatalf("the restored log file should be removed after the ttl")
+	}
+}
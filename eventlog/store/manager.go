@@ -20,8 +20,8 @@ package store
 
 import (
 	"errors"
-	"strconv"
 
+	dbmodel "github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/eventlog/db"
 	coreutil "github.com/goodrain/rainbond/util"
 
@@ -29,8 +29,6 @@ import (
 
 	"time"
 
-	"strings"
-
 	"fmt"
 
 	"encoding/json"
@@ -38,8 +36,6 @@ import (
 	"bytes"
 
 	"context"
-	"os"
-	"path/filepath"
 
 	"github.com/pquerna/ffjson/ffjson"
 	"github.com/prometheus/client_golang/prometheus"
@@ -52,7 +48,11 @@ type Manager interface {
 	SubMessageChan() chan [][]byte
 	PubMessageChan() chan [][]byte
 	DockerLogMessageChan() chan []byte
-	GetDockerLogs(serviceID string, length int) []string
+	GetDockerLogs(serviceID string, length int, filter *db.LogFilter) []string
+	SearchDockerLogs(query *db.LogSearchQuery) (*db.LogSearchResult, error)
+	RestoreLogArchives(query *dbmodel.LogArchiveQuery, hostID string) ([]*dbmodel.LogArchive, error)
+	WriteAccessLogs(logs []*db.AccessLog) error
+	SearchAccessLogs(query *db.AccessLogQuery) (*db.AccessLogResult, error)
 	MonitorMessageChan() chan [][]byte
 	WebSocketMessageChan(mode, eventID, subID string) chan *db.EventLogMessage
 	NewMonitorMessageChan() chan []byte
@@ -77,6 +77,10 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	archiver, err := newLogArchiver(conf.DB)
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	storeManager := &storeManager{
 		cancel:                cancel,
@@ -92,6 +96,8 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 		chanCacheSize:         100,
 		dbPlugin:              dbPlugin,
 		filePlugin:            filePlugin,
+		archiver:              archiver,
+		accessLogs:            db.NewAccessLogStore(conf.DB.HomePath, int64(conf.DB.AccessLogMaxDaySize)*1024*1024),
 		errChan:               make(chan error),
 	}
 	handle := NewStore("handle", storeManager)
@@ -122,6 +128,8 @@ type storeManager struct {
 	log                    *logrus.Entry
 	dbPlugin               db.Manager
 	filePlugin             db.Manager
+	archiver               *logArchiver
+	accessLogs             *db.AccessLogStore
 	errChan                chan error
 }
 
@@ -266,60 +274,32 @@ func (s *storeManager) Run() error {
 	return nil
 }
 
-//cleanLog
-// clean service log that before 7 days in every 24h
-// clean event log that before 30 days message in every 24h
+//cleanLog cleans the logs past the retention every 24h, the retention policies of
+//the tenants and components are applied, and the logs are archived if the policy requires.
 func (s *storeManager) cleanLog() {
 	coreutil.Exec(s.context, func() error {
-		//do something
-		pathname := s.conf.DB.HomePath
-		logrus.Infof("start clean history service log %s", pathname)
-		files, err := coreutil.GetFileList(pathname, 2)
-		if err != nil {
-			logrus.Error("list log dir error, ", err.Error())
-		} else {
-			for _, fi := range files {
-				if !strings.Contains(fi, "eventlog") {
-					if err := s.deleteFile(fi); err != nil {
-						logrus.Errorf("delete log file %s error. %s", fi, err.Error())
-					}
-				}
-			}
-		}
+		logrus.Infof("start clean history logs %s", s.conf.DB.HomePath)
+		now := time.Now()
+		s.archiver.cleanContainerLogs(now)
+		s.archiver.cleanEventLogs(now)
+		s.accessLogs.Clean(now, s.conf.DB.AccessLogRetention)
 		return nil
 	}, time.Hour*24)
 }
 
-func (s *storeManager) deleteFile(filename string) error {
-	now := time.Now()
-	if strings.HasSuffix(filename, "stdout.log") {
-		return nil
+//RestoreLogArchives restores the archived logs which match the query and are made by the instance of the host id
+func (s *storeManager) RestoreLogArchives(query *dbmodel.LogArchiveQuery, hostID string) ([]*dbmodel.LogArchive, error) {
+	return s.archiver.restoreArchives(query, hostID)
+}
 
-	}
-	name := filepath.Base(filename)
-	lis := strings.Split(name, ".")
-	if len(lis) < 1 {
-		return errors.New("file name format error")
-	}
-	date := lis[0]
-	loc, _ := time.LoadLocation("Local")
-	theTime, err := time.ParseInLocation("2006-1-2", date, loc)
-	if err != nil {
-		return err
-	}
-	saveDay, _ := strconv.Atoi(os.Getenv("DOCKER_LOG_SAVE_DAY"))
-	if saveDay == 0 {
-		saveDay = 7
-	}
-	if now.After(theTime.Add(time.Duration(saveDay) * time.Hour * 24)) {
-		if err := os.Remove(filename); err != nil {
-			if !strings.Contains(err.Error(), "No such file or directory") {
-				return err
-			}
-		}
-		logrus.Debugf("clean service log %s", filename)
-	}
-	return nil
+//WriteAccessLogs stores the access logs shipped by the gateway
+func (s *storeManager) WriteAccessLogs(logs []*db.AccessLog) error {
+	return s.accessLogs.Write(logs)
+}
+
+//SearchAccessLogs searches the access logs of a tenant
+func (s *storeManager) SearchAccessLogs(query *db.AccessLogQuery) (*db.AccessLogResult, error) {
+	return s.accessLogs.Search(query)
 }
 
 func (s *storeManager) checkHealth() {
@@ -470,6 +450,18 @@ loop:
 			containerID := m[0:12]        //0-12
 			serviceID := string(m[13:45]) //13-45
 			log := m[45:]
+			var record db.DockerLogRecord
+			if log[0] == db.StructuredLogMarker {
+				//the message of the structured log is stored as the log line, the level and fields are kept apart
+				if err := json.Unmarshal(log[1:], &record); err != nil {
+					s.log.Debugf("decode structured container log error. %s", err.Error())
+					continue
+				}
+				log = []byte(record.Msg)
+			}
+			if record.Time == "" {
+				record.Time = time.Now().Format(time.RFC3339Nano)
+			}
 			buffer := bytes.NewBuffer(containerID)
 			buffer.WriteString(":")
 			buffer.Write(log)
@@ -477,6 +469,9 @@ loop:
 				Message: buffer.String(),
 				Content: buffer.Bytes(),
 				EventID: serviceID,
+				Time:    record.Time,
+				Level:   record.Level,
+				Fields:  record.Fields,
 			}
 			s.dockerLogStore.InsertMessage(&message)
 			buffer.Reset()
@@ -522,6 +517,18 @@ func (s *storeManager) Error() chan error {
 }
 
 //GetDockerLogs get history docker log
-func (s *storeManager) GetDockerLogs(serviceID string, length int) []string {
+func (s *storeManager) GetDockerLogs(serviceID string, length int, filter *db.LogFilter) []string {
+	if docker, ok := s.dockerLogStore.(*dockerLogStore); ok && !filter.Empty() {
+		return docker.GetFilteredHistoryMessage(serviceID, length, filter)
+	}
 	return s.dockerLogStore.GetHistoryMessage(serviceID, length)
 }
+
+//SearchDockerLogs searches the indexed container logs, the logs are indexed after they are persisted
+func (s *storeManager) SearchDockerLogs(query *db.LogSearchQuery) (*db.LogSearchResult, error) {
+	sm, ok := s.filePlugin.(db.SearchManager)
+	if !ok {
+		return nil, errors.New("container log storage does not support searching")
+	}
+	return sm.SearchMessages(query)
+}
@@ -0,0 +1,178 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Tag the syslog tag of the access logs which are shipped
+	Tag = "rbd_access"
+	// the access logs are shipped when the batch is full or every flush interval
+	batchSize     = 500
+	flushInterval = time.Second
+	// the access logs are dropped if the buffer is full
+	bufferSize     = 10000
+	maxMessageSize = 64 * 1024
+)
+
+// Shipper receives the json access logs from the data plane by syslog over udp,
+// and ships them to the eventlog in batches.
+type Shipper struct {
+	listen   string
+	endpoint string
+	client   *http.Client
+	records  chan json.RawMessage
+	conn     net.PacketConn
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewShipper creates a new shipper, the access logs are shipped to the eventlog endpoint such as http://rbd-eventlog:6363
+func NewShipper(listen, endpoint string) *Shipper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Shipper{
+		listen:   listen,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		records:  make(chan json.RawMessage, bufferSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start starts to receive and ship the access logs
+func (s *Shipper) Start() error {
+	conn, err := net.ListenPacket("udp", s.listen)
+	if err != nil {
+		return fmt.Errorf("listen access log syslog %s: %v", s.listen, err)
+	}
+	s.conn = conn
+	go s.receive()
+	go s.ship()
+	logrus.Infof("access logs received on %s are shipped to %s", s.listen, s.endpoint)
+	return nil
+}
+
+// Stop stops the shipper, the buffered access logs are dropped
+func (s *Shipper) Stop() {
+	s.cancel()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func (s *Shipper) receive() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			default:
+			}
+			logrus.Warningf("receive access log: %v", err)
+			continue
+		}
+		record, ok := parseMessage(buf[:n])
+		if !ok {
+			continue
+		}
+		select {
+		case s.records <- record:
+		default:
+			logrus.Debugf("access log buffer is full, drop the access log")
+		}
+	}
+}
+
+func (s *Shipper) ship() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]json.RawMessage, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.send(batch); err != nil {
+			logrus.Warningf("ship %d access logs: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case record := <-s.records:
+			batch = append(batch, record)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *Shipper) send(batch []json.RawMessage) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.endpoint+"/access-logs", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.client.Do(req.WithContext(s.ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("eventlog responds %s", res.Status)
+	}
+	return nil
+}
+
+// parseMessage returns the json access log in the syslog message,
+// such as: <190>Oct 18 16:00:00 rbd_access: {"status":200}
+func parseMessage(msg []byte) (json.RawMessage, bool) {
+	i := bytes.Index(msg, []byte(Tag+": "))
+	if i < 0 {
+		return nil, false
+	}
+	record := bytes.TrimSpace(msg[i+len(Tag)+2:])
+	if !json.Valid(record) {
+		return nil, false
+	}
+	// the buffer is reused by the receiver
+	return json.RawMessage(append([]byte(nil), record...)), true
+}
@@ -0,0 +1,86 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package accesslog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseMessage(t *testing.T) {
+	testCases := []struct {
+		msg    string
+		record string
+	}{
+		{`<190>Oct 18 16:00:00 rbd_access: {"status":200,"path":"/"}`, `{"status":200,"path":"/"}`},
+		{`<190>Oct 18 16:00:00 rbd_access: {"status":200,`, ""},
+		{`<190>Oct 18 16:00:00 nginx: {"status":200}`, ""},
+	}
+	for _, tc := range testCases {
+		record, ok := parseMessage([]byte(tc.msg))
+		if ok != (tc.record != "") || string(record) != tc.record {
+			t.Errorf("expected %s from %s, but returned %s", tc.record, tc.msg, record)
+		}
+	}
+}
+
+func TestShipper(t *testing.T) {
+	received := make(chan []map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/access-logs" {
+			w.WriteHeader(404)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var logs []map[string]interface{}
+		if err := json.Unmarshal(body, &logs); err != nil {
+			t.Errorf("decode access logs: %v", err)
+		}
+		received <- logs
+	}))
+	defer server.Close()
+
+	shipper := NewShipper("127.0.0.1:0", server.URL+"/")
+	if err := shipper.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer shipper.Stop()
+
+	conn, err := net.Dial("udp", shipper.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`<190>Oct 18 16:00:00 rbd_access: {"tenant_id":"foo","status":502}`))
+	conn.Write([]byte(`<190>Oct 18 16:00:00 rbd_access: invalid`))
+
+	select {
+	case logs := <-received:
+		if len(logs) != 1 || logs[0]["tenant_id"] != "foo" {
+			t.Errorf("expected the access log of foo, but received %v", logs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the access logs are not shipped")
+	}
+}
@@ -0,0 +1,131 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/sirupsen/logrus"
+)
+
+//HTTP01ChallengePath the path of the http-01 challenges, the gateway proxies it to the gateway controller
+const HTTP01ChallengePath = "/.well-known/acme-challenge/"
+
+//challengePrefix the etcd key prefix of the http-01 challenges
+const challengePrefix = "/rainbond/gateway/acme/challenges/"
+
+//ChallengeStore stores the key authorizations of the pending http-01 challenges,
+//the acme client of worker puts them and all the gateway nodes serve them
+type ChallengeStore interface {
+	Put(ctx context.Context, token, keyAuth string, ttl time.Duration) error
+	Get(ctx context.Context, token string) (string, error)
+	Delete(ctx context.Context, token string) error
+}
+
+//NewEtcdChallengeStore creates a challenge store based on etcd
+func NewEtcdChallengeStore(etcdCli *clientv3.Client) ChallengeStore {
+	return &etcdChallengeStore{etcdCli: etcdCli}
+}
+
+type etcdChallengeStore struct {
+	etcdCli *clientv3.Client
+}
+
+func (e *etcdChallengeStore) Put(ctx context.Context, token, keyAuth string, ttl time.Duration) error {
+	lease, err := e.etcdCli.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return fmt.Errorf("grant lease for challenge %s: %v", token, err)
+	}
+	_, err = e.etcdCli.Put(ctx, challengePrefix+token, keyAuth, clientv3.WithLease(lease.ID))
+	return err
+}
+
+func (e *etcdChallengeStore) Get(ctx context.Context, token string) (string, error) {
+	res, err := e.etcdCli.Get(ctx, challengePrefix+token)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Kvs) == 0 {
+		return "", nil
+	}
+	return string(res.Kvs[0].Value), nil
+}
+
+func (e *etcdChallengeStore) Delete(ctx context.Context, token string) error {
+	_, err := e.etcdCli.Delete(ctx, challengePrefix+token)
+	return err
+}
+
+//NewMemoryChallengeStore creates a challenge store in memory, it only works in one process
+func NewMemoryChallengeStore() ChallengeStore {
+	return &memoryChallengeStore{challenges: make(map[string]string)}
+}
+
+type memoryChallengeStore struct {
+	lock       sync.RWMutex
+	challenges map[string]string
+}
+
+func (m *memoryChallengeStore) Put(ctx context.Context, token, keyAuth string, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.challenges[token] = keyAuth
+	return nil
+}
+
+func (m *memoryChallengeStore) Get(ctx context.Context, token string) (string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.challenges[token], nil
+}
+
+func (m *memoryChallengeStore) Delete(ctx context.Context, token string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.challenges, token)
+	return nil
+}
+
+//NewHTTP01Handler creates the handler answering the http-01 challenges
+func NewHTTP01Handler(store ChallengeStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, HTTP01ChallengePath)
+		if token == "" || token == r.URL.Path || strings.Contains(token, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		keyAuth, err := store.Get(r.Context(), token)
+		if err != nil {
+			logrus.Errorf("get acme challenge %s failure %s", token, err.Error())
+			http.Error(w, "get challenge failure", http.StatusInternalServerError)
+			return
+		}
+		if keyAuth == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
@@ -19,13 +19,17 @@
 package annotations
 
 import (
+	"github.com/goodrain/rainbond/gateway/annotations/backendprotocol"
 	"github.com/goodrain/rainbond/gateway/annotations/cookie"
+	"github.com/goodrain/rainbond/gateway/annotations/cors"
+	"github.com/goodrain/rainbond/gateway/annotations/errorpage"
 	"github.com/goodrain/rainbond/gateway/annotations/header"
 	"github.com/goodrain/rainbond/gateway/annotations/l4"
 	"github.com/goodrain/rainbond/gateway/annotations/lbtype"
 	"github.com/goodrain/rainbond/gateway/annotations/parser"
 	"github.com/goodrain/rainbond/gateway/annotations/proxy"
 	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+	"github.com/goodrain/rainbond/gateway/annotations/responseheader"
 	"github.com/goodrain/rainbond/gateway/annotations/rewrite"
 	"github.com/goodrain/rainbond/gateway/annotations/upstreamhashby"
 	weight "github.com/goodrain/rainbond/gateway/annotations/wight"
@@ -50,6 +54,10 @@ type Ingress struct {
 	UpstreamHashBy    string
 	LoadBalancingType string
 	Proxy             proxy.Config
+	BackendProtocol   backendprotocol.Config
+	CORS              cors.Config
+	ResponseHeader    responseheader.Config
+	ErrorPage         errorpage.Config
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
@@ -69,6 +77,10 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"UpstreamHashBy":    upstreamhashby.NewParser(cfg),
 			"LoadBalancingType": lbtype.NewParser(cfg),
 			"Proxy":             proxy.NewParser(cfg),
+			"BackendProtocol":   backendprotocol.NewParser(cfg),
+			"CORS":              cors.NewParser(cfg),
+			"ResponseHeader":    responseheader.NewParser(cfg),
+			"ErrorPage":         errorpage.NewParser(cfg),
 		},
 	}
 }
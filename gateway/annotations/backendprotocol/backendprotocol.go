@@ -0,0 +1,93 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package backendprotocol
+
+import (
+	"strings"
+
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+	"github.com/goodrain/rainbond/util/ingress-nginx/ingress/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+const (
+	// HTTP proxies requests to the upstream with plain http, it is the default
+	HTTP = "HTTP"
+	// HTTPS proxies requests to the upstream with https
+	HTTPS = "HTTPS"
+	// GRPC proxies requests to the upstream with grpc over plain http2
+	GRPC = "GRPC"
+	// GRPCS proxies requests to the upstream with grpc over tls
+	GRPCS = "GRPCS"
+)
+
+var validProtocols = map[string]bool{HTTP: true, HTTPS: true, GRPC: true, GRPCS: true}
+
+// IsValid checks if the protocol is a supported backend protocol
+func IsValid(protocol string) bool {
+	return validProtocols[strings.ToUpper(strings.TrimSpace(protocol))]
+}
+
+// Config describes the protocol used to communicate with the upstream
+type Config struct {
+	Protocol string `json:"protocol"`
+	// SSLVerify enables the verification of the upstream certificate, it works with HTTPS and GRPCS
+	SSLVerify bool `json:"sslVerify"`
+}
+
+// IsGRPC checks if the upstream is a grpc service
+func (c *Config) IsGRPC() bool {
+	return c.Protocol == GRPC || c.Protocol == GRPCS
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c1 *Config) bool {
+	if c == c1 {
+		return true
+	}
+	if c == nil || c1 == nil {
+		return false
+	}
+	return c.Protocol == c1.Protocol && c.SSLVerify == c1.SSLVerify
+}
+
+type backendProtocol struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new backend protocol annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return backendProtocol{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to indicate the protocol of the upstream
+func (a backendProtocol) Parse(ing *extensions.Ingress) (interface{}, error) {
+	protocol, err := parser.GetStringAnnotation("backend-protocol", ing)
+	if err != nil {
+		return nil, err
+	}
+	protocol = strings.ToUpper(strings.TrimSpace(protocol))
+	if !validProtocols[protocol] {
+		return nil, errors.NewInvalidAnnotationContent("backend-protocol", protocol)
+	}
+	config := &Config{Protocol: protocol}
+	config.SSLVerify, _ = parser.GetBoolAnnotation("proxy-ssl-verify", ing)
+	return config, nil
+}
@@ -0,0 +1,152 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package cors
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+	"github.com/sirupsen/logrus"
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+const (
+	// DefaultAllowOrigin allows all origins
+	DefaultAllowOrigin = "*"
+	// DefaultAllowMethods the methods allowed by default
+	DefaultAllowMethods = "GET, PUT, POST, DELETE, PATCH, OPTIONS"
+	// DefaultAllowHeaders the request headers allowed by default
+	DefaultAllowHeaders = "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Authorization"
+	// DefaultMaxAge the preflight responses are cached for 20 days by default
+	DefaultMaxAge = 1728000
+)
+
+var (
+	// the values are rendered into the nginx config, so they are restricted
+	originRegex  = regexp.MustCompile(`^(\*|https?://(\*\.)?[A-Za-z0-9\-\.]+(:[0-9]+)?)$`)
+	methodsRegex = regexp.MustCompile(`^([A-Za-z]+,?\s?)+$`)
+	headersRegex = regexp.MustCompile(`^([A-Za-z0-9\-\_]+,?\s?)+$`)
+)
+
+// Config contains the cross-origin resource sharing policy of a location
+type Config struct {
+	Enabled          bool     `json:"enabled"`
+	AllowOrigin      []string `json:"allowOrigin"`
+	AllowMethods     string   `json:"allowMethods"`
+	AllowHeaders     string   `json:"allowHeaders"`
+	ExposeHeaders    string   `json:"exposeHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAge           int      `json:"maxAge"`
+}
+
+// AllowAnyOrigin checks if the requests from any origin are allowed
+func (c *Config) AllowAnyOrigin() bool {
+	for _, origin := range c.AllowOrigin {
+		if origin == DefaultAllowOrigin {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c1 *Config) bool {
+	if c == c1 {
+		return true
+	}
+	if c == nil || c1 == nil {
+		return false
+	}
+	if c.Enabled != c1.Enabled || c.AllowMethods != c1.AllowMethods || c.AllowHeaders != c1.AllowHeaders ||
+		c.ExposeHeaders != c1.ExposeHeaders || c.AllowCredentials != c1.AllowCredentials || c.MaxAge != c1.MaxAge {
+		return false
+	}
+	if len(c.AllowOrigin) != len(c1.AllowOrigin) {
+		return false
+	}
+	for i := range c.AllowOrigin {
+		if c.AllowOrigin[i] != c1.AllowOrigin[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type cors struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new cors annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return cors{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to enable cross-origin requests, the invalid values are replaced with the defaults
+func (a cors) Parse(ing *extensions.Ingress) (interface{}, error) {
+	enabled, err := parser.GetBoolAnnotation("enable-cors", ing)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{Enabled: enabled}
+	if !enabled {
+		return config, nil
+	}
+
+	origins, _ := parser.GetStringAnnotation("cors-allow-origin", ing)
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if !originRegex.MatchString(origin) {
+			logrus.Warningf("ingress %s/%s: invalid cors origin %s", ing.Namespace, ing.Name, origin)
+			continue
+		}
+		config.AllowOrigin = append(config.AllowOrigin, origin)
+	}
+	if len(config.AllowOrigin) == 0 {
+		config.AllowOrigin = []string{DefaultAllowOrigin}
+	}
+
+	config.AllowMethods, _ = parser.GetStringAnnotation("cors-allow-methods", ing)
+	if !methodsRegex.MatchString(config.AllowMethods) {
+		config.AllowMethods = DefaultAllowMethods
+	}
+	config.AllowHeaders, _ = parser.GetStringAnnotation("cors-allow-headers", ing)
+	if !headersRegex.MatchString(config.AllowHeaders) {
+		config.AllowHeaders = DefaultAllowHeaders
+	}
+	config.ExposeHeaders, _ = parser.GetStringAnnotation("cors-expose-headers", ing)
+	if !headersRegex.MatchString(config.ExposeHeaders) {
+		config.ExposeHeaders = ""
+	}
+	config.AllowCredentials, _ = parser.GetBoolAnnotation("cors-allow-credentials", ing)
+	if config.AllowCredentials && config.AllowAnyOrigin() {
+		// the credentials are only shared with the origins listed explicitly
+		logrus.Warningf("ingress %s/%s: cors credentials require an explicit origin list", ing.Namespace, ing.Name)
+		config.AllowCredentials = false
+	}
+	config.MaxAge, err = parser.GetIntAnnotation("cors-max-age", ing)
+	if err != nil || config.MaxAge < 0 {
+		config.MaxAge = DefaultMaxAge
+	}
+	return config, nil
+}
@@ -0,0 +1,94 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package cors
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+)
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix("enable-cors")
+	origin := parser.GetAnnotationWithPrefix("cors-allow-origin")
+	methods := parser.GetAnnotationWithPrefix("cors-allow-methods")
+	credentials := parser.GetAnnotationWithPrefix("cors-allow-credentials")
+	maxAge := parser.GetAnnotationWithPrefix("cors-max-age")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	defaults := &Config{
+		Enabled:          true,
+		AllowOrigin:      []string{DefaultAllowOrigin},
+		AllowMethods:     DefaultAllowMethods,
+		AllowHeaders:     DefaultAllowHeaders,
+		AllowCredentials: false,
+		MaxAge:           DefaultMaxAge,
+	}
+	custom := *defaults
+	custom.AllowOrigin = []string{"https://foo.com", "https://*.bar.com"}
+	custom.AllowMethods = "GET, POST"
+	custom.AllowCredentials = true
+	custom.MaxAge = 600
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{enable: "true"}, defaults},
+		{map[string]string{enable: "false", origin: "https://foo.com"}, &Config{}},
+		{map[string]string{
+			enable:      "true",
+			origin:      "https://foo.com, https://*.bar.com, javascript:alert(1)",
+			methods:     "GET, POST",
+			credentials: "true",
+			maxAge:      "600",
+		}, &custom},
+		// the credentials are not allowed for any origin
+		{map[string]string{enable: "true", credentials: "true"}, defaults},
+		// the invalid values are replaced with the defaults
+		{map[string]string{enable: "true", origin: "foo.com", methods: "GET; return 200"}, defaults},
+		{map[string]string{origin: "https://foo.com"}, nil},
+		{nil, nil},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, _ := ap.Parse(ing)
+		config, _ := result.(*Config)
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
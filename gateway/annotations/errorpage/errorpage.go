@@ -0,0 +1,83 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package errorpage
+
+import (
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+	"github.com/sirupsen/logrus"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultRetryAfter the seconds of the Retry-After header of the maintenance page by default
+const DefaultRetryAfter = 300
+
+// Config contains the custom error pages and the maintenance mode of a server
+type Config struct {
+	// ConfigMap the name of the configmap which contains the error pages,
+	// it is in the same namespace as the ingress
+	ConfigMap   string `json:"configMap"`
+	Maintenance bool   `json:"maintenance"`
+	RetryAfter  int    `json:"retryAfter"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c1 *Config) bool {
+	if c == c1 {
+		return true
+	}
+	if c == nil || c1 == nil {
+		return false
+	}
+	return c.ConfigMap == c1.ConfigMap && c.Maintenance == c1.Maintenance && c.RetryAfter == c1.RetryAfter
+}
+
+type errorPage struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new error page annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return errorPage{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to customize the error pages and to enable the maintenance mode
+func (a errorPage) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+	configMap, err := parser.GetStringAnnotation("error-pages", ing)
+	if err == nil {
+		if errs := validation.IsDNS1123Subdomain(configMap); len(errs) > 0 {
+			logrus.Warningf("ingress %s/%s: invalid error pages %s: %v", ing.Namespace, ing.Name, configMap, errs)
+		} else {
+			config.ConfigMap = configMap
+		}
+	}
+	config.Maintenance, _ = parser.GetBoolAnnotation("maintenance", ing)
+	if config.Maintenance {
+		config.RetryAfter, err = parser.GetIntAnnotation("maintenance-retry-after", ing)
+		if err != nil || config.RetryAfter <= 0 {
+			config.RetryAfter = DefaultRetryAfter
+		}
+	}
+	if config.ConfigMap == "" && !config.Maintenance {
+		return nil, nil
+	}
+	return config, nil
+}
@@ -0,0 +1,72 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package errorpage
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+)
+
+func TestParse(t *testing.T) {
+	errorPages := parser.GetAnnotationWithPrefix("error-pages")
+	maintenance := parser.GetAnnotationWithPrefix("maintenance")
+	retryAfter := parser.GetAnnotationWithPrefix("maintenance-retry-after")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{errorPages: "my-pages"}, &Config{ConfigMap: "my-pages"}},
+		{map[string]string{errorPages: "My_Pages"}, nil},
+		{map[string]string{maintenance: "true"}, &Config{Maintenance: true, RetryAfter: DefaultRetryAfter}},
+		{map[string]string{maintenance: "true", retryAfter: "60"}, &Config{Maintenance: true, RetryAfter: 60}},
+		{map[string]string{maintenance: "true", retryAfter: "-1"}, &Config{Maintenance: true, RetryAfter: DefaultRetryAfter}},
+		{map[string]string{maintenance: "false", retryAfter: "60"}, nil},
+		{map[string]string{errorPages: "my-pages", maintenance: "true", retryAfter: "60"},
+			&Config{ConfigMap: "my-pages", Maintenance: true, RetryAfter: 60}},
+		{nil, nil},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		result, _ := ap.Parse(ing)
+		config, _ := result.(*Config)
+		if !config.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, config, testCase.annotations)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package responseheader
+
+import (
+	"strings"
+
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	"github.com/goodrain/rainbond/gateway/annotations/resolver"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpguts"
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// Config contains the headers added to or removed from the responses of a location,
+// eg: the security headers Strict-Transport-Security and Content-Security-Policy
+type Config struct {
+	SetHeaders    map[string]string `json:"setHeaders"`
+	RemoveHeaders []string          `json:"removeHeaders"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c1 *Config) bool {
+	if c == c1 {
+		return true
+	}
+	if c == nil || c1 == nil {
+		return false
+	}
+	if len(c.SetHeaders) != len(c1.SetHeaders) || len(c.RemoveHeaders) != len(c1.RemoveHeaders) {
+		return false
+	}
+	for k, v := range c.SetHeaders {
+		if v1, ok := c1.SetHeaders[k]; !ok || v != v1 {
+			return false
+		}
+	}
+	for i := range c.RemoveHeaders {
+		if c.RemoveHeaders[i] != c1.RemoveHeaders[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type responseHeader struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new response header annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return responseHeader{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to set or remove the headers of the responses, the invalid headers are ignored
+func (a responseHeader) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+	setHeaders, err := parser.GetStringAnnotationWithPrefix("set-response-header-", ing)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range setHeaders {
+		if !httpguts.ValidHeaderFieldName(k) || !httpguts.ValidHeaderFieldValue(v) {
+			logrus.Warningf("ingress %s/%s: invalid response header %s: %s", ing.Namespace, ing.Name, k, v)
+			continue
+		}
+		if config.SetHeaders == nil {
+			config.SetHeaders = make(map[string]string)
+		}
+		config.SetHeaders[k] = v
+	}
+	removeHeaders, _ := parser.GetStringAnnotation("remove-response-headers", ing)
+	for _, k := range strings.Split(removeHeaders, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !httpguts.ValidHeaderFieldName(k) {
+			logrus.Warningf("ingress %s/%s: invalid response header %s", ing.Namespace, ing.Name, k)
+			continue
+		}
+		config.RemoveHeaders = append(config.RemoveHeaders, k)
+	}
+	if config.SetHeaders == nil && config.RemoveHeaders == nil {
+		return nil, nil
+	}
+	return config, nil
+}
@@ -19,20 +19,26 @@
 package cluster
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/coreos/etcd/clientv3"
 	"github.com/goodrain/rainbond/cmd/gateway/option"
 )
 
 func TestCreateIPManager(t *testing.T) {
-	i, err := CreateIPManager(option.Config{
+	etcdCli, err := clientv3.New(clientv3.Config{Endpoints: []string{"http://127.0.0.1:2379"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := CreateIPManager(context.Background(), option.Config{
 		ListenPorts: option.ListenPorts{
 			HTTP: 80,
 		},
 		EtcdEndpoint: []string{"http://127.0.0.1:2379"},
-	})
+	}, etcdCli)
 	if err != nil {
 		t.Fatal(err)
 	}
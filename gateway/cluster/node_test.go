@@ -19,18 +19,24 @@
 package cluster
 
 import (
+	"context"
 	"testing"
 
+	"github.com/coreos/etcd/clientv3"
 	"github.com/goodrain/rainbond/cmd/gateway/option"
 )
 
 func TestCreateNodeManager(t *testing.T) {
-	nm, err := CreateNodeManager(option.Config{
+	etcdCli, err := clientv3.New(clientv3.Config{Endpoints: []string{"http://127.0.0.1:2379"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nm, err := CreateNodeManager(context.Background(), option.Config{
 		ListenPorts: option.ListenPorts{
 			HTTP: 80,
 		},
 		EtcdEndpoint: []string{"http://127.0.0.1:2379"},
-	})
+	}, etcdCli)
 	if err != nil {
 		t.Fatal(err)
 	}
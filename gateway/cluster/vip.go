@@ -0,0 +1,276 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/util/etcd/etcdlock"
+	"github.com/sirupsen/logrus"
+)
+
+//VIP a floating virtual ip which is held by one of the gateway nodes
+type VIP struct {
+	IPNet     *net.IPNet
+	Interface string
+}
+
+//ParseVIP parses the virtual ip such as 192.168.0.100/24, the prefix length is
+//the full length of the ip if it is not given.
+func ParseVIP(s string) (*VIP, error) {
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		ip = net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid virtual ip %s", s)
+		}
+		bits := net.IPv6len * 8
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = net.IPv4len * 8
+		}
+		return &VIP{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+	}
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+	return &VIP{IPNet: &net.IPNet{IP: ip, Mask: ipnet.Mask}}, nil
+}
+
+// the interface which has an address in the same subnet as the virtual ip
+func findVIPInterface(vip *VIP) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.Equal(vip.IPNet.IP) {
+				continue
+			}
+			if ipnet.Contains(vip.IPNet.IP) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface in the subnet of the virtual ip %s", vip.IPNet.IP)
+}
+
+//vipNetwork manages the virtual ips on the network interfaces
+type vipNetwork interface {
+	AddIP(iface string, ip *net.IPNet) error
+	DelIP(iface string, ip *net.IPNet) error
+	GratuitousARP(iface string, ip net.IP) error
+}
+
+//VIPManager keeps the floating virtual ips on the healthy gateway nodes.
+//Each virtual ip has an election in etcd, the healthy nodes campaign for it and
+//only the master adds the ip to its interface. The master gives up the ip when its
+//health checks fail, and the lease expires when it is gone, then the ip is moved to
+//the newly elected node.
+type VIPManager struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	config  option.Config
+	vips    []*VIP
+	check   func() error
+	network vipNetwork
+	newLock func(election string) (etcdlock.MasterInterface, error)
+	wg      sync.WaitGroup
+}
+
+//CreateVIPManager create vip manager, check is the health check of the gateway node
+func CreateVIPManager(ctx context.Context, config option.Config, etcdcli *clientv3.Client, check func() error) (*VIPManager, error) {
+	if config.VIPLeaseTTL <= 0 {
+		return nil, fmt.Errorf("invalid vip lease ttl %d", config.VIPLeaseTTL)
+	}
+	if config.VIPHealthInterval <= 0 {
+		return nil, fmt.Errorf("invalid vip health interval %s", config.VIPHealthInterval)
+	}
+	var vips []*VIP
+	for _, s := range config.VIPs {
+		vip, err := ParseVIP(s)
+		if err != nil {
+			return nil, err
+		}
+		vip.Interface = config.VIPInterface
+		if vip.Interface == "" {
+			if vip.Interface, err = findVIPInterface(vip); err != nil {
+				return nil, err
+			}
+		}
+		vips = append(vips, vip)
+	}
+	newCtx, cancel := context.WithCancel(ctx)
+	return &VIPManager{
+		ctx:     newCtx,
+		cancel:  cancel,
+		config:  config,
+		vips:    vips,
+		check:   check,
+		network: newHostNetwork(""),
+		newLock: func(election string) (etcdlock.MasterInterface, error) {
+			return etcdlock.NewMasterLock(etcdcli, election, config.NodeName, int64(config.VIPLeaseTTL))
+		},
+	}, nil
+}
+
+//Start starts keeping the virtual ips
+func (v *VIPManager) Start() {
+	for _, vip := range v.vips {
+		logrus.Infof("start keeping the virtual ip %s on %s", vip.IPNet, vip.Interface)
+		v.wg.Add(1)
+		go v.keep(vip)
+	}
+}
+
+//Stop releases the virtual ips held by the current node
+func (v *VIPManager) Stop() {
+	v.cancel()
+	v.wg.Wait()
+}
+
+func (v *VIPManager) keep(vip *VIP) {
+	defer v.wg.Done()
+	for v.waitHealthy() {
+		if err := v.elect(vip); err != nil {
+			logrus.Warningf("elect the holder of the virtual ip %s: %v", vip.IPNet.IP, err)
+		}
+		select {
+		case <-v.ctx.Done():
+			return
+		case <-time.After(v.config.VIPHealthInterval):
+		}
+	}
+}
+
+// waitHealthy waits until the current node is healthy, it returns false if the manager is stopped.
+func (v *VIPManager) waitHealthy() bool {
+	ticker := time.NewTicker(v.config.VIPHealthInterval)
+	defer ticker.Stop()
+	for {
+		err := v.check()
+		if err == nil {
+			return true
+		}
+		logrus.Debugf("the gateway node is unhealthy, do not campaign for the virtual ips: %v", err)
+		select {
+		case <-v.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// elect campaigns for the virtual ip and holds it once elected, until the health checks
+// fail, the lease is lost or the manager is stopped.
+func (v *VIPManager) elect(vip *VIP) error {
+	lock, err := v.newLock(fmt.Sprintf("/rainbond/gateway/vips/%s", vip.IPNet.IP))
+	if err != nil {
+		return err
+	}
+	lock.Start()
+	defer lock.Stop()
+	var holding bool
+	// the ip is released before resigning, so that it is never on two nodes
+	defer func() {
+		if holding {
+			v.release(vip)
+		}
+	}()
+	ticker := time.NewTicker(v.config.VIPHealthInterval)
+	defer ticker.Stop()
+	var failures int
+	for {
+		select {
+		case <-v.ctx.Done():
+			return nil
+		case event := <-lock.EventsChan():
+			switch event.Type {
+			case etcdlock.MasterAdded:
+				if err := v.acquire(vip); err != nil {
+					return err
+				}
+				holding = true
+			case etcdlock.MasterError:
+				return event.Error
+			}
+		case <-ticker.C:
+			if err := v.check(); err != nil {
+				failures++
+				if failures >= v.config.VIPHealthFailures {
+					return fmt.Errorf("health check failure: %v", err)
+				}
+				continue
+			}
+			failures = 0
+		}
+	}
+}
+
+func (v *VIPManager) acquire(vip *VIP) error {
+	if err := v.network.AddIP(vip.Interface, vip.IPNet); err != nil {
+		return fmt.Errorf("add virtual ip %s to %s: %v", vip.IPNet, vip.Interface, err)
+	}
+	logrus.Infof("the current node is elected, the virtual ip %s is added to %s", vip.IPNet, vip.Interface)
+	// the neighbours update their arp caches, so the traffic is moved to the current node at once
+	if err := v.network.GratuitousARP(vip.Interface, vip.IPNet.IP); err != nil {
+		logrus.Warningf("send gratuitous arp of %s: %v", vip.IPNet.IP, err)
+	}
+	return nil
+}
+
+func (v *VIPManager) release(vip *VIP) {
+	if err := v.network.DelIP(vip.Interface, vip.IPNet); err != nil {
+		logrus.Errorf("delete virtual ip %s from %s: %v", vip.IPNet, vip.Interface, err)
+		return
+	}
+	logrus.Infof("the virtual ip %s is deleted from %s", vip.IPNet, vip.Interface)
+}
+
+// gratuitousARP builds the gratuitous arp request of the ip, in an ethernet frame
+func gratuitousARP(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 42)
+	// ethernet header
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], mac)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0806)
+	// arp request whose sender and target are both the ip
+	binary.BigEndian.PutUint16(frame[14:16], 1)
+	binary.BigEndian.PutUint16(frame[16:18], 0x0800)
+	frame[18] = 6
+	frame[19] = 4
+	binary.BigEndian.PutUint16(frame[20:22], 1)
+	copy(frame[22:28], mac)
+	copy(frame[28:32], ip.To4())
+	copy(frame[38:42], ip.To4())
+	return frame
+}
@@ -0,0 +1,128 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type hostNetwork struct {
+	// the named network namespace which the virtual ips are managed in,
+	// it is the namespace of the gateway if empty
+	netns string
+}
+
+func newHostNetwork(netns string) vipNetwork {
+	return &hostNetwork{netns: netns}
+}
+
+func (h *hostNetwork) AddIP(iface string, ip *net.IPNet) error {
+	return h.ip("addr", "replace", ip.String(), "dev", iface)
+}
+
+func (h *hostNetwork) DelIP(iface string, ip *net.IPNet) error {
+	return h.ip("addr", "del", ip.String(), "dev", iface)
+}
+
+func (h *hostNetwork) ip(args ...string) error {
+	if h.netns != "" {
+		args = append([]string{"-n", h.netns}, args...)
+	}
+	out, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip %s: %v, %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// GratuitousARP sends the gratuitous arp requests of the ipv4 address,
+// the ipv6 addresses are announced by the kernel.
+func (h *hostNetwork) GratuitousARP(iface string, ip net.IP) error {
+	if ip.To4() == nil {
+		return nil
+	}
+	if h.netns == "" {
+		return sendGratuitousARP(iface, ip)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		// the thread is never unlocked, it exits with the goroutine rather than
+		// being reused in the other network namespace
+		runtime.LockOSThread()
+		ns, err := os.Open(filepath.Join("/var/run/netns", h.netns))
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer ns.Close()
+		if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+			errCh <- fmt.Errorf("enter network namespace %s: %v", h.netns, err)
+			return
+		}
+		errCh <- sendGratuitousARP(iface, ip)
+	}()
+	return <-errCh
+}
+
+func sendGratuitousARP(iface string, ip net.IP) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	if len(ifi.HardwareAddr) != 6 {
+		return fmt.Errorf("interface %s is not an ethernet interface", iface)
+	}
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return fmt.Errorf("create packet socket: %v", err)
+	}
+	defer unix.Close(fd)
+	addr := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  ifi.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	frame := gratuitousARP(ifi.HardwareAddr, ip)
+	// a few requests in case of some of them are lost
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(time.Millisecond * 200)
+		}
+		if err := unix.Sendto(fd, frame, 0, addr); err != nil {
+			return fmt.Errorf("send gratuitous arp: %v", err)
+		}
+	}
+	return nil
+}
+
+func htons(i uint16) uint16 {
+	return i<<8 | i>>8
+}
@@ -0,0 +1,168 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+)
+
+// setupNetns creates two network namespaces rbd-vip-a and rbd-vip-b, which
+// are connected by the veth pair va and vb in the subnet 10.99.0.0/24.
+func setupNetns(t *testing.T) func() {
+	if os.Geteuid() != 0 {
+		t.Skip("the network namespaces require root")
+	}
+	run := func(args ...string) {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v, %s", strings.Join(args, " "), err, out)
+		}
+	}
+	cleanup := func() {
+		exec.Command("ip", "netns", "del", "rbd-vip-a").Run()
+		exec.Command("ip", "netns", "del", "rbd-vip-b").Run()
+	}
+	cleanup()
+	run("ip", "netns", "add", "rbd-vip-a")
+	run("ip", "netns", "add", "rbd-vip-b")
+	run("ip", "-n", "rbd-vip-a", "link", "add", "va", "type", "veth", "peer", "name", "vb", "netns", "rbd-vip-b")
+	run("ip", "-n", "rbd-vip-a", "addr", "add", "10.99.0.1/24", "dev", "va")
+	run("ip", "-n", "rbd-vip-b", "addr", "add", "10.99.0.2/24", "dev", "vb")
+	run("ip", "-n", "rbd-vip-a", "link", "set", "va", "up")
+	run("ip", "-n", "rbd-vip-b", "link", "set", "vb", "up")
+	// the gratuitous arp creates the neighbour entry
+	run("ip", "netns", "exec", "rbd-vip-b", "sysctl", "-qw", "net.ipv4.conf.vb.arp_accept=1")
+	run("ip", "netns", "exec", "rbd-vip-a", "sysctl", "-qw", "net.ipv4.conf.va.arp_accept=1")
+	return cleanup
+}
+
+func netnsHasIP(netns, iface, ip string) bool {
+	out, _ := exec.Command("ip", "-n", netns, "addr", "show", "dev", iface).CombinedOutput()
+	return strings.Contains(string(out), " "+ip+" ")
+}
+
+func TestHostNetworkNetns(t *testing.T) {
+	defer setupNetns(t)()
+	network := newHostNetwork("rbd-vip-a")
+	vip, _ := ParseVIP("10.99.0.100/24")
+	if err := network.AddIP("va", vip.IPNet); err != nil {
+		t.Fatal(err)
+	}
+	if !netnsHasIP("rbd-vip-a", "va", "10.99.0.100/24") {
+		t.Fatal("expected the vip on va")
+	}
+	// replacing the existing ip succeeds
+	if err := network.AddIP("va", vip.IPNet); err != nil {
+		t.Fatal(err)
+	}
+	if err := network.GratuitousARP("va", vip.IPNet.IP); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := exec.Command("ip", "netns", "exec", "rbd-vip-a", "cat", "/sys/class/net/va/address").CombinedOutput()
+	mac := strings.TrimSpace(string(out))
+	waitFor(t, "the neighbour of the vip", func() bool {
+		out, _ := exec.Command("ip", "-n", "rbd-vip-b", "neigh", "show", "10.99.0.100").CombinedOutput()
+		return strings.Contains(string(out), mac)
+	})
+	if err := network.DelIP("va", vip.IPNet); err != nil {
+		t.Fatal(err)
+	}
+	if netnsHasIP("rbd-vip-a", "va", "10.99.0.100/24") {
+		t.Fatal("expected the vip is deleted from va")
+	}
+}
+
+// TestVIPFailoverNetns runs two vip managers in the network namespaces, and
+// the vip is moved to the other one when the holder is unhealthy.
+func TestVIPFailoverNetns(t *testing.T) {
+	if c, err := net.DialTimeout("tcp", "127.0.0.1:2379", time.Second); err != nil {
+		t.Skip("the election requires etcd on 127.0.0.1:2379")
+	} else {
+		c.Close()
+	}
+	defer setupNetns(t)()
+	etcdCli, err := clientv3.New(clientv3.Config{Endpoints: []string{"http://127.0.0.1:2379"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer etcdCli.Close()
+
+	var lock sync.Mutex
+	healthy := map[string]bool{"a": true, "b": true}
+	create := func(node string) *VIPManager {
+		v, err := CreateVIPManager(context.Background(), option.Config{
+			NodeName:          node,
+			VIPs:              []string{"10.99.0.100/24"},
+			VIPInterface:      "v" + node,
+			VIPLeaseTTL:       2,
+			VIPHealthInterval: time.Millisecond * 200,
+			VIPHealthFailures: 2,
+		}, etcdCli, func() error {
+			lock.Lock()
+			defer lock.Unlock()
+			if !healthy[node] {
+				return errors.New("unhealthy")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.network = newHostNetwork("rbd-vip-" + node)
+		v.Start()
+		return v
+	}
+	holder := func() string {
+		a := netnsHasIP("rbd-vip-a", "va", "10.99.0.100/24")
+		b := netnsHasIP("rbd-vip-b", "vb", "10.99.0.100/24")
+		switch {
+		case a && b:
+			t.Fatal("the vip is held by both nodes")
+		case a:
+			return "a"
+		case b:
+			return "b"
+		}
+		return ""
+	}
+	va := create("a")
+	defer va.Stop()
+	vb := create("b")
+	defer vb.Stop()
+
+	waitFor(t, "the vip held", func() bool { return holder() != "" })
+	first := holder()
+	other := map[string]string{"a": "b", "b": "a"}[first]
+	lock.Lock()
+	healthy[first] = false
+	lock.Unlock()
+	waitFor(t, "the vip moved", func() bool { return holder() == other })
+}
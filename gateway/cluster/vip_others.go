@@ -0,0 +1,44 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+)
+
+type hostNetwork struct{}
+
+func newHostNetwork(netns string) vipNetwork {
+	return &hostNetwork{}
+}
+
+func (h *hostNetwork) AddIP(iface string, ip *net.IPNet) error {
+	return fmt.Errorf("the virtual ip is not supported on this platform")
+}
+
+func (h *hostNetwork) DelIP(iface string, ip *net.IPNet) error {
+	return fmt.Errorf("the virtual ip is not supported on this platform")
+}
+
+func (h *hostNetwork) GratuitousARP(iface string, ip net.IP) error {
+	return fmt.Errorf("the virtual ip is not supported on this platform")
+}
@@ -0,0 +1,193 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/util/etcd/etcdlock"
+)
+
+type fakeLock struct {
+	events  chan etcdlock.MasterEvent
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (f *fakeLock) Start() {}
+func (f *fakeLock) Stop() {
+	f.once.Do(func() { close(f.stopped) })
+}
+func (f *fakeLock) EventsChan() <-chan etcdlock.MasterEvent { return f.events }
+func (f *fakeLock) GetHolder() string                       { return "" }
+
+type fakeNetwork struct {
+	lock sync.Mutex
+	ips  map[string]string
+}
+
+func (f *fakeNetwork) AddIP(iface string, ip *net.IPNet) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.ips[ip.String()] = iface
+	return nil
+}
+
+func (f *fakeNetwork) DelIP(iface string, ip *net.IPNet) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.ips, ip.String())
+	return nil
+}
+
+func (f *fakeNetwork) GratuitousARP(iface string, ip net.IP) error {
+	return nil
+}
+
+func (f *fakeNetwork) has(ip string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	_, ok := f.ips[ip]
+	return ok
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("timeout waiting for %s", desc)
+}
+
+func TestParseVIP(t *testing.T) {
+	testCases := []struct {
+		vip, ipnet string
+		err        bool
+	}{
+		{vip: "192.168.0.100/24", ipnet: "192.168.0.100/24"},
+		{vip: "192.168.0.100", ipnet: "192.168.0.100/32"},
+		{vip: "fd00::100/64", ipnet: "fd00::100/64"},
+		{vip: "192.168.0.300", err: true},
+	}
+	for _, tc := range testCases {
+		vip, err := ParseVIP(tc.vip)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for %s", tc.vip)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vip.IPNet.String() != tc.ipnet {
+			t.Errorf("expected %s, but returned %s", tc.ipnet, vip.IPNet)
+		}
+	}
+}
+
+func TestVIPManagerFailover(t *testing.T) {
+	var healthy = true
+	var lock sync.Mutex
+	locks := make(chan *fakeLock, 10)
+	network := &fakeNetwork{ips: make(map[string]string)}
+	vip, _ := ParseVIP("10.0.0.100/24")
+	vip.Interface = "eth0"
+	ctx, cancel := context.WithCancel(context.Background())
+	v := &VIPManager{
+		ctx:    ctx,
+		cancel: cancel,
+		config: option.Config{VIPHealthInterval: time.Millisecond * 10, VIPHealthFailures: 2},
+		vips:   []*VIP{vip},
+		check: func() error {
+			lock.Lock()
+			defer lock.Unlock()
+			if !healthy {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+		network: network,
+		newLock: func(election string) (etcdlock.MasterInterface, error) {
+			if election != "/rainbond/gateway/vips/10.0.0.100" {
+				t.Errorf("unexpected election %s", election)
+			}
+			l := &fakeLock{events: make(chan etcdlock.MasterEvent, 2), stopped: make(chan struct{})}
+			locks <- l
+			return l, nil
+		},
+	}
+	setHealthy := func(h bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		healthy = h
+	}
+	v.Start()
+
+	// elected
+	l := <-locks
+	l.events <- etcdlock.MasterEvent{Type: etcdlock.MasterAdded}
+	waitFor(t, "the vip added", func() bool { return network.has("10.0.0.100/24") })
+
+	// the health checks fail, the vip is released and the election is resigned
+	setHealthy(false)
+	waitFor(t, "the vip deleted", func() bool { return !network.has("10.0.0.100/24") })
+	<-l.stopped
+
+	// healthy again, campaign and then lose the lease
+	setHealthy(true)
+	l = <-locks
+	l.events <- etcdlock.MasterEvent{Type: etcdlock.MasterAdded}
+	waitFor(t, "the vip added", func() bool { return network.has("10.0.0.100/24") })
+	l.events <- etcdlock.MasterEvent{Type: etcdlock.MasterError, Error: errors.New("session expired")}
+	waitFor(t, "the vip deleted", func() bool { return !network.has("10.0.0.100/24") })
+	<-l.stopped
+
+	// the vip is released when the manager is stopped
+	l = <-locks
+	l.events <- etcdlock.MasterEvent{Type: etcdlock.MasterAdded}
+	waitFor(t, "the vip added", func() bool { return network.has("10.0.0.100/24") })
+	v.Stop()
+	if network.has("10.0.0.100/24") {
+		t.Errorf("expected the vip is deleted when the manager is stopped")
+	}
+	<-l.stopped
+}
+
+func TestGratuitousARP(t *testing.T) {
+	mac, _ := net.ParseMAC("02:42:ac:11:00:02")
+	frame := gratuitousARP(mac, net.ParseIP("10.0.0.100"))
+	if len(frame) != 42 {
+		t.Fatalf("expected 42 bytes, but returned %d", len(frame))
+	}
+	if net.HardwareAddr(frame[6:12]).String() != mac.String() || net.HardwareAddr(frame[22:28]).String() != mac.String() {
+		t.Errorf("unexpected sender mac in %x", frame)
+	}
+	if !net.IP(frame[28:32]).Equal(net.ParseIP("10.0.0.100")) || !net.IP(frame[38:42]).Equal(net.ParseIP("10.0.0.100")) {
+		t.Errorf("unexpected sender or target ip in %x", frame)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/eapache/channels"
 	"github.com/goodrain/rainbond/cmd/gateway/option"
 	"github.com/goodrain/rainbond/gateway/controller/openresty"
+	"github.com/goodrain/rainbond/gateway/controller/zeus"
 	"github.com/goodrain/rainbond/gateway/metric"
 	"github.com/goodrain/rainbond/gateway/store"
 	v1 "github.com/goodrain/rainbond/gateway/v1"
@@ -189,7 +190,7 @@ func NewGWController(ctx context.Context, clientset kubernetes.Interface, cfg *o
 		metricCollector: mc,
 	}
 
-	gwc.GWS = openresty.CreateOpenrestyService(cfg, &gwc.isShuttingDown)
+	gwc.GWS = newGWServicer(cfg, &gwc.isShuttingDown)
 
 	gwc.store = store.New(
 		clientset,
@@ -200,6 +201,16 @@ func NewGWController(ctx context.Context, clientset kubernetes.Interface, cfg *o
 	return gwc, nil
 }
 
+//newGWServicer creates the service of the data plane
+func newGWServicer(cfg *option.Config, isShuttingDown *bool) GWServicer {
+	switch cfg.DataPlane {
+	case option.DataPlaneEnvoy:
+		return zeus.CreateZeusService(cfg, isShuttingDown)
+	default:
+		return openresty.CreateOpenrestyService(cfg, isShuttingDown)
+	}
+}
+
 func poolsEqual(a []*v1.Pool, b []*v1.Pool) bool {
 	if len(a) != len(b) {
 		return false
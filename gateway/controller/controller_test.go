@@ -19,27 +19,143 @@
 package controller
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
 	"testing"
-	"time"
 
-	"github.com/coreos/etcd/clientv3"
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/gateway/annotations"
+	"github.com/goodrain/rainbond/gateway/defaults"
+	"github.com/goodrain/rainbond/gateway/metric"
 	v1 "github.com/goodrain/rainbond/gateway/v1"
+	"github.com/goodrain/rainbond/util/ingress-nginx/task"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
-func TestGWController_WatchRbdEndpoints(t *testing.T) {
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{"localhost:2379"},
-		DialTimeout: 10 * time.Second,
-	})
+// fakeStore returns the fixed virtual services and pools
+type fakeStore struct {
+	l7vs, l4vs     []*v1.VirtualService
+	hpools, tpools []*v1.Pool
+}
+
+func (f *fakeStore) ListPool() ([]*v1.Pool, []*v1.Pool) { return f.hpools, f.tpools }
+func (f *fakeStore) ListVirtualService() ([]*v1.VirtualService, []*v1.VirtualService) {
+	return f.l7vs, f.l4vs
+}
+func (f *fakeStore) ListIngresses() []*extensions.Ingress { return nil }
+func (f *fakeStore) GetIngressAnnotations(key string) (*annotations.Ingress, error) {
+	return &annotations.Ingress{}, nil
+}
+func (f *fakeStore) Run(stopCh chan struct{})            {}
+func (f *fakeStore) GetDefaultBackend() defaults.Backend { return defaults.Backend{} }
+
+// recordServicer records the configs persisted by the data plane
+type recordServicer struct {
+	GWServicer
+	persists []*v1.Config
+	errs     []error
+}
+
+func (r *recordServicer) PersistConfig(conf *v1.Config) error {
+	err := r.GWServicer.PersistConfig(conf)
+	r.persists = append(r.persists, conf)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return err
+}
+
+func (r *recordServicer) UpdatePools(hpools []*v1.Pool, tpools []*v1.Pool) error {
+	err := r.GWServicer.UpdatePools(hpools, tpools)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return err
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		l7vs: []*v1.VirtualService{{
+			ServerName: "foo.com",
+			Listening:  []string{"18880"},
+			Locations: []*v1.Location{{
+				Path:          "/",
+				NameCondition: map[string]*v1.Condition{"foo_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}}},
+			}},
+		}},
+		l4vs:   []*v1.VirtualService{{Listening: []string{"0.0.0.0:18881"}, PoolName: "tcp_pool", Protocol: "TCP"}},
+		hpools: []*v1.Pool{{Meta: v1.Meta{Name: "foo_default"}, Nodes: []*v1.Node{{Host: "127.0.0.1", Port: 5000, Weight: 1}}}},
+		tpools: []*v1.Pool{{Meta: v1.Meta{Name: "tcp_pool"}, Nodes: []*v1.Node{{Host: "127.0.0.1", Port: 3306, Weight: 1}}}},
+	}
+}
+
+// startDataPlane starts the data plane which must be running to accept the configs, it returns the stop func
+func startDataPlane(t *testing.T, cfg *option.Config, gws GWServicer) func() {
+	if cfg.DataPlane != option.DataPlaneOpenresty {
+		// envoy gets the configs by xds, they are kept in the snapshot until envoy connects
+		return func() {}
+	}
+	if _, err := exec.LookPath("nginx"); err != nil {
+		t.Skip("openresty is not installed")
+	}
+	confDir, err := ioutil.TempDir("", "gateway")
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Close()
+	os.Setenv("NGINX_CONFIG_TMPL", "../../hack/contrib/docker/gateway/nginxtmp")
+	os.Setenv("NGINX_CUSTOM_CONFIG", confDir)
+	errCh := make(chan error, 1)
+	if err := gws.Start(errCh); err != nil {
+		t.Fatal(err)
+	}
+	gws.WaitPluginReady()
+	return func() {
+		gws.Stop()
+		os.RemoveAll(confDir)
+	}
+}
 
-	gwc := GWController{
-		EtcdCli: cli,
+func TestGWController_SyncGateway(t *testing.T) {
+	for _, dataPlane := range []string{option.DataPlaneOpenresty, option.DataPlaneEnvoy} {
+		t.Run(dataPlane, func(t *testing.T) {
+			cfg := &option.Config{
+				DataPlane:   dataPlane,
+				ListenPorts: option.ListenPorts{HTTP: 18880, HTTPS: 18443, Status: 18890, Stream: 18891, Health: 18892, XDS: 18893},
+			}
+			gwc := &GWController{
+				store:           newFakeStore(),
+				ocfg:            cfg,
+				stopLock:        &sync.Mutex{},
+				stopCh:          make(chan struct{}),
+				syncRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+				metricCollector: metric.NewDummyCollector(),
+			}
+			gws := &recordServicer{GWServicer: newGWServicer(cfg, &gwc.isShuttingDown)}
+			gwc.GWS = gws
+			gwc.syncQueue = task.NewTaskQueue(gwc.syncGateway)
+			defer startDataPlane(t, cfg, gws)()
+
+			if err := gwc.syncGateway(nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(gws.errs) != 0 {
+				t.Fatalf("the data plane rejects the config: %v", gws.errs)
+			}
+			if len(gws.persists) != 1 || gwc.rcfg != gws.persists[0] {
+				t.Fatalf("expected the config to be persisted once, got %d", len(gws.persists))
+			}
+			// the unchanged config is not persisted again
+			if err := gwc.syncGateway(nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(gws.persists) != 1 || len(gws.errs) != 0 {
+				t.Fatalf("expected the unchanged config not to be persisted, got %d, errors: %v", len(gws.persists), gws.errs)
+			}
+		})
 	}
-	go gwc.watchRbdEndpoints(0)
 }
 
 func poolsIsEqual(old []*v1.Pool, new []*v1.Pool) bool {
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/goodrain/rainbond/cmd/gateway/option"
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+)
 
 // HTTP contains data for nginx http configuration
 type HTTP struct {
@@ -23,6 +27,7 @@ type HTTP struct {
 	AccessLogPath        string
 	DisableAccessLog     bool
 	AccessLogFormat      string
+	AccessLogShipper     string // the syslog server which ships the access logs of the http rules
 }
 
 // LogFormat -
@@ -53,6 +58,7 @@ func NewHTTP(conf *option.Config) *HTTP {
 			return conf.AccessLogFormat
 		}(),
 		DisableAccessLog: conf.AccessLogPath == "",
+		AccessLogShipper: AccessLogShipperAddr(conf),
 		KeepaliveTimeout: Time{
 			Num:  30,
 			Unit: "s",
@@ -91,3 +97,12 @@ func NewHTTP(conf *option.Config) *HTTP {
 		},
 	}
 }
+
+// AccessLogShipperAddr returns the syslog address which the access logs are shipped to,
+// it is empty if the access logs are not shipped.
+func AccessLogShipperAddr(conf *option.Config) string {
+	if conf.AccessLogShipTo == "" {
+		return ""
+	}
+	return fmt.Sprintf("127.0.0.1:%d", conf.ListenPorts.AccessLog)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/goodrain/rainbond/gateway/annotations/cors"
 	"github.com/goodrain/rainbond/gateway/annotations/proxy"
+	"github.com/goodrain/rainbond/gateway/annotations/responseheader"
 	"github.com/goodrain/rainbond/gateway/annotations/rewrite"
 	v1 "github.com/goodrain/rainbond/gateway/v1"
 )
@@ -34,6 +36,14 @@ type Server struct {
 	Locations               []*Location
 	OptionValue             map[string]string
 	UpstreamName            string //used for tcp and udp server
+	ACMEChallengePass       string // the acme http-01 challenges are answered by the gateway controller
+	BackendProtocol         string // the protocol to communicate with the upstream, HTTP, HTTPS, GRPC or GRPCS
+	ProxySSLVerify          bool   // enables the verification of the upstream certificate
+
+	// the custom error pages, the keys are the status codes
+	ErrorPages map[int]string
+	// the maintenance page of the locations in maintenance mode
+	Maintenance *v1.Maintenance
 
 	// Sets the number of datagrams expected from the proxied server in response
 	// to the client request if the UDP protocol is used.
@@ -109,6 +119,7 @@ type Location struct {
 	EnableMetrics    bool //Enables or disables monitor
 	DisableAccessLog bool //disable or enables access log
 	AccessLogPath    string
+	AccessLogShipper string // the syslog server which ships the access logs of the http rules
 	ErrorLogPath     string
 	DisableProxyPass bool
 	//PathRewrite if true, path will not passed to the upstream
@@ -119,6 +130,12 @@ type Location struct {
 	// to be used in connections against endpoints
 	// +optional
 	Proxy proxy.Config `json:"proxy,omitempty"`
+	// CORS the cross-origin policy, the preflight requests are answered by the gateway
+	CORS cors.Config `json:"cors,omitempty"`
+	// ResponseHeader the headers added to or removed from the responses
+	ResponseHeader responseheader.Config `json:"responseHeader,omitempty"`
+	// Maintenance the requests are answered with the maintenance page of the server
+	Maintenance bool `json:"maintenance,omitempty"`
 }
 
 //Validation validation nginx parameters
@@ -203,9 +203,13 @@ func (o *OrService) persistUpstreams(pools []*v1.Pool) error {
 func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv []*model.Server) {
 	for _, vs := range conf.L7VS {
 		server := &model.Server{
-			Listen:     strings.Join(vs.Listening, " "),
-			Protocol:   "HTTP",
-			ServerName: strings.Replace(vs.ServerName, "tls", "", 1),
+			Listen:          strings.Join(vs.Listening, " "),
+			Protocol:        "HTTP",
+			ServerName:      strings.Replace(vs.ServerName, "tls", "", 1),
+			BackendProtocol: vs.BackendProtocol,
+			ProxySSLVerify:  vs.ProxySSLVerify,
+			ErrorPages:      vs.ErrorPages,
+			Maintenance:     vs.Maintenance,
 			// ForceSSLRedirect: vs.ForceSSLRedirect,
 			OptionValue: map[string]string{
 				"tenant_id":  vs.Namespace,
@@ -218,12 +222,15 @@ func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv
 			server.SSLCertificateKey = vs.SSLCert.CertificatePem
 			server.EnableSSLStapling = o.ocfg.EnableSSLStapling
 
+		} else {
+			server.ACMEChallengePass = fmt.Sprintf("http://127.0.0.1:%d", o.ocfg.ListenPorts.Health)
 		}
 		for _, loc := range vs.Locations {
 			location := &model.Location{
 				DisableAccessLog: o.ocfg.AccessLogPath == "",
 				// TODO: Distinguish between server output logs
 				AccessLogPath:    o.ocfg.AccessLogPath,
+				AccessLogShipper: model.AccessLogShipperAddr(o.ocfg),
 				EnableMetrics:    true,
 				Path:             loc.Path,
 				NameCondition:    loc.NameCondition,
@@ -231,6 +238,9 @@ func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv
 				Rewrite:          loc.Rewrite,
 				PathRewrite:      false,
 				DisableProxyPass: loc.DisableProxyPass,
+				CORS:             loc.CORS,
+				ResponseHeader:   loc.ResponseHeader,
+				Maintenance:      loc.Maintenance,
 			}
 			server.Locations = append(server.Locations, location)
 		}
@@ -21,10 +21,12 @@ package template
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	text_template "text/template"
 
 	"github.com/golang/glog"
+	"github.com/goodrain/rainbond/gateway/annotations/backendprotocol"
 	"github.com/goodrain/rainbond/gateway/controller/openresty/model"
 	v1 "github.com/goodrain/rainbond/gateway/v1"
 	"github.com/sirupsen/logrus"
@@ -40,10 +42,163 @@ var (
 			return true
 		},
 		"buildLuaHeaderRouter": buildLuaHeaderRouter,
+		"buildProxyPass":       buildProxyPass,
+		"buildCORS":            buildCORS,
+		"buildResponseHeaders": buildResponseHeaders,
 		"isValidByteSize":      isValidByteSize,
 	}
 )
 
+// upstreamTrustedCertificate the ca bundle used to verify the certificates of upstreams
+const upstreamTrustedCertificate = "/etc/ssl/certs/ca-certificates.crt"
+
+// buildProxyPass builds the directives passing the requests of location to the upstream balancer
+// with the backend protocol of server
+func buildProxyPass(s interface{}, l interface{}) string {
+	server, ok := s.(*model.Server)
+	if !ok {
+		glog.Errorf("expected an '*model.Server' type but %T was returned", s)
+		return ""
+	}
+	loc, ok := l.(*model.Location)
+	if !ok {
+		glog.Errorf("expected an '*model.Location' type but %T was returned", l)
+		return ""
+	}
+	var out []string
+	prefix := "proxy"
+	switch server.BackendProtocol {
+	case backendprotocol.GRPC, backendprotocol.GRPCS:
+		prefix = "grpc"
+		out = append(out, fmt.Sprintf("grpc_connect_timeout %ds;", loc.Proxy.ConnectTimeout))
+		out = append(out, fmt.Sprintf("grpc_send_timeout %ds;", loc.Proxy.SendTimeout))
+		out = append(out, fmt.Sprintf("grpc_read_timeout %ds;", loc.Proxy.ReadTimeout))
+		var keys []string
+		for k := range loc.Proxy.SetHeaders {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, fmt.Sprintf("grpc_set_header %s %s;", k, loc.Proxy.SetHeaders[k]))
+		}
+		// grpc_pass does not support uri, so the path is always passed to the upstream
+		out = append(out, fmt.Sprintf("grpc_pass %s://upstream_balancer;", strings.ToLower(server.BackendProtocol)))
+	default:
+		scheme := "http"
+		if server.BackendProtocol == backendprotocol.HTTPS {
+			scheme = "https"
+		}
+		if loc.PathRewrite {
+			out = append(out, fmt.Sprintf("proxy_pass %s://upstream_balancer/;", scheme))
+		} else {
+			out = append(out, fmt.Sprintf("proxy_pass %s://upstream_balancer;", scheme))
+		}
+	}
+	if server.BackendProtocol == backendprotocol.HTTPS || server.BackendProtocol == backendprotocol.GRPCS {
+		out = append(out, fmt.Sprintf("%s_ssl_server_name on;", prefix))
+		out = append(out, fmt.Sprintf("%s_ssl_name $host;", prefix))
+		if server.ProxySSLVerify {
+			out = append(out, fmt.Sprintf("%s_ssl_verify on;", prefix))
+			out = append(out, fmt.Sprintf("%s_ssl_trusted_certificate %s;", prefix, upstreamTrustedCertificate))
+		} else {
+			out = append(out, fmt.Sprintf("%s_ssl_verify off;", prefix))
+		}
+	}
+	return strings.Join(out, "\n\t\t")
+}
+
+// buildCORS builds the directives of the cross-origin policy of location,
+// the preflight requests are answered without being passed to the upstream
+func buildCORS(l interface{}) string {
+	loc, ok := l.(*model.Location)
+	if !ok {
+		glog.Errorf("expected an '*model.Location' type but %T was returned", l)
+		return ""
+	}
+	if !loc.CORS.Enabled {
+		return ""
+	}
+	c := loc.CORS
+	out := []string{"# cross-origin resource sharing"}
+	origin := "$cors_origin"
+	if c.AllowAnyOrigin() {
+		origin = "'*'"
+	} else {
+		var origins []string
+		for _, o := range c.AllowOrigin {
+			origins = append(origins, strings.Replace(regexp.QuoteMeta(o), `\*`, `[A-Za-z0-9\-]+`, -1))
+		}
+		out = append(out, "set $cors_origin '';")
+		out = append(out, fmt.Sprintf("if ($http_origin ~* ^(%s)$ ) {", strings.Join(origins, "|")))
+		out = append(out, "\tset $cors_origin $http_origin;")
+		out = append(out, "}")
+	}
+	headers := []string{fmt.Sprintf("add_header Access-Control-Allow-Origin %s always;", origin)}
+	if origin != "'*'" {
+		headers = append(headers, "add_header Vary Origin always;")
+	}
+	// the credentials are never shared with the wildcard origin
+	if c.AllowCredentials && origin != "'*'" {
+		headers = append(headers, "add_header Access-Control-Allow-Credentials true always;")
+	}
+
+	out = append(out, "if ($request_method = 'OPTIONS') {")
+	for _, h := range headers {
+		out = append(out, "\t"+h)
+	}
+	out = append(out, fmt.Sprintf("\tadd_header Access-Control-Allow-Methods '%s' always;", c.AllowMethods))
+	out = append(out, fmt.Sprintf("\tadd_header Access-Control-Allow-Headers '%s' always;", c.AllowHeaders))
+	out = append(out, fmt.Sprintf("\tadd_header Access-Control-Max-Age %d;", c.MaxAge))
+	out = append(out, "\tadd_header Content-Type 'text/plain charset=UTF-8';")
+	out = append(out, "\tadd_header Content-Length 0;")
+	out = append(out, "\treturn 204;")
+	out = append(out, "}")
+	out = append(out, headers...)
+	if c.ExposeHeaders != "" {
+		out = append(out, fmt.Sprintf("add_header Access-Control-Expose-Headers '%s' always;", c.ExposeHeaders))
+	}
+	return strings.Join(out, "\n\t\t")
+}
+
+// buildResponseHeaders builds the directives setting or removing the response headers of location,
+// the headers set by the gateway replace the ones of the upstream
+func buildResponseHeaders(s interface{}, l interface{}) string {
+	server, ok := s.(*model.Server)
+	if !ok {
+		glog.Errorf("expected an '*model.Server' type but %T was returned", s)
+		return ""
+	}
+	loc, ok := l.(*model.Location)
+	if !ok {
+		glog.Errorf("expected an '*model.Location' type but %T was returned", l)
+		return ""
+	}
+	prefix := "proxy"
+	if strings.HasPrefix(strings.ToUpper(server.BackendProtocol), backendprotocol.GRPC) {
+		prefix = "grpc"
+	}
+	var out []string
+	var keys []string
+	for k := range loc.ResponseHeader.SetHeaders {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, fmt.Sprintf("%s_hide_header %s;", prefix, k))
+		out = append(out, fmt.Sprintf("add_header %s %s always;", k, quote(loc.ResponseHeader.SetHeaders[k])))
+	}
+	for _, k := range loc.ResponseHeader.RemoveHeaders {
+		out = append(out, fmt.Sprintf("%s_hide_header %s;", prefix, k))
+	}
+	return strings.Join(out, "\n\t\t")
+}
+
+// quote quotes the value of nginx directive
+func quote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
+
 func buildLuaHeaderRouter(input interface{}) string {
 	loc, ok := input.(*model.Location)
 	if !ok {
@@ -118,6 +273,25 @@ func buildLuaHeaderRouter(input interface{}) string {
 		}
 	}
 
+	// the rule and the service of the target are recorded in the access logs
+	var rules []string
+	for name, c := range loc.NameCondition {
+		if c.RuleID != "" {
+			rules = append(rules, fmt.Sprintf("\t\t\t\t[\"%s\"] = {\"%s\", \"%s\"},", name, c.RuleID, c.ServiceID))
+		}
+	}
+	if len(rules) > 0 {
+		sort.Strings(rules)
+		out = append(out, "\t\t\tlocal rules = {")
+		out = append(out, rules...)
+		out = append(out, "\t\t\t}",
+			"\t\t\tlocal rule = rules[ngx.var.target]",
+			"\t\t\tif rule then",
+			"\t\t\t\tngx.var.rule_id = rule[1]",
+			"\t\t\t\tngx.var.service_id = rule[2]",
+			"\t\t\tend")
+	}
+
 	out = append(out, "\t\t}")
 
 	return strings.Join(out, "\n\r")
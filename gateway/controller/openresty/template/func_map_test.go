@@ -0,0 +1,100 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/gateway/annotations/cors"
+	"github.com/goodrain/rainbond/gateway/annotations/responseheader"
+	"github.com/goodrain/rainbond/gateway/controller/openresty/model"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+)
+
+func TestBuildCORS(t *testing.T) {
+	testCases := []struct {
+		config   cors.Config
+		contains []string
+	}{
+		{cors.Config{}, nil},
+		{
+			cors.Config{Enabled: true, AllowOrigin: []string{"*"}, AllowMethods: "GET", AllowHeaders: "Content-Type", MaxAge: 600},
+			[]string{"add_header Access-Control-Allow-Origin '*' always;", "add_header Access-Control-Max-Age 600;", "return 204;"},
+		},
+		{
+			cors.Config{Enabled: true, AllowOrigin: []string{"https://foo.com", "https://*.bar.com"}, AllowCredentials: true},
+			[]string{`if ($http_origin ~* ^(https://foo\.com|https://[A-Za-z0-9\-]+\.bar\.com)$ ) {`, "add_header Access-Control-Allow-Origin $cors_origin always;", "add_header Access-Control-Allow-Credentials true always;"},
+		},
+	}
+	for _, tc := range testCases {
+		out := buildCORS(&model.Location{CORS: tc.config})
+		if tc.contains == nil && out != "" {
+			t.Errorf("expected nothing when cors is disabled, but returned %s", out)
+		}
+		if tc.config.AllowAnyOrigin() && strings.Contains(out, "$http_origin") {
+			t.Errorf("expected the origin of request not to be echoed for any origin, but returned %s", out)
+		}
+		for _, c := range tc.contains {
+			if !strings.Contains(out, c) {
+				t.Errorf("expected %s in %s", c, out)
+			}
+		}
+	}
+}
+
+func TestBuildResponseHeaders(t *testing.T) {
+	loc := &model.Location{ResponseHeader: responseheader.Config{
+		SetHeaders:    map[string]string{"Content-Security-Policy": `default-src 'self'; script-src "x"`},
+		RemoveHeaders: []string{"Server"},
+	}}
+	out := buildResponseHeaders(&model.Server{}, loc)
+	for _, c := range []string{
+		"proxy_hide_header Content-Security-Policy;",
+		`add_header Content-Security-Policy "default-src 'self'; script-src \"x\"" always;`,
+		"proxy_hide_header Server;",
+	} {
+		if !strings.Contains(out, c) {
+			t.Errorf("expected %s in %s", c, out)
+		}
+	}
+	out = buildResponseHeaders(&model.Server{BackendProtocol: "GRPC"}, loc)
+	if !strings.Contains(out, "grpc_hide_header Server;") {
+		t.Errorf("expected grpc_hide_header in %s", out)
+	}
+}
+
+func TestBuildLuaHeaderRouterRuleID(t *testing.T) {
+	loc := &model.Location{NameCondition: map[string]*v1.Condition{
+		"foo": {Type: v1.DefaultType, RuleID: "rule-foo", ServiceID: "service-foo"},
+		"bar": {Type: v1.DefaultType},
+	}}
+	out := buildLuaHeaderRouter(loc)
+	for _, c := range []string{`["foo"] = {"rule-foo", "service-foo"},`, "local rule = rules[ngx.var.target]", "ngx.var.rule_id = rule[1]"} {
+		if !strings.Contains(out, c) {
+			t.Errorf("expected %s in %s", c, out)
+		}
+	}
+	if strings.Contains(out, `["bar"]`) {
+		t.Errorf("expected no rule of bar in %s", out)
+	}
+	out = buildLuaHeaderRouter(&model.Location{NameCondition: map[string]*v1.Condition{"bar": {Type: v1.DefaultType}}})
+	if strings.Contains(out, "ngx.var.rule_id") {
+		t.Errorf("expected no rule id in %s", out)
+	}
+}
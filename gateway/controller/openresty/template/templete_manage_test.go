@@ -0,0 +1,68 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goodrain/rainbond/gateway/controller/openresty/model"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+)
+
+func TestServersTemplateErrorPages(t *testing.T) {
+	tmpl, err := NewTemplate("../../../../hack/contrib/docker/gateway/nginxtmp/servers.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &model.Server{
+		Listen:     "80",
+		ServerName: "www.example.com",
+		ErrorPages: map[int]string{
+			502: "/run/nginx/conf/error-pages/foo-pages/502.html",
+		},
+		Maintenance: &v1.Maintenance{
+			RetryAfter: 300,
+			Page:       "/run/nginx/conf/error-pages/maintenance.html",
+		},
+		Locations: []*model.Location{
+			{Path: "/", Maintenance: true, DisableProxyPass: true},
+			{Path: "/api", DisableProxyPass: true},
+		},
+	}
+	var buf bytes.Buffer
+	if err := tmpl.tmpl.Execute(&buf, NginxServerContext{Servers: []*model.Server{server}}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, c := range []string{
+		"error_page 502 /rbd-error-pages/502.html;",
+		"alias /run/nginx/conf/error-pages/foo-pages/502.html;",
+		"add_header Retry-After 300 always;",
+		"alias /run/nginx/conf/error-pages/maintenance.html;",
+	} {
+		if !strings.Contains(out, c) {
+			t.Errorf("expected %s in %s", c, out)
+		}
+	}
+	// only the location in maintenance mode returns 503
+	if n := strings.Count(out, "return 503;"); n != 1 {
+		t.Errorf("expected 1 location in maintenance mode, but returned %d", n)
+	}
+}
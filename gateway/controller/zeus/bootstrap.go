@@ -0,0 +1,86 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package zeus
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+)
+
+//the listeners and clusters are discovered from the xds server of the gateway,
+//the admin api is used to check whether envoy is ready.
+var bootstrapTemplate = template.Must(template.New("bootstrap").Parse(`node:
+  id: {{ .NodeID }}
+  cluster: {{ .NodeID }}
+
+# the v2 xds api is disabled by default since envoy 1.16
+layered_runtime:
+  layers:
+  - name: static_layer
+    static_layer:
+      envoy.reloadable_features.enable_deprecated_v2_api: true
+
+admin:
+  access_log_path: /dev/null
+  address:
+    socket_address: { address: 127.0.0.1, port_value: {{ .AdminPort }} }
+
+dynamic_resources:
+  lds_config:
+    api_config_source:
+      api_type: GRPC
+      grpc_services:
+        envoy_grpc:
+          cluster_name: rainbond_xds_cluster
+  cds_config:
+    api_config_source:
+      api_type: GRPC
+      grpc_services:
+        envoy_grpc:
+          cluster_name: rainbond_xds_cluster
+
+static_resources:
+  clusters:
+  - name: rainbond_xds_cluster
+    connect_timeout: 0.25s
+    type: STATIC
+    lb_policy: ROUND_ROBIN
+    http2_protocol_options: {}
+    load_assignment:
+      cluster_name: rainbond_xds_cluster
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: 127.0.0.1
+                port_value: {{ .XDSPort }}
+`))
+
+//bootstrap returns the envoy bootstrap config of the gateway
+func bootstrap(conf *option.Config) string {
+	var buf bytes.Buffer
+	bootstrapTemplate.Execute(&buf, map[string]interface{}{
+		"NodeID":    NodeID,
+		"AdminPort": conf.ListenPorts.Status,
+		"XDSPort":   conf.ListenPorts.XDS,
+	})
+	return buf.String()
+}
@@ -0,0 +1,577 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package zeus
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sirupsen/logrus"
+
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/gateway/acme"
+	"github.com/goodrain/rainbond/gateway/annotations/backendprotocol"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+)
+
+const (
+	//ACMEChallengeCluster the cluster of the gateway controller which answers the acme http-01 challenges
+	ACMEChallengeCluster = "rbd_acme_challenge"
+	//defaultServerName the server name of the virtual service which matches all hosts
+	defaultServerName = "_"
+	//upstreamTrustedCA verifies the certificates of the https and grpcs services
+	upstreamTrustedCA = "/etc/ssl/certs/ca-certificates.crt"
+	//defaultConnectTimeout the connect timeout of the clusters, in seconds
+	defaultConnectTimeout = 5
+)
+
+//resources the xds resources generated from the gateway config
+type resources struct {
+	listeners []types.Resource
+	clusters  []types.Resource
+	endpoints []types.Resource
+}
+
+//Equal returns true if the resources are not changed
+func (r *resources) Equal(c *resources) bool {
+	return resourcesEqual(r.listeners, c.listeners) &&
+		resourcesEqual(r.clusters, c.clusters) &&
+		resourcesEqual(r.endpoints, c.endpoints)
+}
+
+func resourcesEqual(a, b []types.Resource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//upstream describes how envoy connects to the nodes of a pool
+type upstream struct {
+	protocol       string
+	backend        string
+	sslVerify      bool
+	connectTimeout int
+}
+
+//buildResources converts the virtual services and pools to envoy resources
+func buildResources(conf *option.Config, cfg *v1.Config, pools []*v1.Pool) *resources {
+	res := &resources{}
+	upstreams := make(map[string]*upstream)
+	if cfg != nil {
+		res.listeners = append(res.listeners, buildL7Listeners(conf, cfg.L7VS, upstreams)...)
+		res.listeners = append(res.listeners, buildL4Listeners(cfg.L4VS, upstreams)...)
+	}
+	// the clusters referenced by routes must exist, or envoy rejects the listener
+	poolMap := make(map[string]*v1.Pool, len(pools))
+	for _, pool := range pools {
+		poolMap[pool.Name] = pool
+	}
+	for name := range upstreams {
+		if _, ok := poolMap[name]; !ok {
+			poolMap[name] = &v1.Pool{Meta: v1.Meta{Name: name}}
+		}
+	}
+	var names []string
+	for name := range poolMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		up := upstreams[name]
+		if up == nil {
+			up = &upstream{protocol: "tcp"}
+		}
+		if cluster := buildCluster(name, up); cluster != nil {
+			res.clusters = append(res.clusters, cluster)
+			res.endpoints = append(res.endpoints, buildLoadAssignment(poolMap[name]))
+		}
+	}
+	if cluster := buildACMEChallengeCluster(conf); cluster != nil {
+		res.clusters = append(res.clusters, cluster)
+	}
+	return res
+}
+
+//buildL7Listeners creates a http or https listener for each port of the virtual services
+func buildL7Listeners(conf *option.Config, services []*v1.VirtualService, upstreams map[string]*upstream) (listeners []types.Resource) {
+	ports := make(map[uint32][]*v1.VirtualService)
+	secure := make(map[uint32]bool)
+	for _, vs := range services {
+		if len(vs.Listening) == 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(vs.Listening[0], 10, 32)
+		if err != nil {
+			logrus.Warningf("virtual service %s listening %v is invalid", vs.ServerName, vs.Listening)
+			continue
+		}
+		ports[uint32(port)] = append(ports[uint32(port)], vs)
+		for _, opt := range vs.Listening[1:] {
+			if opt == "ssl" {
+				secure[uint32(port)] = true
+			}
+		}
+	}
+	var keys []int
+	for port := range ports {
+		keys = append(keys, int(port))
+	}
+	sort.Ints(keys)
+	for _, key := range keys {
+		port := uint32(key)
+		var listener *apiv2.Listener
+		if secure[port] {
+			listener = buildHTTPSListener(port, ports[port], upstreams)
+		} else {
+			listener = buildHTTPListener(conf, port, ports[port], upstreams)
+		}
+		if listener != nil {
+			listeners = append(listeners, listener)
+		}
+	}
+	return
+}
+
+func buildHTTPListener(conf *option.Config, port uint32, services []*v1.VirtualService, upstreams map[string]*upstream) *apiv2.Listener {
+	name := fmt.Sprintf("http_%d", port)
+	var vhosts []*route.VirtualHost
+	// the domains of the virtual hosts in a route config must be unique
+	vhostMap := make(map[string]*route.VirtualHost)
+	for _, vs := range services {
+		domain := serverDomain(vs.ServerName)
+		vhost := vhostMap[domain]
+		if vhost == nil {
+			vhost = &route.VirtualHost{Name: domain, Domains: []string{domain}}
+			// the http-01 challenges of the domain are answered by the gateway controller
+			if conf.ListenPorts.Health != 0 {
+				vhost.Routes = append(vhost.Routes, &route.Route{
+					Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: acme.HTTP01ChallengePath}},
+					Action: &route.Route_Route{Route: &route.RouteAction{
+						ClusterSpecifier: &route.RouteAction_Cluster{Cluster: ACMEChallengeCluster},
+					}},
+				})
+			}
+			vhostMap[domain] = vhost
+			vhosts = append(vhosts, vhost)
+		}
+		vhost.Routes = append(vhost.Routes, buildRoutes(vs, upstreams)...)
+	}
+	return envoyv2.CreateHTTPListener(name, "0.0.0.0", name, port, nil, nil, vhosts...)
+}
+
+func buildHTTPSListener(port uint32, services []*v1.VirtualService, upstreams map[string]*upstream) *apiv2.Listener {
+	name := fmt.Sprintf("https_%d", port)
+	listener := &apiv2.Listener{
+		Name:    name,
+		Address: envoyv2.CreateSocketAddress("tcp", "0.0.0.0", port),
+		ListenerFilters: []*envoy_api_v2_listener.ListenerFilter{
+			&envoy_api_v2_listener.ListenerFilter{Name: envoyv2.TLSInspectorListenerFilter},
+		},
+	}
+	// a filter chain serves the certificate of each server name
+	chains := make(map[string]*envoy_api_v2_listener.FilterChain)
+	vhosts := make(map[string][]*route.Route)
+	var domains []string
+	for _, vs := range services {
+		if vs.SSLCert == nil || vs.SSLCert.CertificatePem == "" {
+			logrus.Warningf("virtual service %s has no certificate, ignore it", vs.ServerName)
+			continue
+		}
+		domain := serverDomain(vs.ServerName)
+		if _, ok := chains[domain]; !ok {
+			chain := &envoy_api_v2_listener.FilterChain{
+				TlsContext: createDownstreamTLSContext(vs),
+			}
+			if domain != "*" {
+				chain.FilterChainMatch = &envoy_api_v2_listener.FilterChainMatch{ServerNames: []string{domain}}
+			}
+			chains[domain] = chain
+			domains = append(domains, domain)
+		}
+		vhosts[domain] = append(vhosts[domain], buildRoutes(vs, upstreams)...)
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+	for _, domain := range domains {
+		vhost := &route.VirtualHost{Name: domain, Domains: []string{domain}, Routes: vhosts[domain]}
+		hcm := envoyv2.CreateHTTPConnectionManager(fmt.Sprintf("%s_%s", name, domain), name, nil, nil, vhost)
+		if hcm == nil {
+			continue
+		}
+		chain := chains[domain]
+		chain.Filters = []*envoy_api_v2_listener.Filter{
+			&envoy_api_v2_listener.Filter{
+				Name:       wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{TypedConfig: envoyv2.Message2Any(hcm)},
+			},
+		}
+		listener.FilterChains = append(listener.FilterChains, chain)
+	}
+	if err := listener.Validate(); err != nil {
+		logrus.Errorf("validate listener config failure %s", err.Error())
+		return nil
+	}
+	return listener
+}
+
+func createDownstreamTLSContext(vs *v1.VirtualService) *auth.DownstreamTlsContext {
+	// the certificate pem file contains both the certificate and the private key
+	pem := &core.DataSource{Specifier: &core.DataSource_Filename{Filename: vs.SSLCert.CertificatePem}}
+	return &auth.DownstreamTlsContext{
+		CommonTlsContext: &auth.CommonTlsContext{
+			TlsParams:       createTLSParameters(vs.SSlProtocols),
+			TlsCertificates: []*auth.TlsCertificate{&auth.TlsCertificate{CertificateChain: pem, PrivateKey: pem}},
+			AlpnProtocols:   []string{"h2", "http/1.1"},
+		},
+	}
+}
+
+//createTLSParameters converts the nginx ssl protocols, eg: TLSv1.2 TLSv1.3
+func createTLSParameters(protocols string) *auth.TlsParameters {
+	versions := map[string]auth.TlsParameters_TlsProtocol{
+		"TLSv1":   auth.TlsParameters_TLSv1_0,
+		"TLSv1.1": auth.TlsParameters_TLSv1_1,
+		"TLSv1.2": auth.TlsParameters_TLSv1_2,
+		"TLSv1.3": auth.TlsParameters_TLSv1_3,
+	}
+	var params *auth.TlsParameters
+	for _, protocol := range strings.Fields(protocols) {
+		version, ok := versions[protocol]
+		if !ok {
+			continue
+		}
+		if params == nil {
+			params = &auth.TlsParameters{TlsMinimumProtocolVersion: version, TlsMaximumProtocolVersion: version}
+			continue
+		}
+		if version < params.TlsMinimumProtocolVersion {
+			params.TlsMinimumProtocolVersion = version
+		}
+		if version > params.TlsMaximumProtocolVersion {
+			params.TlsMaximumProtocolVersion = version
+		}
+	}
+	return params
+}
+
+//serverDomain returns the domain of the virtual service, the https virtual services are prefixed with tls
+func serverDomain(serverName string) string {
+	domain := strings.Replace(serverName, "tls", "", 1)
+	if domain == "" || domain == defaultServerName {
+		return "*"
+	}
+	return domain
+}
+
+//buildRoutes creates the routes of the locations, the longer paths take precedence like nginx.
+//In a location the header condition is matched first, then the cookie condition, then the default.
+func buildRoutes(vs *v1.VirtualService, upstreams map[string]*upstream) (routes []*route.Route) {
+	locations := make([]*v1.Location, len(vs.Locations))
+	copy(locations, vs.Locations)
+	sort.SliceStable(locations, func(i, j int) bool {
+		return len(locations[i].Path) > len(locations[j].Path)
+	})
+	for _, loc := range locations {
+		if loc.Maintenance && vs.Maintenance != nil {
+			routes = append(routes, buildMaintenanceRoute(loc, vs.Maintenance))
+			continue
+		}
+		if loc.DisableProxyPass {
+			if r := buildRedirectRoute(loc); r != nil {
+				routes = append(routes, r)
+			}
+			continue
+		}
+		priority := map[v1.ConditionType]int{v1.HeaderType: 0, v1.CookieType: 1, v1.DefaultType: 2}
+		var names []string
+		for name := range loc.NameCondition {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			pi, pj := priority[loc.NameCondition[names[i]].Type], priority[loc.NameCondition[names[j]].Type]
+			if pi != pj {
+				return pi < pj
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			upstreams[name] = &upstream{
+				protocol:       "tcp",
+				backend:        vs.BackendProtocol,
+				sslVerify:      vs.ProxySSLVerify,
+				connectTimeout: loc.Proxy.ConnectTimeout,
+			}
+			r := &route.Route{
+				Match: &route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: loc.Path},
+					Headers:       createHeaderMatchers(loc.NameCondition[name]),
+				},
+				Action: &route.Route_Route{Route: createRouteAction(name, loc)},
+			}
+			r.RequestHeadersToAdd = createRequestHeaders(loc.Proxy.SetHeaders)
+			routes = append(routes, r)
+		}
+	}
+	return
+}
+
+//buildRedirectRoute redirects the requests to https, other rewrites are not supported yet
+func buildRedirectRoute(loc *v1.Location) *route.Route {
+	for _, rewrite := range loc.Rewrite.Rewrites {
+		if !strings.HasPrefix(rewrite.Replacement, "https://") {
+			continue
+		}
+		code := route.RedirectAction_FOUND
+		if rewrite.Flag == "permanent" {
+			code = route.RedirectAction_MOVED_PERMANENTLY
+		}
+		return &route.Route{
+			Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: loc.Path}},
+			Action: &route.Route_Redirect{Redirect: &route.RedirectAction{
+				SchemeRewriteSpecifier: &route.RedirectAction_HttpsRedirect{HttpsRedirect: true},
+				ResponseCode:           code,
+			}},
+		}
+	}
+	logrus.Warningf("location %s does not proxy the requests, but no supported redirect", loc.Path)
+	return nil
+}
+
+//buildMaintenanceRoute answers the requests with the maintenance page,
+//envoy limits the size of the page to 4KB. The custom error pages are not supported yet.
+func buildMaintenanceRoute(loc *v1.Location, maintenance *v1.Maintenance) *route.Route {
+	return &route.Route{
+		Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: loc.Path}},
+		Action: &route.Route_DirectResponse{DirectResponse: &route.DirectResponseAction{
+			Status: 503,
+			Body:   &core.DataSource{Specifier: &core.DataSource_Filename{Filename: maintenance.Page}},
+		}},
+		ResponseHeadersToAdd: []*core.HeaderValueOption{
+			{Header: &core.HeaderValue{Key: "Content-Type", Value: "text/html"}, Append: &wrappers.BoolValue{Value: false}},
+			{Header: &core.HeaderValue{Key: "Retry-After", Value: strconv.Itoa(maintenance.RetryAfter)}, Append: &wrappers.BoolValue{Value: false}},
+		},
+	}
+}
+
+func createHeaderMatchers(condition *v1.Condition) (headers []*route.HeaderMatcher) {
+	if condition == nil {
+		return nil
+	}
+	var keys []string
+	for key := range condition.Value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := condition.Value[key]
+		switch condition.Type {
+		case v1.HeaderType:
+			headers = append(headers, &route.HeaderMatcher{
+				Name:                 key,
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: value},
+			})
+		case v1.CookieType:
+			// the cookie header looks like: a=1; b=2
+			regex := fmt.Sprintf("(^|.*;\\s*)%s=%s(;.*|$)", regexp.QuoteMeta(key), regexp.QuoteMeta(value))
+			headers = append(headers, &route.HeaderMatcher{
+				Name: "cookie",
+				HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: &matcher.RegexMatcher{
+					EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+					Regex:      regex,
+				}},
+			})
+		}
+	}
+	return
+}
+
+func createRouteAction(clusterName string, loc *v1.Location) *route.RouteAction {
+	action := &route.RouteAction{
+		ClusterSpecifier: &route.RouteAction_Cluster{Cluster: clusterName},
+	}
+	// nginx times out if the upstream does not respond in read timeout
+	if loc.Proxy.ReadTimeout > 0 {
+		action.Timeout = envoyv2.ConverTimeDuration(int64(loc.Proxy.ReadTimeout))
+	}
+	return action
+}
+
+//createRequestHeaders sets the request headers of the location, the nginx variables are not supported by envoy
+func createRequestHeaders(setHeaders map[string]string) (headers []*core.HeaderValueOption) {
+	var keys []string
+	for key, value := range setHeaders {
+		if strings.Contains(value, "$") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		headers = append(headers, &core.HeaderValueOption{
+			Header: &core.HeaderValue{Key: key, Value: setHeaders[key]},
+			Append: &wrappers.BoolValue{Value: false},
+		})
+	}
+	return
+}
+
+//buildL4Listeners creates a tcp or udp listener for each l4 virtual service
+func buildL4Listeners(services []*v1.VirtualService, upstreams map[string]*upstream) (listeners []types.Resource) {
+	for _, vs := range services {
+		if len(vs.Listening) == 0 {
+			continue
+		}
+		// the listening looks like: 0.0.0.0:10000 or 0.0.0.0:10000 udp
+		fields := strings.Fields(vs.Listening[0])
+		host, portStr, err := net.SplitHostPort(fields[0])
+		if err != nil {
+			logrus.Warningf("virtual service %s listening %v is invalid", vs.PoolName, vs.Listening)
+			continue
+		}
+		port, err := strconv.ParseUint(portStr, 10, 32)
+		if err != nil {
+			logrus.Warningf("virtual service %s listening %v is invalid", vs.PoolName, vs.Listening)
+			continue
+		}
+		protocol := "tcp"
+		if strings.EqualFold(string(vs.Protocol), string(v1.ProtocolUDP)) || (len(fields) > 1 && fields[1] == "udp") {
+			protocol = "udp"
+		}
+		name := fmt.Sprintf("%s_%s_%d", protocol, host, port)
+		upstreams[vs.PoolName] = &upstream{protocol: protocol, connectTimeout: vs.ConnectTimeout}
+		var listener *apiv2.Listener
+		if protocol == "udp" {
+			listener = envoyv2.CreateUDPListener(name, vs.PoolName, host, name, uint32(port))
+		} else {
+			listener = envoyv2.CreateTCPListener(name, vs.PoolName, host, name, uint32(port), int64(vs.Timeout))
+		}
+		if listener != nil {
+			listeners = append(listeners, listener)
+		}
+	}
+	return
+}
+
+//buildCluster creates the eds cluster of a pool
+func buildCluster(name string, up *upstream) *apiv2.Cluster {
+	timeout := up.connectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	options := envoyv2.ClusterOptions{
+		Name:              name,
+		ServiceName:       name,
+		ClusterType:       apiv2.Cluster_EDS,
+		ConnectionTimeout: envoyv2.ConverTimeDuration(int64(timeout)),
+	}
+	backend := strings.ToUpper(up.backend)
+	if backend == backendprotocol.HTTPS || backend == backendprotocol.GRPCS {
+		options.TLSContext = createUpstreamTLSContext(backend, up.sslVerify)
+	}
+	cluster := envoyv2.CreateCluster(options)
+	if cluster == nil {
+		return nil
+	}
+	if backend == backendprotocol.GRPC || backend == backendprotocol.GRPCS {
+		cluster.Http2ProtocolOptions = &core.Http2ProtocolOptions{}
+	}
+	return cluster
+}
+
+func createUpstreamTLSContext(backend string, verify bool) *auth.UpstreamTlsContext {
+	tlsContext := &auth.UpstreamTlsContext{CommonTlsContext: &auth.CommonTlsContext{}}
+	if backend == backendprotocol.GRPCS {
+		tlsContext.CommonTlsContext.AlpnProtocols = []string{"h2"}
+	}
+	if verify {
+		tlsContext.CommonTlsContext.ValidationContextType = &auth.CommonTlsContext_ValidationContext{
+			ValidationContext: &auth.CertificateValidationContext{
+				TrustedCa: &core.DataSource{Specifier: &core.DataSource_Filename{Filename: upstreamTrustedCA}},
+			},
+		}
+	}
+	return tlsContext
+}
+
+//buildLoadAssignment creates the endpoints of a pool, the weight of the node is the weight of the endpoint
+func buildLoadAssignment(pool *v1.Pool) *apiv2.ClusterLoadAssignment {
+	var lbe []*endpoint.LbEndpoint
+	for _, node := range pool.Nodes {
+		weight := node.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		lbe = append(lbe, &endpoint.LbEndpoint{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+				Endpoint: &endpoint.Endpoint{
+					Address: envoyv2.CreateSocketAddress("tcp", node.Host, uint32(node.Port)),
+				},
+			},
+			LoadBalancingWeight: envoyv2.ConversionUInt32(uint32(weight)),
+		})
+	}
+	cla := &apiv2.ClusterLoadAssignment{ClusterName: pool.Name}
+	if len(lbe) > 0 {
+		cla.Endpoints = []*endpoint.LocalityLbEndpoints{&endpoint.LocalityLbEndpoints{LbEndpoints: lbe}}
+	}
+	return cla
+}
+
+func buildACMEChallengeCluster(conf *option.Config) *apiv2.Cluster {
+	if conf.ListenPorts.Health == 0 {
+		return nil
+	}
+	return envoyv2.CreateCluster(envoyv2.ClusterOptions{
+		Name:              ACMEChallengeCluster,
+		ClusterType:       apiv2.Cluster_STATIC,
+		ConnectionTimeout: envoyv2.ConverTimeDuration(defaultConnectTimeout),
+		LoadAssignment: &apiv2.ClusterLoadAssignment{
+			ClusterName: ACMEChallengeCluster,
+			Endpoints: []*endpoint.LocalityLbEndpoints{&endpoint.LocalityLbEndpoints{
+				LbEndpoints: []*endpoint.LbEndpoint{&endpoint.LbEndpoint{
+					HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+						Address: envoyv2.CreateSocketAddress("tcp", "127.0.0.1", uint32(conf.ListenPorts.Health)),
+					}},
+				}},
+			}},
+		},
+	})
+}
@@ -15,5 +15,214 @@
 
 // You should have received a copy of the GNU General Public License
 // along with this program. If not, see <http://www.gnu.org/licenses/>.
-
 package zeus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/server/v2"
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+//NodeID the envoy node id of the gateway
+const NodeID = "rbd-gateway"
+
+//DefaultBootstrapPath the path of the envoy bootstrap config
+var DefaultBootstrapPath = "/run/rbd-gateway/envoy.yaml"
+
+//envoyRestartDelay the delay before restarting the exited envoy
+const envoyRestartDelay = time.Second
+
+// ZeusService programs envoy over xds, it implements controller.GWServicer
+type ZeusService struct {
+	IsShuttingDown *bool
+	ocfg           *option.Config
+	bootstrapPath  string
+
+	// lock protects the running config and pools, PersistConfig and UpdatePools
+	// are called by the sync queue, but the snapshot must not go backwards.
+	lock    sync.Mutex
+	config  *v1.Config
+	hpools  []*v1.Pool
+	tpools  []*v1.Pool
+	version int64
+	current *resources
+
+	cache        cache.SnapshotCache
+	server       server.Server
+	grpcServer   *grpc.Server
+	ctx          context.Context
+	cancel       context.CancelFunc
+	envoyProcess *os.Process
+}
+
+//CreateZeusService create envoy service
+func CreateZeusService(config *option.Config, isShuttingDown *bool) *ZeusService {
+	ctx, cancel := context.WithCancel(context.Background())
+	configcache := cache.NewSnapshotCache(false, cache.IDHash{}, logrus.WithField("module", "gateway-xds"))
+	return &ZeusService{
+		IsShuttingDown: isShuttingDown,
+		ocfg:           config,
+		bootstrapPath:  DefaultBootstrapPath,
+		cache:          configcache,
+		server:         server.NewServer(ctx, configcache, nil),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Start starts the xds server and envoy
+func (z *ZeusService) Start(errCh chan error) error {
+	logrus.Infof("envoy data plane starting")
+	if err := writeBootstrap(z.bootstrapPath, z.ocfg); err != nil {
+		logrus.Errorf("create envoy bootstrap config failure %s", err.Error())
+		return err
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", z.ocfg.ListenPorts.XDS))
+	if err != nil {
+		return err
+	}
+	z.grpcServer = grpc.NewServer()
+	discovery.RegisterAggregatedDiscoveryServiceServer(z.grpcServer, z.server)
+	v2.RegisterEndpointDiscoveryServiceServer(z.grpcServer, z.server)
+	v2.RegisterClusterDiscoveryServiceServer(z.grpcServer, z.server)
+	v2.RegisterListenerDiscoveryServiceServer(z.grpcServer, z.server)
+	go func() {
+		logrus.Infof("gateway xds server listening %s", lis.Addr().String())
+		if err := z.grpcServer.Serve(lis); err != nil {
+			errCh <- err
+		}
+	}()
+	// envoy restarts with the last snapshot if it exits, the gateway keeps running meanwhile
+	go func() {
+		for {
+			if *z.IsShuttingDown {
+				return
+			}
+			logrus.Infof("start envoy progress")
+			cmd := exec.Command(z.ocfg.EnvoyBin, "-c", z.bootstrapPath)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Start(); err != nil {
+				logrus.Errorf("envoy start error: %v", err)
+				errCh <- err
+				return
+			}
+			z.envoyProcess = cmd.Process
+			err := cmd.Wait()
+			if *z.IsShuttingDown {
+				return
+			}
+			logrus.Errorf("envoy exited: %v, restart it after %s", err, envoyRestartDelay)
+			time.Sleep(envoyRestartDelay)
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully stops envoy and the xds server
+func (z *ZeusService) Stop() error {
+	logrus.Info("Stopping envoy process")
+	if z.envoyProcess != nil {
+		if err := z.envoyProcess.Signal(syscall.SIGTERM); err != nil {
+			return err
+		}
+	}
+	if z.grpcServer != nil {
+		z.grpcServer.Stop()
+	}
+	z.cancel()
+	return nil
+}
+
+// Check checks envoy is ready by the admin api
+func (z *ZeusService) Check() error {
+	client := &http.Client{
+		Timeout:   z.ocfg.HealthCheckTimeout * time.Second,
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/ready", z.ocfg.ListenPorts.Status)
+	res, err := client.Get(url)
+	if err != nil {
+		logrus.Errorf("error checking %s: %v", url, err)
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("envoy is not ready")
+	}
+	return nil
+}
+
+// PersistConfig updates the listeners of envoy
+func (z *ZeusService) PersistConfig(conf *v1.Config) error {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+	z.config = conf
+	return z.setSnapshot()
+}
+
+// UpdatePools updates the clusters and endpoints of envoy
+func (z *ZeusService) UpdatePools(hpools []*v1.Pool, tpools []*v1.Pool) error {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+	logrus.Debugf("start update pools(tcp pools count %d, http pool count %d)", len(tpools), len(hpools))
+	z.hpools, z.tpools = hpools, tpools
+	return z.setSnapshot()
+}
+
+func (z *ZeusService) setSnapshot() error {
+	pools := append(append([]*v1.Pool{}, z.hpools...), z.tpools...)
+	res := buildResources(z.ocfg, z.config, pools)
+	if z.current != nil && z.current.Equal(res) {
+		logrus.Debug("No need to update envoy resources.")
+		return nil
+	}
+	z.version++
+	snapshot := cache.NewSnapshot(strconv.FormatInt(z.version, 10), res.endpoints, res.clusters, nil, res.listeners, nil)
+	if err := snapshot.Consistent(); err != nil {
+		return fmt.Errorf("envoy resources are inconsistent: %v", err)
+	}
+	if err := z.cache.SetSnapshot(NodeID, snapshot); err != nil {
+		return err
+	}
+	z.current = res
+	logrus.Debugf("update envoy resources success, version: %d", z.version)
+	return nil
+}
+
+// WaitPluginReady waits for envoy to be ready.
+func (z *ZeusService) WaitPluginReady() {
+	for {
+		if err := z.Check(); err == nil {
+			logrus.Info("Envoy is ready")
+			break
+		}
+		logrus.Infof("Envoy is not ready yet")
+		time.Sleep(1 * time.Second)
+	}
+}
+
+func writeBootstrap(file string, conf *option.Config) error {
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, []byte(bootstrap(conf)), 0644)
+}
@@ -0,0 +1,258 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package zeus
+
+import (
+	"testing"
+
+	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http_connection_manager "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/gateway/annotations/rewrite"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+)
+
+func newFakeConfig() *option.Config {
+	return &option.Config{
+		ListenPorts: option.ListenPorts{HTTP: 80, HTTPS: 443, Status: 18080, Health: 10254, XDS: 18082},
+	}
+}
+
+func newFakeGatewayConfig() (*v1.Config, []*v1.Pool) {
+	loc := &v1.Location{
+		Path: "/",
+		NameCondition: map[string]*v1.Condition{
+			"foo_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}},
+			"foo_header":  {Type: v1.HeaderType, Value: map[string]string{"x-version": "v2"}},
+			"foo_cookie":  {Type: v1.CookieType, Value: map[string]string{"canary": "1"}},
+		},
+	}
+	api := &v1.Location{
+		Path:          "/api",
+		NameCondition: map[string]*v1.Condition{"api_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}}},
+	}
+	cfg := &v1.Config{
+		L7VS: []*v1.VirtualService{
+			{ServerName: "foo.com", Listening: []string{"80"}, Locations: []*v1.Location{loc, api}},
+			{
+				ServerName:      "tlsbar.com",
+				Listening:       []string{"443", "ssl", "http2"},
+				BackendProtocol: "GRPC",
+				SSlProtocols:    "TLSv1.2 TLSv1.3",
+				SSLCert:         &v1.SSLCert{Meta: &v1.Meta{Name: "bar"}, CertificatePem: "/run/ssl/bar.pem"},
+				Locations: []*v1.Location{{
+					Path:          "/",
+					NameCondition: map[string]*v1.Condition{"bar_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}}},
+				}},
+			},
+		},
+		L4VS: []*v1.VirtualService{
+			{Listening: []string{"0.0.0.0:10000"}, PoolName: "tcp_pool", Protocol: "TCP"},
+			{Listening: []string{"0.0.0.0:10001 udp"}, PoolName: "udp_pool", Protocol: "UDP"},
+		},
+	}
+	pools := []*v1.Pool{
+		{Meta: v1.Meta{Name: "foo_default"}, Nodes: []*v1.Node{
+			{Host: "10.0.0.1", Port: 5000, Weight: 80},
+			{Host: "10.0.0.2", Port: 5000, Weight: 20},
+		}},
+		{Meta: v1.Meta{Name: "foo_header"}, Nodes: []*v1.Node{{Host: "10.0.0.3", Port: 5000}}},
+		{Meta: v1.Meta{Name: "bar_default"}, Nodes: []*v1.Node{{Host: "10.0.0.4", Port: 5000}}},
+		{Meta: v1.Meta{Name: "tcp_pool"}, Nodes: []*v1.Node{{Host: "10.0.0.5", Port: 3306}}},
+	}
+	return cfg, pools
+}
+
+func listenerMap(resources []types.Resource) map[string]*apiv2.Listener {
+	listeners := make(map[string]*apiv2.Listener)
+	for _, res := range resources {
+		listener := res.(*apiv2.Listener)
+		listeners[listener.Name] = listener
+	}
+	return listeners
+}
+
+func routeConfig(t *testing.T, listener *apiv2.Listener, chain int) *apiv2.RouteConfiguration {
+	var hcm http_connection_manager.HttpConnectionManager
+	if err := ptypes.UnmarshalAny(listener.FilterChains[chain].Filters[0].GetTypedConfig(), &hcm); err != nil {
+		t.Fatal(err)
+	}
+	return hcm.GetRouteConfig()
+}
+
+func TestBuildResources(t *testing.T) {
+	cfg, pools := newFakeGatewayConfig()
+	res := buildResources(newFakeConfig(), cfg, pools)
+
+	listeners := listenerMap(res.listeners)
+	for _, name := range []string{"http_80", "https_443", "tcp_0.0.0.0_10000", "udp_0.0.0.0_10001"} {
+		if listeners[name] == nil {
+			t.Fatalf("listener %s is not found, listeners: %v", name, listeners)
+		}
+	}
+
+	// the longer path first, then header > cookie > default
+	vhosts := routeConfig(t, listeners["http_80"], 0).VirtualHosts
+	if len(vhosts) != 1 || vhosts[0].Domains[0] != "foo.com" {
+		t.Fatalf("unexpected virtual hosts %v", vhosts)
+	}
+	var clusters []string
+	for _, r := range vhosts[0].Routes {
+		clusters = append(clusters, r.GetRoute().GetCluster())
+	}
+	want := []string{ACMEChallengeCluster, "api_default", "foo_header", "foo_cookie", "foo_default"}
+	if len(clusters) != len(want) {
+		t.Fatalf("want routes to %v, got %v", want, clusters)
+	}
+	for i := range want {
+		if clusters[i] != want[i] {
+			t.Fatalf("want routes to %v, got %v", want, clusters)
+		}
+	}
+	if vhosts[0].Routes[2].Match.Headers[0].GetExactMatch() != "v2" {
+		t.Errorf("the header condition is not matched exactly")
+	}
+	if vhosts[0].Routes[3].Match.Headers[0].Name != "cookie" {
+		t.Errorf("the cookie condition is not matched by the cookie header")
+	}
+
+	// the certificate is served by server name
+	chain := listeners["https_443"].FilterChains[0]
+	if chain.FilterChainMatch.ServerNames[0] != "bar.com" {
+		t.Errorf("want server name bar.com, got %v", chain.FilterChainMatch.ServerNames)
+	}
+	if chain.TlsContext.CommonTlsContext.TlsCertificates[0].CertificateChain.GetFilename() != "/run/ssl/bar.pem" {
+		t.Errorf("the certificate of bar.com is not served")
+	}
+
+	clusterMap := make(map[string]*apiv2.Cluster)
+	for _, res := range res.clusters {
+		cluster := res.(*apiv2.Cluster)
+		clusterMap[cluster.Name] = cluster
+	}
+	// the referenced pools without nodes must exist
+	for _, name := range []string{"foo_default", "foo_header", "foo_cookie", "api_default", "bar_default", "tcp_pool", "udp_pool"} {
+		if clusterMap[name] == nil {
+			t.Fatalf("cluster %s is not found", name)
+		}
+	}
+	if clusterMap["bar_default"].Http2ProtocolOptions == nil {
+		t.Errorf("the grpc cluster should use http2")
+	}
+	if clusterMap["foo_default"].Http2ProtocolOptions != nil {
+		t.Errorf("the http cluster should not use http2")
+	}
+
+	for _, res := range res.endpoints {
+		cla := res.(*apiv2.ClusterLoadAssignment)
+		if cla.ClusterName != "foo_default" {
+			continue
+		}
+		lbe := cla.Endpoints[0].LbEndpoints
+		if lbe[0].LoadBalancingWeight.GetValue() != 80 || lbe[1].LoadBalancingWeight.GetValue() != 20 {
+			t.Errorf("the weight of the nodes is not respected")
+		}
+	}
+}
+
+func TestBuildRedirectRoute(t *testing.T) {
+	cfg := &v1.Config{L7VS: []*v1.VirtualService{{
+		ServerName: "foo.com",
+		Listening:  []string{"80"},
+		Locations: []*v1.Location{{
+			Path:             "/",
+			DisableProxyPass: true,
+			Rewrite: rewrite.Config{Rewrites: []*rewrite.Rewrite{
+				{Regex: "^", Replacement: "https://$http_host$request_uri?", Flag: "permanent"},
+			}},
+		}},
+	}}}
+	res := buildResources(newFakeConfig(), cfg, nil)
+	routes := routeConfig(t, listenerMap(res.listeners)["http_80"], 0).VirtualHosts[0].Routes
+	redirect := routes[len(routes)-1].GetRedirect()
+	if redirect == nil || !redirect.GetHttpsRedirect() || redirect.ResponseCode != route.RedirectAction_MOVED_PERMANENTLY {
+		t.Errorf("want a permanent https redirect, got %v", routes[len(routes)-1])
+	}
+}
+
+func TestBuildMaintenanceRoute(t *testing.T) {
+	cfg := &v1.Config{L7VS: []*v1.VirtualService{{
+		ServerName:  "foo.com",
+		Listening:   []string{"80"},
+		Maintenance: &v1.Maintenance{RetryAfter: 300, Page: "/run/nginx/conf/error-pages/maintenance.html"},
+		Locations: []*v1.Location{{
+			Path:        "/",
+			Maintenance: true,
+		}},
+	}}}
+	res := buildResources(newFakeConfig(), cfg, nil)
+	routes := routeConfig(t, listenerMap(res.listeners)["http_80"], 0).VirtualHosts[0].Routes
+	last := routes[len(routes)-1]
+	direct := last.GetDirectResponse()
+	if direct == nil || direct.Status != 503 || direct.Body.GetFilename() != "/run/nginx/conf/error-pages/maintenance.html" {
+		t.Fatalf("want a maintenance response, got %v", last)
+	}
+	if h := last.ResponseHeadersToAdd[1].Header; h.Key != "Retry-After" || h.Value != "300" {
+		t.Errorf("want Retry-After: 300, got %v", h)
+	}
+}
+
+func TestZeusServiceSnapshot(t *testing.T) {
+	isShuttingDown := false
+	z := CreateZeusService(newFakeConfig(), &isShuttingDown)
+	cfg, pools := newFakeGatewayConfig()
+	if err := z.UpdatePools(pools, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.PersistConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := z.cache.GetSnapshot(NodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.Consistent(); err != nil {
+		t.Fatal(err)
+	}
+	version := snapshot.GetVersion(resource.ListenerType)
+	if len(snapshot.GetResources(resource.ListenerType)) != 4 {
+		t.Errorf("want 4 listeners, got %d", len(snapshot.GetResources(resource.ListenerType)))
+	}
+
+	// nothing is changed
+	if err := z.UpdatePools(pools, nil); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ = z.cache.GetSnapshot(NodeID)
+	if snapshot.GetVersion(resource.ListenerType) != version {
+		t.Errorf("the snapshot should not be updated if nothing is changed")
+	}
+
+	pools[0].Nodes = pools[0].Nodes[:1]
+	if err := z.UpdatePools(pools, nil); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ = z.cache.GetSnapshot(NodeID)
+	if snapshot.GetVersion(resource.ListenerType) == version {
+		t.Errorf("the snapshot should be updated if the nodes are changed")
+	}
+}
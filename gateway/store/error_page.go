@@ -0,0 +1,146 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/goodrain/rainbond/gateway/annotations"
+	ik8s "github.com/goodrain/rainbond/util/ingress-nginx/k8s"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// ErrorPagePath is the default path of the custom error pages
+const ErrorPagePath = "/run/nginx/conf/error-pages"
+
+// the status codes which support custom error pages
+var errorPageCodes = []int{404, 502, 503, 504}
+
+// maintenanceKey is the key of the maintenance page in the configmap
+const maintenanceKey = "maintenance.html"
+
+const defaultMaintenancePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Under maintenance</title>
+</head>
+<body>
+<h1>Under maintenance</h1>
+<p>The service is temporarily unavailable for maintenance, please try again later.</p>
+</body>
+</html>
+`
+
+// errorPagesReferenced checks if the configmap is referenced by the error pages of any ingress
+func (s *k8sStore) errorPagesReferenced(cm *corev1.ConfigMap) bool {
+	for _, item := range s.listers.IngressAnnotation.List() {
+		anns := item.(*annotations.Ingress)
+		if anns.Namespace == cm.Namespace && anns.ErrorPage.ConfigMap == cm.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// servesErrorPages checks if the http ingress answers the requests with its error pages
+// or maintenance page, so it is kept even if there is no ready endpoint
+func (s *k8sStore) servesErrorPages(ing *extensions.Ingress) bool {
+	if ing.Spec.Backend != nil {
+		return false
+	}
+	anns, err := s.GetIngressAnnotations(ik8s.MetaNamespaceKey(ing))
+	if err != nil {
+		return false
+	}
+	return anns.ErrorPage.ConfigMap != "" || anns.ErrorPage.Maintenance
+}
+
+// syncErrorPages writes the error pages in the configmap to the file system,
+// and returns the files of the error pages, the keys are the status codes.
+func (s *k8sStore) syncErrorPages(namespace, name string) map[int]string {
+	cm, err := s.listers.ConfigMap.ByKey(fmt.Sprintf("%s/%s", namespace, name))
+	if err != nil {
+		logrus.Warningf("error pages %s/%s: %v", namespace, name, err)
+		return nil
+	}
+	pages := make(map[int]string)
+	for _, code := range errorPageCodes {
+		content, ok := cm.Data[strconv.Itoa(code)+".html"]
+		if !ok {
+			continue
+		}
+		filename := errorPageFile(namespace, name, strconv.Itoa(code)+".html")
+		if err := writeFileIfChanged(filename, []byte(content)); err != nil {
+			logrus.Errorf("write error page %s: %v", filename, err)
+			continue
+		}
+		pages[code] = filename
+	}
+	if len(pages) == 0 {
+		return nil
+	}
+	return pages
+}
+
+// maintenancePage returns the file of the maintenance page in the configmap,
+// the default maintenance page is used if the configmap does not contain one.
+func (s *k8sStore) maintenancePage(namespace, name string) string {
+	if name != "" {
+		cm, err := s.listers.ConfigMap.ByKey(fmt.Sprintf("%s/%s", namespace, name))
+		if err == nil {
+			if content, ok := cm.Data[maintenanceKey]; ok {
+				filename := errorPageFile(namespace, name, maintenanceKey)
+				if err := writeFileIfChanged(filename, []byte(content)); err == nil {
+					return filename
+				}
+				logrus.Errorf("write maintenance page %s: %v", filename, err)
+			}
+		}
+	}
+	filename := path.Join(ErrorPagePath, maintenanceKey)
+	if err := writeFileIfChanged(filename, []byte(defaultMaintenancePage)); err != nil {
+		logrus.Errorf("write maintenance page %s: %v", filename, err)
+	}
+	return filename
+}
+
+func errorPageFile(namespace, name, key string) string {
+	return path.Join(ErrorPagePath, strings.Join([]string{namespace, name}, "-"), key)
+}
+
+// writeFileIfChanged writes data to the file only if the content is changed,
+// so that the file is not rewritten on every synchronization.
+func writeFileIfChanged(filename string, data []byte) error {
+	if old, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(path.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
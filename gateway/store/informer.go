@@ -28,10 +28,11 @@ import (
 
 // Informer defines the required SharedIndexInformers that interact with the API server.
 type Informer struct {
-	Ingress  cache.SharedIndexInformer
-	Service  cache.SharedIndexInformer
-	Endpoint cache.SharedIndexInformer
-	Secret   cache.SharedIndexInformer
+	Ingress   cache.SharedIndexInformer
+	Service   cache.SharedIndexInformer
+	Endpoint  cache.SharedIndexInformer
+	Secret    cache.SharedIndexInformer
+	ConfigMap cache.SharedIndexInformer
 }
 
 // Run initiates the synchronization of the informers against the API server.
@@ -39,6 +40,7 @@ func (i *Informer) Run(stopCh chan struct{}) {
 	go i.Endpoint.Run(stopCh)
 	go i.Service.Run(stopCh)
 	go i.Secret.Run(stopCh)
+	go i.ConfigMap.Run(stopCh)
 
 	// wait for all involved caches to be synced before processing items
 	// from the queue
@@ -46,6 +48,7 @@ func (i *Informer) Run(stopCh chan struct{}) {
 		i.Endpoint.HasSynced,
 		i.Service.HasSynced,
 		i.Secret.HasSynced,
+		i.ConfigMap.HasSynced,
 	) {
 		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 	}
@@ -36,7 +36,9 @@ import (
 	"github.com/eapache/channels"
 	"github.com/goodrain/rainbond/cmd/gateway/option"
 	"github.com/goodrain/rainbond/gateway/annotations"
+	"github.com/goodrain/rainbond/gateway/annotations/backendprotocol"
 	"github.com/goodrain/rainbond/gateway/annotations/l4"
+	"github.com/goodrain/rainbond/gateway/annotations/parser"
 	"github.com/goodrain/rainbond/gateway/annotations/rewrite"
 	"github.com/goodrain/rainbond/gateway/controller/config"
 	"github.com/goodrain/rainbond/gateway/defaults"
@@ -51,6 +53,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -119,6 +122,7 @@ type Lister struct {
 	Service           istroe.ServiceLister
 	Endpoint          istroe.EndpointLister
 	Secret            istroe.SecretLister
+	ConfigMap         istroe.ConfigMapLister
 	IngressAnnotation IngressAnnotationsLister
 }
 
@@ -189,6 +193,15 @@ func New(client kubernetes.Interface,
 	store.informers.Secret = store.sharedInformer.Core().V1().Secrets().Informer()
 	store.listers.Secret.Store = store.informers.Secret.GetStore()
 
+	// only the error pages created by rainbond are watched, not all the configmaps in the cluster,
+	// so the other configmaps of a tenant can not be served as error pages
+	store.informers.ConfigMap = coreinformers.NewFilteredConfigMapInformer(client, corev1.NamespaceAll, conf.ResyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = "creator=Rainbond,error_pages=true"
+		})
+	store.listers.ConfigMap.Store = store.informers.ConfigMap.GetStore()
+
 	ingEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			ing := obj.(*extensions.Ingress)
@@ -309,6 +322,51 @@ func New(client kubernetes.Interface,
 		},
 	}
 
+	// the configmaps contain the custom error pages
+	cmEventHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			cm := obj.(*corev1.ConfigMap)
+			if store.errorPagesReferenced(cm) {
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			ocm := old.(*corev1.ConfigMap)
+			ccm := cur.(*corev1.ConfigMap)
+			if ccm.ResourceVersion != ocm.ResourceVersion && !reflect.DeepEqual(ccm.Data, ocm.Data) &&
+				store.errorPagesReferenced(ccm) {
+				updateCh.In() <- Event{
+					Type: UpdateEvent,
+					Obj:  cur,
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					logrus.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				cm, ok = tombstone.Obj.(*corev1.ConfigMap)
+				if !ok {
+					logrus.Errorf("Tombstone contained object that is not a ConfigMap: %#v", obj)
+					return
+				}
+			}
+			if store.errorPagesReferenced(cm) {
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+		},
+	}
+
 	// Endpoint Event Handler
 	epEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -338,6 +396,7 @@ func New(client kubernetes.Interface,
 	store.informers.Ingress.AddEventHandler(ingEventHandler)
 	store.informers.Secret.AddEventHandler(secEventHandler)
 	store.informers.Endpoint.AddEventHandler(epEventHandler)
+	store.informers.ConfigMap.AddEventHandler(cmEventHandler)
 	store.informers.Service.AddEventHandler(cache.ResourceEventHandlerFuncs{})
 
 	return store
@@ -458,6 +517,14 @@ func (s *k8sStore) ListPool() ([]*v1.Pool, []*v1.Pool) {
 }
 
 // ListVirtualService list l7 virtual service and l4 virtual service
+//backendProtocolOf returns the backend protocol, the empty one is HTTP
+func backendProtocolOf(protocol string) string {
+	if protocol == "" {
+		return backendprotocol.HTTP
+	}
+	return protocol
+}
+
 func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.VirtualService) {
 	l7PoolBackendMap = make(map[string][]backend)
 	l4PoolBackendMap = make(map[string][]backend)
@@ -550,6 +617,13 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 					hostSSLMap[DefVirSrvName] = sslCert
 				}
 			}
+			// grpc needs http2, which is only negotiated with alpn on the ssl listener,
+			// the plain listener is shared by all http virtual services and can not speak h2c.
+			if anns.BackendProtocol.IsGRPC() && len(hostSSLMap) == 0 {
+				logrus.Warningf("ingress %s (Namespace:%s) with backend protocol %s requires tls, it will be ignored",
+					ing.Name, ing.Namespace, anns.BackendProtocol.Protocol)
+				continue
+			}
 
 			for _, rule := range ing.Spec.Rules {
 				var vs *v1.VirtualService
@@ -577,8 +651,19 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 
 					vs.Namespace = ing.Namespace
 					vs.ServiceID = anns.Labels["service_id"]
+					// the first ingress backend protocol takes effect, the conflicting rules are discarded
+					vs.BackendProtocol = anns.BackendProtocol.Protocol
+					vs.ProxySSLVerify = anns.BackendProtocol.SSLVerify
+					// the first ingress error pages take effect
+					if anns.ErrorPage.ConfigMap != "" {
+						vs.ErrorPages = s.syncErrorPages(ing.Namespace, anns.ErrorPage.ConfigMap)
+					}
 					if len(hostSSLMap) != 0 {
 						vs.Listening = []string{strconv.Itoa(s.conf.ListenPorts.HTTPS), "ssl"}
+						if anns.BackendProtocol.IsGRPC() {
+							// grpc clients negotiate http2 with alpn
+							vs.Listening = append(vs.Listening, "http2")
+						}
 						if hostSSLMap[virSrvName] != nil {
 							vs.SSLCert = hostSSLMap[virSrvName]
 						} else { // TODO: if there is necessary to provide a default virtual service name
@@ -588,6 +673,11 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 
 					l7vsMap[virSrvName] = vs
 					l7vs = append(l7vs, vs)
+				} else if backendProtocolOf(vs.BackendProtocol) != backendProtocolOf(anns.BackendProtocol.Protocol) {
+					// the backend protocol applies to the whole virtual service, the conflicting rule is discarded
+					logrus.Warningf("ingress %s (Namespace:%s) backend protocol %s conflicts with %s of host %s, the rule will be ignored",
+						ing.Name, ing.Namespace, backendProtocolOf(anns.BackendProtocol.Protocol), backendProtocolOf(vs.BackendProtocol), virSrvName)
+					continue
 				}
 
 				for _, path := range rule.IngressRuleValue.HTTP.Paths {
@@ -604,9 +694,21 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 						vs.Locations = append(vs.Locations, location)
 						// the first ingress proxy takes effect
 						location.Proxy = anns.Proxy
+						location.CORS = anns.CORS
+						location.ResponseHeader = anns.ResponseHeader
+						location.Maintenance = anns.ErrorPage.Maintenance
+						if location.Maintenance && vs.Maintenance == nil {
+							vs.Maintenance = &v1.Maintenance{
+								RetryAfter: anns.ErrorPage.RetryAfter,
+								Page:       s.maintenancePage(ing.Namespace, anns.ErrorPage.ConfigMap),
+							}
+						}
 					}
 					// If their ServiceName is the same, then the new one will overwrite the old one.
-					nameCondition := &v1.Condition{}
+					nameCondition := &v1.Condition{
+						RuleID:    ing.Name,
+						ServiceID: anns.Labels["service_id"],
+					}
 					var backendName string
 					if anns.Header.Header != nil {
 						nameCondition.Type = v1.HeaderType
@@ -697,9 +799,36 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 			// endregion
 		}
 	}
+	l7vs = append(l7vs, s.listACMEVirtualService(l7vsMap)...)
 	return l7vs, l4vs
 }
 
+// listACMEVirtualService returns the http virtual services of the acme domains which are only served by https,
+// so that the http-01 challenges of the renewal are answered even if the certificate is bound.
+func (s *k8sStore) listACMEVirtualService(l7vsMap map[string]*v1.VirtualService) (l7vs []*v1.VirtualService) {
+	for _, item := range s.listers.Ingress.List() {
+		ing := item.(*extensions.Ingress)
+		if ing.Annotations[parser.GetAnnotationWithPrefix("acme")] != "true" || len(ing.Spec.TLS) == 0 {
+			continue
+		}
+		for _, rule := range ing.Spec.Rules {
+			virSrvName := strings.TrimSpace(rule.Host)
+			if virSrvName == "" || l7vsMap[virSrvName] != nil {
+				continue
+			}
+			vs := &v1.VirtualService{
+				Listening:  []string{strconv.Itoa(s.conf.ListenPorts.HTTP)},
+				ServerName: virSrvName,
+				Locations:  []*v1.Location{},
+			}
+			vs.Namespace = ing.Namespace
+			l7vsMap[virSrvName] = vs
+			l7vs = append(l7vs, vs)
+		}
+	}
+	return l7vs
+}
+
 // ingressIsValid checks if the specified ingress is valid
 func (s *k8sStore) ingressIsValid(ing *extensions.Ingress) bool {
 	var endpointKey string
@@ -723,20 +852,16 @@ func (s *k8sStore) ingressIsValid(ing *extensions.Ingress) bool {
 	}
 	if !exists {
 		logrus.Debugf("Endpoint %s does not exist.", endpointKey)
-		return false
+		return s.servesErrorPages(ing)
 	}
 	endpoint, ok := item.(*corev1.Endpoints)
 	if !ok {
 		logrus.Errorf("Cant not convert %v to %v", reflect.TypeOf(item), reflect.TypeOf(endpoint))
 		return false
 	}
-	if len(endpoint.Subsets) == 0 {
+	if len(endpoint.Subsets) == 0 || !hasReadyAddresses(endpoint) {
 		logrus.Debugf("Endpoints(%s) is empty, ignore it", endpointKey)
-		return false
-	}
-	if !hasReadyAddresses(endpoint) {
-		logrus.Debugf("Endpoints(%s) is empty, ignore it", endpointKey)
-		return false
+		return s.servesErrorPages(ing)
 	}
 
 	return true
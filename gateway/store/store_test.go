@@ -5,10 +5,16 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/goodrain/rainbond/cmd/gateway/option"
+	"github.com/goodrain/rainbond/gateway/annotations"
+	"github.com/goodrain/rainbond/gateway/annotations/errorpage"
 	"github.com/goodrain/rainbond/gateway/annotations/parser"
+	v1 "github.com/goodrain/rainbond/gateway/v1"
+	istroe "github.com/goodrain/rainbond/util/ingress-nginx/ingress/controller/store"
 	api "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestRbdStore_checkIngress(t *testing.T) {
@@ -68,3 +74,52 @@ func buildIngress() *extensions.Ingress {
 		},
 	}
 }
+
+func TestListACMEVirtualService(t *testing.T) {
+	ing := buildIngress()
+	ing.SetAnnotations(map[string]string{parser.GetAnnotationWithPrefix("acme"): "true"})
+	ing.Spec.TLS = []extensions.IngressTLS{{Hosts: []string{"a.example.com", "b.example.com"}}}
+	ing.Spec.Rules = []extensions.IngressRule{{Host: "a.example.com"}, {Host: "b.example.com"}}
+	s := k8sStore{
+		conf:    &option.Config{ListenPorts: option.ListenPorts{HTTP: 80}},
+		listers: &Lister{Ingress: istroe.IngressLister{Store: cache.NewStore(cache.MetaNamespaceKeyFunc)}},
+	}
+	s.listers.Ingress.Add(ing)
+	// the http virtual service of b.example.com exists for httptohttps
+	l7vsMap := map[string]*v1.VirtualService{"b.example.com": {}}
+	l7vs := s.listACMEVirtualService(l7vsMap)
+	if len(l7vs) != 1 || l7vs[0].ServerName != "a.example.com" || l7vs[0].Listening[0] != "80" || l7vs[0].SSLCert != nil {
+		t.Errorf("unexpected acme virtual services %+v", l7vs)
+	}
+}
+
+func TestIngressIsValidWithErrorPages(t *testing.T) {
+	ing := buildIngress()
+	ing.Spec.Rules = []extensions.IngressRule{{
+		Host: "a.example.com",
+		IngressRuleValue: extensions.IngressRuleValue{HTTP: &extensions.HTTPIngressRuleValue{
+			Paths: []extensions.HTTPIngressPath{{Path: "/", Backend: extensions.IngressBackend{ServiceName: "foo"}}},
+		}},
+	}}
+	testCases := []struct {
+		errorPage errorpage.Config
+		expected  bool
+	}{
+		{errorpage.Config{}, false},
+		{errorpage.Config{ConfigMap: "pages"}, true},
+		{errorpage.Config{Maintenance: true}, true},
+	}
+	for _, tc := range testCases {
+		s := k8sStore{listers: &Lister{
+			Endpoint:          istroe.EndpointLister{Store: cache.NewStore(cache.MetaNamespaceKeyFunc)},
+			IngressAnnotation: IngressAnnotationsLister{Store: cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)},
+		}}
+		anns := &annotations.Ingress{ObjectMeta: ing.ObjectMeta, ErrorPage: tc.errorPage}
+		s.listers.IngressAnnotation.Add(anns)
+		// the endpoints of foo have no ready address
+		s.listers.Endpoint.Add(&api.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: ing.Namespace}})
+		if valid := s.ingressIsValid(ing); valid != tc.expected {
+			t.Errorf("expected %v for error page %+v, but returned %v", tc.expected, tc.errorPage, valid)
+		}
+	}
+}
@@ -19,7 +19,9 @@
 package v1
 
 import (
+	"github.com/goodrain/rainbond/gateway/annotations/cors"
 	"github.com/goodrain/rainbond/gateway/annotations/proxy"
+	"github.com/goodrain/rainbond/gateway/annotations/responseheader"
 	"github.com/goodrain/rainbond/gateway/annotations/rewrite"
 )
 
@@ -47,12 +49,24 @@ type Location struct {
 	// +optional
 	Proxy            proxy.Config `json:"proxy,omitempty"`
 	DisableProxyPass bool
+	// CORS describes the cross-origin policy, the preflight requests are answered by the gateway
+	// +optional
+	CORS cors.Config `json:"cors,omitempty"`
+	// ResponseHeader describes the headers added to or removed from the responses
+	// +optional
+	ResponseHeader responseheader.Config `json:"responseHeader,omitempty"`
+	// Maintenance the requests are answered with the maintenance page of the virtual service
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
 }
 
 // Condition is the condition that the traffic can reach the specified backend
 type Condition struct {
 	Type  ConditionType
 	Value map[string]string
+	// the http rule and the service which the backend comes from, they are recorded in the access logs
+	RuleID    string
+	ServiceID string
 }
 
 // Equals determines if two locations are equal
@@ -79,6 +93,15 @@ func (l *Location) Equals(c *Location) bool {
 	if !l.Proxy.Equal(&c.Proxy) {
 		return false
 	}
+	if !l.CORS.Equal(&c.CORS) {
+		return false
+	}
+	if !l.ResponseHeader.Equal(&c.ResponseHeader) {
+		return false
+	}
+	if l.Maintenance != c.Maintenance {
+		return false
+	}
 
 	return true
 }
@@ -94,6 +117,9 @@ func (c *Condition) Equals(cc *Condition) bool {
 	if c.Type != cc.Type {
 		return false
 	}
+	if c.RuleID != cc.RuleID || c.ServiceID != cc.ServiceID {
+		return false
+	}
 
 	if len(c.Value) != len(cc.Value) {
 		return false
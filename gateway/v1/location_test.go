@@ -24,15 +24,15 @@ func TestLocation_Equals(t *testing.T) {
 	l := newFakeLocation()
 	l.NameCondition = map[string]*Condition{
 		"foo-a": {
-			HeaderType,
-			map[string]string{
+			Type: HeaderType,
+			Value: map[string]string{
 				"k1": "v1",
 				"k2": "v2",
 			},
 		},
 		"foo-b": {
-			CookieType,
-			map[string]string{
+			Type: CookieType,
+			Value: map[string]string{
 				"k1": "v1",
 				"k2": "v2",
 			},
@@ -41,15 +41,15 @@ func TestLocation_Equals(t *testing.T) {
 	c := newFakeLocation()
 	c.NameCondition = map[string]*Condition{
 		"foo-a": {
-			HeaderType,
-			map[string]string{
+			Type: HeaderType,
+			Value: map[string]string{
 				"k1": "v1",
 				"k2": "v2",
 			},
 		},
 		"foo-b": {
-			CookieType,
-			map[string]string{
+			Type: CookieType,
+			Value: map[string]string{
 				"k1": "v1",
 				"k2": "v2",
 			},
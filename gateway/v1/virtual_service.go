@@ -39,6 +39,7 @@ type VirtualService struct {
 	Protocol corev1.Protocol `json:"protocol"`
 	// BackendProtocol indicates which protocol should be used to communicate with the service
 	BackendProtocol        string   `json:"backend-protocol"`
+	ProxySSLVerify         bool     `json:"proxy-ssl-verify"` // verify the certificate of the service, it works with HTTPS and GRPCS
 	Port                   int32    `json:"port"`
 	Listening              []string `json:"listening"` //if Listening is nil,will listen all
 	Note                   string   `json:"note"`
@@ -60,6 +61,29 @@ type VirtualService struct {
 	Locations        []*Location            `json:"locations"`
 	ForceSSLRedirect bool                   `json:"force_ssl_redirect"`
 	ExtensionConfig  map[string]interface{} `json:"extension_config"`
+	// ErrorPages the custom error pages, the keys are the status codes and the values are the html files
+	ErrorPages map[int]string `json:"error_pages"`
+	// Maintenance if it is not nil, all requests are answered with the maintenance page
+	Maintenance *Maintenance `json:"maintenance"`
+}
+
+//Maintenance the maintenance mode of a virtual service
+type Maintenance struct {
+	// RetryAfter the seconds of the Retry-After header
+	RetryAfter int `json:"retry_after"`
+	// Page the html file of the maintenance page
+	Page string `json:"page"`
+}
+
+//Equals equals maintenance
+func (m *Maintenance) Equals(c *Maintenance) bool {
+	if m == c {
+		return true
+	}
+	if m == nil || c == nil {
+		return false
+	}
+	return m.RetryAfter == c.RetryAfter && m.Page == c.Page
 }
 
 //Equals equals vs
@@ -82,6 +106,9 @@ func (v *VirtualService) Equals(c *VirtualService) bool {
 	if v.BackendProtocol != c.BackendProtocol {
 		return false
 	}
+	if v.ProxySSLVerify != c.ProxySSLVerify {
+		return false
+	}
 	if v.Port != c.Port {
 		return false
 	}
@@ -186,6 +213,17 @@ func (v *VirtualService) Equals(c *VirtualService) bool {
 			return false
 		}
 	}
+	if len(v.ErrorPages) != len(c.ErrorPages) {
+		return false
+	}
+	for code, page := range v.ErrorPages {
+		if cp, ok := c.ErrorPages[code]; !ok || cp != page {
+			return false
+		}
+	}
+	if !v.Maintenance.Equals(c.Maintenance) {
+		return false
+	}
 
 	return true
 }
@@ -38,6 +38,23 @@ func TestVirtualService_Equals(t *testing.T) {
 	if !v.Equals(c) {
 		t.Errorf("v should equal c")
 	}
+
+	v.ErrorPages = map[int]string{502: "/run/nginx/conf/error-pages/foo/502.html"}
+	if v.Equals(c) {
+		t.Errorf("v should not equal c")
+	}
+	c.ErrorPages = map[int]string{502: "/run/nginx/conf/error-pages/foo/502.html"}
+	if !v.Equals(c) {
+		t.Errorf("v should equal c")
+	}
+	v.Maintenance = &Maintenance{RetryAfter: 300}
+	if v.Equals(c) {
+		t.Errorf("v should not equal c")
+	}
+	c.Maintenance = &Maintenance{RetryAfter: 300}
+	if !v.Equals(c) {
+		t.Errorf("v should equal c")
+	}
 }
 
 func newFakeVirtualService() *VirtualService {
@@ -33,6 +33,7 @@ import (
 
 	"github.com/goodrain/rainbond/api/util"
 	"github.com/goodrain/rainbond/grctl/clients"
+	"github.com/goodrain/rainbond/node/api/model"
 	"github.com/goodrain/rainbond/node/nodem/client"
 	coreutil "github.com/goodrain/rainbond/util"
 	"github.com/goodrain/rainbond/util/termtables"
@@ -372,6 +373,63 @@ func NewCmdNode() cli.Command {
 					return nil
 				},
 			},
+			{
+				Name:  "drain",
+				Usage: "Mark node as unschedulable and evict the pods safely, the stateful components are evicted last",
+				Flags: []cli.Flag{
+					cli.IntFlag{
+						Name:  "timeout",
+						Usage: "the seconds waiting for each pod to be evicted and replaced",
+						Value: 600,
+					},
+					cli.BoolFlag{
+						Name:  "detach,d",
+						Usage: "do not wait for the drain to complete",
+					},
+					cli.BoolFlag{
+						Name:  "status",
+						Usage: "show the progress of the drain",
+					},
+					cli.BoolFlag{
+						Name:  "cancel",
+						Usage: "cancel the drain in progress",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					Common(c)
+					id := c.Args().First()
+					if id == "" {
+						logrus.Errorf("need hostID")
+						return nil
+					}
+					if c.Bool("cancel") {
+						err := clients.RegionClient.Nodes().CancelDrain(id)
+						handleErr(err)
+						fmt.Printf("cancel draining node %s success\n", id)
+						return nil
+					}
+					if c.Bool("status") {
+						status, err := clients.RegionClient.Nodes().DrainStatus(id)
+						handleErr(err)
+						showDrainStatus(status)
+						return nil
+					}
+					node, err := clients.RegionClient.Nodes().Get(id)
+					handleErr(err)
+					if !node.Role.HasRule("compute") {
+						logrus.Errorf("管理节点不支持此功能")
+						return nil
+					}
+					status, err := clients.RegionClient.Nodes().Drain(id, c.Int("timeout"))
+					handleErr(err)
+					if c.Bool("detach") {
+						fmt.Printf("start draining node %s, %d pods to be evicted\n", id, len(status.Pods))
+						return nil
+					}
+					followDrain(id, status)
+					return nil
+				},
+			},
 			{
 				Name:  "delete",
 				Usage: "delete hostID",
@@ -734,3 +792,40 @@ func listNodeLabelsCommand(c *cli.Context) error {
 	fmt.Print(labelTable.Render())
 	return nil
 }
+
+func showDrainStatus(status *model.DrainStatus) {
+	fmt.Printf("Node: %s  Phase: %s  Evicted: %d/%d  %s\n", status.NodeID, status.Phase, status.Evicted, len(status.Pods), status.Message)
+	table := termtables.CreateTable()
+	table.AddHeaders("Pod", "Kind", "Status", "Message")
+	for _, pod := range status.Pods {
+		table.AddRow(pod.Namespace+"/"+pod.Name, pod.Kind, string(pod.Status), pod.Message)
+	}
+	fmt.Println(table.Render())
+}
+
+// followDrain prints the progress of draining the node until it is finished
+func followDrain(id string, status *model.DrainStatus) {
+	printed := make(map[string]string)
+	for {
+		for i, pod := range status.Pods {
+			key := pod.Namespace + "/" + pod.Name
+			state := string(pod.Status) + pod.Message
+			if pod.Status == model.DrainPodPending || printed[key] == state {
+				continue
+			}
+			printed[key] = state
+			fmt.Printf("[%d/%d] %s %s(%s) %s\n", i+1, len(status.Pods), pod.Status, key, pod.Kind, pod.Message)
+		}
+		if status.Phase.Finished() {
+			fmt.Printf("drain node %s %s: %s\n", id, status.Phase, status.Message)
+			if status.Phase != model.DrainPhaseCompleted {
+				os.Exit(1)
+			}
+			return
+		}
+		time.Sleep(time.Second * 3)
+		var err *util.APIHandleError
+		status, err = clients.RegionClient.Nodes().DrainStatus(id)
+		handleErr(err)
+	}
+}
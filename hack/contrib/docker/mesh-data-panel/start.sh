@@ -6,10 +6,45 @@ if [ "$1" = "bash" ];then
 elif [ "$1" = "version" ];then
     echo /root/rainbond-mesh-data-panel version
 else
-    env2file conversion -f /root/envoy_config.yaml
+    # the xds version of the sidecar, it is reported to the discover server by the node metadata
+    envoy_config=/root/envoy_config.yaml
+    zipkin_config_type=envoy.config.trace.v2.ZipkinConfig
+    if [ "${XDS_VERSION}" = "v3" ];then
+        envoy_config=/root/envoy_config_v3.yaml
+        zipkin_config_type=envoy.config.trace.v3.ZipkinConfig
+    fi
+    env2file conversion -f ${envoy_config}
+    # the tracing driver and the collector cluster of the built-in mesh must be static in bootstrap
+    if [ -n "${TRACING_COLLECTOR_HOST}" ];then
+        cat >> ${envoy_config} <<EOF
+
+  - name: rainbond_tracing_cluster
+    connect_timeout: 1s
+    type: STRICT_DNS
+    lb_policy: ROUND_ROBIN
+    load_assignment:
+      cluster_name: rainbond_tracing_cluster
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: ${TRACING_COLLECTOR_HOST}
+                port_value: ${TRACING_COLLECTOR_PORT:-9411}
+
+tracing:
+  http:
+    name: envoy.zipkin
+    typed_config:
+      "@type": type.googleapis.com/${zipkin_config_type}
+      collector_cluster: rainbond_tracing_cluster
+      collector_endpoint: "/api/v2/spans"
+      collector_endpoint_version: HTTP_JSON
+EOF
+    fi
     cluster_name=${TENANT_ID}_${PLUGIN_ID}_${SERVICE_NAME}
     # start sidecar process
     /root/rainbond-mesh-data-panel&
     # start envoy process
-    exec envoy -c /root/envoy_config.yaml --service-cluster ${cluster_name} --service-node ${cluster_name}     
+    exec envoy -c ${envoy_config} --service-cluster ${cluster_name} --service-node ${cluster_name}     
 fi
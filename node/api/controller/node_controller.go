@@ -382,6 +382,51 @@ func UpNode(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, node)
 }
 
+//DrainNode cordons the node and evicts its pods safely
+//`{"timeout":600,"force":false}`, force evicts the pods which are not managed by a controller
+func DrainNode(w http.ResponseWriter, r *http.Request) {
+	nodeUID := strings.TrimSpace(chi.URLParam(r, "node_id"))
+	var req struct {
+		Timeout int  `json:"timeout"`
+		Force   bool `json:"force"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
+	}
+	logrus.Info("Node drain by node api controller: ", nodeUID)
+	status, err := nodeService.DrainNode(nodeUID, req.Timeout, req.Force)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+//GetDrainStatus get the progress of draining the node
+func GetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	nodeUID := strings.TrimSpace(chi.URLParam(r, "node_id"))
+	status, err := nodeService.GetDrainStatus(nodeUID)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+//CancelDrain cancel draining the node
+func CancelDrain(w http.ResponseWriter, r *http.Request) {
+	nodeUID := strings.TrimSpace(chi.URLParam(r, "node_id"))
+	status, err := nodeService.CancelDrain(nodeUID)
+	if err != nil {
+		err.Handle(r, w)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
 //Instances get node service instances
 func Instances(w http.ResponseWriter, r *http.Request) {
 	nodeUID := strings.TrimSpace(chi.URLParam(r, "node_id"))
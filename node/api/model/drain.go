@@ -0,0 +1,81 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import "time"
+
+//DrainPhase the phase of draining a node
+type DrainPhase string
+
+const (
+	//DrainPhaseDraining the pods are being evicted
+	DrainPhaseDraining DrainPhase = "draining"
+	//DrainPhaseCompleted all the pods are evicted
+	DrainPhaseCompleted DrainPhase = "completed"
+	//DrainPhaseFailed the drain stopped with an error
+	DrainPhaseFailed DrainPhase = "failed"
+	//DrainPhaseCanceled the drain is canceled by the operator
+	DrainPhaseCanceled DrainPhase = "canceled"
+)
+
+//Finished whether the drain is finished
+func (d DrainPhase) Finished() bool {
+	return d != DrainPhaseDraining
+}
+
+//DrainPodStatus the status of a pod being drained
+type DrainPodStatus string
+
+const (
+	//DrainPodPending the pod is not evicted yet
+	DrainPodPending DrainPodStatus = "pending"
+	//DrainPodEvicting the pod is being evicted, it may be blocked by the pod disruption budget
+	DrainPodEvicting DrainPodStatus = "evicting"
+	//DrainPodWaiting the pod is evicted, waiting for the replacement to become ready
+	DrainPodWaiting DrainPodStatus = "waiting"
+	//DrainPodDone the pod is evicted and replaced
+	DrainPodDone DrainPodStatus = "done"
+)
+
+//DrainPod a pod to be evicted from the draining node
+type DrainPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	//Kind could be stateless, platform or stateful, the pods are evicted in this order
+	Kind      string         `json:"kind"`
+	OwnerKind string         `json:"owner_kind,omitempty"`
+	OwnerName string         `json:"owner_name,omitempty"`
+	Status    DrainPodStatus `json:"status"`
+	Message   string         `json:"message,omitempty"`
+}
+
+//DrainStatus the progress of draining a node, it is persisted so that the drain
+//is resumed after the node service restarts
+type DrainStatus struct {
+	NodeID  string     `json:"node_id"`
+	Phase   DrainPhase `json:"phase"`
+	Message string     `json:"message,omitempty"`
+	//Timeout the seconds waiting for each pod to be evicted and replaced
+	Timeout    int         `json:"timeout"`
+	Pods       []*DrainPod `json:"pods"`
+	Evicted    int         `json:"evicted"`
+	CreateTime time.Time   `json:"create_time"`
+	UpdateTime time.Time   `json:"update_time"`
+}
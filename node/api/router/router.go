@@ -84,6 +84,9 @@ func Routers(mode string) *chi.Mux {
 				r.Delete("/{node_id}/labels", controller.DeleteLabel)
 				r.Post("/{node_id}/down", controller.DownNode)
 				r.Post("/{node_id}/up", controller.UpNode)
+				r.Post("/{node_id}/drain", controller.DrainNode)
+				r.Get("/{node_id}/drain", controller.GetDrainStatus)
+				r.Delete("/{node_id}/drain", controller.CancelDrain)
 				r.Get("/{node_id}/instance", controller.Instances)
 				r.Get("/{node_id}/check", controller.CheckNode)
 				r.Get("/{node_id}/resource", controller.Resource)
@@ -20,10 +20,12 @@ package v2
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/sirupsen/logrus"
 
@@ -34,6 +36,8 @@ import (
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	fault_common "github.com/envoyproxy/go-control-plane/envoy/config/filter/fault/v2"
+	http_fault "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/fault/v2"
 	http_rate_limit "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rate_limit/v2"
 	http_connection_manager "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
@@ -42,6 +46,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	tracing_type "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v2"
 
 	v1 "github.com/goodrain/rainbond/node/core/envoy/v1"
 )
@@ -163,8 +168,17 @@ func CreateHTTPRateLimit(option RateLimitOptions) *http_rate_limit.RateLimit {
 }
 
 //CreateHTTPConnectionManager create http connection manager
-func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *RateLimitOptions, routes ...*route.VirtualHost) *http_connection_manager.HttpConnectionManager {
+func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *RateLimitOptions, tracing *TracingOptions, routes ...*route.VirtualHost) *http_connection_manager.HttpConnectionManager {
 	var httpFilters []*http_connection_manager.HttpFilter
+	// the token buckets of local rate limit are configured by routes
+	if hasRouteFilterConfig(HTTPLocalRateLimit, routes...) {
+		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+			Name: HTTPLocalRateLimit,
+			ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+				TypedConfig: CreateHTTPLocalRateLimit(statPrefix, LocalRateLimitOptions{}),
+			},
+		})
+	}
 	if rateOpt != nil && rateOpt.Enable {
 		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
 			Name: wellknown.HTTPRateLimit,
@@ -173,6 +187,15 @@ func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *RateLimitOpti
 			},
 		})
 	}
+	// the fault filter is a noop unless a route overrides its config
+	if hasRouteFilterConfig(wellknown.Fault, routes...) {
+		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+			Name: wellknown.Fault,
+			ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+				TypedConfig: Message2Any(&http_fault.HTTPFault{}),
+			},
+		})
+	}
 	httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
 		Name: wellknown.Router,
 	})
@@ -185,6 +208,11 @@ func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *RateLimitOpti
 			},
 		},
 		HttpFilters: httpFilters,
+		Tracing:     CreateHTTPTracing(tracing),
+		// the client certificate header is set only from the verified mutual tls peer,
+		// it tells the calling component to the per source local rate limit
+		ForwardClientCertDetails:    http_connection_manager.HttpConnectionManager_SANITIZE_SET,
+		SetCurrentClientCertDetails: &http_connection_manager.HttpConnectionManager_SetCurrentClientCertDetails{Uri: true},
 	}
 	if err := hcm.Validate(); err != nil {
 		logrus.Errorf("validate http connertion manager config failure %s", err.Error())
@@ -193,9 +221,44 @@ func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *RateLimitOpti
 	return hcm
 }
 
+//TracingOptions the tracing options of the application, the tracing driver is configured in the envoy bootstrap
+type TracingOptions struct {
+	SamplingRate float64           `json:"sampling_rate"`
+	Tags         map[string]string `json:"tags"`
+}
+
+//CreateHTTPTracing create the tracing config of http connection manager, return nil if tracing is disabled
+func CreateHTTPTracing(options *TracingOptions) *http_connection_manager.HttpConnectionManager_Tracing {
+	if options == nil {
+		return nil
+	}
+	tracing := &http_connection_manager.HttpConnectionManager_Tracing{
+		RandomSampling: &_type.Percent{Value: options.SamplingRate},
+	}
+	// sort the tags, or the listener is changed every time it is generated
+	var keys []string
+	for k := range options.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tracing.CustomTags = append(tracing.CustomTags, &tracing_type.CustomTag{
+			Tag: k,
+			Type: &tracing_type.CustomTag_Literal_{
+				Literal: &tracing_type.CustomTag_Literal{Value: options.Tags[k]},
+			},
+		})
+	}
+	if err := tracing.Validate(); err != nil {
+		logrus.Errorf("validate http tracing config failure %s", err.Error())
+		return nil
+	}
+	return tracing
+}
+
 //CreateHTTPListener create http manager listener
-func CreateHTTPListener(name, address, statPrefix string, port uint32, rateOpt *RateLimitOptions, routes ...*route.VirtualHost) *apiv2.Listener {
-	hcm := CreateHTTPConnectionManager(name, statPrefix, rateOpt, routes...)
+func CreateHTTPListener(name, address, statPrefix string, port uint32, rateOpt *RateLimitOptions, tracing *TracingOptions, routes ...*route.VirtualHost) *apiv2.Listener {
+	hcm := CreateHTTPConnectionManager(name, statPrefix, rateOpt, tracing, routes...)
 	if hcm == nil {
 		logrus.Warningf("create http connection manager failure %s", name)
 		return nil
@@ -384,6 +447,91 @@ func CreateRoute(clusterName, prefix string, headers []*route.HeaderMatcher, wei
 	return rout
 }
 
+//CreateRetryPolicy create the retry policy of route, return nil if retry is disabled
+func CreateRetryPolicy(options RainbondPluginOptions) *route.RetryPolicy {
+	if options.NumRetries == 0 {
+		return nil
+	}
+	retryPolicy := &route.RetryPolicy{
+		RetryOn:    options.RetryOn,
+		NumRetries: ConversionUInt32(options.NumRetries),
+	}
+	if options.PerTryTimeoutMS > 0 {
+		retryPolicy.PerTryTimeout = ConverTimeDurationMS(options.PerTryTimeoutMS)
+	}
+	if err := retryPolicy.Validate(); err != nil {
+		logrus.Errorf("validate route retry policy failure %s", err.Error())
+		return nil
+	}
+	return retryPolicy
+}
+
+//CreateHTTPFault create the fault injection config of route, return nil if fault injection is disabled
+func CreateHTTPFault(options RainbondPluginOptions) *http_fault.HTTPFault {
+	var fault http_fault.HTTPFault
+	if options.FaultDelayPercent > 0 && options.FaultDelayMS > 0 {
+		fault.Delay = &fault_common.FaultDelay{
+			FaultDelaySecifier: &fault_common.FaultDelay_FixedDelay{
+				FixedDelay: ConverTimeDurationMS(options.FaultDelayMS),
+			},
+			Percentage: &_type.FractionalPercent{
+				Numerator:   options.FaultDelayPercent,
+				Denominator: _type.FractionalPercent_HUNDRED,
+			},
+		}
+	}
+	if options.FaultAbortPercent > 0 {
+		fault.Abort = &http_fault.FaultAbort{
+			ErrorType: &http_fault.FaultAbort_HttpStatus{
+				HttpStatus: options.FaultAbortStatus,
+			},
+			Percentage: &_type.FractionalPercent{
+				Numerator:   options.FaultAbortPercent,
+				Denominator: _type.FractionalPercent_HUNDRED,
+			},
+		}
+	}
+	if fault.Delay == nil && fault.Abort == nil {
+		return nil
+	}
+	if err := fault.Validate(); err != nil {
+		logrus.Errorf("validate http fault config failure %s", err.Error())
+		return nil
+	}
+	return &fault
+}
+
+//ApplyRoutePolicy applies the timeout, retry and fault injection options to the route
+func ApplyRoutePolicy(r *route.Route, options RainbondPluginOptions) *route.Route {
+	if r == nil {
+		return nil
+	}
+	if action, ok := r.Action.(*route.Route_Route); ok {
+		if options.TimeoutMS > 0 {
+			action.Route.Timeout = ConverTimeDurationMS(options.TimeoutMS)
+		}
+		action.Route.RetryPolicy = CreateRetryPolicy(options)
+	}
+	if fault := CreateHTTPFault(options); fault != nil {
+		if r.TypedPerFilterConfig == nil {
+			r.TypedPerFilterConfig = make(map[string]*any.Any)
+		}
+		r.TypedPerFilterConfig[wellknown.Fault] = Message2Any(fault)
+	}
+	return r
+}
+
+func hasRouteFilterConfig(filterName string, virtualHosts ...*route.VirtualHost) bool {
+	for _, vh := range virtualHosts {
+		for _, r := range vh.Routes {
+			if _, ok := r.TypedPerFilterConfig[filterName]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //CreateHeaderMatcher create http route config header matcher
 func CreateHeaderMatcher(header v1.Header) *route.HeaderMatcher {
 	if header.Name == "" {
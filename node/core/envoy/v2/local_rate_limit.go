@@ -0,0 +1,185 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package v2
+
+import (
+	"fmt"
+	"regexp"
+
+	udpa_type_v1 "github.com/cncf/udpa/go/udpa/type/v1"
+	"github.com/golang/protobuf/ptypes/any"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/sirupsen/logrus"
+
+	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	local_rate_limit "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/local_rate_limit/v2alpha"
+	_type "github.com/envoyproxy/go-control-plane/envoy/type"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+)
+
+const (
+	//NetworkLocalRateLimit the name of the network local rate limit filter, it limits the connections
+	NetworkLocalRateLimit = "envoy.filters.network.local_ratelimit"
+	//HTTPLocalRateLimit the name of the http local rate limit filter, it limits the requests
+	HTTPLocalRateLimit = "envoy.filters.http.local_ratelimit"
+	//HTTPLocalRateLimitType the type url of the http local rate limit config,
+	//it is not generated in go-control-plane, so the config is sent as a typed struct
+	HTTPLocalRateLimitType = "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit"
+	//ClientCertHeader the header with the details of the client certificate, it is set by the inbound
+	//listener from the verified mutual tls peer and the one sent by the client is removed
+	ClientCertHeader = "x-forwarded-client-cert"
+	//LocalRateLimitAllSources the source of the stats of the port level local rate limit
+	LocalRateLimitAllSources = "all"
+)
+
+//LocalRateLimitOptions the token bucket of a local rate limit
+type LocalRateLimitOptions struct {
+	MaxTokens      uint32
+	TokensPerFill  uint32
+	FillIntervalMS int64
+}
+
+//Enable whether the local rate limit is enabled
+func (l LocalRateLimitOptions) Enable() bool {
+	return l.MaxTokens > 0
+}
+
+//LocalRateLimitStatPrefix the stat prefix of a local rate limit, the stats are exported
+//to the node statsd exporter as service_id.port.source.*
+func LocalRateLimitStatPrefix(serviceID string, port int, source string) string {
+	return fmt.Sprintf("%s.%d.%s", serviceID, port, source)
+}
+
+//CreateTokenBucket create the token bucket of local rate limit
+func CreateTokenBucket(options LocalRateLimitOptions) *_type.TokenBucket {
+	tokensPerFill := options.TokensPerFill
+	if tokensPerFill == 0 {
+		tokensPerFill = options.MaxTokens
+	}
+	fillInterval := options.FillIntervalMS
+	if fillInterval <= 0 {
+		fillInterval = 1000
+	}
+	bucket := &_type.TokenBucket{
+		MaxTokens:     options.MaxTokens,
+		TokensPerFill: ConversionUInt32(tokensPerFill),
+		FillInterval:  ConverTimeDurationMS(fillInterval),
+	}
+	if err := bucket.Validate(); err != nil {
+		logrus.Errorf("validate local rate limit token bucket failure %s", err.Error())
+		return nil
+	}
+	return bucket
+}
+
+//CreateNetworkLocalRateLimit create the network local rate limit filter, return nil if the limit is disabled
+func CreateNetworkLocalRateLimit(statPrefix string, options LocalRateLimitOptions) *envoy_api_v2_listener.Filter {
+	if !options.Enable() {
+		return nil
+	}
+	limit := &local_rate_limit.LocalRateLimit{
+		StatPrefix:  statPrefix,
+		TokenBucket: CreateTokenBucket(options),
+	}
+	if err := limit.Validate(); err != nil {
+		logrus.Errorf("validate network local rate limit config failure %s", err.Error())
+		return nil
+	}
+	return &envoy_api_v2_listener.Filter{
+		Name:       NetworkLocalRateLimit,
+		ConfigType: &envoy_api_v2_listener.Filter_TypedConfig{TypedConfig: Message2Any(limit)},
+	}
+}
+
+//ApplyNetworkLocalRateLimit puts the network local rate limit filter in front of all the filter chains of the listener
+func ApplyNetworkLocalRateLimit(listener *apiv2.Listener, statPrefix string, options LocalRateLimitOptions) *apiv2.Listener {
+	if listener == nil {
+		return nil
+	}
+	filter := CreateNetworkLocalRateLimit(statPrefix, options)
+	if filter == nil {
+		return listener
+	}
+	for _, chain := range listener.FilterChains {
+		chain.Filters = append([]*envoy_api_v2_listener.Filter{filter}, chain.Filters...)
+	}
+	return listener
+}
+
+//CreateHTTPLocalRateLimit create the config of the http local rate limit filter,
+//without token bucket the filter only counts the requests
+func CreateHTTPLocalRateLimit(statPrefix string, options LocalRateLimitOptions) *any.Any {
+	fields := map[string]*_struct.Value{
+		"stat_prefix": &_struct.Value{Kind: &_struct.Value_StringValue{StringValue: statPrefix}},
+	}
+	if options.Enable() {
+		bucket := CreateTokenBucket(options)
+		if bucket == nil {
+			return nil
+		}
+		// the filter is neither enabled nor enforced by default
+		percent := &_struct.Value{Kind: &_struct.Value_StructValue{StructValue: MessageToStruct(&core.RuntimeFractionalPercent{
+			DefaultValue: &_type.FractionalPercent{Numerator: 100, Denominator: _type.FractionalPercent_HUNDRED},
+			RuntimeKey:   "local_rate_limit_enabled",
+		})}}
+		fields["token_bucket"] = &_struct.Value{Kind: &_struct.Value_StructValue{StructValue: MessageToStruct(bucket)}}
+		fields["filter_enabled"] = percent
+		fields["filter_enforced"] = percent
+	}
+	return Message2Any(&udpa_type_v1.TypedStruct{
+		TypeUrl: HTTPLocalRateLimitType,
+		Value:   &_struct.Struct{Fields: fields},
+	})
+}
+
+//ApplyRouteLocalRateLimit applies the local rate limit to the route, every route has its own token bucket
+func ApplyRouteLocalRateLimit(r *route.Route, statPrefix string, options LocalRateLimitOptions) *route.Route {
+	if r == nil || !options.Enable() {
+		return r
+	}
+	config := CreateHTTPLocalRateLimit(statPrefix, options)
+	if config == nil {
+		return nil
+	}
+	if r.TypedPerFilterConfig == nil {
+		r.TypedPerFilterConfig = make(map[string]*any.Any)
+	}
+	r.TypedPerFilterConfig[HTTPLocalRateLimit] = config
+	return r
+}
+
+//SourceServiceRegex matches the client certificate header of the calling component in the namespace,
+//the source is the identity of the mutual tls peer, so it can not be forged by the request.
+func SourceServiceRegex(namespace, serviceAlias string) string {
+	return fmt.Sprintf("(.*;)?URI=%s(;.*)?", regexp.QuoteMeta(MeshSPIFFEID(namespace, serviceAlias)))
+}
+
+//CreateSourceServiceMatcher matches the requests from the calling component, only the mutual tls
+//connections carry the identity of the calling component
+func CreateSourceServiceMatcher(namespace, serviceAlias string) *route.HeaderMatcher {
+	return &route.HeaderMatcher{
+		Name: ClientCertHeader,
+		HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: &matcher.RegexMatcher{
+			EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+			Regex:      SourceServiceRegex(namespace, serviceAlias),
+		}},
+	}
+}
@@ -0,0 +1,179 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package v2
+
+import (
+	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	envoy_api_v2_listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sirupsen/logrus"
+
+	"github.com/goodrain/rainbond/util/cert"
+)
+
+const (
+	//MeshCertSecretName the sds secret name of the workload certificate
+	MeshCertSecretName = "mesh_cert"
+	//MeshValidationSecretName the sds secret name of the platform ca
+	MeshValidationSecretName = "mesh_validation"
+	//TLSInspectorListenerFilter detects whether the transport is tls
+	TLSInspectorListenerFilter = "envoy.listener.tls_inspector"
+	//MTLSModeStrict the mutual tls mode of the application which rejects plaintext traffic
+	MTLSModeStrict = "STRICT"
+)
+
+//MeshTrustDomain the trust domain of the SPIFFE ids issued by the platform ca
+var MeshTrustDomain = "rainbond"
+
+//MeshSPIFFEID returns the identity of a component in the built-in service mesh
+func MeshSPIFFEID(namespace, serviceAlias string) string {
+	return cert.SPIFFEID(MeshTrustDomain, namespace, serviceAlias)
+}
+
+//CreateSDSSecretConfig create a secret config which fetches the secret from the rainbond xds server
+func CreateSDSSecretConfig(name string) *auth.SdsSecretConfig {
+	return &auth.SdsSecretConfig{
+		Name: name,
+		SdsConfig: &core.ConfigSource{
+			ConfigSourceSpecifier: &core.ConfigSource_ApiConfigSource{
+				ApiConfigSource: &core.ApiConfigSource{
+					ApiType: core.ApiConfigSource_GRPC,
+					GrpcServices: []*core.GrpcService{
+						&core.GrpcService{
+							TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+								EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
+									ClusterName: "rainbond_xds_cluster",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+//CreateMeshCommonTLSContext create the tls context presenting the workload certificate,
+//the peer certificate must be issued by the platform ca and match one of subjectAltNames if not empty
+func CreateMeshCommonTLSContext(subjectAltNames ...string) *auth.CommonTlsContext {
+	var sanMatchers []*matcher.StringMatcher
+	for _, san := range subjectAltNames {
+		sanMatchers = append(sanMatchers, &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Exact{Exact: san},
+		})
+	}
+	return &auth.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*auth.SdsSecretConfig{CreateSDSSecretConfig(MeshCertSecretName)},
+		ValidationContextType: &auth.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &auth.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext:         &auth.CertificateValidationContext{MatchSubjectAltNames: sanMatchers},
+				ValidationContextSdsSecretConfig: CreateSDSSecretConfig(MeshValidationSecretName),
+			},
+		},
+	}
+}
+
+//CreateUpstreamMTLSContext create the tls context of the cluster which originates mutual tls to a mesh component
+func CreateUpstreamMTLSContext(sni string, subjectAltNames ...string) *auth.UpstreamTlsContext {
+	tlsContext := &auth.UpstreamTlsContext{
+		CommonTlsContext: CreateMeshCommonTLSContext(subjectAltNames...),
+		Sni:              sni,
+	}
+	if err := tlsContext.Validate(); err != nil {
+		logrus.Errorf("validate upstream tls context failure %s", err.Error())
+		return nil
+	}
+	return tlsContext
+}
+
+//CreateDownstreamMTLSContext create the tls context of the listener which terminates mutual tls
+func CreateDownstreamMTLSContext() *auth.DownstreamTlsContext {
+	tlsContext := &auth.DownstreamTlsContext{
+		CommonTlsContext:         CreateMeshCommonTLSContext(),
+		RequireClientCertificate: &wrappers.BoolValue{Value: true},
+	}
+	if err := tlsContext.Validate(); err != nil {
+		logrus.Errorf("validate downstream tls context failure %s", err.Error())
+		return nil
+	}
+	return tlsContext
+}
+
+//ApplyDownstreamMTLS makes the listener terminate mutual tls.
+//In strict mode the plaintext connections are rejected, otherwise the listener detects
+//the transport and serves both the mutual tls and the plaintext connections.
+func ApplyDownstreamMTLS(listener *apiv2.Listener, strict bool) *apiv2.Listener {
+	if listener == nil || len(listener.FilterChains) == 0 {
+		return listener
+	}
+	tlsContext := CreateDownstreamMTLSContext()
+	if tlsContext == nil {
+		return nil
+	}
+	filters := listener.FilterChains[0].Filters
+	tlsChain := &envoy_api_v2_listener.FilterChain{
+		TlsContext: tlsContext,
+		Filters:    filters,
+	}
+	if strict {
+		listener.FilterChains = []*envoy_api_v2_listener.FilterChain{tlsChain}
+	} else {
+		tlsChain.FilterChainMatch = &envoy_api_v2_listener.FilterChainMatch{TransportProtocol: "tls"}
+		listener.ListenerFilters = append(listener.ListenerFilters, &envoy_api_v2_listener.ListenerFilter{
+			Name: TLSInspectorListenerFilter,
+		})
+		listener.FilterChains = []*envoy_api_v2_listener.FilterChain{
+			tlsChain,
+			&envoy_api_v2_listener.FilterChain{Filters: filters},
+		}
+	}
+	if err := listener.Validate(); err != nil {
+		logrus.Errorf("validate mutual tls listener config failure %s", err.Error())
+		return nil
+	}
+	return listener
+}
+
+//CreateTLSCertificateSecret create the sds secret of a certificate
+func CreateTLSCertificateSecret(name string, certPEM, keyPEM []byte) *auth.Secret {
+	return &auth.Secret{
+		Name: name,
+		Type: &auth.Secret_TlsCertificate{
+			TlsCertificate: &auth.TlsCertificate{
+				CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: certPEM}},
+				PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: keyPEM}},
+			},
+		},
+	}
+}
+
+//CreateValidationContextSecret create the sds secret of a trusted ca
+func CreateValidationContextSecret(name string, caPEM []byte) *auth.Secret {
+	return &auth.Secret{
+		Name: name,
+		Type: &auth.Secret_ValidationContext{
+			ValidationContext: &auth.CertificateValidationContext{
+				TrustedCa: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: caPEM}},
+			},
+		},
+	}
+}
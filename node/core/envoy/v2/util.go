@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
 	"github.com/gogo/protobuf/proto"
@@ -82,6 +83,11 @@ func ConverTimeDuration(second int64) *duration.Duration {
 	}
 }
 
+//ConverTimeDurationMS conver millisecond to duration
+func ConverTimeDurationMS(millisecond int64) *duration.Duration {
+	return ptypes.DurationProto(time.Duration(millisecond) * time.Millisecond)
+}
+
 const (
 	//KeyPrefix request path prefix
 	KeyPrefix string = "Prefix"
@@ -127,6 +133,22 @@ const (
 	KeyConnectionTimeout string = "ConnectionTimeout"
 	//KeyTCPIdleTimeout tcp idle timeout
 	KeyTCPIdleTimeout string = "TCPIdleTimeout"
+	//KeyRetryOn the conditions under which the request is retried, eg. 5xx,connect-failure
+	KeyRetryOn string = "RetryOn"
+	//KeyNumRetries the number of retries of a request, 0 disables the retry policy
+	KeyNumRetries string = "NumRetries"
+	//KeyPerTryTimeoutMS the timeout of each try of a request, including the first one
+	KeyPerTryTimeoutMS string = "PerTryTimeoutMS"
+	//KeyTimeoutMS the timeout of a request, covering all the retries. 0 means the envoy default(15s)
+	KeyTimeoutMS string = "TimeoutMS"
+	//KeyFaultDelayPercent the percentage of requests to be delayed, for chaos testing
+	KeyFaultDelayPercent string = "FaultDelayPercent"
+	//KeyFaultDelayMS the delay of the delayed requests
+	KeyFaultDelayMS string = "FaultDelayMS"
+	//KeyFaultAbortPercent the percentage of requests to be aborted, for chaos testing
+	KeyFaultAbortPercent string = "FaultAbortPercent"
+	//KeyFaultAbortStatus the http status code of the aborted requests
+	KeyFaultAbortStatus string = "FaultAbortStatus"
 )
 
 //RainbondPluginOptions rainbond plugin config struct
@@ -147,12 +169,25 @@ type RainbondPluginOptions struct {
 	HealthyPanicThreshold    int64
 	ConnectionTimeout        int64
 	TCPIdleTimeout           int64
+	RetryOn                  string
+	NumRetries               uint32
+	PerTryTimeoutMS          int64
+	TimeoutMS                int64
+	FaultDelayPercent        uint32
+	FaultDelayMS             int64
+	FaultAbortPercent        uint32
+	FaultAbortStatus         uint32
 }
 
 //RainbondInboundPluginOptions rainbond inbound plugin options
 type RainbondInboundPluginOptions struct {
 	OpenLimit   bool
 	LimitDomain string
+	// LocalLimit the local rate limit of the port
+	LocalLimit LocalRateLimitOptions
+	// SourceLocalLimits the max requests in a fill interval of every calling component, key is the service alias.
+	// The calling component is identified by its mutual tls certificate, so the limits apply to mutual tls connections only.
+	SourceLocalLimits map[string]uint32
 }
 
 //RouteBasicHash get basic hash for weight
@@ -185,6 +220,8 @@ func GetOptionValues(sr map[string]interface{}) RainbondPluginOptions {
 		HealthyPanicThreshold: 50,
 		ConnectionTimeout:     250,
 		TCPIdleTimeout:        60 * 60 * 2,
+		RetryOn:               "5xx,connect-failure",
+		FaultAbortStatus:      503,
 	}
 	if sr == nil {
 		return rpo
@@ -275,11 +312,50 @@ func GetOptionValues(sr map[string]interface{}) RainbondPluginOptions {
 			if i, err := strconv.Atoi(v.(string)); err == nil {
 				rpo.TCPIdleTimeout = int64(i)
 			}
+		case KeyRetryOn:
+			if v.(string) != "" {
+				rpo.RetryOn = v.(string)
+			}
+		case KeyNumRetries:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.NumRetries = uint32(i)
+			}
+		case KeyPerTryTimeoutMS:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.PerTryTimeoutMS = int64(i)
+			}
+		case KeyTimeoutMS:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.TimeoutMS = int64(i)
+			}
+		case KeyFaultDelayPercent:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.FaultDelayPercent = percent(i)
+			}
+		case KeyFaultDelayMS:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.FaultDelayMS = int64(i)
+			}
+		case KeyFaultAbortPercent:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				rpo.FaultAbortPercent = percent(i)
+			}
+		case KeyFaultAbortStatus:
+			if i, err := strconv.Atoi(v.(string)); err == nil && i >= 200 && i < 600 {
+				rpo.FaultAbortStatus = uint32(i)
+			}
 		}
 	}
 	return rpo
 }
 
+func percent(i int) uint32 {
+	if i > 100 {
+		return 100
+	}
+	return uint32(i)
+}
+
 //GetRainbondInboundPluginOptions get rainbond inbound plugin options
 func GetRainbondInboundPluginOptions(sr map[string]interface{}) (r RainbondInboundPluginOptions) {
 	for k, v := range sr {
@@ -290,11 +366,45 @@ func GetRainbondInboundPluginOptions(sr map[string]interface{}) (r RainbondInbou
 			}
 		case "LIMIT_DOMAIN":
 			r.LimitDomain = v.(string)
+		case "LOCAL_LIMIT_MAX_TOKENS":
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				r.LocalLimit.MaxTokens = uint32(i)
+			}
+		case "LOCAL_LIMIT_TOKENS_PER_FILL":
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				r.LocalLimit.TokensPerFill = uint32(i)
+			}
+		case "LOCAL_LIMIT_FILL_INTERVAL_MS":
+			if i, err := strconv.Atoi(v.(string)); err == nil && i > 0 {
+				r.LocalLimit.FillIntervalMS = int64(i)
+			}
+		case "LOCAL_LIMIT_SOURCES":
+			// eg. gr123456:100,gr654321:20
+			for _, source := range strings.Split(v.(string), ",") {
+				kv := strings.Split(strings.TrimSpace(source), ":")
+				if len(kv) != 2 || kv[0] == "" {
+					continue
+				}
+				if i, err := strconv.Atoi(kv[1]); err == nil && i > 0 {
+					if r.SourceLocalLimits == nil {
+						r.SourceLocalLimits = make(map[string]uint32)
+					}
+					r.SourceLocalLimits[kv[0]] = uint32(i)
+				}
+			}
 		}
 	}
 	return
 }
 
+//SourceLocalLimit the local rate limit of the calling component, it shares the fill interval of the port
+func (r RainbondInboundPluginOptions) SourceLocalLimit(serviceAlias string) LocalRateLimitOptions {
+	return LocalRateLimitOptions{
+		MaxTokens:      r.SourceLocalLimits[serviceAlias],
+		FillIntervalMS: r.LocalLimit.FillIntervalMS,
+	}
+}
+
 //ParseLocalityLbEndpointsResource parse envoy xds server response ParseLocalityLbEndpointsResource
 func ParseLocalityLbEndpointsResource(resources []*any.Any) []v2.ClusterLoadAssignment {
 	var endpoints []v2.ClusterLoadAssignment
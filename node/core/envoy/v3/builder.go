@@ -0,0 +1,667 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package v3
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/sirupsen/logrus"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	udp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/udp/udp_proxy/v2alpha"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	configratelimit "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	fault_common "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	http_fault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	http_rate_limit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	http_router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	http_connection_manager "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tracing_type "github.com/envoyproxy/go-control-plane/envoy/type/tracing/v3"
+	_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/goodrain/rainbond/node/core/envoy/v1"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+)
+
+//the canonical extension names, the deprecated names of v2 are not accepted by current envoy releases
+const (
+	//HTTPConnectionManager network filter name
+	HTTPConnectionManager = "envoy.filters.network.http_connection_manager"
+	//TCPProxy network filter name
+	TCPProxy = "envoy.filters.network.tcp_proxy"
+	//UDPProxy udp listener filter name
+	UDPProxy = "envoy.filters.udp_listener.udp_proxy"
+	//HTTPRateLimit http filter name
+	HTTPRateLimit = "envoy.filters.http.ratelimit"
+	//Fault http filter name
+	Fault = "envoy.filters.http.fault"
+	//Router http filter name
+	Router = "envoy.filters.http.router"
+	//TLSInspector listener filter name
+	TLSInspector = "envoy.filters.listener.tls_inspector"
+	//TransportSocketTLS transport socket name
+	TransportSocketTLS = "envoy.transport_sockets.tls"
+)
+
+//CreateTCPListener listener builder
+func CreateTCPListener(name, clusterName, address, statPrefix string, port uint32, idleTimeout int64) *listener.Listener {
+	if address == "" {
+		address = envoyv2.DefaultLocalhostListenerAddress
+	}
+	tcpProxy := &tcp_proxy.TcpProxy{
+		StatPrefix: statPrefix,
+		ClusterSpecifier: &tcp_proxy.TcpProxy_Cluster{
+			Cluster: clusterName,
+		},
+		IdleTimeout: envoyv2.ConverTimeDuration(idleTimeout),
+	}
+	if err := tcpProxy.Validate(); err != nil {
+		logrus.Errorf("validate listener tcp proxy config failure %s", err.Error())
+		return nil
+	}
+	l := &listener.Listener{
+		Name:    name,
+		Address: CreateSocketAddress("tcp", address, port),
+		FilterChains: []*listener.FilterChain{
+			&listener.FilterChain{
+				Filters: []*listener.Filter{
+					&listener.Filter{
+						Name:       TCPProxy,
+						ConfigType: &listener.Filter_TypedConfig{TypedConfig: envoyv2.Message2Any(tcpProxy)},
+					},
+				},
+			},
+		},
+	}
+	if err := l.Validate(); err != nil {
+		logrus.Errorf("validate listener config failure %s", err.Error())
+		return nil
+	}
+	return l
+}
+
+//CreateUDPListener create udp listenner
+func CreateUDPListener(name, clusterName, address, statPrefix string, port uint32) *listener.Listener {
+	if address == "" {
+		address = envoyv2.DefaultLocalhostListenerAddress
+	}
+	config := &udp_proxy.UdpProxyConfig{
+		StatPrefix: statPrefix,
+		RouteSpecifier: &udp_proxy.UdpProxyConfig_Cluster{
+			Cluster: clusterName,
+		},
+	}
+	if err := config.Validate(); err != nil {
+		logrus.Errorf("validate listener udp config failure %s", err.Error())
+		return nil
+	}
+	l := &listener.Listener{
+		Name:    name,
+		Address: CreateSocketAddress("udp", address, port),
+		ListenerFilters: []*listener.ListenerFilter{
+			&listener.ListenerFilter{
+				Name: UDPProxy,
+				ConfigType: &listener.ListenerFilter_TypedConfig{
+					TypedConfig: envoyv2.Message2Any(config),
+				},
+			},
+		},
+		// Listening on UDP without SO_REUSEPORT socket option may result to unstable packet proxying.
+		ReusePort: true,
+	}
+	if err := l.Validate(); err != nil {
+		logrus.Errorf("validate listener config failure %s", err.Error())
+		return nil
+	}
+	return l
+}
+
+//CreateHTTPRateLimit create http rate limit
+func CreateHTTPRateLimit(option envoyv2.RateLimitOptions) *http_rate_limit.RateLimit {
+	httpRateLimit := &http_rate_limit.RateLimit{
+		Domain: option.Domain,
+		Stage:  option.Stage,
+		RateLimitService: &configratelimit.RateLimitServiceConfig{
+			GrpcService: &core.GrpcService{
+				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
+						ClusterName: option.RateServerClusterName,
+					},
+				},
+			},
+		},
+	}
+	if err := httpRateLimit.Validate(); err != nil {
+		logrus.Errorf("create http rate limit failure %s", err.Error())
+		return nil
+	}
+	return httpRateLimit
+}
+
+//CreateHTTPConnectionManager create http connection manager
+func CreateHTTPConnectionManager(name, statPrefix string, rateOpt *envoyv2.RateLimitOptions, tracing *envoyv2.TracingOptions, routes ...*route.VirtualHost) *http_connection_manager.HttpConnectionManager {
+	var httpFilters []*http_connection_manager.HttpFilter
+	// the token buckets of local rate limit are configured by routes
+	if hasRouteFilterConfig(envoyv2.HTTPLocalRateLimit, routes...) {
+		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+			Name: envoyv2.HTTPLocalRateLimit,
+			ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+				TypedConfig: envoyv2.CreateHTTPLocalRateLimit(statPrefix, envoyv2.LocalRateLimitOptions{}),
+			},
+		})
+	}
+	if rateOpt != nil && rateOpt.Enable {
+		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+			Name: HTTPRateLimit,
+			ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+				TypedConfig: envoyv2.Message2Any(CreateHTTPRateLimit(*rateOpt)),
+			},
+		})
+	}
+	// the fault filter is a noop unless a route overrides its config
+	if hasRouteFilterConfig(Fault, routes...) {
+		httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+			Name: Fault,
+			ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+				TypedConfig: envoyv2.Message2Any(&http_fault.HTTPFault{}),
+			},
+		})
+	}
+	httpFilters = append(httpFilters, &http_connection_manager.HttpFilter{
+		Name: Router,
+		ConfigType: &http_connection_manager.HttpFilter_TypedConfig{
+			TypedConfig: envoyv2.Message2Any(&http_router.Router{}),
+		},
+	})
+	hcm := &http_connection_manager.HttpConnectionManager{
+		StatPrefix: statPrefix,
+		RouteSpecifier: &http_connection_manager.HttpConnectionManager_RouteConfig{
+			RouteConfig: &route.RouteConfiguration{
+				Name:         name,
+				VirtualHosts: routes,
+			},
+		},
+		HttpFilters: httpFilters,
+		Tracing:     CreateHTTPTracing(tracing),
+		// the client certificate header is set only from the verified mutual tls peer,
+		// it tells the calling component to the per source local rate limit
+		ForwardClientCertDetails:    http_connection_manager.HttpConnectionManager_SANITIZE_SET,
+		SetCurrentClientCertDetails: &http_connection_manager.HttpConnectionManager_SetCurrentClientCertDetails{Uri: true},
+	}
+	if err := hcm.Validate(); err != nil {
+		logrus.Errorf("validate http connertion manager config failure %s", err.Error())
+		return nil
+	}
+	return hcm
+}
+
+//CreateHTTPTracing create the tracing config of http connection manager, return nil if tracing is disabled
+func CreateHTTPTracing(options *envoyv2.TracingOptions) *http_connection_manager.HttpConnectionManager_Tracing {
+	if options == nil {
+		return nil
+	}
+	tracing := &http_connection_manager.HttpConnectionManager_Tracing{
+		RandomSampling: &_type.Percent{Value: options.SamplingRate},
+	}
+	// sort the tags, or the listener is changed every time it is generated
+	var keys []string
+	for k := range options.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tracing.CustomTags = append(tracing.CustomTags, &tracing_type.CustomTag{
+			Tag: k,
+			Type: &tracing_type.CustomTag_Literal_{
+				Literal: &tracing_type.CustomTag_Literal{Value: options.Tags[k]},
+			},
+		})
+	}
+	if err := tracing.Validate(); err != nil {
+		logrus.Errorf("validate http tracing config failure %s", err.Error())
+		return nil
+	}
+	return tracing
+}
+
+//CreateHTTPListener create http manager listener
+func CreateHTTPListener(name, address, statPrefix string, port uint32, rateOpt *envoyv2.RateLimitOptions, tracing *envoyv2.TracingOptions, routes ...*route.VirtualHost) *listener.Listener {
+	hcm := CreateHTTPConnectionManager(name, statPrefix, rateOpt, tracing, routes...)
+	if hcm == nil {
+		logrus.Warningf("create http connection manager failure %s", name)
+		return nil
+	}
+	l := &listener.Listener{
+		Name:    name,
+		Address: CreateSocketAddress("tcp", address, port),
+		FilterChains: []*listener.FilterChain{
+			&listener.FilterChain{
+				Filters: []*listener.Filter{
+					&listener.Filter{
+						Name:       HTTPConnectionManager,
+						ConfigType: &listener.Filter_TypedConfig{TypedConfig: envoyv2.Message2Any(hcm)},
+					},
+				},
+			},
+		},
+	}
+	if err := l.Validate(); err != nil {
+		logrus.Errorf("validate listener config failure %s", err.Error())
+		return nil
+	}
+	return l
+}
+
+//CreateSocketAddress create socket address
+func CreateSocketAddress(protocol, address string, port uint32) *core.Address {
+	if strings.HasPrefix(address, "https://") {
+		address = strings.Split(address, "https://")[1]
+	}
+	if strings.HasPrefix(address, "http://") {
+		address = strings.Split(address, "http://")[1]
+	}
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: func(protocol string) core.SocketAddress_Protocol {
+					if protocol == "udp" {
+						return core.SocketAddress_UDP
+					}
+					return core.SocketAddress_TCP
+				}(protocol),
+				Address: address,
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: port,
+				},
+			},
+		},
+	}
+}
+
+//CreateCircuitBreaker create down cluster circuitbreaker
+func CreateCircuitBreaker(options envoyv2.RainbondPluginOptions) *cluster.CircuitBreakers {
+	circuitBreakers := &cluster.CircuitBreakers{
+		Thresholds: []*cluster.CircuitBreakers_Thresholds{
+			&cluster.CircuitBreakers_Thresholds{
+				Priority:           core.RoutingPriority_DEFAULT,
+				MaxConnections:     envoyv2.ConversionUInt32(uint32(options.MaxConnections)),
+				MaxRequests:        envoyv2.ConversionUInt32(uint32(options.MaxRequests)),
+				MaxRetries:         envoyv2.ConversionUInt32(uint32(options.MaxActiveRetries)),
+				MaxPendingRequests: envoyv2.ConversionUInt32(uint32(options.MaxPendingRequests)),
+			},
+		},
+	}
+	if err := circuitBreakers.Validate(); err != nil {
+		logrus.Errorf("validate envoy config circuitBreakers failure %s", err.Error())
+		return nil
+	}
+	return circuitBreakers
+}
+
+//CreatOutlierDetection create up cluster OutlierDetection
+func CreatOutlierDetection(options envoyv2.RainbondPluginOptions) *cluster.OutlierDetection {
+	outlierDetection := &cluster.OutlierDetection{
+		Interval:           envoyv2.ConverTimeDuration(options.Interval),
+		BaseEjectionTime:   envoyv2.ConverTimeDuration(options.BaseEjectionTimeMS / 1000),
+		MaxEjectionPercent: envoyv2.ConversionUInt32(uint32(options.MaxEjectionPercent)),
+		Consecutive_5Xx:    envoyv2.ConversionUInt32(uint32(options.ConsecutiveErrors)),
+	}
+	if err := outlierDetection.Validate(); err != nil {
+		logrus.Errorf("validate envoy config outlierDetection failure %s", err.Error())
+		return nil
+	}
+	return outlierDetection
+}
+
+//CreateRouteVirtualHost create route virtual host
+func CreateRouteVirtualHost(name string, domains []string, rateLimits []*route.RateLimit, routes ...*route.Route) *route.VirtualHost {
+	pvh := &route.VirtualHost{
+		Name:       name,
+		Domains:    domains,
+		Routes:     routes,
+		RateLimits: rateLimits,
+	}
+	if err := pvh.Validate(); err != nil {
+		logrus.Errorf("route virtualhost config validate failure %s domains %s", err.Error(), domains)
+		return nil
+	}
+	return pvh
+}
+
+//CreateRouteWithHostRewrite create route with hostRewrite
+func CreateRouteWithHostRewrite(host, clusterName, prefix string, headers []*route.HeaderMatcher, weight uint32) *route.Route {
+	if host == "" {
+		return nil
+	}
+	if strings.HasPrefix(host, "https://") {
+		host = strings.Split(host, "https://")[1]
+	}
+	if strings.HasPrefix(host, "http://") {
+		host = strings.Split(host, "http://")[1]
+	}
+	rout := &route.Route{
+		Match: &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{
+				Prefix: prefix,
+			},
+			Headers: headers,
+		},
+		Action: &route.Route_Route{
+			Route: &route.RouteAction{
+				ClusterSpecifier: &route.RouteAction_Cluster{
+					Cluster: clusterName,
+				},
+				Priority: core.RoutingPriority_DEFAULT,
+				HostRewriteSpecifier: &route.RouteAction_HostRewriteLiteral{
+					HostRewriteLiteral: host,
+				},
+			},
+		},
+	}
+	if err := rout.Validate(); err != nil {
+		logrus.Errorf("route http route config validate failure %s", err.Error())
+		return nil
+	}
+	return rout
+}
+
+//CreateRoute create http route
+func CreateRoute(clusterName, prefix string, headers []*route.HeaderMatcher, weight uint32) *route.Route {
+	rout := &route.Route{
+		Match: &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{
+				Prefix: prefix,
+			},
+			Headers: headers,
+		},
+		Action: &route.Route_Route{
+			Route: &route.RouteAction{
+				ClusterSpecifier: &route.RouteAction_WeightedClusters{
+					WeightedClusters: &route.WeightedCluster{
+						Clusters: []*route.WeightedCluster_ClusterWeight{
+							&route.WeightedCluster_ClusterWeight{
+								Name:   clusterName,
+								Weight: envoyv2.ConversionUInt32(weight),
+							},
+						},
+					},
+				},
+				Priority: core.RoutingPriority_DEFAULT,
+			},
+		},
+	}
+	if err := rout.Validate(); err != nil {
+		logrus.Errorf("route http route config validate failure %s", err.Error())
+		return nil
+	}
+	return rout
+}
+
+//CheckWeightSum check all cluster weight sum
+func CheckWeightSum(clusters []*route.WeightedCluster_ClusterWeight, weight uint32) uint32 {
+	var sum uint32
+	for _, cluster := range clusters {
+		sum += cluster.Weight.GetValue()
+	}
+	if sum >= 100 {
+		return 0
+	}
+	if (sum + weight) > 100 {
+		return 100 - sum
+	}
+	return weight
+}
+
+//CreateRetryPolicy create the retry policy of route, return nil if retry is disabled
+func CreateRetryPolicy(options envoyv2.RainbondPluginOptions) *route.RetryPolicy {
+	if options.NumRetries == 0 {
+		return nil
+	}
+	retryPolicy := &route.RetryPolicy{
+		RetryOn:    options.RetryOn,
+		NumRetries: envoyv2.ConversionUInt32(options.NumRetries),
+	}
+	if options.PerTryTimeoutMS > 0 {
+		retryPolicy.PerTryTimeout = envoyv2.ConverTimeDurationMS(options.PerTryTimeoutMS)
+	}
+	if err := retryPolicy.Validate(); err != nil {
+		logrus.Errorf("validate route retry policy failure %s", err.Error())
+		return nil
+	}
+	return retryPolicy
+}
+
+//CreateHTTPFault create the fault injection config of route, return nil if fault injection is disabled
+func CreateHTTPFault(options envoyv2.RainbondPluginOptions) *http_fault.HTTPFault {
+	var fault http_fault.HTTPFault
+	if options.FaultDelayPercent > 0 && options.FaultDelayMS > 0 {
+		fault.Delay = &fault_common.FaultDelay{
+			FaultDelaySecifier: &fault_common.FaultDelay_FixedDelay{
+				FixedDelay: envoyv2.ConverTimeDurationMS(options.FaultDelayMS),
+			},
+			Percentage: &_type.FractionalPercent{
+				Numerator:   options.FaultDelayPercent,
+				Denominator: _type.FractionalPercent_HUNDRED,
+			},
+		}
+	}
+	if options.FaultAbortPercent > 0 {
+		fault.Abort = &http_fault.FaultAbort{
+			ErrorType: &http_fault.FaultAbort_HttpStatus{
+				HttpStatus: options.FaultAbortStatus,
+			},
+			Percentage: &_type.FractionalPercent{
+				Numerator:   options.FaultAbortPercent,
+				Denominator: _type.FractionalPercent_HUNDRED,
+			},
+		}
+	}
+	if fault.Delay == nil && fault.Abort == nil {
+		return nil
+	}
+	if err := fault.Validate(); err != nil {
+		logrus.Errorf("validate http fault config failure %s", err.Error())
+		return nil
+	}
+	return &fault
+}
+
+//ApplyRoutePolicy applies the timeout, retry and fault injection options to the route
+func ApplyRoutePolicy(r *route.Route, options envoyv2.RainbondPluginOptions) *route.Route {
+	if r == nil {
+		return nil
+	}
+	if action, ok := r.Action.(*route.Route_Route); ok {
+		if options.TimeoutMS > 0 {
+			action.Route.Timeout = envoyv2.ConverTimeDurationMS(options.TimeoutMS)
+		}
+		action.Route.RetryPolicy = CreateRetryPolicy(options)
+	}
+	if fault := CreateHTTPFault(options); fault != nil {
+		if r.TypedPerFilterConfig == nil {
+			r.TypedPerFilterConfig = make(map[string]*any.Any)
+		}
+		r.TypedPerFilterConfig[Fault] = envoyv2.Message2Any(fault)
+	}
+	return r
+}
+
+func hasRouteFilterConfig(filterName string, virtualHosts ...*route.VirtualHost) bool {
+	for _, vh := range virtualHosts {
+		for _, r := range vh.Routes {
+			if _, ok := r.TypedPerFilterConfig[filterName]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//CreateHeaderMatcher create http route config header matcher
+func CreateHeaderMatcher(header v1.Header) *route.HeaderMatcher {
+	if header.Name == "" {
+		return nil
+	}
+	headerMatcher := &route.HeaderMatcher{
+		Name: header.Name,
+		HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{
+			PrefixMatch: header.Value,
+		},
+	}
+	if err := headerMatcher.Validate(); err != nil {
+		logrus.Errorf("route http header(%s) matcher config validate failure %s", header.Name, err.Error())
+		return nil
+	}
+	return headerMatcher
+}
+
+//CreateADSConfigSource create the config source which fetches the resources over the aggregated stream
+func CreateADSConfigSource() *core.ConfigSource {
+	return &core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{
+			Ads: &core.AggregatedConfigSource{},
+		},
+		ResourceApiVersion: core.ApiVersion_V3,
+	}
+}
+
+//CreateEDSClusterConfig create ads eds cluster config
+func CreateEDSClusterConfig(serviceName string) *cluster.Cluster_EdsClusterConfig {
+	edsClusterConfig := &cluster.Cluster_EdsClusterConfig{
+		EdsConfig:   CreateADSConfigSource(),
+		ServiceName: serviceName,
+	}
+	if err := edsClusterConfig.Validate(); err != nil {
+		logrus.Errorf("validate eds cluster config failure %s", err.Error())
+		return nil
+	}
+	return edsClusterConfig
+}
+
+//ClusterOptions cluster options
+type ClusterOptions struct {
+	Name                     string
+	ServiceName              string
+	ConnectionTimeout        *duration.Duration
+	ClusterType              cluster.Cluster_DiscoveryType
+	MaxRequestsPerConnection *uint32
+	OutlierDetection         *cluster.OutlierDetection
+	CircuitBreakers          *cluster.CircuitBreakers
+	Hosts                    []*core.Address
+	HealthyPanicThreshold    int64
+	TransportSocket          *core.TransportSocket
+	LoadAssignment           *endpoint.ClusterLoadAssignment
+}
+
+//CreateCluster create cluster config
+func CreateCluster(options ClusterOptions) *cluster.Cluster {
+	var edsClusterConfig *cluster.Cluster_EdsClusterConfig
+	if options.ClusterType == cluster.Cluster_EDS {
+		edsClusterConfig = CreateEDSClusterConfig(options.ServiceName)
+		if edsClusterConfig == nil {
+			logrus.Errorf("create eds cluster config failure")
+			return nil
+		}
+	}
+	c := &cluster.Cluster{
+		Name:                 options.Name,
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: options.ClusterType},
+		ConnectTimeout:       options.ConnectionTimeout,
+		LbPolicy:             cluster.Cluster_ROUND_ROBIN,
+		EdsClusterConfig:     edsClusterConfig,
+		OutlierDetection:     options.OutlierDetection,
+		CircuitBreakers:      options.CircuitBreakers,
+		TransportSocket:      options.TransportSocket,
+		CommonLbConfig: &cluster.Cluster_CommonLbConfig{
+			HealthyPanicThreshold: &_type.Percent{Value: float64(options.HealthyPanicThreshold) / 100},
+		},
+	}
+	if options.LoadAssignment != nil {
+		c.LoadAssignment = options.LoadAssignment
+	} else if len(options.Hosts) > 0 {
+		// the hosts of cluster is removed in v3, the static hosts are assigned by load assignment
+		c.LoadAssignment = CreateStaticLoadAssignment(options.Name, options.Hosts...)
+	}
+	if options.MaxRequestsPerConnection != nil {
+		c.MaxRequestsPerConnection = envoyv2.ConversionUInt32(*options.MaxRequestsPerConnection)
+	}
+	if err := c.Validate(); err != nil {
+		logrus.Errorf("validate cluster config failure %s", err.Error())
+		return nil
+	}
+	return c
+}
+
+//CreateStaticLoadAssignment create the load assignment of static hosts
+func CreateStaticLoadAssignment(clusterName string, hosts ...*core.Address) *endpoint.ClusterLoadAssignment {
+	var lbe []*endpoint.LbEndpoint
+	for _, host := range hosts {
+		lbe = append(lbe, &endpoint.LbEndpoint{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+				Endpoint: &endpoint.Endpoint{Address: host},
+			},
+		})
+	}
+	return &endpoint.ClusterLoadAssignment{
+		ClusterName: clusterName,
+		Endpoints:   []*endpoint.LocalityLbEndpoints{&endpoint.LocalityLbEndpoints{LbEndpoints: lbe}},
+	}
+}
+
+//CreateDNSLoadAssignment create dns loadAssignment
+func CreateDNSLoadAssignment(serviceAlias, namespace, domain string, service *corev1.Service) *endpoint.ClusterLoadAssignment {
+	destServiceAlias := envoyv2.GetServiceAliasByService(service)
+	if destServiceAlias == "" {
+		logrus.Errorf("service alias is empty in k8s service %s", service.Name)
+		return nil
+	}
+	clusterName := fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, destServiceAlias, service.Spec.Ports[0].Port)
+	protocol := service.Labels["port_protocol"]
+	port := service.Spec.Ports[0].Port
+	cla := &endpoint.ClusterLoadAssignment{
+		ClusterName: clusterName,
+		Endpoints: []*endpoint.LocalityLbEndpoints{
+			&endpoint.LocalityLbEndpoints{
+				LbEndpoints: []*endpoint.LbEndpoint{
+					&endpoint.LbEndpoint{
+						HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+							Endpoint: &endpoint.Endpoint{
+								Address:           CreateSocketAddress(protocol, domain, uint32(port)),
+								HealthCheckConfig: &endpoint.Endpoint_HealthCheckConfig{PortValue: uint32(port)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := cla.Validate(); err != nil {
+		logrus.Errorf("endpoints discover validate failure %s", err.Error())
+	}
+	return cla
+}
@@ -0,0 +1,116 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package v3
+
+import (
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/sirupsen/logrus"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	local_rate_limit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/local_ratelimit/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+)
+
+//CreateTokenBucket create the token bucket of local rate limit
+func CreateTokenBucket(options envoyv2.LocalRateLimitOptions) *_type.TokenBucket {
+	tokensPerFill := options.TokensPerFill
+	if tokensPerFill == 0 {
+		tokensPerFill = options.MaxTokens
+	}
+	fillInterval := options.FillIntervalMS
+	if fillInterval <= 0 {
+		fillInterval = 1000
+	}
+	bucket := &_type.TokenBucket{
+		MaxTokens:     options.MaxTokens,
+		TokensPerFill: envoyv2.ConversionUInt32(tokensPerFill),
+		FillInterval:  envoyv2.ConverTimeDurationMS(fillInterval),
+	}
+	if err := bucket.Validate(); err != nil {
+		logrus.Errorf("validate local rate limit token bucket failure %s", err.Error())
+		return nil
+	}
+	return bucket
+}
+
+//CreateNetworkLocalRateLimit create the network local rate limit filter, return nil if the limit is disabled
+func CreateNetworkLocalRateLimit(statPrefix string, options envoyv2.LocalRateLimitOptions) *listener.Filter {
+	if !options.Enable() {
+		return nil
+	}
+	limit := &local_rate_limit.LocalRateLimit{
+		StatPrefix:  statPrefix,
+		TokenBucket: CreateTokenBucket(options),
+	}
+	if err := limit.Validate(); err != nil {
+		logrus.Errorf("validate network local rate limit config failure %s", err.Error())
+		return nil
+	}
+	return &listener.Filter{
+		Name:       envoyv2.NetworkLocalRateLimit,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: envoyv2.Message2Any(limit)},
+	}
+}
+
+//ApplyNetworkLocalRateLimit puts the network local rate limit filter in front of all the filter chains of the listener
+func ApplyNetworkLocalRateLimit(l *listener.Listener, statPrefix string, options envoyv2.LocalRateLimitOptions) *listener.Listener {
+	if l == nil {
+		return nil
+	}
+	filter := CreateNetworkLocalRateLimit(statPrefix, options)
+	if filter == nil {
+		return l
+	}
+	for _, chain := range l.FilterChains {
+		chain.Filters = append([]*listener.Filter{filter}, chain.Filters...)
+	}
+	return l
+}
+
+//ApplyRouteLocalRateLimit applies the local rate limit to the route, every route has its own token bucket
+func ApplyRouteLocalRateLimit(r *route.Route, statPrefix string, options envoyv2.LocalRateLimitOptions) *route.Route {
+	if r == nil || !options.Enable() {
+		return r
+	}
+	// the config is a typed struct of the v3 api in both versions
+	config := envoyv2.CreateHTTPLocalRateLimit(statPrefix, options)
+	if config == nil {
+		return nil
+	}
+	if r.TypedPerFilterConfig == nil {
+		r.TypedPerFilterConfig = make(map[string]*any.Any)
+	}
+	r.TypedPerFilterConfig[envoyv2.HTTPLocalRateLimit] = config
+	return r
+}
+
+//CreateSourceServiceMatcher matches the requests from the calling component, only the mutual tls
+//connections carry the identity of the calling component
+func CreateSourceServiceMatcher(namespace, serviceAlias string) *route.HeaderMatcher {
+	return &route.HeaderMatcher{
+		Name: envoyv2.ClientCertHeader,
+		HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: &matcher.RegexMatcher{
+			EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+			Regex:      envoyv2.SourceServiceRegex(namespace, serviceAlias),
+		}},
+	}
+}
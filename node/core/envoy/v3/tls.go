@@ -0,0 +1,155 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package v3
+
+import (
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sirupsen/logrus"
+
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+)
+
+//CreateSDSSecretConfig create a secret config which fetches the secret over the aggregated stream
+func CreateSDSSecretConfig(name string) *tls.SdsSecretConfig {
+	return &tls.SdsSecretConfig{
+		Name:      name,
+		SdsConfig: CreateADSConfigSource(),
+	}
+}
+
+//CreateMeshCommonTLSContext create the tls context presenting the workload certificate,
+//the peer certificate must be issued by the platform ca and match one of subjectAltNames if not empty
+func CreateMeshCommonTLSContext(subjectAltNames ...string) *tls.CommonTlsContext {
+	var sanMatchers []*matcher.StringMatcher
+	for _, san := range subjectAltNames {
+		sanMatchers = append(sanMatchers, &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Exact{Exact: san},
+		})
+	}
+	return &tls.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{CreateSDSSecretConfig(envoyv2.MeshCertSecretName)},
+		ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext:         &tls.CertificateValidationContext{MatchSubjectAltNames: sanMatchers},
+				ValidationContextSdsSecretConfig: CreateSDSSecretConfig(envoyv2.MeshValidationSecretName),
+			},
+		},
+	}
+}
+
+//CreateTLSTransportSocket wraps the tls context into a transport socket, the tls context of v2 is replaced by it
+func CreateTLSTransportSocket(tlsContext proto.Message) *core.TransportSocket {
+	return &core.TransportSocket{
+		Name: TransportSocketTLS,
+		ConfigType: &core.TransportSocket_TypedConfig{
+			TypedConfig: envoyv2.Message2Any(tlsContext),
+		},
+	}
+}
+
+//CreateUpstreamMTLSContext create the tls context of the cluster which originates mutual tls to a mesh component
+func CreateUpstreamMTLSContext(sni string, subjectAltNames ...string) *tls.UpstreamTlsContext {
+	tlsContext := &tls.UpstreamTlsContext{
+		CommonTlsContext: CreateMeshCommonTLSContext(subjectAltNames...),
+		Sni:              sni,
+	}
+	if err := tlsContext.Validate(); err != nil {
+		logrus.Errorf("validate upstream tls context failure %s", err.Error())
+		return nil
+	}
+	return tlsContext
+}
+
+//CreateDownstreamMTLSContext create the tls context of the listener which terminates mutual tls
+func CreateDownstreamMTLSContext() *tls.DownstreamTlsContext {
+	tlsContext := &tls.DownstreamTlsContext{
+		CommonTlsContext:         CreateMeshCommonTLSContext(),
+		RequireClientCertificate: &wrappers.BoolValue{Value: true},
+	}
+	if err := tlsContext.Validate(); err != nil {
+		logrus.Errorf("validate downstream tls context failure %s", err.Error())
+		return nil
+	}
+	return tlsContext
+}
+
+//ApplyDownstreamMTLS makes the listener terminate mutual tls.
+//In strict mode the plaintext connections are rejected, otherwise the listener detects
+//the transport and serves both the mutual tls and the plaintext connections.
+func ApplyDownstreamMTLS(l *listener.Listener, strict bool) *listener.Listener {
+	if l == nil || len(l.FilterChains) == 0 {
+		return l
+	}
+	tlsContext := CreateDownstreamMTLSContext()
+	if tlsContext == nil {
+		return nil
+	}
+	filters := l.FilterChains[0].Filters
+	tlsChain := &listener.FilterChain{
+		TransportSocket: CreateTLSTransportSocket(tlsContext),
+		Filters:         filters,
+	}
+	if strict {
+		l.FilterChains = []*listener.FilterChain{tlsChain}
+	} else {
+		tlsChain.FilterChainMatch = &listener.FilterChainMatch{TransportProtocol: "tls"}
+		l.ListenerFilters = append(l.ListenerFilters, &listener.ListenerFilter{
+			Name: TLSInspector,
+		})
+		l.FilterChains = []*listener.FilterChain{
+			tlsChain,
+			&listener.FilterChain{Filters: filters},
+		}
+	}
+	if err := l.Validate(); err != nil {
+		logrus.Errorf("validate mutual tls listener config failure %s", err.Error())
+		return nil
+	}
+	return l
+}
+
+//CreateTLSCertificateSecret create the sds secret of a certificate
+func CreateTLSCertificateSecret(name string, certPEM, keyPEM []byte) *tls.Secret {
+	return &tls.Secret{
+		Name: name,
+		Type: &tls.Secret_TlsCertificate{
+			TlsCertificate: &tls.TlsCertificate{
+				CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: certPEM}},
+				PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: keyPEM}},
+			},
+		},
+	}
+}
+
+//CreateValidationContextSecret create the sds secret of a trusted ca
+func CreateValidationContextSecret(name string, caPEM []byte) *tls.Secret {
+	return &tls.Secret{
+		Name: name,
+		Type: &tls.Secret_ValidationContext{
+			ValidationContext: &tls.CertificateValidationContext{
+				TrustedCa: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: caPEM}},
+			},
+		},
+	}
+}
@@ -0,0 +1,431 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/goodrain/rainbond/node/api/model"
+	"github.com/goodrain/rainbond/node/core/store"
+	"github.com/goodrain/rainbond/node/kubecache"
+	"github.com/goodrain/rainbond/node/utils"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	drainPath = "/rainbond/drains"
+	//DefaultDrainTimeout the default seconds waiting for each pod to be evicted and replaced
+	DefaultDrainTimeout = 600
+	drainInterval       = time.Second * 5
+)
+
+var errDrainChanged = errors.New("the drain is changed by the others")
+
+var errDrainLockLost = errors.New("the drain lock is lost")
+
+//DrainNode cordons the node and evicts its pods one by one, the stateless components
+//are evicted first and the stateful ones last. The drain runs in background, its progress
+//is returned by GetDrainStatus. The drain in progress is returned if there is one.
+//The pods not managed by a controller are lost once evicted, the drain is refused unless force is true.
+func (n *NodeService) DrainNode(nodeID string, timeout int, force bool) (*model.DrainStatus, *utils.APIHandleError) {
+	status, _, err := getDrainStatus(nodeID)
+	if err != nil {
+		return nil, utils.CreateAPIHandleError(500, fmt.Errorf("get drain status: %v", err))
+	}
+	if status != nil && !status.Phase.Finished() {
+		return status, nil
+	}
+	pods, err := n.kubecli.GetPodsByNodes(nodeID)
+	if err != nil {
+		return nil, utils.CreateAPIHandleError(500, fmt.Errorf("get pods of node %s: %v", nodeID, err))
+	}
+	evictPods := drainPods(pods, n.c.RbdNamespace)
+	if unmanaged := unmanagedPods(evictPods); len(unmanaged) > 0 && !force {
+		return nil, utils.CreateAPIHandleError(400, fmt.Errorf("pods %s are not managed by a controller, they will not be recreated once evicted, drain the node with force to evict them", strings.Join(unmanaged, ", ")))
+	}
+	if apierr := n.CordonNode(nodeID, true); apierr != nil {
+		return nil, apierr
+	}
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	now := time.Now()
+	status = &model.DrainStatus{
+		NodeID:     nodeID,
+		Phase:      model.DrainPhaseDraining,
+		Timeout:    timeout,
+		Pods:       evictPods,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	data, _ := json.Marshal(status)
+	if _, err := store.DefalutClient.Put(drainPath+"/"+nodeID, string(data)); err != nil {
+		return nil, utils.CreateAPIHandleError(500, fmt.Errorf("save drain status: %v", err))
+	}
+	logrus.Infof("start draining node %s, %d pods to be evicted", nodeID, len(status.Pods))
+	go n.runDrain(nodeID)
+	return status, nil
+}
+
+//GetDrainStatus returns the progress of draining the node
+func (n *NodeService) GetDrainStatus(nodeID string) (*model.DrainStatus, *utils.APIHandleError) {
+	status, _, err := getDrainStatus(nodeID)
+	if err != nil {
+		return nil, utils.CreateAPIHandleError(500, fmt.Errorf("get drain status: %v", err))
+	}
+	if status == nil {
+		return nil, utils.CreateAPIHandleError(404, fmt.Errorf("node %s is not drained", nodeID))
+	}
+	return status, nil
+}
+
+//CancelDrain stops draining the node, the evicted pods are not moved back and the node is
+//still unschedulable.
+func (n *NodeService) CancelDrain(nodeID string) (*model.DrainStatus, *utils.APIHandleError) {
+	status, apierr := n.GetDrainStatus(nodeID)
+	if apierr != nil {
+		return nil, apierr
+	}
+	if status.Phase.Finished() {
+		return nil, utils.CreateAPIHandleError(400, fmt.Errorf("the drain of node %s is %s", nodeID, status.Phase))
+	}
+	status.Phase = model.DrainPhaseCanceled
+	status.Message = "canceled by the operator"
+	status.UpdateTime = time.Now()
+	data, _ := json.Marshal(status)
+	if _, err := store.DefalutClient.Put(drainPath+"/"+nodeID, string(data)); err != nil {
+		return nil, utils.CreateAPIHandleError(500, fmt.Errorf("save drain status: %v", err))
+	}
+	return status, nil
+}
+
+func getDrainStatus(nodeID string) (*model.DrainStatus, int64, error) {
+	res, err := store.DefalutClient.Get(drainPath + "/" + nodeID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, 0, nil
+	}
+	var status model.DrainStatus
+	if err := json.Unmarshal(res.Kvs[0].Value, &status); err != nil {
+		return nil, 0, err
+	}
+	return &status, res.Kvs[0].ModRevision, nil
+}
+
+// resumeDrains resumes the drains interrupted by the restarts of the node services
+func (n *NodeService) resumeDrains() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		res, err := store.DefalutClient.Get(drainPath+"/", clientv3.WithPrefix())
+		if err != nil {
+			logrus.Warningf("list drains: %v", err)
+		} else {
+			for _, kv := range res.Kvs {
+				var status model.DrainStatus
+				if err := json.Unmarshal(kv.Value, &status); err == nil && !status.Phase.Finished() {
+					go n.runDrain(status.NodeID)
+				}
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// runDrain drains the node if no other node service is draining it, the lock is held
+// with a lease, so the drain is taken over by the others if the current one is gone.
+func (n *NodeService) runDrain(nodeID string) {
+	lease, err := store.DefalutClient.Grant(30)
+	if err != nil {
+		logrus.Errorf("grant lease for draining node %s: %v", nodeID, err)
+		return
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		store.DefalutClient.Revoke(ctx, lease.ID)
+	}()
+	if ok, err := store.DefalutClient.GetLock("/drain/"+nodeID, lease.ID); err != nil || !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keepalive, err := store.DefalutClient.KeepAlive(ctx, lease.ID)
+	if err != nil {
+		logrus.Errorf("keep alive the drain lock of node %s: %v", nodeID, err)
+		return
+	}
+	// the keepalive channel is closed once the lease is lost, the drain may be taken over by the others
+	lockCtx, lockLost := context.WithCancel(ctx)
+	defer lockLost()
+	go func() {
+		for range keepalive {
+		}
+		lockLost()
+	}()
+	status, rev, err := getDrainStatus(nodeID)
+	if err != nil || status == nil || status.Phase.Finished() {
+		return
+	}
+	d := &drainer{ctx: lockCtx, kubecli: n.kubecli, status: status, rev: rev}
+	if err := d.run(); err != nil {
+		if err == errDrainChanged {
+			logrus.Infof("stop draining node %s, it is changed by the others", nodeID)
+			return
+		}
+		if err == errDrainLockLost {
+			logrus.Warningf("stop draining node %s, the drain lock is lost", nodeID)
+			return
+		}
+		logrus.Errorf("drain node %s: %v", nodeID, err)
+		d.status.Phase = model.DrainPhaseFailed
+		d.status.Message = err.Error()
+		d.save()
+		return
+	}
+	logrus.Infof("node %s is drained", nodeID)
+}
+
+type drainer struct {
+	// ctx is done if the drain lock is lost
+	ctx     context.Context
+	kubecli kubecache.KubeClient
+	status  *model.DrainStatus
+	// the revision of the drain status, it is changed if the drain is canceled
+	rev int64
+}
+
+func (d *drainer) run() error {
+	for _, pod := range d.status.Pods {
+		if pod.Status == model.DrainPodDone {
+			continue
+		}
+		if err := d.drainPod(pod); err != nil {
+			return err
+		}
+	}
+	d.status.Phase = model.DrainPhaseCompleted
+	d.status.Message = fmt.Sprintf("%d pods are evicted", d.status.Evicted)
+	return d.save()
+}
+
+// drainPod evicts the pod, and waits for it terminated and the replacement ready
+func (d *drainer) drainPod(pod *model.DrainPod) error {
+	deadline := time.Now().Add(time.Duration(d.status.Timeout) * time.Second)
+	pod.Status = model.DrainPodEvicting
+	if err := d.save(); err != nil {
+		return err
+	}
+	for {
+		err := d.kubecli.EvictPod(pod.Namespace, pod.Name, pod.UID)
+		// the pod is gone or replaced by another one with the same name, such as the stateful pod
+		if err == nil || apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("evict pod %s/%s: the pod disruption budget is not satisfied in %d seconds", pod.Namespace, pod.Name, d.status.Timeout)
+		}
+		if pod.Message == "" {
+			pod.Message = "blocked by the pod disruption budget"
+			if err := d.save(); err != nil {
+				return err
+			}
+		}
+		if err := d.wait(); err != nil {
+			return err
+		}
+	}
+
+	pod.Status = model.DrainPodWaiting
+	pod.Message = ""
+	if err := d.save(); err != nil {
+		return err
+	}
+	for {
+		p, err := d.kubecli.GetPod(pod.Namespace, pod.Name)
+		if apierrors.IsNotFound(err) || (err == nil && string(p.UID) != pod.UID) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("get pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pod %s/%s is not terminated in %d seconds", pod.Namespace, pod.Name, d.status.Timeout)
+		}
+		if err := d.wait(); err != nil {
+			return err
+		}
+	}
+	for pod.OwnerKind != "" {
+		ready, err := d.kubecli.ReplicasReady(pod.Namespace, pod.OwnerKind, pod.OwnerName)
+		if apierrors.IsNotFound(err) || ready {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("get %s %s/%s: %v", pod.OwnerKind, pod.Namespace, pod.OwnerName, err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the replacement of pod %s/%s is not ready in %d seconds", pod.Namespace, pod.Name, d.status.Timeout)
+		}
+		if err := d.wait(); err != nil {
+			return err
+		}
+	}
+
+	pod.Status = model.DrainPodDone
+	d.status.Evicted++
+	return d.save()
+}
+
+// wait waits for the next check, it returns errDrainChanged if the drain is canceled,
+// and errDrainLockLost if the drain lock is lost
+func (d *drainer) wait() error {
+	select {
+	case <-d.ctx.Done():
+		return errDrainLockLost
+	case <-time.After(drainInterval):
+	}
+	res, err := store.DefalutClient.Get(drainPath + "/" + d.status.NodeID)
+	if err != nil {
+		return nil
+	}
+	if len(res.Kvs) == 0 || res.Kvs[0].ModRevision != d.rev {
+		return errDrainChanged
+	}
+	return nil
+}
+
+// save saves the drain status unless it is changed by the others or the drain lock is lost
+func (d *drainer) save() error {
+	if d.ctx.Err() != nil {
+		return errDrainLockLost
+	}
+	d.status.UpdateTime = time.Now()
+	data, _ := json.Marshal(d.status)
+	res, err := store.DefalutClient.PutWithModRev(drainPath+"/"+d.status.NodeID, string(data), d.rev)
+	if err != nil {
+		if err == utils.ErrValueMayChanged {
+			return errDrainChanged
+		}
+		return err
+	}
+	d.rev = res.Header.Revision
+	return nil
+}
+
+const (
+	drainKindStateless = "stateless"
+	drainKindPlatform  = "platform"
+	drainKindStateful  = "stateful"
+)
+
+// drainPods returns the pods to be evicted in order. The stateless components are evicted
+// first, then the platform components, and the stateful components last, the stateful pods
+// of the same component are evicted from the highest ordinal like scaling down.
+// The mirror pods, the daemon set pods and the finished pods are not evicted.
+func drainPods(pods []corev1.Pod, rbdNamespace string) []*model.DrainPod {
+	var result []*model.DrainPod
+	for i := range pods {
+		pod := pods[i]
+		if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		dp := &model.DrainPod{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			UID:       string(pod.UID),
+			Kind:      drainKindStateless,
+			Status:    model.DrainPodPending,
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil {
+			if owner.Kind == "DaemonSet" {
+				continue
+			}
+			dp.OwnerKind, dp.OwnerName = owner.Kind, owner.Name
+		}
+		if dp.OwnerKind == "StatefulSet" {
+			dp.Kind = drainKindStateful
+		} else if pod.Namespace == rbdNamespace {
+			dp.Kind = drainKindPlatform
+		}
+		result = append(result, dp)
+	}
+	rank := func(p *model.DrainPod) int {
+		switch p.Kind {
+		case drainKindPlatform:
+			return 1
+		case drainKindStateful:
+			// the stateful platform components such as the database go at the very last
+			if p.Namespace == rbdNamespace {
+				return 3
+			}
+			return 2
+		}
+		return 0
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		if a.Kind == drainKindStateful && a.Namespace == b.Namespace && a.OwnerName == b.OwnerName {
+			return podOrdinal(a.Name) > podOrdinal(b.Name)
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	return result
+}
+
+// unmanagedPods returns the pods which are not managed by a controller
+func unmanagedPods(pods []*model.DrainPod) []string {
+	var names []string
+	for _, pod := range pods {
+		if pod.OwnerKind == "" {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return names
+}
+
+func podOrdinal(name string) int {
+	ordinal, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
@@ -0,0 +1,81 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/goodrain/rainbond/node/api/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainPods(t *testing.T) {
+	controller := true
+	newPod := func(namespace, name, ownerKind, ownerName string) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+		}
+		return pod
+	}
+	mirror := newPod("kube-system", "kube-proxy-node1", "", "")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}
+	finished := newPod("t1", "job-x", "Job", "job")
+	finished.Status.Phase = corev1.PodSucceeded
+	pods := []corev1.Pod{
+		newPod("rbd-system", "rbd-db-0", "StatefulSet", "rbd-db"),
+		newPod("t1", "mysql-1", "StatefulSet", "mysql"),
+		newPod("t1", "mysql-10", "StatefulSet", "mysql"),
+		newPod("rbd-system", "rbd-api-xx", "ReplicaSet", "rbd-api-x"),
+		newPod("t1", "web-xx", "ReplicaSet", "web-x"),
+		newPod("t1", "mysql-2", "StatefulSet", "mysql"),
+		newPod("rbd-system", "rbd-node-xx", "DaemonSet", "rbd-node"),
+		mirror,
+		finished,
+		newPod("t1", "bare", "", ""),
+	}
+	var names []string
+	for _, pod := range drainPods(pods, "rbd-system") {
+		if pod.Status != model.DrainPodPending {
+			t.Errorf("expected pending pod, but returned %s", pod.Status)
+		}
+		names = append(names, pod.Namespace+"/"+pod.Name+"/"+pod.Kind)
+	}
+	expected := []string{
+		"t1/bare/stateless",
+		"t1/web-xx/stateless",
+		"rbd-system/rbd-api-xx/platform",
+		"t1/mysql-10/stateful",
+		"t1/mysql-2/stateful",
+		"t1/mysql-1/stateful",
+		"rbd-system/rbd-db-0/stateful",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, but returned %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, but returned %v", expected, names)
+		}
+	}
+	if unmanaged := unmanagedPods(drainPods(pods, "rbd-system")); len(unmanaged) != 1 || unmanaged[0] != "t1/bare" {
+		t.Errorf("expected the bare pod is not managed by a controller, but returned %v", unmanaged)
+	}
+}
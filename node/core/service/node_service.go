@@ -63,11 +63,13 @@ func CreateNodeService(c *option.Conf, nodecluster *node.Cluster, kubecli kubeca
 	}); err != nil {
 		logrus.Errorf("create event manager faliure")
 	}
-	return &NodeService{
+	ns := &NodeService{
 		c:           c,
 		nodecluster: nodecluster,
 		kubecli:     kubecli,
 	}
+	go ns.resumeDrains()
+	return ns
 }
 
 //AddNode add node
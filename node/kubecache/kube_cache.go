@@ -91,6 +91,9 @@ type KubeClient interface {
 	CordonOrUnCordon(nodeName string, drain bool) (*v1.Node, error)
 	UpdateLabels(nodeName string, labels map[string]string) (*v1.Node, error)
 	DeleteOrEvictPodsSimple(nodeName string) error
+	EvictPod(namespace, name, uid string) error
+	GetPod(namespace, name string) (*v1.Pod, error)
+	ReplicasReady(namespace, kind, name string) (bool, error)
 	GetPodsByNodes(nodeName string) (pods []v1.Pod, err error)
 	GetEndpoints(namespace string, selector labels.Selector) ([]*v1.Endpoints, error)
 	GetServices(namespace string, selector labels.Selector) ([]*v1.Service, error)
@@ -178,6 +181,51 @@ func (k *kubeClient) DeleteOrEvictPodsSimple(nodeName string) error {
 	}
 	return nil
 }
+//EvictPod evicts the pod through the eviction api, which respects the pod disruption budgets.
+//A TooManyRequests error is returned if the eviction would violate the budget, and a Conflict error
+//is returned if the pod is replaced by another one with the same name but a different uid.
+func (k *kubeClient) EvictPod(namespace, name, uid string) error {
+	policyGroupVersion, err := k.SupportEviction()
+	if err != nil {
+		return err
+	}
+	if policyGroupVersion == "" {
+		return fmt.Errorf("the server can not support eviction subresource")
+	}
+	return k.evictPod(v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(uid)}}, policyGroupVersion)
+}
+
+//GetPod get pod from the api server
+func (k *kubeClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	return k.kubeclient.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+}
+
+//ReplicasReady checks whether all the replicas of the pod controller are ready,
+//the controllers which are not replicated are always ready.
+func (k *kubeClient) ReplicasReady(namespace, kind, name string) (bool, error) {
+	switch kind {
+	case "ReplicaSet":
+		rs, err := k.kubeclient.AppsV1().ReplicaSets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return rs.Spec.Replicas == nil || rs.Status.ReadyReplicas >= *rs.Spec.Replicas, nil
+	case "StatefulSet":
+		sts, err := k.kubeclient.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return sts.Spec.Replicas == nil || sts.Status.ReadyReplicas >= *sts.Spec.Replicas, nil
+	case "ReplicationController":
+		rc, err := k.kubeclient.CoreV1().ReplicationControllers(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return rc.Spec.Replicas == nil || rc.Status.ReadyReplicas >= *rc.Spec.Replicas, nil
+	}
+	return true, nil
+}
+
 func (k *kubeClient) GetPodsByNodes(nodeName string) (pods []v1.Pod, err error) {
 	podList, err := k.kubeclient.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
 		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String()})
@@ -193,6 +241,10 @@ func (k *kubeClient) GetPodsByNodes(nodeName string) (pods []v1.Pod, err error)
 //evictPod 驱离POD
 func (k *kubeClient) evictPod(pod v1.Pod, policyGroupVersion string) error {
 	deleteOptions := &metav1.DeleteOptions{}
+	if pod.UID != "" {
+		// do not evict the pod which replaces the original one
+		deleteOptions.Preconditions = &metav1.Preconditions{UID: &pod.UID}
+	}
 	eviction := &v1beta1.Eviction{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: policyGroupVersion,
@@ -294,7 +346,8 @@ func (k *kubeClient) evictPods(pods []v1.Pod, policyGroupVersion string, getPodF
 				err = k.evictPod(pod, policyGroupVersion)
 				if err == nil {
 					break
-				} else if apierrors.IsNotFound(err) {
+				} else if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+					// the pod is gone or replaced by another one
 					doneCh <- true
 					return
 				} else if apierrors.IsTooManyRequests(err) {
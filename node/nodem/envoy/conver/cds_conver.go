@@ -110,6 +110,10 @@ func upstreamClusters(serviceAlias, namespace string, dependsServices []*api_mod
 			}
 		} else {
 			clusterOption.ClusterType = v2.Cluster_EDS
+			// the dependent component serves mutual tls on its inbound listener
+			if mode := service.Annotations["mtls_mode"]; mode != "" && service.Labels["port_protocol"] != "udp" {
+				clusterOption.TLSContext = envoyv2.CreateUpstreamMTLSContext("", envoyv2.MeshSPIFFEID(namespace, destServiceAlias))
+			}
 		}
 		clusterOption.HealthyPanicThreshold = options.HealthyPanicThreshold
 		clusterOption.ConnectionTimeout = envoyv2.ConverTimeDuration(options.ConnectionTimeout)
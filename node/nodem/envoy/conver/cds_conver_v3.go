@@ -0,0 +1,145 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	api_model "github.com/goodrain/rainbond/api/model"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	envoyv3 "github.com/goodrain/rainbond/node/core/envoy/v3"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+//OneNodeClusterV3 conver v3 cluster of on envoy node
+func OneNodeClusterV3(serviceAlias, namespace string, configs *corev1.ConfigMap, services []*corev1.Service) ([]types.Resource, error) {
+	resources, _, err := GetPluginConfigs(configs)
+	if err != nil {
+		return nil, err
+	}
+	var clusters []types.Resource
+	if resources.BaseServices != nil && len(resources.BaseServices) > 0 {
+		for _, cl := range upstreamClustersV3(serviceAlias, namespace, resources.BaseServices, services) {
+			if err := cl.Validate(); err != nil {
+				logrus.Errorf("cluster validate failure %s", err.Error())
+			} else {
+				clusters = append(clusters, cl)
+			}
+		}
+	}
+	if resources.BasePorts != nil && len(resources.BasePorts) > 0 {
+		for _, cl := range downstreamClustersV3(serviceAlias, namespace, resources.BasePorts) {
+			if err := cl.Validate(); err != nil {
+				logrus.Errorf("cluster validate failure %s", err.Error())
+			} else {
+				clusters = append(clusters, cl)
+			}
+		}
+	}
+	if len(clusters) == 0 {
+		logrus.Warningf("configmap name: %s; plugin-config: %s; create clusters zero length", configs.Name, configs.Data["plugin-config"])
+	}
+	return clusters, nil
+}
+
+//upstreamClustersV3 is the v3 version of upstreamClusters
+func upstreamClustersV3(serviceAlias, namespace string, dependsServices []*api_model.BaseService, services []*corev1.Service) (cdsClusters []*clusterv3.Cluster) {
+	var clusterConfig = make(map[string]*api_model.BaseService, len(dependsServices))
+	for i, dService := range dependsServices {
+		depServiceIndex := fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, dService.DependServiceAlias, dService.Port)
+		clusterConfig[depServiceIndex] = dependsServices[i]
+	}
+	for _, service := range services {
+		inner, ok := service.Labels["service_type"]
+		destServiceAlias := GetServiceAliasByService(service)
+		port := service.Spec.Ports[0]
+		if !ok || inner != "inner" {
+			continue
+		}
+		relPort, _ := strconv.Atoi(service.Labels["origin_port"])
+		if relPort == 0 {
+			relPort = int(port.TargetPort.IntVal)
+		}
+		options := envoyv2.GetOptionValues(nil)
+		if dService, ok := clusterConfig[fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, destServiceAlias, relPort)]; ok {
+			options = envoyv2.GetOptionValues(dService.Options)
+		}
+		var clusterOption envoyv3.ClusterOptions
+		clusterOption.Name = fmt.Sprintf("%s_%s_%s_%v", namespace, serviceAlias, destServiceAlias, port.Port)
+		clusterOption.OutlierDetection = envoyv3.CreatOutlierDetection(options)
+		clusterOption.CircuitBreakers = envoyv3.CreateCircuitBreaker(options)
+		clusterOption.ServiceName = clusterOption.Name
+		if domain, ok := service.Annotations["domain"]; ok && domain != "" {
+			clusterOption.ClusterType = clusterv3.Cluster_LOGICAL_DNS
+			clusterOption.LoadAssignment = envoyv3.CreateDNSLoadAssignment(serviceAlias, namespace, domain, service)
+			if strings.HasPrefix(domain, "https://") {
+				splitDomain := strings.Split(domain, "https://")
+				if len(splitDomain) == 2 {
+					clusterOption.TransportSocket = envoyv3.CreateTLSTransportSocket(&tlsv3.UpstreamTlsContext{Sni: splitDomain[1]})
+				}
+			}
+		} else {
+			clusterOption.ClusterType = clusterv3.Cluster_EDS
+			// the dependent component serves mutual tls on its inbound listener
+			if mode := service.Annotations["mtls_mode"]; mode != "" && service.Labels["port_protocol"] != "udp" {
+				if tlsContext := envoyv3.CreateUpstreamMTLSContext("", envoyv2.MeshSPIFFEID(namespace, destServiceAlias)); tlsContext != nil {
+					clusterOption.TransportSocket = envoyv3.CreateTLSTransportSocket(tlsContext)
+				}
+			}
+		}
+		clusterOption.HealthyPanicThreshold = options.HealthyPanicThreshold
+		clusterOption.ConnectionTimeout = envoyv2.ConverTimeDuration(options.ConnectionTimeout)
+		cluster := envoyv3.CreateCluster(clusterOption)
+		if cluster != nil {
+			cdsClusters = append(cdsClusters, cluster)
+		}
+	}
+	return
+}
+
+//downstreamClustersV3 is the v3 version of downstreamClusters
+func downstreamClustersV3(serviceAlias, namespace string, ports []*api_model.BasePort) (cdsClusters []*clusterv3.Cluster) {
+	for i := range ports {
+		port := ports[i]
+		address := envoyv3.CreateSocketAddress(port.Protocol, "127.0.0.1", uint32(port.Port))
+		clusterName := fmt.Sprintf("%s_%s_%v", namespace, serviceAlias, port.Port)
+		option := envoyv2.GetOptionValues(port.Options)
+		cluster := envoyv3.CreateCluster(envoyv3.ClusterOptions{
+			Name:                     clusterName,
+			ConnectionTimeout:        envoyv2.ConverTimeDuration(option.ConnectionTimeout),
+			ClusterType:              clusterv3.Cluster_STATIC,
+			CircuitBreakers:          envoyv3.CreateCircuitBreaker(option),
+			OutlierDetection:         envoyv3.CreatOutlierDetection(option),
+			MaxRequestsPerConnection: option.MaxRequestsPerConnection,
+			Hosts:                    []*corev3.Address{address},
+			HealthyPanicThreshold:    option.HealthyPanicThreshold,
+		})
+		if cluster != nil {
+			cdsClusters = append(cdsClusters, cluster)
+		}
+	}
+	return
+}
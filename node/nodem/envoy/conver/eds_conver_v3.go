@@ -0,0 +1,110 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conver
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	envoyv3 "github.com/goodrain/rainbond/node/core/envoy/v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+//OneNodeClusterLoadAssignmentV3 one envoy node v3 endpoints
+func OneNodeClusterLoadAssignmentV3(serviceAlias, namespace string, endpoints []*corev1.Endpoints, services []*corev1.Service) (clusterLoadAssignment []types.Resource) {
+	for i := range services {
+		if domain, ok := services[i].Annotations["domain"]; ok && domain != "" {
+			logrus.Warnf("service[sid: %s] endpoint id domain endpoint[domain: %s], use dns cluster type, do not create eds", services[i].GetUID(), domain)
+			continue
+		}
+		service := services[i]
+		destServiceAlias := GetServiceAliasByService(service)
+		if destServiceAlias == "" {
+			logrus.Errorf("service alias is empty in k8s service %s", service.Name)
+			continue
+		}
+		clusterName := fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, destServiceAlias, service.Spec.Ports[0].Port)
+		var lendpoints []*endpointv3.LocalityLbEndpoints
+		for _, en := range getEndpointsByServiceName(endpoints, service.Name) {
+			var notReadyAddress *corev1.EndpointAddress
+			var notReadyPort *corev1.EndpointPort
+			var notreadyToPort int
+			for _, subset := range en.Subsets {
+				for i, port := range subset.Ports {
+					toport := int(port.Port)
+					if serviceAlias == destServiceAlias {
+						//use real port
+						if origin, err := strconv.Atoi(service.Labels["origin_port"]); err == nil {
+							toport = origin
+						}
+					}
+					if len(subset.Addresses) == 0 && len(subset.NotReadyAddresses) > 0 {
+						notReadyAddress = &subset.NotReadyAddresses[0]
+						notreadyToPort = toport
+						notReadyPort = &subset.Ports[i]
+					}
+					var lbe []*endpointv3.LbEndpoint
+					for _, address := range subset.Addresses {
+						lbe = append(lbe, &endpointv3.LbEndpoint{
+							HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+								Endpoint: &endpointv3.Endpoint{
+									Address:           envoyv3.CreateSocketAddress(string(port.Protocol), address.IP, uint32(toport)),
+									HealthCheckConfig: &endpointv3.Endpoint_HealthCheckConfig{PortValue: uint32(toport)},
+								},
+							},
+						})
+					}
+					if len(lbe) > 0 {
+						lendpoints = append(lendpoints, &endpointv3.LocalityLbEndpoints{LbEndpoints: lbe})
+					}
+				}
+			}
+			if len(lendpoints) == 0 && notReadyAddress != nil && notReadyPort != nil {
+				lendpoints = append(lendpoints, &endpointv3.LocalityLbEndpoints{
+					LbEndpoints: []*endpointv3.LbEndpoint{
+						&endpointv3.LbEndpoint{
+							HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+								Endpoint: &endpointv3.Endpoint{
+									Address: envoyv3.CreateSocketAddress(string(notReadyPort.Protocol), notReadyAddress.IP, uint32(notreadyToPort)),
+								},
+							},
+						},
+					},
+				})
+			}
+		}
+		cla := &endpointv3.ClusterLoadAssignment{
+			ClusterName: clusterName,
+			Endpoints:   lendpoints,
+		}
+		if err := cla.Validate(); err != nil {
+			logrus.Errorf("endpoints discover validate failure %s", err.Error())
+		} else {
+			clusterLoadAssignment = append(clusterLoadAssignment, cla)
+		}
+	}
+	if len(clusterLoadAssignment) == 0 {
+		logrus.Warn("create clusterLoadAssignment zero length")
+	}
+	return clusterLoadAssignment
+}
@@ -0,0 +1,161 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conver
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev2 "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/golang/protobuf/jsonpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const goldenPluginConfig = `{
+	"base_ports": [
+		{"service_alias": "app", "service_id": "app-id", "port": 5000, "listen_port": 65301, "protocol": "http",
+			"options": {"OPEN_LIMIT": "false", "LOCAL_LIMIT_MAX_TOKENS": "100", "LOCAL_LIMIT_SOURCES": "web:10,db:5"}},
+		{"service_alias": "app", "service_id": "app-id", "port": 3306, "listen_port": 65302, "protocol": "tcp",
+			"options": {"LOCAL_LIMIT_MAX_TOKENS": "50", "LOCAL_LIMIT_TOKENS_PER_FILL": "10", "LOCAL_LIMIT_FILL_INTERVAL_MS": "500"}}
+	],
+	"base_services": [
+		{"service_alias": "app", "depend_service_alias": "web", "depend_service_id": "web-id", "port": 8080, "protocol": "http",
+			"options": {"Prefix": "/", "Domains": "web", "Weight": "100", "NumRetries": "2", "TimeoutMS": "3000"}},
+		{"service_alias": "app", "depend_service_alias": "db", "depend_service_id": "db-id", "port": 5432, "protocol": "tcp"},
+		{"service_alias": "app", "depend_service_alias": "ext", "depend_service_id": "ext-id", "port": 443, "protocol": "https"}
+	]
+}`
+
+func goldenFixture() (*corev1.ConfigMap, []*corev1.Service) {
+	configs := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-plugin",
+			Namespace:   "tenant",
+			Labels:      map[string]string{"service_alias": "app", "plugin_id": "plugin"},
+			Annotations: map[string]string{"mtls_mode": "PERMISSIVE"},
+		},
+		Data: map[string]string{
+			"plugin-config":  goldenPluginConfig,
+			"plugin-model":   "net-plugin:in-and-out",
+			"tracing-config": `{"sampling_rate": 10, "tags": {"env": "test"}}`,
+		},
+	}
+	newService := func(alias string, port int32, protocol string, annotations map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        alias + "-inner",
+				Namespace:   "tenant",
+				Labels:      map[string]string{"service_type": "inner", "port_protocol": protocol, "service_alias": alias},
+				Annotations: annotations,
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: port, TargetPort: intstr.FromInt(int(port)), Protocol: corev1.ProtocolTCP}},
+			},
+		}
+	}
+	services := []*corev1.Service{
+		newService("web", 8080, "http", map[string]string{"mtls_mode": "STRICT"}),
+		newService("db", 5432, "tcp", nil),
+		newService("ext", 443, "https", map[string]string{"domain": "https://www.example.com"}),
+	}
+	return configs, services
+}
+
+func marshalResources(t *testing.T, resources []types.Resource) []byte {
+	var buf bytes.Buffer
+	marshaler := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	for _, r := range resources {
+		if err := marshaler.Marshal(&buf, r); err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func assertGolden(t *testing.T, name string, resources []types.Resource) {
+	got := marshalResources(t, resources)
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch, run the test with -update if the change is expected\n%s", path, got)
+	}
+}
+
+func TestGoldenListenersAndClusters(t *testing.T) {
+	configs, services := goldenFixture()
+	listenersV2, err := OneNodeListerner("app", "tenant", configs, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listenersV3, err := OneNodeListernerV3("app", "tenant", configs, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clustersV2, err := OneNodeCluster("app", "tenant", configs, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clustersV3, err := OneNodeClusterV3("app", "tenant", configs, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "listeners_v2.golden", listenersV2)
+	assertGolden(t, "listeners_v3.golden", listenersV3)
+	assertGolden(t, "clusters_v2.golden", clustersV2)
+	assertGolden(t, "clusters_v3.golden", clustersV3)
+
+	// the node gets the same resources whichever xds version it uses
+	if v2, v3 := cachev2.IndexResourcesByName(listenersV2), cachev3.IndexResourcesByName(listenersV3); !sameNames(v2, v3) {
+		t.Errorf("v2 and v3 listeners are different, v2: %v, v3: %v", v2, v3)
+	}
+	if v2, v3 := cachev2.IndexResourcesByName(clustersV2), cachev3.IndexResourcesByName(clustersV3); !sameNames(v2, v3) {
+		t.Errorf("v2 and v3 clusters are different, v2: %v, v3: %v", v2, v3)
+	}
+}
+
+func sameNames(v2, v3 map[string]types.Resource) bool {
+	if len(v2) == 0 || len(v2) != len(v3) {
+		return false
+	}
+	for name := range v2 {
+		if _, ok := v3[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -20,6 +20,7 @@ package conver
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -54,6 +55,7 @@ func OneNodeListerner(serviceAlias, namespace string, configs *corev1.ConfigMap,
 		return nil, err
 	}
 	var listener []types.Resource
+	tracing := getTracingOptions(configs)
 	var notCreateCommonHTTPListener = func() bool {
 		if configs.Annotations["disable_create_http_common_listener"] == "true" {
 			return true
@@ -64,7 +66,7 @@ func OneNodeListerner(serviceAlias, namespace string, configs *corev1.ConfigMap,
 		return false
 	}()
 	if resources.BaseServices != nil && len(resources.BaseServices) > 0 {
-		for _, l := range upstreamListener(serviceAlias, namespace, resources.BaseServices, services, !notCreateCommonHTTPListener) {
+		for _, l := range upstreamListener(serviceAlias, namespace, resources.BaseServices, services, !notCreateCommonHTTPListener, tracing) {
 			if err := l.Validate(); err != nil {
 				logrus.Errorf("listener validate failure %s", err.Error())
 			} else {
@@ -74,7 +76,7 @@ func OneNodeListerner(serviceAlias, namespace string, configs *corev1.ConfigMap,
 		}
 	}
 	if resources.BasePorts != nil && len(resources.BasePorts) > 0 {
-		for _, l := range downstreamListener(serviceAlias, namespace, resources.BasePorts) {
+		for _, l := range downstreamListener(serviceAlias, namespace, resources.BasePorts, configs.Annotations["mtls_mode"], tracing) {
 			if err := l.Validate(); err != nil {
 				logrus.Errorf("listener validate failure %s", err.Error())
 			} else {
@@ -91,7 +93,7 @@ func OneNodeListerner(serviceAlias, namespace string, configs *corev1.ConfigMap,
 
 //upstreamListener handle upstream app listener
 // handle kubernetes inner service
-func upstreamListener(serviceAlias, namespace string, dependsServices []*api_model.BaseService, services []*corev1.Service, createHTTPListen bool) (ldsL []*v2.Listener) {
+func upstreamListener(serviceAlias, namespace string, dependsServices []*api_model.BaseService, services []*corev1.Service, createHTTPListen bool, tracing *envoyv2.TracingOptions) (ldsL []*v2.Listener) {
 	var ListennerConfig = make(map[string]*api_model.BaseService, len(dependsServices))
 	for i, dService := range dependsServices {
 		protoccol := "tcp"
@@ -139,7 +141,7 @@ func upstreamListener(serviceAlias, namespace string, dependsServices []*api_mod
 			var listener *v2.Listener
 			protocol := service.Labels["port_protocol"]
 			if domain, ok := service.Annotations["domain"]; ok && domain != "" && (protocol == "https" || protocol == "http") {
-				route := envoyv2.CreateRouteWithHostRewrite(domain, clusterName, "/", nil, 0)
+				route := envoyv2.ApplyRoutePolicy(envoyv2.CreateRouteWithHostRewrite(domain, clusterName, "/", nil, 0), options)
 				if route != nil {
 					pvh := envoyv2.CreateRouteVirtualHost(
 						fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, GetServiceAliasByService(service), port),
@@ -148,7 +150,7 @@ func upstreamListener(serviceAlias, namespace string, dependsServices []*api_mod
 						route,
 					)
 					if pvh != nil {
-						listener = envoyv2.CreateHTTPListener(fmt.Sprintf("%s_%s_http_%d", namespace, serviceAlias, port), envoyv2.DefaultLocalhostListenerAddress, fmt.Sprintf("%s_%d", serviceAlias, port), uint32(port), nil, pvh)
+						listener = envoyv2.CreateHTTPListener(fmt.Sprintf("%s_%s_http_%d", namespace, serviceAlias, port), envoyv2.DefaultLocalhostListenerAddress, fmt.Sprintf("%s_%d", serviceAlias, port), uint32(port), nil, tracing, pvh)
 					} else {
 						logrus.Warnf("create route virtual host of domain listener %s failure", fmt.Sprintf("%s_%s_http_%d", namespace, serviceAlias, port))
 					}
@@ -200,6 +202,7 @@ func upstreamListener(serviceAlias, namespace string, dependsServices []*api_mod
 					} else {
 						route = envoyv2.CreateRoute(clusterName, options.Prefix, headerMatchers, options.Weight)
 					}
+					route = envoyv2.ApplyRoutePolicy(route, options)
 
 					if route != nil {
 						pvh := envoyv2.CreateRouteVirtualHost(fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias,
@@ -223,7 +226,7 @@ func upstreamListener(serviceAlias, namespace string, dependsServices []*api_mod
 			ldsL = append(ldsL[:i], ldsL[i+1:]...)
 		}
 		statsPrefix := fmt.Sprintf("%s_80", serviceAlias)
-		plds := envoyv2.CreateHTTPListener(fmt.Sprintf("%s_%s_http_80", namespace, serviceAlias), envoyv2.DefaultLocalhostListenerAddress, statsPrefix, 80, nil, newVHL...)
+		plds := envoyv2.CreateHTTPListener(fmt.Sprintf("%s_%s_http_80", namespace, serviceAlias), envoyv2.DefaultLocalhostListenerAddress, statsPrefix, 80, nil, tracing, newVHL...)
 		if plds != nil {
 			ldsL = append(ldsL, plds)
 		} else {
@@ -234,7 +237,7 @@ func upstreamListener(serviceAlias, namespace string, dependsServices []*api_mod
 }
 
 //downstreamListener handle app self port listener
-func downstreamListener(serviceAlias, namespace string, ports []*api_model.BasePort) (ls []*v2.Listener) {
+func downstreamListener(serviceAlias, namespace string, ports []*api_model.BasePort, mtlsMode string, tracing *envoyv2.TracingOptions) (ls []*v2.Listener) {
 	var portMap = make(map[int32]int, 0)
 	for i := range ports {
 		p := ports[i]
@@ -260,12 +263,22 @@ func downstreamListener(serviceAlias, namespace string, ports []*api_model.BaseP
 						},
 					}
 				}
-				route := envoyv2.CreateRoute(clusterName, "/", nil, 100)
+				// the requests of a limited calling component only take the tokens of its own bucket
+				var routes []*route.Route
+				for _, source := range sourceServices(inboundConfig.SourceLocalLimits) {
+					statPrefix := envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, source)
+					sourceRoute := envoyv2.CreateRoute(clusterName, "/", []*route.HeaderMatcher{envoyv2.CreateSourceServiceMatcher(namespace, source)}, 100)
+					if sourceRoute = envoyv2.ApplyRouteLocalRateLimit(sourceRoute, statPrefix, inboundConfig.SourceLocalLimit(source)); sourceRoute != nil {
+						routes = append(routes, sourceRoute)
+					}
+				}
+				route := envoyv2.ApplyRouteLocalRateLimit(envoyv2.CreateRoute(clusterName, "/", nil, 100),
+					envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, envoyv2.LocalRateLimitAllSources), inboundConfig.LocalLimit)
 				if route == nil {
 					logrus.Warning("create route cirtual route failure")
 					continue
 				}
-				virtuals := envoyv2.CreateRouteVirtualHost(listenerName, []string{"*"}, limit, route)
+				virtuals := envoyv2.CreateRouteVirtualHost(listenerName, []string{"*"}, limit, append(routes, route)...)
 				if virtuals == nil {
 					logrus.Warning("create route cirtual failure")
 					continue
@@ -275,7 +288,10 @@ func downstreamListener(serviceAlias, namespace string, ports []*api_model.BaseP
 					Domain:                inboundConfig.LimitDomain,
 					RateServerClusterName: envoyv2.DefaultRateLimitServerClusterName,
 					Stage:                 0,
-				}, virtuals)
+				}, tracing, virtuals)
+				if mtlsMode != "" {
+					listener = envoyv2.ApplyDownstreamMTLS(listener, mtlsMode == envoyv2.MTLSModeStrict)
+				}
 				if listener != nil {
 					ls = append(ls, listener)
 				}
@@ -289,6 +305,10 @@ func downstreamListener(serviceAlias, namespace string, ports []*api_model.BaseP
 				}
 			} else {
 				listener := envoyv2.CreateTCPListener(listenerName, clusterName, "0.0.0.0", statsPrefix, uint32(p.ListenPort), options.TCPIdleTimeout)
+				listener = envoyv2.ApplyNetworkLocalRateLimit(listener, envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, envoyv2.LocalRateLimitAllSources), inboundConfig.LocalLimit)
+				if mtlsMode != "" {
+					listener = envoyv2.ApplyDownstreamMTLS(listener, mtlsMode == envoyv2.MTLSModeStrict)
+				}
 				if listener != nil {
 					ls = append(ls, listener)
 				} else {
@@ -302,6 +322,30 @@ func downstreamListener(serviceAlias, namespace string, ports []*api_model.BaseP
 	return
 }
 
+//sourceServices the sorted calling components with local rate limit, or the routes change every time they are generated
+func sourceServices(limits map[string]uint32) []string {
+	var sources []string
+	for source := range limits {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+//getTracingOptions get the tracing options of the application from the plugin config map, nil if tracing is disabled
+func getTracingOptions(configs *corev1.ConfigMap) *envoyv2.TracingOptions {
+	config, ok := configs.Data["tracing-config"]
+	if !ok || config == "" {
+		return nil
+	}
+	var tracing envoyv2.TracingOptions
+	if err := ffjson.Unmarshal([]byte(config), &tracing); err != nil {
+		logrus.Warningf("unmarshal tracing config of %s failure %s", configs.Name, err.Error())
+		return nil
+	}
+	return &tracing
+}
+
 //GetServiceAliasByService get service alias from k8s service
 func GetServiceAliasByService(service *corev1.Service) string {
 	//v5.1 and later
@@ -19,9 +19,17 @@
 package conver
 
 import (
+	"regexp"
 	"testing"
 
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http_connection_manager "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes"
+	api_model "github.com/goodrain/rainbond/api/model"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestOneNodeListerner(t *testing.T) {
@@ -31,3 +39,128 @@ func TestOneNodeListerner(t *testing.T) {
 	}
 	t.Log(listeners)
 }
+
+func TestDownstreamListenerMTLS(t *testing.T) {
+	ports := []*api_model.BasePort{
+		&api_model.BasePort{Port: 5000, ListenPort: 65301, Protocol: "tcp"},
+		&api_model.BasePort{Port: 8080, ListenPort: 65302, Protocol: "http"},
+	}
+	for _, l := range downstreamListener("serviceAlias", "namespace", ports, envoyv2.MTLSModeStrict, nil) {
+		if len(l.FilterChains) != 1 || l.FilterChains[0].TlsContext == nil {
+			t.Errorf("strict listener %s should only serve mutual tls", l.Name)
+		}
+	}
+	for _, l := range downstreamListener("serviceAlias", "namespace", ports, "PERMISSIVE", nil) {
+		if len(l.FilterChains) != 2 || l.FilterChains[0].TlsContext == nil || l.FilterChains[1].TlsContext != nil {
+			t.Errorf("permissive listener %s should serve both mutual tls and plaintext", l.Name)
+		}
+		if len(l.ListenerFilters) != 1 || l.ListenerFilters[0].Name != envoyv2.TLSInspectorListenerFilter {
+			t.Errorf("permissive listener %s should detect the transport protocol", l.Name)
+		}
+	}
+	for _, l := range downstreamListener("serviceAlias", "namespace", ports, "", nil) {
+		if len(l.FilterChains) != 1 || l.FilterChains[0].TlsContext != nil {
+			t.Errorf("listener %s should serve plaintext", l.Name)
+		}
+	}
+}
+
+func TestUpstreamListenerRoutePolicy(t *testing.T) {
+	dependsServices := []*api_model.BaseService{
+		&api_model.BaseService{
+			DependServiceAlias: "dep",
+			Port:               8080,
+			Protocol:           "http",
+			Options: map[string]interface{}{
+				envoyv2.KeyNumRetries:        "3",
+				envoyv2.KeyPerTryTimeoutMS:   "500",
+				envoyv2.KeyTimeoutMS:         "2000",
+				envoyv2.KeyFaultAbortPercent: "10",
+			},
+		},
+	}
+	services := []*corev1.Service{
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"service_type": "inner", "port_protocol": "http", "service_alias": "dep"},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 8080, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+	}
+	var hcm http_connection_manager.HttpConnectionManager
+	for _, l := range upstreamListener("serviceAlias", "namespace", dependsServices, services, true, &envoyv2.TracingOptions{SamplingRate: 10, Tags: map[string]string{"env": "test"}}) {
+		if l.Name == "namespace_serviceAlias_http_80" {
+			if err := ptypes.UnmarshalAny(l.FilterChains[0].Filters[0].GetTypedConfig(), &hcm); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if hcm.GetRouteConfig() == nil {
+		t.Fatal("common http listener not found")
+	}
+	if len(hcm.HttpFilters) != 2 || hcm.HttpFilters[0].Name != wellknown.Fault {
+		t.Errorf("expected the fault filter before the router, got %v", hcm.HttpFilters)
+	}
+	r := hcm.GetRouteConfig().VirtualHosts[0].Routes[0]
+	action := r.Action.(*route.Route_Route).Route
+	if action.RetryPolicy == nil || action.RetryPolicy.NumRetries.GetValue() != 3 {
+		t.Errorf("expected 3 retries, got %v", action.RetryPolicy)
+	}
+	if action.Timeout.GetSeconds() != 2 {
+		t.Errorf("expected timeout 2s, got %v", action.Timeout)
+	}
+	if _, ok := r.TypedPerFilterConfig[wellknown.Fault]; !ok {
+		t.Error("expected fault injection config on route")
+	}
+	if hcm.Tracing == nil || hcm.Tracing.RandomSampling.GetValue() != 10 || len(hcm.Tracing.CustomTags) != 1 {
+		t.Errorf("expected tracing with 10%% sampling and custom tags, got %v", hcm.Tracing)
+	}
+}
+
+func TestDownstreamListenerLocalRateLimit(t *testing.T) {
+	ports := []*api_model.BasePort{
+		&api_model.BasePort{ServiceID: "sid", Port: 5000, ListenPort: 65301, Protocol: "tcp",
+			Options: map[string]interface{}{"LOCAL_LIMIT_MAX_TOKENS": "50"}},
+		&api_model.BasePort{ServiceID: "sid", Port: 8080, ListenPort: 65302, Protocol: "http",
+			Options: map[string]interface{}{"LOCAL_LIMIT_MAX_TOKENS": "100", "LOCAL_LIMIT_SOURCES": "web:10"}},
+	}
+	listeners := downstreamListener("serviceAlias", "namespace", ports, "", nil)
+	if len(listeners) != 2 {
+		t.Fatalf("expected 2 listeners, got %d", len(listeners))
+	}
+	if filters := listeners[0].FilterChains[0].Filters; len(filters) != 2 || filters[0].Name != envoyv2.NetworkLocalRateLimit {
+		t.Errorf("expected the network local rate limit before the tcp proxy, got %v", filters)
+	}
+	var hcm http_connection_manager.HttpConnectionManager
+	if err := ptypes.UnmarshalAny(listeners[1].FilterChains[0].Filters[0].GetTypedConfig(), &hcm); err != nil {
+		t.Fatal(err)
+	}
+	if len(hcm.HttpFilters) != 2 || hcm.HttpFilters[0].Name != envoyv2.HTTPLocalRateLimit {
+		t.Errorf("expected the http local rate limit before the router, got %v", hcm.HttpFilters)
+	}
+	routes := hcm.GetRouteConfig().VirtualHosts[0].Routes
+	if len(routes) != 2 {
+		t.Fatalf("expected a route for the calling component and a default route, got %d", len(routes))
+	}
+	headers := routes[0].Match.Headers
+	if len(headers) != 1 || headers[0].Name != envoyv2.ClientCertHeader {
+		t.Fatalf("expected the first route to match the certificate of the calling component, got %v", headers)
+	}
+	re := regexp.MustCompile("^(?:" + headers[0].GetSafeRegexMatch().GetRegex() + ")$")
+	if !re.MatchString("Hash=abc;URI=" + envoyv2.MeshSPIFFEID("namespace", "web")) {
+		t.Errorf("expected the route to match the identity of web")
+	}
+	if re.MatchString("Hash=abc;URI=" + envoyv2.MeshSPIFFEID("namespace", "web2")) {
+		t.Errorf("expected the route not to match the identity of the other component")
+	}
+	if hcm.ForwardClientCertDetails != http_connection_manager.HttpConnectionManager_SANITIZE_SET {
+		t.Errorf("expected the client certificate header sent by the client is replaced")
+	}
+	for _, r := range routes {
+		if _, ok := r.TypedPerFilterConfig[envoyv2.HTTPLocalRateLimit]; !ok {
+			t.Errorf("expected local rate limit config on route %v", r.Match)
+		}
+	}
+}
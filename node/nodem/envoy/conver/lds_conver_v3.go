@@ -0,0 +1,281 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	api_model "github.com/goodrain/rainbond/api/model"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	envoyv3 "github.com/goodrain/rainbond/node/core/envoy/v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+//OneNodeListernerV3 conver v3 listerner of on envoy node
+func OneNodeListernerV3(serviceAlias, namespace string, configs *corev1.ConfigMap, services []*corev1.Service) ([]types.Resource, error) {
+	resources, _, err := GetPluginConfigs(configs)
+	if err != nil {
+		return nil, err
+	}
+	var listener []types.Resource
+	tracing := getTracingOptions(configs)
+	notCreateCommonHTTPListener := configs.Annotations["disable_create_http_common_listener"] == "true" || strings.Contains(configs.Name, "def-mesh")
+	if resources.BaseServices != nil && len(resources.BaseServices) > 0 {
+		for _, l := range upstreamListenerV3(serviceAlias, namespace, resources.BaseServices, services, !notCreateCommonHTTPListener, tracing) {
+			if err := l.Validate(); err != nil {
+				logrus.Errorf("listener validate failure %s", err.Error())
+			} else {
+				logrus.Debugf("create listener %s for service %s", l.Name, serviceAlias)
+				listener = append(listener, l)
+			}
+		}
+	}
+	if resources.BasePorts != nil && len(resources.BasePorts) > 0 {
+		for _, l := range downstreamListenerV3(serviceAlias, namespace, resources.BasePorts, configs.Annotations["mtls_mode"], tracing) {
+			if err := l.Validate(); err != nil {
+				logrus.Errorf("listener validate failure %s", err.Error())
+			} else {
+				logrus.Debugf("create listener %s for service %s", l.Name, serviceAlias)
+				listener = append(listener, l)
+			}
+		}
+	}
+	if len(listener) == 0 {
+		logrus.Warningf("configmap name: %s; plugin-config: %s; create listener zero length", configs.Name, configs.Data["plugin-config"])
+	}
+	return listener, nil
+}
+
+//upstreamListenerV3 is the v3 version of upstreamListener
+func upstreamListenerV3(serviceAlias, namespace string, dependsServices []*api_model.BaseService, services []*corev1.Service, createHTTPListen bool, tracing *envoyv2.TracingOptions) (ldsL []*listenerv3.Listener) {
+	var ListennerConfig = make(map[string]*api_model.BaseService, len(dependsServices))
+	for i, dService := range dependsServices {
+		protoccol := "tcp"
+		if strings.ToLower(dService.Protocol) == "udp" {
+			protoccol = "udp"
+		}
+		if strings.ToLower(dService.Protocol) == "sctp" {
+			protoccol = "sctp"
+		}
+		listennerName := fmt.Sprintf("%s_%s_%s_%s_%d", namespace, serviceAlias, dService.DependServiceAlias, protoccol, dService.Port)
+		ListennerConfig[listennerName] = dependsServices[i]
+	}
+	var portMap = make(map[int32]int)
+	var uniqRoute = make(map[string]*routev3.Route, len(services))
+	var newVHL []*routev3.VirtualHost
+	for _, service := range services {
+		inner, ok := service.Labels["service_type"]
+		if !ok || inner != "inner" {
+			continue
+		}
+		port := service.Spec.Ports[0].Port
+		protocol := service.Spec.Ports[0].Protocol
+		var ListenPort = port
+		//listener real port
+		if value, ok := service.Labels["origin_port"]; ok {
+			origin, _ := strconv.Atoi(value)
+			if origin != 0 {
+				ListenPort = int32(origin)
+			}
+		}
+		clusterName := fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, GetServiceAliasByService(service), port)
+		listennerName := fmt.Sprintf("%s_%s_%s_%s_%d", namespace, serviceAlias, GetServiceAliasByService(service), strings.ToLower(string(protocol)), ListenPort)
+		destService := ListennerConfig[listennerName]
+		statPrefix := fmt.Sprintf("%s_%s", serviceAlias, GetServiceAliasByService(service))
+		var options envoyv2.RainbondPluginOptions
+		if destService != nil {
+			options = envoyv2.GetOptionValues(destService.Options)
+		} else {
+			logrus.Warningf("destService is nil for service %s listenner name %s", serviceAlias, listennerName)
+		}
+		// Unique by listen port
+		if _, ok := portMap[ListenPort]; !ok {
+			//listener name depend listner port
+			listenerName := fmt.Sprintf("%s_%s_%d", namespace, serviceAlias, ListenPort)
+			var listener *listenerv3.Listener
+			protocol := service.Labels["port_protocol"]
+			if domain, ok := service.Annotations["domain"]; ok && domain != "" && (protocol == "https" || protocol == "http") {
+				route := envoyv3.ApplyRoutePolicy(envoyv3.CreateRouteWithHostRewrite(domain, clusterName, "/", nil, 0), options)
+				if route != nil {
+					pvh := envoyv3.CreateRouteVirtualHost(
+						fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias, GetServiceAliasByService(service), port),
+						[]string{"*"},
+						nil,
+						route,
+					)
+					if pvh != nil {
+						listener = envoyv3.CreateHTTPListener(fmt.Sprintf("%s_%s_http_%d", namespace, serviceAlias, port), envoyv2.DefaultLocalhostListenerAddress, fmt.Sprintf("%s_%d", serviceAlias, port), uint32(port), nil, tracing, pvh)
+					} else {
+						logrus.Warnf("create route virtual host of domain listener %s failure", fmt.Sprintf("%s_%s_http_%d", namespace, serviceAlias, port))
+					}
+				}
+			} else if protocol == "udp" {
+				listener = envoyv3.CreateUDPListener(listenerName, clusterName, envoyv2.DefaultLocalhostListenerAddress, statPrefix, uint32(ListenPort))
+			} else {
+				listener = envoyv3.CreateTCPListener(listenerName, clusterName, envoyv2.DefaultLocalhostListenerAddress, statPrefix, uint32(ListenPort), options.TCPIdleTimeout)
+			}
+			if listener != nil {
+				ldsL = append(ldsL, listener)
+			} else {
+				logrus.Warningf("create tcp listenner %s failure", listenerName)
+				continue
+			}
+			portMap[ListenPort] = len(ldsL) - 1
+		}
+
+		portProtocol := service.Labels["port_protocol"]
+		if destService != nil && destService.Protocol != "" {
+			portProtocol = destService.Protocol
+		}
+		if portProtocol != "http" && portProtocol != "https" {
+			continue
+		}
+		hashKey := options.RouteBasicHash()
+		if oldroute, ok := uniqRoute[hashKey]; ok {
+			oldrr := oldroute.Action.(*routev3.Route_Route)
+			if oldrrwc, ok := oldrr.Route.ClusterSpecifier.(*routev3.RouteAction_WeightedClusters); ok {
+				weight := envoyv3.CheckWeightSum(oldrrwc.WeightedClusters.Clusters, options.Weight)
+				oldrrwc.WeightedClusters.Clusters = append(oldrrwc.WeightedClusters.Clusters, &routev3.WeightedCluster_ClusterWeight{
+					Name:   clusterName,
+					Weight: envoyv2.ConversionUInt32(weight),
+				})
+			}
+			continue
+		}
+		var headerMatchers []*routev3.HeaderMatcher
+		for _, header := range options.Headers {
+			headerMatcher := envoyv3.CreateHeaderMatcher(header)
+			if headerMatcher != nil {
+				headerMatchers = append(headerMatchers, headerMatcher)
+			}
+		}
+		var route *routev3.Route
+		if domain, ok := service.Annotations["domain"]; ok && domain != "" {
+			route = envoyv3.CreateRouteWithHostRewrite(domain, clusterName, options.Prefix, headerMatchers, options.Weight)
+		} else {
+			route = envoyv3.CreateRoute(clusterName, options.Prefix, headerMatchers, options.Weight)
+		}
+		route = envoyv3.ApplyRoutePolicy(route, options)
+		if route != nil {
+			pvh := envoyv3.CreateRouteVirtualHost(fmt.Sprintf("%s_%s_%s_%d", namespace, serviceAlias,
+				GetServiceAliasByService(service), port), options.Domains, nil, route)
+			if pvh != nil {
+				newVHL = append(newVHL, pvh)
+				uniqRoute[hashKey] = route
+			}
+		}
+	}
+	// create common http listener
+	if len(newVHL) > 0 && createHTTPListen {
+		//remove 80 tcp listener is exist
+		if i, ok := portMap[80]; ok {
+			ldsL = append(ldsL[:i], ldsL[i+1:]...)
+		}
+		statsPrefix := fmt.Sprintf("%s_80", serviceAlias)
+		plds := envoyv3.CreateHTTPListener(fmt.Sprintf("%s_%s_http_80", namespace, serviceAlias), envoyv2.DefaultLocalhostListenerAddress, statsPrefix, 80, nil, tracing, newVHL...)
+		if plds != nil {
+			ldsL = append(ldsL, plds)
+		} else {
+			logrus.Warnf("create listenner %s failure", fmt.Sprintf("%s_%s_http_80", namespace, serviceAlias))
+		}
+	}
+	return
+}
+
+//downstreamListenerV3 is the v3 version of downstreamListener
+func downstreamListenerV3(serviceAlias, namespace string, ports []*api_model.BasePort, mtlsMode string, tracing *envoyv2.TracingOptions) (ls []*listenerv3.Listener) {
+	var portMap = make(map[int32]int, 0)
+	for i := range ports {
+		p := ports[i]
+		port := int32(p.Port)
+		clusterName := fmt.Sprintf("%s_%s_%d", namespace, serviceAlias, port)
+		listenerName := clusterName
+		statsPrefix := fmt.Sprintf("%s_%d", serviceAlias, port)
+		if _, ok := portMap[port]; ok {
+			continue
+		}
+		inboundConfig := envoyv2.GetRainbondInboundPluginOptions(p.Options)
+		options := envoyv2.GetOptionValues(p.Options)
+		var listener *listenerv3.Listener
+		if p.Protocol == "http" || p.Protocol == "https" {
+			var limit []*routev3.RateLimit
+			if inboundConfig.OpenLimit {
+				limit = []*routev3.RateLimit{
+					&routev3.RateLimit{
+						Actions: []*routev3.RateLimit_Action{
+							&routev3.RateLimit_Action{
+								ActionSpecifier: &routev3.RateLimit_Action_RemoteAddress_{
+									RemoteAddress: &routev3.RateLimit_Action_RemoteAddress{},
+								},
+							},
+						},
+					},
+				}
+			}
+			// the requests of a limited calling component only take the tokens of its own bucket
+			var routes []*routev3.Route
+			for _, source := range sourceServices(inboundConfig.SourceLocalLimits) {
+				statPrefix := envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, source)
+				sourceRoute := envoyv3.CreateRoute(clusterName, "/", []*routev3.HeaderMatcher{envoyv3.CreateSourceServiceMatcher(namespace, source)}, 100)
+				if sourceRoute = envoyv3.ApplyRouteLocalRateLimit(sourceRoute, statPrefix, inboundConfig.SourceLocalLimit(source)); sourceRoute != nil {
+					routes = append(routes, sourceRoute)
+				}
+			}
+			route := envoyv3.ApplyRouteLocalRateLimit(envoyv3.CreateRoute(clusterName, "/", nil, 100),
+				envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, envoyv2.LocalRateLimitAllSources), inboundConfig.LocalLimit)
+			if route == nil {
+				logrus.Warning("create route cirtual route failure")
+				continue
+			}
+			virtuals := envoyv3.CreateRouteVirtualHost(listenerName, []string{"*"}, limit, append(routes, route)...)
+			if virtuals == nil {
+				logrus.Warning("create route cirtual failure")
+				continue
+			}
+			listener = envoyv3.CreateHTTPListener(listenerName, "0.0.0.0", statsPrefix, uint32(p.ListenPort), &envoyv2.RateLimitOptions{
+				Enable:                inboundConfig.OpenLimit,
+				Domain:                inboundConfig.LimitDomain,
+				RateServerClusterName: envoyv2.DefaultRateLimitServerClusterName,
+				Stage:                 0,
+			}, tracing, virtuals)
+		} else if p.Protocol == "udp" {
+			listener = envoyv3.CreateUDPListener(listenerName, clusterName, "0.0.0.0", statsPrefix, uint32(p.ListenPort))
+		} else {
+			listener = envoyv3.CreateTCPListener(listenerName, clusterName, "0.0.0.0", statsPrefix, uint32(p.ListenPort), options.TCPIdleTimeout)
+			listener = envoyv3.ApplyNetworkLocalRateLimit(listener, envoyv2.LocalRateLimitStatPrefix(p.ServiceID, p.Port, envoyv2.LocalRateLimitAllSources), inboundConfig.LocalLimit)
+		}
+		if mtlsMode != "" && p.Protocol != "udp" {
+			listener = envoyv3.ApplyDownstreamMTLS(listener, mtlsMode == envoyv2.MTLSModeStrict)
+		}
+		if listener == nil {
+			logrus.Warningf("create %s listener %s failure", p.Protocol, listenerName)
+			continue
+		}
+		ls = append(ls, listener)
+		portMap[port] = 1
+	}
+	return
+}
@@ -0,0 +1,338 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//deltaTypeURLs the order of resource types pushed after the snapshot changed,
+//clusters and listeners are warmed by the endpoints and secrets pushed after them
+var deltaTypeURLs = []string{
+	resourcev3.ClusterType,
+	resourcev3.EndpointType,
+	resourcev3.ListenerType,
+	resourcev3.RouteType,
+	resourcev3.SecretType,
+}
+
+//deltaServer serves the incremental xDS protocol of the aggregated discovery service
+//over the v3 snapshot cache, the state of the world xDS is served by the embedded server.
+type deltaServer struct {
+	serverv3.Server
+	cache cachev3.SnapshotCache
+	hash  cachev3.NodeHash
+	// onNode is called with the node of each new delta stream
+	onNode      func(node *corev3.Node)
+	streamCount int64
+	nonce       int64
+	lock        sync.Mutex
+	watches     map[string]map[int64]chan struct{}
+}
+
+func newDeltaServer(server serverv3.Server, cache cachev3.SnapshotCache, hash cachev3.NodeHash, onNode func(node *corev3.Node)) *deltaServer {
+	return &deltaServer{
+		Server:  server,
+		cache:   cache,
+		hash:    hash,
+		onNode:  onNode,
+		watches: make(map[string]map[int64]chan struct{}),
+	}
+}
+
+//deltaSubscription the resources of a type subscribed by a delta stream
+type deltaSubscription struct {
+	// wildcard subscribes all resources of the type, used by listeners and clusters
+	wildcard bool
+	names    map[string]bool
+	// versions the versions of the resources the envoy node has acked
+	versions map[string]string
+	// sent the versions of the resources sent to the envoy node, including the ones not acked yet
+	sent map[string]string
+	// pending the responses waiting for the ack of the envoy node by nonce
+	pending map[string]*discoveryv3.DeltaDiscoveryResponse
+	// responded the initial response is sent
+	responded bool
+}
+
+func newDeltaSubscription(req *discoveryv3.DeltaDiscoveryRequest) *deltaSubscription {
+	sub := &deltaSubscription{
+		wildcard: len(req.ResourceNamesSubscribe) == 0,
+		names:    make(map[string]bool),
+		versions: make(map[string]string),
+		sent:     make(map[string]string),
+		pending:  make(map[string]*discoveryv3.DeltaDiscoveryResponse),
+	}
+	for name, version := range req.InitialResourceVersions {
+		sub.versions[name] = version
+		sub.sent[name] = version
+	}
+	return sub
+}
+
+func (s *deltaSubscription) update(req *discoveryv3.DeltaDiscoveryRequest) {
+	for _, name := range req.ResourceNamesSubscribe {
+		s.names[name] = true
+		// the envoy node expects a response for the newly subscribed resource even if it is unchanged
+		delete(s.versions, name)
+		delete(s.sent, name)
+	}
+	for _, name := range req.ResourceNamesUnsubscribe {
+		delete(s.names, name)
+		delete(s.versions, name)
+		delete(s.sent, name)
+	}
+}
+
+//record records the response sent to the envoy node, the versions in it are kept after it is acked
+func (s *deltaSubscription) record(resp *discoveryv3.DeltaDiscoveryResponse) {
+	for _, resource := range resp.Resources {
+		s.sent[resource.Name] = resource.Version
+	}
+	for _, name := range resp.RemovedResources {
+		delete(s.sent, name)
+	}
+	s.pending[resp.Nonce] = resp
+}
+
+//ack handles the ack or nack of the response, the versions of the acked response are kept.
+//The resources of the rejected response are sent again after the snapshot changed.
+func (s *deltaSubscription) ack(req *discoveryv3.DeltaDiscoveryRequest) {
+	resp, ok := s.pending[req.ResponseNonce]
+	if !ok {
+		return
+	}
+	delete(s.pending, req.ResponseNonce)
+	if req.ErrorDetail != nil {
+		s.sent = make(map[string]string, len(s.versions))
+		for name, version := range s.versions {
+			s.sent[name] = version
+		}
+		return
+	}
+	for _, resource := range resp.Resources {
+		s.versions[resource.Name] = resource.Version
+	}
+	for _, name := range resp.RemovedResources {
+		delete(s.versions, name)
+	}
+}
+
+func (s *deltaSubscription) subscribed(name string) bool {
+	return s.wildcard || s.names[name]
+}
+
+//notify wakes up the delta streams of the node after its snapshot changed
+func (d *deltaServer) notify(nodeID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, ch := range d.watches[nodeID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *deltaServer) watch(nodeID string, streamID int64) chan struct{} {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	ch := make(chan struct{}, 1)
+	if _, ok := d.watches[nodeID]; !ok {
+		d.watches[nodeID] = make(map[int64]chan struct{})
+	}
+	d.watches[nodeID][streamID] = ch
+	return ch
+}
+
+func (d *deltaServer) cancelWatch(nodeID string, streamID int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.watches[nodeID], streamID)
+	if len(d.watches[nodeID]) == 0 {
+		delete(d.watches, nodeID)
+	}
+}
+
+//DeltaAggregatedResources serves the incremental aggregated discovery stream
+func (d *deltaServer) DeltaAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	streamID := atomic.AddInt64(&d.streamCount, 1)
+	reqCh := make(chan *discoveryv3.DeltaDiscoveryRequest)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case reqCh <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+	var nodeID string
+	var notify chan struct{}
+	defer func() {
+		if nodeID != "" {
+			d.cancelWatch(nodeID, streamID)
+		}
+	}()
+	subscriptions := make(map[string]*deltaSubscription)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case req := <-reqCh:
+			if nodeID == "" {
+				if req.Node == nil {
+					return status.Errorf(codes.InvalidArgument, "the first request of delta stream must carry the node")
+				}
+				nodeID = d.hash.ID(req.Node)
+				notify = d.watch(nodeID, streamID)
+				if d.onNode != nil {
+					d.onNode(req.Node)
+				}
+			}
+			if req.ErrorDetail != nil {
+				logrus.Warningf("envoy node %s rejected %s config: %s", nodeID, req.TypeUrl, req.ErrorDetail.Message)
+			}
+			sub, ok := subscriptions[req.TypeUrl]
+			if !ok {
+				sub = newDeltaSubscription(req)
+				subscriptions[req.TypeUrl] = sub
+			}
+			sub.ack(req)
+			sub.update(req)
+			// the ack or nack without subscription changes waits for the next change of the snapshot,
+			// the rejected config is not sent again and again
+			if req.ResponseNonce != "" && len(req.ResourceNamesSubscribe) == 0 && len(req.ResourceNamesUnsubscribe) == 0 {
+				continue
+			}
+			if err := d.respond(stream, nodeID, req.TypeUrl, sub); err != nil {
+				return err
+			}
+		case <-notify:
+			for _, typeURL := range deltaTypeURLs {
+				if sub, ok := subscriptions[typeURL]; ok {
+					if err := d.respond(stream, nodeID, typeURL, sub); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+}
+
+//respond sends the resources changed since the last response, nothing is sent if the snapshot is not ready
+func (d *deltaServer) respond(stream discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer, nodeID, typeURL string, sub *deltaSubscription) error {
+	snapshot, err := d.cache.GetSnapshot(nodeID)
+	if err != nil {
+		return nil
+	}
+	resp, err := deltaResponse(&snapshot, typeURL, sub)
+	if err != nil {
+		return err
+	}
+	if len(resp.Resources) == 0 && len(resp.RemovedResources) == 0 && sub.responded {
+		return nil
+	}
+	resp.Nonce = strconv.FormatInt(atomic.AddInt64(&d.nonce, 1), 10)
+	sub.record(resp)
+	sub.responded = true
+	return stream.Send(resp)
+}
+
+//deltaResponse compares the snapshot with the versions sent to the envoy node
+func deltaResponse(snapshot *cachev3.Snapshot, typeURL string, sub *deltaSubscription) (*discoveryv3.DeltaDiscoveryResponse, error) {
+	resources := snapshot.GetResources(typeURL)
+	resp := &discoveryv3.DeltaDiscoveryResponse{
+		SystemVersionInfo: snapshot.GetVersion(typeURL),
+		TypeUrl:           typeURL,
+	}
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !sub.subscribed(name) {
+			continue
+		}
+		version, err := resourceVersion(resources[name])
+		if err != nil {
+			return nil, err
+		}
+		if sub.sent[name] == version {
+			continue
+		}
+		any, err := ptypes.MarshalAny(resources[name])
+		if err != nil {
+			return nil, err
+		}
+		resp.Resources = append(resp.Resources, &discoveryv3.Resource{
+			Name:     name,
+			Version:  version,
+			Resource: any,
+		})
+	}
+	for name := range sub.sent {
+		if _, ok := resources[name]; !ok {
+			resp.RemovedResources = append(resp.RemovedResources, name)
+		}
+	}
+	sort.Strings(resp.RemovedResources)
+	return resp, nil
+}
+
+//resourceVersion the version of a resource is the hash of its content
+func resourceVersion(resource types.Resource) (string, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(resource); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,93 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	"google.golang.org/genproto/googleapis/rpc/status"
+)
+
+func TestDeltaResponse(t *testing.T) {
+	newSnapshot := func(version string, clusters ...types.Resource) *cachev3.Snapshot {
+		snapshot := cachev3.NewSnapshot(version, nil, clusters, nil, nil, nil)
+		return &snapshot
+	}
+	a := &clusterv3.Cluster{Name: "a", ConnectTimeout: envoyv2.ConverTimeDuration(1)}
+	b := &clusterv3.Cluster{Name: "b", ConnectTimeout: envoyv2.ConverTimeDuration(1)}
+	sub := newDeltaSubscription(&discoveryv3.DeltaDiscoveryRequest{TypeUrl: resourcev3.ClusterType})
+
+	resp, err := deltaResponse(newSnapshot("1", a, b), resourcev3.ClusterType, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Resources) != 2 || len(resp.RemovedResources) != 0 {
+		t.Fatalf("expected all clusters in the first response, got %v", resp)
+	}
+	resp.Nonce = "1"
+	sub.record(resp)
+	sub.ack(&discoveryv3.DeltaDiscoveryRequest{TypeUrl: resourcev3.ClusterType, ResponseNonce: "1"})
+	resp, _ = deltaResponse(newSnapshot("2", a, b), resourcev3.ClusterType, sub)
+	if len(resp.Resources) != 0 || len(resp.RemovedResources) != 0 {
+		t.Fatalf("expected nothing is sent if the clusters are unchanged, got %v", resp)
+	}
+	changed := &clusterv3.Cluster{Name: "a", ConnectTimeout: envoyv2.ConverTimeDuration(2)}
+	resp, _ = deltaResponse(newSnapshot("3", changed), resourcev3.ClusterType, sub)
+	if len(resp.Resources) != 1 || resp.Resources[0].Name != "a" {
+		t.Errorf("expected the changed cluster a is sent, got %v", resp.Resources)
+	}
+	if len(resp.RemovedResources) != 1 || resp.RemovedResources[0] != "b" {
+		t.Errorf("expected the cluster b is removed, got %v", resp.RemovedResources)
+	}
+}
+
+func TestDeltaResponseNack(t *testing.T) {
+	a := &clusterv3.Cluster{Name: "a", ConnectTimeout: envoyv2.ConverTimeDuration(1)}
+	snapshot := cachev3.NewSnapshot("1", nil, []types.Resource{a}, nil, nil, nil)
+	sub := newDeltaSubscription(&discoveryv3.DeltaDiscoveryRequest{TypeUrl: resourcev3.ClusterType})
+
+	resp, err := deltaResponse(&snapshot, resourcev3.ClusterType, sub)
+	if err != nil || len(resp.Resources) != 1 {
+		t.Fatalf("expected the cluster a is sent, got %v: %v", resp, err)
+	}
+	resp.Nonce = "1"
+	sub.record(resp)
+	// nothing is sent again while waiting for the ack
+	if resp, _ := deltaResponse(&snapshot, resourcev3.ClusterType, sub); len(resp.Resources) != 0 {
+		t.Fatalf("expected nothing is sent before the ack, got %v", resp.Resources)
+	}
+	sub.ack(&discoveryv3.DeltaDiscoveryRequest{
+		TypeUrl:       resourcev3.ClusterType,
+		ResponseNonce: "1",
+		ErrorDetail:   &status.Status{Message: "rejected"},
+	})
+	if len(sub.versions) != 0 {
+		t.Errorf("expected the version of the rejected cluster is not kept, got %v", sub.versions)
+	}
+	resp, _ = deltaResponse(&snapshot, resourcev3.ClusterType, sub)
+	if len(resp.Resources) != 1 || resp.Resources[0].Name != "a" {
+		t.Errorf("expected the rejected cluster a is sent again, got %v", resp.Resources)
+	}
+}
@@ -0,0 +1,124 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	"github.com/goodrain/rainbond/util/cert"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+//meshCASecretName the secret which stores the platform ca shared by the discover servers of all nodes
+const meshCASecretName = "rbd-mesh-ca"
+
+//loadOrCreateMeshCA loads the platform ca of the built-in service mesh, creates it if not exist
+func loadOrCreateMeshCA(kubecli kubernetes.Interface, namespace string) (*cert.CA, error) {
+	secret, err := kubecli.CoreV1().Secrets(namespace).Get(meshCASecretName, metav1.GetOptions{})
+	if err == nil {
+		return cert.ParseCA(secret.Data["ca.crt"], secret.Data["ca.key"])
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+	info := cert.CreateCertInformation()
+	info.CommonName = "rainbond-mesh-ca"
+	ca, err := cert.NewCA(info)
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      meshCASecretName,
+			Namespace: namespace,
+			Labels:    map[string]string{"creator": "Rainbond"},
+		},
+		Data: map[string][]byte{
+			"ca.crt": ca.CertPEM(),
+			"ca.key": ca.KeyPEM(),
+		},
+	}
+	if _, err := kubecli.CoreV1().Secrets(namespace).Create(secret); err != nil {
+		if !k8sErrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		// the discover server of another node created the ca first
+		secret, err = kubecli.CoreV1().Secrets(namespace).Get(meshCASecretName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return cert.ParseCA(secret.Data["ca.crt"], secret.Data["ca.key"])
+	}
+	logrus.Infof("created the platform ca of the built-in service mesh")
+	return ca, nil
+}
+
+//certNeedRenew the certificate is renewed when less than one third of its lifetime remains
+func (d *DiscoverServerManager) certNeedRenew(nc *NodeConfig, now time.Time) bool {
+	return nc.certExpire.Sub(now) < d.conf.MeshCertTTL/3
+}
+
+//updateNodeSecrets issues the workload certificate of the node config if it does not exist or is about to expire,
+//and creates the sds secrets in the xds version of the node config
+func (d *DiscoverServerManager) updateNodeSecrets(nc *NodeConfig) {
+	if d.ca == nil {
+		return
+	}
+	if d.certNeedRenew(nc, time.Now()) {
+		spiffeID := envoyv2.MeshSPIFFEID(nc.namespace, nc.serviceAlias)
+		certPEM, keyPEM, notAfter, err := d.ca.IssueSPIFFECert(spiffeID, d.conf.MeshCertTTL)
+		if err != nil {
+			logrus.Errorf("issue certificate %s failure %s", spiffeID, err.Error())
+		} else {
+			nc.certPEM, nc.keyPEM, nc.certExpire = certPEM, keyPEM, notAfter
+			logrus.Debugf("issued certificate %s, expire at %s", spiffeID, notAfter)
+		}
+	}
+	if nc.certPEM == nil {
+		return
+	}
+	if nc.xdsVersion == xdsVersionV3 {
+		nc.secrets = d.meshSecretsV3(nc)
+		return
+	}
+	nc.secrets = []types.Resource{
+		envoyv2.CreateTLSCertificateSecret(envoyv2.MeshCertSecretName, nc.certPEM, nc.keyPEM),
+		envoyv2.CreateValidationContextSecret(envoyv2.MeshValidationSecretName, d.ca.CertPEM()),
+	}
+}
+
+//certRotateHandle renews the workload certificates which are about to expire
+func (d *DiscoverServerManager) certRotateHandle(obj interface{}, event Event) error {
+	now := time.Now()
+	for i, nodeConfig := range d.cacheNodeConfig {
+		if nodeConfig.certExpire.IsZero() || !d.certNeedRenew(nodeConfig, now) {
+			continue
+		}
+		if err := d.UpdateNodeConfig(d.cacheNodeConfig[i]); err != nil {
+			logrus.Errorf("renew certificate of envoy node %s failure %s", nodeConfig.GetID(), err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	kcache "k8s.io/client-go/tools/cache"
+)
+
+//podIPIndex indexes the pods by their ip
+const podIPIndex = "podIP"
+
+func podIPIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Status.PodIP == "" || pod.Spec.HostNetwork {
+		return nil, nil
+	}
+	return []string{pod.Status.PodIP}, nil
+}
+
+//peerAuthenticator binds the envoy node id to the pod which connects to the discover server.
+//The envoy sidecar connects from the network of its pod, so the node id it claims must be
+//the one of the component running in the pod with the peer ip, otherwise the stream is refused
+//before any resource, such as the private key of the workload certificate, is served.
+type peerAuthenticator struct {
+	pods kcache.Indexer
+}
+
+//authenticate checks that the node id, such as ${TENANT_ID}_${PLUGIN_ID}_${SERVICE_NAME}, belongs to the pod of peer ip
+func (a *peerAuthenticator) authenticate(peerIP, nodeID string) error {
+	parts := strings.SplitN(nodeID, "_", 3)
+	if len(parts) != 3 {
+		return status.Errorf(codes.PermissionDenied, "invalid envoy node %s", nodeID)
+	}
+	objs, err := a.pods.ByIndex(podIPIndex, peerIP)
+	if err != nil {
+		return status.Errorf(codes.Internal, "find the pod of %s: %v", peerIP, err)
+	}
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		if pod.Namespace == parts[0] && pod.Labels["service_alias"] == parts[2] {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "peer %s is not the envoy node %s", peerIP, nodeID)
+}
+
+//streamInterceptor authenticates the node of every discovery request received on the stream
+func (a *peerAuthenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	p, ok := peer.FromContext(ss.Context())
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unknown peer")
+	}
+	peerIP, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return status.Error(codes.Unauthenticated, fmt.Sprintf("invalid peer address %s", p.Addr))
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, peerIP: peerIP, auth: a})
+}
+
+//authenticatedStream refuses the discovery requests whose node is not the peer
+type authenticatedStream struct {
+	grpc.ServerStream
+	peerIP string
+	auth   *peerAuthenticator
+}
+
+func (s *authenticatedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	var nodeID string
+	switch req := m.(type) {
+	case *v2.DiscoveryRequest:
+		if req.Node == nil {
+			return nil
+		}
+		nodeID = req.Node.Cluster
+	case *discoveryv3.DiscoveryRequest:
+		if req.Node == nil {
+			return nil
+		}
+		nodeID = req.Node.Cluster
+	case *discoveryv3.DeltaDiscoveryRequest:
+		if req.Node == nil {
+			return nil
+		}
+		nodeID = req.Node.Cluster
+	default:
+		return status.Errorf(codes.InvalidArgument, "unexpected message %T", m)
+	}
+	return s.auth.authenticate(s.peerIP, nodeID)
+}
@@ -0,0 +1,83 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kcache "k8s.io/client-go/tools/cache"
+)
+
+//requestStream receives the request once
+type requestStream struct {
+	grpc.ServerStream
+	req *discoveryv3.DeltaDiscoveryRequest
+}
+
+func (s *requestStream) RecvMsg(m interface{}) error {
+	*m.(*discoveryv3.DeltaDiscoveryRequest) = *s.req
+	return nil
+}
+
+func TestPeerAuthenticator(t *testing.T) {
+	pods := kcache.NewIndexer(kcache.MetaNamespaceKeyFunc, kcache.Indexers{podIPIndex: podIPIndexFunc})
+	pods.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "gr123456-0", Namespace: "tenant", Labels: map[string]string{"service_alias": "gr123456"}},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+	})
+	pods.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "tenant", Labels: map[string]string{"service_alias": "gr654321"}},
+		Spec:       corev1.PodSpec{HostNetwork: true},
+		Status:     corev1.PodStatus{PodIP: "192.168.0.1"},
+	})
+	auth := &peerAuthenticator{pods: pods}
+	testCases := []struct {
+		peerIP, nodeID string
+		allowed        bool
+	}{
+		{"10.0.0.1", "tenant_plugin_gr123456", true},
+		{"10.0.0.1", "tenant_plugin_gr654321", false},
+		{"10.0.0.1", "other_plugin_gr123456", false},
+		{"10.0.0.2", "tenant_plugin_gr123456", false},
+		// the pods of host network share the ip of node
+		{"192.168.0.1", "tenant_plugin_gr654321", false},
+		{"10.0.0.1", "invalid", false},
+	}
+	for _, tc := range testCases {
+		stream := &authenticatedStream{
+			ServerStream: &requestStream{req: &discoveryv3.DeltaDiscoveryRequest{Node: &corev3.Node{Cluster: tc.nodeID}}},
+			peerIP:       tc.peerIP,
+			auth:         auth,
+		}
+		err := stream.RecvMsg(&discoveryv3.DeltaDiscoveryRequest{})
+		if (err == nil) != tc.allowed {
+			t.Errorf("peer %s node %s: expected allowed %v, got %v", tc.peerIP, tc.nodeID, tc.allowed, err)
+		}
+		if err != nil && status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected permission denied, got %v", err)
+		}
+	}
+}
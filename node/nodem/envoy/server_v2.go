@@ -34,10 +34,14 @@ import (
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/server/v2"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
 	api_model "github.com/goodrain/rainbond/api/model"
 	"github.com/goodrain/rainbond/cmd/node/option"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
 	"github.com/goodrain/rainbond/node/nodem/envoy/conver"
+	"github.com/goodrain/rainbond/util/cert"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	corev1 "k8s.io/api/core/v1"
@@ -63,7 +67,13 @@ type DiscoverServerManager struct {
 	services        cacheHandler
 	endpoints       cacheHandler
 	configmaps      cacheHandler
+	pods            kcache.SharedIndexInformer
+	auth            *peerAuthenticator
 	queue           Queue
+	ca              *cert.CA
+	serverV3        *deltaServer
+	cacheManagerV3  cachev3.SnapshotCache
+	nodeVersions    sync.Map
 }
 
 // Hasher returns node ID as an ID
@@ -88,6 +98,11 @@ type NodeConfig struct {
 	configModel                    *api_model.ResourceSpec
 	dependServices                 sync.Map
 	listeners, clusters, endpoints []types.Resource
+	secrets                        []types.Resource
+	certPEM, keyPEM                []byte
+	certExpire                     time.Time
+	// xdsVersion the api version of the resources
+	xdsVersion string
 }
 
 //GetID get envoy node config id
@@ -214,6 +229,20 @@ func (d *DiscoverServerManager) UpdateNodeConfig(nc *NodeConfig) error {
 			endpoint = append(endpoint, downEndpoint...)
 		}
 	}
+	nc.xdsVersion = d.nodeXDSVersion(nc.nodeID)
+	if nc.xdsVersion == xdsVersionV3 {
+		d.updateNodeResourcesV3(nc, services, endpoint)
+	} else {
+		d.updateNodeResourcesV2(nc, services, endpoint)
+	}
+	d.updateNodeSecrets(nc)
+	//Fill the configuration information and inject envoy
+	nc.VersionUpdate()
+	return d.setSnapshot(nc)
+}
+
+//updateNodeResourcesV2 builds the v2 resources of the node config
+func (d *DiscoverServerManager) updateNodeResourcesV2(nc *NodeConfig, services []*corev1.Service, endpoint []*corev1.Endpoints) {
 	listeners, err := conver.OneNodeListerner(nc.serviceAlias, nc.namespace, nc.config, services)
 	if err != nil {
 		logrus.Errorf("create envoy listeners failure %s", err.Error())
@@ -235,9 +264,6 @@ func (d *DiscoverServerManager) UpdateNodeConfig(nc *NodeConfig) error {
 	} else {
 		nc.endpoints = clusterLoadAssignment
 	}
-	//Fill the configuration information and inject envoy
-	nc.VersionUpdate()
-	return d.setSnapshot(nc)
 }
 
 func (d *DiscoverServerManager) setSnapshot(nc *NodeConfig) error {
@@ -245,11 +271,16 @@ func (d *DiscoverServerManager) setSnapshot(nc *NodeConfig) error {
 		logrus.Warningf("node id: %s; node config cluster length is zero or listener length is zero,not set snapshot", nc.GetID())
 		return nil
 	}
+	if nc.xdsVersion == xdsVersionV3 {
+		return d.setSnapshotV3(nc)
+	}
 	snapshot := cache.NewSnapshot(nc.GetVersion(), nc.endpoints, nc.clusters, nil, nc.listeners, nil)
+	snapshot.Resources[types.Secret] = cache.NewResources(nc.GetVersion(), nc.secrets)
 	err := d.cacheManager.SetSnapshot(nc.nodeID, snapshot)
 	if err != nil {
 		return err
 	}
+	d.cacheManagerV3.ClearSnapshot(nc.nodeID)
 	logrus.Infof("cache envoy node %s config,version: %s", nc.GetID(), nc.GetVersion())
 	return nil
 }
@@ -257,13 +288,14 @@ func (d *DiscoverServerManager) setSnapshot(nc *NodeConfig) error {
 //CreateDiscoverServerManager create discover server manager
 func CreateDiscoverServerManager(clientset kubernetes.Interface, conf option.Conf) (*DiscoverServerManager, error) {
 	configcache := cache.NewSnapshotCache(false, Hasher{}, logrus.WithField("module", "config-cache"))
+	configcacheV3 := cachev3.NewSnapshotCache(true, HasherV3{}, logrus.WithField("module", "config-cache-v3"))
 	ctx, cancel := context.WithCancel(context.Background())
 	dsm := &DiscoverServerManager{
-		server:       server.NewServer(ctx, configcache, nil),
-		cacheManager: configcache,
-		kubecli:      clientset,
-		conf:         conf,
-		eventChan:    make(chan *Event, 100),
+		cacheManager:   configcache,
+		cacheManagerV3: configcacheV3,
+		kubecli:        clientset,
+		conf:           conf,
+		eventChan:      make(chan *Event, 100),
 		pool: &sync.Pool{
 			New: func() interface{} {
 				return &Task{}
@@ -273,6 +305,17 @@ func CreateDiscoverServerManager(clientset kubernetes.Interface, conf option.Con
 		cancel: cancel,
 		queue:  NewQueue(1 * time.Second),
 	}
+	dsm.server = server.NewServer(ctx, configcache, callbacksV2{d: dsm})
+	callbacks := callbacksV3{d: dsm}
+	dsm.serverV3 = newDeltaServer(serverv3.NewServer(ctx, configcacheV3, callbacks), configcacheV3, HasherV3{}, callbacks.onNode)
+	envoyv2.MeshTrustDomain = conf.MeshTrustDomain
+	ca, err := loadOrCreateMeshCA(clientset, conf.RbdNamespace)
+	if err != nil {
+		// the listeners and clusters of mutual tls can not be warmed without the secrets issued by the ca
+		cancel()
+		return nil, fmt.Errorf("load the platform ca of the built-in service mesh failure %s", err.Error())
+	}
+	dsm.ca = ca
 	sharedInformers := informers.NewFilteredSharedInformerFactory(dsm.kubecli, time.Second*10, corev1.NamespaceAll, func(options *meta_v1.ListOptions) {
 		options.LabelSelector = "creator=Rainbond"
 	})
@@ -285,6 +328,12 @@ func CreateDiscoverServerManager(clientset kubernetes.Interface, conf option.Con
 	dsm.configmaps.handler.Append(dsm.configHandle)
 	dsm.endpoints.handler.Append(dsm.resourceSimpleHandle)
 	dsm.services.handler.Append(dsm.resourceSimpleHandle)
+	dsm.pods = sharedInformers.Core().V1().Pods().Informer()
+	if err := dsm.pods.AddIndexers(kcache.Indexers{podIPIndex: podIPIndexFunc}); err != nil {
+		cancel()
+		return nil, err
+	}
+	dsm.auth = &peerAuthenticator{pods: dsm.pods.GetIndexer()}
 	return dsm, nil
 }
 
@@ -294,11 +343,13 @@ const grpcMaxConcurrentStreams = 1000000
 func (d *DiscoverServerManager) Start(errch chan error) error {
 	go func() {
 		go d.queue.Run(d.ctx.Done())
+		go d.rotateCerts()
 		go d.services.informer.Run(d.ctx.Done())
 		go d.endpoints.informer.Run(d.ctx.Done())
+		go d.pods.Run(d.ctx.Done())
 		//waiting service and endpoint resource loading is complete
 		logrus.Infof("waiting kube service and endpoint resource loading")
-		kcache.WaitForCacheSync(d.ctx.Done(), d.services.informer.HasSynced, d.endpoints.informer.HasSynced)
+		kcache.WaitForCacheSync(d.ctx.Done(), d.services.informer.HasSynced, d.endpoints.informer.HasSynced, d.pods.HasSynced)
 		logrus.Infof("kube service and endpoint resource loading success")
 		//loading rule config resource
 		go d.configmaps.informer.Run(d.ctx.Done())
@@ -308,6 +359,8 @@ func (d *DiscoverServerManager) Start(errch chan error) error {
 		// availability problems.
 		var grpcOptions []grpc.ServerOption
 		grpcOptions = append(grpcOptions, grpc.MaxConcurrentStreams(grpcMaxConcurrentStreams))
+		// the envoy nodes are bound to the pods they connect from, see peerAuthenticator
+		grpcOptions = append(grpcOptions, grpc.StreamInterceptor(d.auth.streamInterceptor))
 		d.grpcServer = grpc.NewServer(grpcOptions...)
 		// register services
 		discovery.RegisterAggregatedDiscoveryServiceServer(d.grpcServer, d.server)
@@ -316,6 +369,7 @@ func (d *DiscoverServerManager) Start(errch chan error) error {
 		v2.RegisterRouteDiscoveryServiceServer(d.grpcServer, d.server)
 		v2.RegisterListenerDiscoveryServiceServer(d.grpcServer, d.server)
 		discovery.RegisterSecretDiscoveryServiceServer(d.grpcServer, d.server)
+		d.registerV3(d.grpcServer)
 		logrus.Infof("envoy grpc management server listening %s", d.conf.GrpcAPIAddr)
 		lis, err := net.Listen("tcp", d.conf.GrpcAPIAddr)
 		if err != nil {
@@ -329,6 +383,20 @@ func (d *DiscoverServerManager) Start(errch chan error) error {
 	return nil
 }
 
+//rotateCerts checks the expiration of the workload certificates periodically
+func (d *DiscoverServerManager) rotateCerts() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.queue.Push(NewTask(d.certRotateHandle, nil, EventUpdate))
+		}
+	}
+}
+
 //Stop stop grpc server
 func (d *DiscoverServerManager) Stop() {
 	//d.grpcServer.GracefulStop()
@@ -391,6 +459,7 @@ func (d *DiscoverServerManager) AddNodeConfig(nc *NodeConfig) {
 	for i, existNC := range d.cacheNodeConfig {
 		if existNC.nodeID == nc.nodeID {
 			nc.version = existNC.version
+			nc.certPEM, nc.keyPEM, nc.certExpire = existNC.certPEM, existNC.keyPEM, existNC.certExpire
 			d.cacheNodeConfig[i] = nc
 			exist = true
 			break
@@ -409,6 +478,7 @@ func (d *DiscoverServerManager) DeleteNodeConfig(nodeID string) {
 	for i, existNC := range d.cacheNodeConfig {
 		if existNC.nodeID == nodeID {
 			d.cacheManager.ClearSnapshot(existNC.nodeID)
+			d.cacheManagerV3.ClearSnapshot(existNC.nodeID)
 			d.cacheNodeConfig = append(d.cacheNodeConfig[:i], d.cacheNodeConfig[i+1:]...)
 		}
 	}
@@ -0,0 +1,196 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"context"
+
+	corev2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	secretservice "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	envoyv3 "github.com/goodrain/rainbond/node/core/envoy/v3"
+	"github.com/goodrain/rainbond/node/nodem/envoy/conver"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	xdsVersionV2 = "v2"
+	xdsVersionV3 = "v3"
+	//nodeMetadataXDSVersion the node metadata reported by the sidecar which chooses the xds version of the node
+	nodeMetadataXDSVersion = "xds_version"
+)
+
+// HasherV3 returns node ID as an ID
+type HasherV3 struct {
+}
+
+// ID function
+func (h HasherV3) ID(node *corev3.Node) string {
+	if node == nil {
+		return "unknown"
+	}
+	return node.Cluster
+}
+
+//nodeXDSVersion returns the xds version used by the envoy node, v2 if the node has never connected
+func (d *DiscoverServerManager) nodeXDSVersion(nodeID string) string {
+	if version, ok := d.nodeVersions.Load(nodeID); ok {
+		return version.(string)
+	}
+	return xdsVersionV2
+}
+
+//recordNodeXDSVersion records the xds version of the envoy node, the metadata reported
+//by the sidecar takes precedence over the api version of the transport.
+//The node config is regenerated if the version is changed.
+func (d *DiscoverServerManager) recordNodeXDSVersion(nodeID string, metadata *_struct.Struct, transportVersion string) {
+	version := transportVersion
+	if value, ok := metadata.GetFields()[nodeMetadataXDSVersion]; ok {
+		switch value.GetStringValue() {
+		case xdsVersionV2, xdsVersionV3:
+			version = value.GetStringValue()
+		default:
+			logrus.Warningf("envoy node %s reports unknown xds version %s", nodeID, value.GetStringValue())
+		}
+	}
+	old, ok := d.nodeVersions.Load(nodeID)
+	if ok && old.(string) == version {
+		return
+	}
+	d.nodeVersions.Store(nodeID, version)
+	if !ok && version == xdsVersionV2 {
+		// the node config is generated in v2 by default
+		return
+	}
+	logrus.Infof("envoy node %s uses xds %s", nodeID, version)
+	d.queue.Push(NewTask(d.nodeVersionHandle, nodeID, EventUpdate))
+}
+
+//nodeVersionHandle regenerates the config of the node whose xds version is changed
+func (d *DiscoverServerManager) nodeVersionHandle(obj interface{}, event Event) error {
+	nodeID, _ := obj.(string)
+	for i, nodeConfig := range d.cacheNodeConfig {
+		if nodeConfig.nodeID == nodeID {
+			return d.UpdateNodeConfig(d.cacheNodeConfig[i])
+		}
+	}
+	return nil
+}
+
+//updateNodeResourcesV3 builds the v3 resources of the node config
+func (d *DiscoverServerManager) updateNodeResourcesV3(nc *NodeConfig, services []*corev1.Service, endpoints []*corev1.Endpoints) {
+	listeners, err := conver.OneNodeListernerV3(nc.serviceAlias, nc.namespace, nc.config, services)
+	if err != nil {
+		logrus.Errorf("create envoy listeners failure %s", err.Error())
+	} else {
+		nc.listeners = listeners
+	}
+	clusters, err := conver.OneNodeClusterV3(nc.serviceAlias, nc.namespace, nc.config, services)
+	if err != nil {
+		logrus.Errorf("create envoy clusters failure %s", err.Error())
+	} else {
+		nc.clusters = clusters
+	}
+	nc.endpoints = conver.OneNodeClusterLoadAssignmentV3(nc.serviceAlias, nc.namespace, endpoints, services)
+	if len(nc.endpoints) == 0 {
+		logrus.Warningf("configmap name: %s; plugin-config: %s; empty clusterLoadAssignment", nc.config.Name, nc.config.Data["plugin-config"])
+	}
+}
+
+//meshSecretsV3 creates the v3 sds secrets of the workload certificate
+func (d *DiscoverServerManager) meshSecretsV3(nc *NodeConfig) []types.Resource {
+	return []types.Resource{
+		envoyv3.CreateTLSCertificateSecret(envoyv2.MeshCertSecretName, nc.certPEM, nc.keyPEM),
+		envoyv3.CreateValidationContextSecret(envoyv2.MeshValidationSecretName, d.ca.CertPEM()),
+	}
+}
+
+func (d *DiscoverServerManager) setSnapshotV3(nc *NodeConfig) error {
+	snapshot := cachev3.NewSnapshot(nc.GetVersion(), nc.endpoints, nc.clusters, nil, nc.listeners, nil)
+	snapshot.Resources[types.Secret] = cachev3.NewResources(nc.GetVersion(), nc.secrets)
+	if err := d.cacheManagerV3.SetSnapshot(nc.nodeID, snapshot); err != nil {
+		return err
+	}
+	d.cacheManager.ClearSnapshot(nc.nodeID)
+	d.serverV3.notify(nc.nodeID)
+	logrus.Infof("cache envoy node %s v3 config,version: %s", nc.GetID(), nc.GetVersion())
+	return nil
+}
+
+//registerV3 registers the v3 discovery services, they are served alongside v2 during the transition
+func (d *DiscoverServerManager) registerV3(grpcServer *grpc.Server) {
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcServer, d.serverV3)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, d.serverV3)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, d.serverV3)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, d.serverV3)
+	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, d.serverV3)
+	secretservice.RegisterSecretDiscoveryServiceServer(grpcServer, d.serverV3)
+}
+
+//callbacksV2 records the xds version of the nodes connected by v2 transport
+type callbacksV2 struct {
+	d *DiscoverServerManager
+}
+
+func (c callbacksV2) OnStreamOpen(context.Context, int64, string) error { return nil }
+func (c callbacksV2) OnStreamClosed(int64)                              {}
+func (c callbacksV2) OnStreamRequest(id int64, req *corev2.DiscoveryRequest) error {
+	c.d.recordNodeXDSVersion(Hasher{}.ID(req.Node), req.Node.GetMetadata(), xdsVersionV2)
+	return nil
+}
+func (c callbacksV2) OnStreamResponse(int64, *corev2.DiscoveryRequest, *corev2.DiscoveryResponse) {}
+func (c callbacksV2) OnFetchRequest(ctx context.Context, req *corev2.DiscoveryRequest) error {
+	c.d.recordNodeXDSVersion(Hasher{}.ID(req.Node), req.Node.GetMetadata(), xdsVersionV2)
+	return nil
+}
+func (c callbacksV2) OnFetchResponse(*corev2.DiscoveryRequest, *corev2.DiscoveryResponse) {}
+
+//callbacksV3 records the xds version of the nodes connected by v3 transport
+type callbacksV3 struct {
+	d *DiscoverServerManager
+}
+
+func (c callbacksV3) OnStreamOpen(context.Context, int64, string) error { return nil }
+func (c callbacksV3) OnStreamClosed(int64)                              {}
+func (c callbacksV3) OnStreamRequest(id int64, req *discoveryv3.DiscoveryRequest) error {
+	c.onNode(req.Node)
+	return nil
+}
+func (c callbacksV3) OnStreamResponse(int64, *discoveryv3.DiscoveryRequest, *discoveryv3.DiscoveryResponse) {
+}
+func (c callbacksV3) OnFetchRequest(ctx context.Context, req *discoveryv3.DiscoveryRequest) error {
+	c.onNode(req.Node)
+	return nil
+}
+func (c callbacksV3) OnFetchResponse(*discoveryv3.DiscoveryRequest, *discoveryv3.DiscoveryResponse) {}
+
+func (c callbacksV3) onNode(node *corev3.Node) {
+	c.d.recordNodeXDSVersion(HasherV3{}.ID(node), node.GetMetadata(), xdsVersionV3)
+}
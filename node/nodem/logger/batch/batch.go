@@ -0,0 +1,358 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	units "github.com/docker/go-units"
+	"github.com/goodrain/rainbond/node/nodem/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultBatchWait     = time.Second
+	defaultQueueSize     = 10000
+	defaultBufferDir     = "/opt/rainbond/data/logger-buffer"
+	defaultMaxBufferSize = 256 * 1024 * 1024
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// the max batches replayed from the disk buffer in one tick, so that the new logs are not starved
+	maxReplayBatches = 10
+)
+
+// ErrQueueFull the log is dropped because the queue is full in non-blocking mode
+var ErrQueueFull = errors.New("log queue is full")
+
+// ErrClosed the writer is closed
+var ErrClosed = errors.New("log writer is closed")
+
+// CommonOpts the log opts supported by all batch drivers
+var CommonOpts = map[string]bool{
+	"mode":            true,
+	"batch-size":      true,
+	"batch-wait":      true,
+	"queue-size":      true,
+	"buffer-dir":      true,
+	"max-buffer-size": true,
+}
+
+// Entry a log line of the container
+type Entry struct {
+	Line      []byte    `json:"line"`
+	Source    string    `json:"source"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewEntry creates an entry from the message, the message can be reused after that.
+func NewEntry(msg *logger.Message) *Entry {
+	line := bytes.TrimRight(msg.Line, "\r\n")
+	entry := &Entry{
+		Line:      make([]byte, len(line)),
+		Source:    msg.Source,
+		Timestamp: msg.Timestamp,
+	}
+	copy(entry.Line, line)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	return entry
+}
+
+// Sender sends a batch of logs to the sink
+type Sender interface {
+	Send(entries []*Entry) error
+	Close() error
+}
+
+// Config the batch config of a log driver
+type Config struct {
+	BatchSize int
+	BatchWait time.Duration
+	QueueSize int
+	// the logs are buffered in the dir when the sink is down, 0 MaxBufferSize disables the disk buffer
+	BufferDir     string
+	MaxBufferSize int64
+	// drop the logs instead of blocking the collector when the queue is full
+	NonBlocking bool
+}
+
+// ParseConfig parses the batch config from the log opts
+func ParseConfig(opts map[string]string) (*Config, error) {
+	cfg := &Config{
+		BatchSize:     defaultBatchSize,
+		BatchWait:     defaultBatchWait,
+		QueueSize:     defaultQueueSize,
+		BufferDir:     defaultBufferDir,
+		MaxBufferSize: defaultMaxBufferSize,
+		NonBlocking:   containertypes.LogMode(opts["mode"]) == containertypes.LogModeNonBlock,
+	}
+	switch mode := containertypes.LogMode(opts["mode"]); mode {
+	case containertypes.LogModeUnset, containertypes.LogModeBlocking, containertypes.LogModeNonBlock:
+	default:
+		return nil, fmt.Errorf("mode must be %s or %s", containertypes.LogModeBlocking, containertypes.LogModeNonBlock)
+	}
+	var err error
+	if s, ok := opts["batch-size"]; ok {
+		if cfg.BatchSize, err = strconv.Atoi(s); err != nil || cfg.BatchSize <= 0 {
+			return nil, fmt.Errorf("batch-size must be a positive number")
+		}
+	}
+	if s, ok := opts["batch-wait"]; ok {
+		if cfg.BatchWait, err = time.ParseDuration(s); err != nil || cfg.BatchWait <= 0 {
+			return nil, fmt.Errorf("batch-wait must be a positive duration")
+		}
+	}
+	if s, ok := opts["queue-size"]; ok {
+		if cfg.QueueSize, err = strconv.Atoi(s); err != nil || cfg.QueueSize <= 0 {
+			return nil, fmt.Errorf("queue-size must be a positive number")
+		}
+	}
+	if s, ok := opts["buffer-dir"]; ok && s != "" {
+		cfg.BufferDir = s
+	}
+	if s, ok := opts["max-buffer-size"]; ok {
+		if cfg.MaxBufferSize, err = units.RAMInBytes(s); err != nil {
+			return nil, fmt.Errorf("error parsing option max-buffer-size: %v", err)
+		}
+	}
+	return cfg, nil
+}
+
+// ValidateOpts validates the log opts, the opts of the driver are given by driverOpts.
+func ValidateOpts(driver string, cfg map[string]string, driverOpts map[string]bool) error {
+	for key := range cfg {
+		if !CommonOpts[key] && !driverOpts[key] {
+			return fmt.Errorf("unknown log opt '%s' for %s log driver", key, driver)
+		}
+	}
+	_, err := ParseConfig(cfg)
+	return err
+}
+
+// ContainerMeta returns the metadata attached to every log of the container.
+func ContainerMeta(info logger.Info) map[string]string {
+	meta := info.ExtraAttributes(nil)
+	for _, pair := range info.ContainerEnv {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "TENANT_ID":
+			meta["tenant_id"] = kv[1]
+		case "SERVICE_ID":
+			meta["service_id"] = kv[1]
+		case "SERVICE_NAME":
+			meta["service_alias"] = kv[1]
+		}
+	}
+	meta["container_name"] = info.Name()
+	if len(info.ContainerID) >= 12 {
+		meta["container_id"] = info.ID()
+	}
+	if hostname, err := info.Hostname(); err == nil {
+		meta["host"] = hostname
+	}
+	return meta
+}
+
+// Writer batches the logs and sends them by the sender in the background.
+// When the sink is down, the logs are buffered in the disk and replayed in order after the sink recovers.
+// If the disk buffer is disabled, the writer retries with backoff and blocks the log collector
+// once the queue is full, or drops the logs in non-blocking mode.
+type Writer struct {
+	name    string
+	cfg     *Config
+	sender  Sender
+	queue   chan *Entry
+	buffer  *diskBuffer
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	retryAt time.Time
+	backoff time.Duration
+	dropped int64
+}
+
+// NewWriter creates and starts a writer, id identifies the disk buffer of the writer.
+func NewWriter(name, id string, cfg *Config, sender Sender) (*Writer, error) {
+	w := &Writer{
+		name:   name,
+		cfg:    cfg,
+		sender: sender,
+		queue:  make(chan *Entry, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if cfg.MaxBufferSize > 0 {
+		buffer, err := openDiskBuffer(filepath.Join(cfg.BufferDir, id), cfg.MaxBufferSize)
+		if err != nil {
+			return nil, fmt.Errorf("open disk buffer of %s log driver: %v", name, err)
+		}
+		w.buffer = buffer
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write puts the entry into the queue
+func (w *Writer) Write(entry *Entry) error {
+	select {
+	case w.queue <- entry:
+		return nil
+	default:
+	}
+	if w.cfg.NonBlocking {
+		if n := atomic.AddInt64(&w.dropped, 1); n%1000 == 1 {
+			logrus.Warningf("the log queue of %s log driver is full, %d logs dropped", w.name, n)
+		}
+		return ErrQueueFull
+	}
+	select {
+	case w.queue <- entry:
+		return nil
+	case <-w.stop:
+		return ErrClosed
+	}
+}
+
+// Close sends the logs in the queue and stops the writer
+func (w *Writer) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	return nil
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+	batch := make([]*Entry, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+			w.replay()
+		case <-w.stop:
+			for len(w.queue) > 0 {
+				batch = append(batch, <-w.queue)
+			}
+			if len(batch) > 0 {
+				w.flush(batch)
+			}
+			w.replay()
+			if err := w.sender.Close(); err != nil {
+				logrus.Warningf("close sender of %s log driver: %v", w.name, err)
+			}
+			if w.buffer != nil {
+				w.buffer.Close()
+			}
+			return
+		}
+	}
+}
+
+func (w *Writer) flush(batch []*Entry) {
+	// keep the logs in order, the new logs wait in the disk buffer until the buffered ones are sent.
+	if w.buffer != nil && !w.buffer.Empty() {
+		w.spill(batch)
+		return
+	}
+	for {
+		if !time.Now().Before(w.retryAt) {
+			err := w.sender.Send(batch)
+			if err == nil {
+				w.backoff = 0
+				return
+			}
+			w.failed(err)
+		}
+		if w.buffer != nil {
+			w.spill(batch)
+			return
+		}
+		select {
+		case <-w.stop:
+			logrus.Warningf("%s log driver is closed, %d logs dropped", w.name, len(batch))
+			return
+		case <-time.After(time.Until(w.retryAt)):
+		}
+	}
+}
+
+func (w *Writer) spill(batch []*Entry) {
+	if err := w.buffer.Put(batch); err != nil {
+		logrus.Warningf("buffer logs of %s log driver failure, %d logs dropped: %v", w.name, len(batch), err)
+	}
+}
+
+func (w *Writer) replay() {
+	if w.buffer == nil || time.Now().Before(w.retryAt) {
+		return
+	}
+	for i := 0; i < maxReplayBatches && !w.buffer.Empty(); i++ {
+		entries, offset, err := w.buffer.Read(w.cfg.BatchSize)
+		if err != nil {
+			logrus.Errorf("read disk buffer of %s log driver: %v, the buffer will be reset", w.name, err)
+			w.buffer.Reset()
+			return
+		}
+		if err := w.sender.Send(entries); err != nil {
+			w.failed(err)
+			return
+		}
+		w.backoff = 0
+		w.buffer.Commit(offset)
+	}
+}
+
+func (w *Writer) failed(err error) {
+	w.backoff *= 2
+	if w.backoff < minBackoff {
+		w.backoff = minBackoff
+	}
+	if w.backoff > maxBackoff {
+		w.backoff = maxBackoff
+	}
+	w.retryAt = time.Now().Add(w.backoff)
+	logrus.Warningf("send logs by %s log driver failure, will retry after %s: %v", w.name, w.backoff, err)
+}
@@ -0,0 +1,179 @@
+package batch
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSender struct {
+	lock  sync.Mutex
+	down  bool
+	lines []string
+}
+
+func (f *fakeSender) Send(entries []*Entry) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.down {
+		return errors.New("sink is down")
+	}
+	for _, e := range entries {
+		f.lines = append(f.lines, string(e.Line))
+	}
+	return nil
+}
+
+func (f *fakeSender) Close() error { return nil }
+
+func (f *fakeSender) setDown(down bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.down = down
+}
+
+func (f *fakeSender) received() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.lines...)
+}
+
+func TestDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buffer")
+	buffer, err := openDiskBuffer(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buffer.Put([]*Entry{{Line: []byte("a")}, {Line: []byte("b")}, {Line: []byte("c")}}); err != nil {
+		t.Fatal(err)
+	}
+	entries, offset, err := buffer.Read(2)
+	if err != nil || len(entries) != 2 || string(entries[1].Line) != "b" {
+		t.Fatalf("unexpected entries %v: %v", entries, err)
+	}
+	buffer.Commit(offset)
+	buffer.Close()
+
+	// the read offset survives reopening
+	buffer, err = openDiskBuffer(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, offset, err = buffer.Read(2)
+	if err != nil || len(entries) != 1 || string(entries[0].Line) != "c" {
+		t.Fatalf("unexpected entries %v: %v", entries, err)
+	}
+	buffer.Commit(offset)
+	if !buffer.Empty() {
+		t.Errorf("buffer should be empty")
+	}
+	if err := buffer.Put([]*Entry{{Line: make([]byte, 1024)}}); err != errBufferFull {
+		t.Errorf("expected buffer full, but got %v", err)
+	}
+	buffer.Close()
+}
+
+func TestDiskBufferTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buffer")
+	buffer, err := openDiskBuffer(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buffer.Put([]*Entry{{Line: []byte("a")}, {Line: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Close()
+	// the node crashed while writing the last record
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"line":"Y`)
+	file.Close()
+
+	buffer, err = openDiskBuffer(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, offset, err := buffer.Read(10)
+	if err != nil || len(entries) != 2 || string(entries[1].Line) != "b" {
+		t.Fatalf("unexpected entries %v: %v", entries, err)
+	}
+	buffer.Commit(offset)
+	if !buffer.Empty() {
+		t.Errorf("buffer should be empty after reading all the complete records")
+	}
+	if err := buffer.Put([]*Entry{{Line: []byte("c")}}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err = buffer.Read(10)
+	if err != nil || len(entries) != 1 || string(entries[0].Line) != "c" {
+		t.Fatalf("unexpected entries %v: %v", entries, err)
+	}
+	buffer.Close()
+}
+
+func TestWriterReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sender := &fakeSender{down: true}
+	w, err := NewWriter("fake", "container", &Config{
+		BatchSize:     2,
+		BatchWait:     10 * time.Millisecond,
+		QueueSize:     10,
+		BufferDir:     dir,
+		MaxBufferSize: 1024 * 1024,
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"1", "2", "3"} {
+		w.Write(&Entry{Line: []byte(line), Timestamp: time.Now()})
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(sender.received()) != 0 {
+		t.Fatalf("no logs should be sent while the sink is down")
+	}
+	sender.setDown(false)
+	// wait for the backoff
+	time.Sleep(1500 * time.Millisecond)
+	w.Write(&Entry{Line: []byte("4"), Timestamp: time.Now()})
+	w.Close()
+	got := sender.received()
+	if len(got) != 4 || got[0] != "1" || got[3] != "4" {
+		t.Errorf("expected logs sent in order, but got %v", got)
+	}
+}
+
+func TestValidateOpts(t *testing.T) {
+	if err := ValidateOpts("loki", map[string]string{"mode": "non-blocking", "queue-size": "10"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, _ := ParseConfig(map[string]string{"mode": "non-blocking"})
+	if !cfg.NonBlocking {
+		t.Errorf("expected non-blocking mode")
+	}
+	if err := ValidateOpts("loki", map[string]string{"mode": "async"}, nil); err == nil {
+		t.Errorf("expected error for the unknown mode")
+	}
+	if err := ValidateOpts("loki", map[string]string{"unknown": "1"}, nil); err == nil {
+		t.Errorf("expected error for the unknown opt")
+	}
+}
@@ -0,0 +1,179 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var errBufferFull = errors.New("disk buffer is full")
+
+// diskBuffer a file based fifo queue of log entries, the entries are stored as json lines.
+// The read offset is persisted in the offset file, so that the buffered logs survive the restart of node.
+// It is not safe for concurrent use.
+type diskBuffer struct {
+	file       *os.File
+	offsetPath string
+	size       int64
+	offset     int64
+	maxSize    int64
+}
+
+func openDiskBuffer(path string, maxSize int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// drop the torn tail left by a crash while writing, so that the buffer ends with a complete line
+	size, err := lastLineEnd(file, info.Size())
+	if err == nil && size < info.Size() {
+		err = file.Truncate(size)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	d := &diskBuffer{
+		file:       file,
+		offsetPath: path + ".offset",
+		size:       size,
+		maxSize:    maxSize,
+	}
+	if data, err := ioutil.ReadFile(d.offsetPath); err == nil {
+		offset, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if offset > 0 && offset <= d.size {
+			d.offset = offset
+		}
+	}
+	return d, nil
+}
+
+// Empty returns whether all the buffered entries are read
+func (d *diskBuffer) Empty() bool {
+	return d.offset >= d.size
+}
+
+// Put appends the entries to the buffer
+func (d *diskBuffer) Put(entries []*Entry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if d.size+int64(buf.Len()) > d.maxSize {
+		return errBufferFull
+	}
+	if _, err := d.file.WriteAt(buf.Bytes(), d.size); err != nil {
+		// drop the partially written entries
+		d.file.Truncate(d.size)
+		return err
+	}
+	d.size += int64(buf.Len())
+	return nil
+}
+
+// Read reads at most n entries from the read offset, and returns the offset after them.
+// The entries are not removed until the offset is committed.
+func (d *diskBuffer) Read(n int) ([]*Entry, int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(d.file, d.offset, d.size-d.offset))
+	offset := d.offset
+	var entries []*Entry
+	for len(entries) < n {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, 0, err
+		}
+		offset += int64(len(line))
+		entries = append(entries, &entry)
+	}
+	return entries, offset, nil
+}
+
+// Commit moves the read offset, the file is truncated once all entries are read.
+func (d *diskBuffer) Commit(offset int64) {
+	d.offset = offset
+	if d.offset >= d.size {
+		d.Reset()
+		return
+	}
+	ioutil.WriteFile(d.offsetPath, []byte(strconv.FormatInt(d.offset, 10)), 0644)
+}
+
+// lastLineEnd returns the offset after the last newline of the file
+func lastLineEnd(file *os.File, size int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(buf[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// Reset drops all the buffered entries
+func (d *diskBuffer) Reset() {
+	d.file.Truncate(0)
+	d.size, d.offset = 0, 0
+	os.Remove(d.offsetPath)
+}
+
+// Close closes the buffer, the files are removed if there are no entries left.
+func (d *diskBuffer) Close() {
+	empty := d.Empty()
+	d.file.Close()
+	if empty {
+		os.Remove(d.file.Name())
+		os.Remove(d.offsetPath)
+	}
+}
@@ -39,6 +39,7 @@ type Copier struct {
 	reader  *LogWatcher
 	since   time.Time
 	once    sync.Once
+	parser  *LogParser
 }
 
 // NewCopier creates a new Copier
@@ -60,6 +61,12 @@ func (c *Copier) Run() {
 
 func (c *Copier) copySrc() {
 	defer c.reader.ConsumerGone()
+	var flush <-chan time.Time
+	if c.parser != nil && c.parser.pattern != nil {
+		ticker := time.NewTicker(c.parser.timeout)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
 lool:
 	for {
 		select {
@@ -76,10 +83,25 @@ lool:
 			if !ok {
 				break lool
 			}
-			for _, d := range c.dst {
-				if err := d.Log(msg); err != nil {
-					logrus.Debugf("copy container log failure %s", err.Error())
-				}
+			if c.parser != nil {
+				c.copy(c.parser.Parse(msg)...)
+				continue
+			}
+			c.copy(msg)
+		case <-flush:
+			c.copy(c.parser.Flush(false)...)
+		}
+	}
+	if c.parser != nil {
+		c.copy(c.parser.Flush(true)...)
+	}
+}
+
+func (c *Copier) copy(msgs ...*Message) {
+	for _, msg := range msgs {
+		for _, d := range c.dst {
+			if err := d.Log(msg); err != nil {
+				logrus.Debugf("copy container log failure %s", err.Error())
 			}
 		}
 	}
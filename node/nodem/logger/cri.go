@@ -0,0 +1,266 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	criTagPartial = 'P'
+	// the interval of scanning the pod log dir
+	criSyncInterval = time.Second * 5
+)
+
+// parseCRILog parses a log line in CRI format, such as:
+// 2016-10-06T00:17:09.669794202Z stdout F log content
+func parseCRILog(line []byte) (*Message, bool, error) {
+	parts := bytes.SplitN(bytes.TrimSuffix(line, []byte("\n")), []byte{' '}, 4)
+	if len(parts) < 3 {
+		return nil, false, fmt.Errorf("invalid cri log")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, string(parts[0]))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid timestamp of cri log: %v", err)
+	}
+	stream := string(parts[1])
+	if stream != "stdout" && stream != "stderr" {
+		return nil, false, fmt.Errorf("invalid stream of cri log: %s", stream)
+	}
+	if len(parts[2]) == 0 {
+		return nil, false, fmt.Errorf("empty tag of cri log")
+	}
+	partial := parts[2][0] == criTagPartial
+	msg := &Message{
+		Source:    stream,
+		Timestamp: timestamp,
+	}
+	if len(parts) == 4 {
+		msg.Line = append(msg.Line, parts[3]...)
+	}
+	// keep the same as json-file, the full line ends with a newline
+	if !partial {
+		msg.Line = append(msg.Line, '\n')
+	}
+	return msg, partial, nil
+}
+
+// criDecodeFunc creates a decoder for the log file in CRI format, the partial lines are merged into one message.
+func criDecodeFunc(rdr io.Reader) func() (*Message, error) {
+	reader := bufio.NewReader(rdr)
+	return func() (*Message, error) {
+		var msg *Message
+		// the bytes read for the current message
+		var consumed int64
+		for {
+			line, err := reader.ReadBytes('\n')
+			consumed += int64(len(line))
+			if err != nil {
+				// the rest of the message has not been written yet. The decoder is recreated
+				// after the file is written, so seek back to the beginning of the message.
+				if err == io.EOF && consumed > 0 {
+					if seeker, ok := rdr.(io.Seeker); ok {
+						if _, serr := seeker.Seek(-consumed, io.SeekCurrent); serr != nil {
+							return nil, serr
+						}
+					}
+				}
+				return nil, err
+			}
+			m, partial, err := parseCRILog(line)
+			if err != nil {
+				logrus.Debugf("skip the line %q: %v", line, err)
+				if msg == nil {
+					consumed = 0
+				}
+				continue
+			}
+			if msg == nil {
+				msg = m
+			} else {
+				msg.Line = append(msg.Line, m.Line...)
+			}
+			if !partial {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// listAndWatchPodLogs collects the logs of the containers created by CRI runtimes, such as containerd and CRI-O.
+// The containers are discovered from the pod log dir of kubelet(<pod-log-path>/<namespace>_<pod>_<uid>/<container>/<restart>.log),
+// and their metadata come from the pods scheduled to this node.
+func (c *ContainerLogManage) listAndWatchPodLogs() {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.conf.KubeClient, time.Minute*10,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "spec.nodeName=" + c.conf.HostID
+		}))
+	podInformer := factory.Core().V1().Pods()
+	lister := podInformer.Lister()
+	factory.Start(c.ctx.Done())
+	if !cache.WaitForCacheSync(c.ctx.Done(), podInformer.Informer().HasSynced) {
+		logrus.Errorf("wait for the pods of node %s synced failure", c.conf.HostID)
+		return
+	}
+	logrus.Infof("pods of node %s synced, start collect logs from %s", c.conf.HostID, c.conf.PodLogPath)
+	ticker := time.NewTicker(criSyncInterval)
+	defer ticker.Stop()
+	for {
+		c.syncPodLogs(lister)
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncPodLogs starts copying the logs of the new containers, and stops the ones of the exited containers.
+func (c *ContainerLogManage) syncPodLogs(lister corelisters.PodLister) {
+	dirs, err := filepath.Glob(filepath.Join(c.conf.PodLogPath, "*", "*"))
+	if err != nil {
+		logrus.Errorf("list pod log dir %s failure %s", c.conf.PodLogPath, err.Error())
+		return
+	}
+	running := make(map[string]bool)
+	for _, dir := range dirs {
+		container := c.getCRIContainer(lister, dir)
+		if container == nil {
+			continue
+		}
+		running[container.ID] = true
+		if _, ok := c.containerLogs.Load(container.ID); ok {
+			continue
+		}
+		reader, err := NewLogFile(container.LogPath, 2, false, criDecodeFunc, 0640, getTailReader)
+		if err != nil {
+			logrus.Errorf("create logger for container %s failure %s", container.Name, err.Error())
+			continue
+		}
+		clog := createContainerLog(c.ctx, *container, reader)
+		if err := clog.StartLogging(); err != nil {
+			clog.Stop()
+			logrus.Errorf("start copy container log failure %s", err.Error())
+			continue
+		}
+		c.containerLogs.Store(container.ID, clog)
+		logrus.Infof("start copy container log for container %s", container.Name)
+	}
+	c.containerLogs.Range(func(k, v interface{}) bool {
+		if !running[k.(string)] {
+			clog := v.(*ContainerLog)
+			clog.Stop()
+			c.containerLogs.Delete(k)
+			logrus.Infof("remove copy container log for container %s", clog.Name)
+		}
+		return true
+	})
+}
+
+// getCRIContainer returns the container info of the container log dir,
+// it returns nil if the container is not running or does not define rainbond logger.
+func (c *ContainerLogManage) getCRIContainer(lister corelisters.PodLister, dir string) *types.ContainerJSON {
+	containerName := filepath.Base(dir)
+	// the namespace and the name of pod can not contain '_'
+	podInfo := strings.SplitN(filepath.Base(filepath.Dir(dir)), "_", 3)
+	if len(podInfo) != 3 {
+		return nil
+	}
+	pod, err := lister.Pods(podInfo[0]).Get(podInfo[1])
+	if err != nil || string(pod.UID) != podInfo[2] {
+		return nil
+	}
+	var status *corev1.ContainerStatus
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == containerName {
+			status = &pod.Status.ContainerStatuses[i]
+		}
+	}
+	if status == nil || status.State.Running == nil || status.ContainerID == "" {
+		return nil
+	}
+	var spec *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			spec = &pod.Spec.Containers[i]
+		}
+	}
+	if spec == nil {
+		return nil
+	}
+	// the metadata from the pod labels, which can be overwritten by the container envs
+	envs := []string{
+		"TENANT_ID=" + pod.Labels["tenant_id"],
+		"SERVICE_ID=" + pod.Labels["service_id"],
+		"SERVICE_NAME=" + pod.Labels["service_alias"],
+	}
+	for _, env := range spec.Env {
+		if env.ValueFrom == nil {
+			envs = append(envs, env.Name+"="+env.Value)
+		}
+	}
+	if len(getLoggerConfig(envs)) == 0 {
+		return nil
+	}
+	logPath := filepath.Join(dir, fmt.Sprintf("%d.log", status.RestartCount))
+	if _, err := os.Stat(logPath); err != nil {
+		return nil
+	}
+	// the container id is in format <type>://<container_id>
+	containerID := status.ContainerID
+	if index := strings.Index(containerID, "://"); index >= 0 {
+		containerID = containerID[index+3:]
+	}
+	var path string
+	if len(spec.Command) > 0 {
+		path = spec.Command[0]
+	}
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID: containerID,
+			// the same as the container name created by dockershim
+			Name:    fmt.Sprintf("/k8s_%s_%s_%s_%s_%d", containerName, pod.Name, pod.Namespace, pod.UID, status.RestartCount),
+			Created: status.State.Running.StartedAt.Format(RFC3339NanoFixed),
+			Path:    path,
+			Args:    spec.Args,
+			LogPath: logPath,
+		},
+		Config: &containertypes.Config{
+			Env:    envs,
+			Labels: pod.Labels,
+			Image:  status.Image,
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCRIDecodeFunc(t *testing.T) {
+	w, err := ioutil.TempFile("", "cri-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+	f, err := os.Open(w.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w.WriteString("2016-10-06T00:17:09.669794202Z stdout F hello\n")
+	w.WriteString("2016-10-06T00:17:09.669794203Z stderr P part1 \n")
+	w.WriteString("2016-10-06T00:17:09.669794204Z stderr F part2\n")
+	// the message is not finished
+	w.WriteString("2016-10-06T00:17:10.669794203Z stdout P part3 \n2016-10-06T00:17")
+
+	decode := criDecodeFunc(f)
+	msg, err := decode()
+	if err != nil || string(msg.Line) != "hello\n" || msg.Source != "stdout" {
+		t.Fatalf("unexpected message %+v: %v", msg, err)
+	}
+	msg, err = decode()
+	if err != nil || string(msg.Line) != "part1 part2\n" || msg.Source != "stderr" {
+		t.Fatalf("unexpected message %+v: %v", msg, err)
+	}
+	if _, err = decode(); err != io.EOF {
+		t.Fatalf("expected EOF, but got %v", err)
+	}
+
+	// the decoder is recreated after the rest of the message is written
+	w.WriteString(":10.669794204Z stdout F part4\n")
+	decode = criDecodeFunc(f)
+	msg, err = decode()
+	if err != nil || string(msg.Line) != "part3 part4\n" {
+		t.Fatalf("unexpected message %+v: %v", msg, err)
+	}
+}
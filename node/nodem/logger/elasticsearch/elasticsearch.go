@@ -0,0 +1,230 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/node/nodem/logger"
+	"github.com/goodrain/rainbond/node/nodem/logger/batch"
+	"github.com/sirupsen/logrus"
+)
+
+const name = "elasticsearch"
+const defaultIndex = "rainbond-log"
+
+var driverOpts = map[string]bool{
+	"es-url":          true,
+	"es-index":        true,
+	"es-username":     true,
+	"es-password":     true,
+	"es-timeout":      true,
+	"tls-skip-verify": true,
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// Elasticsearch writes the container logs into the daily indices by the bulk api.
+// It is enabled for a component by the envs:
+// LOGGER_DRIVER_NAME_ES=elasticsearch
+// LOGGER_DRIVER_OPT_ELASTICSEARCH={"es-url":"http://elasticsearch:9200"}
+type Elasticsearch struct {
+	writer *batch.Writer
+}
+
+// New creates an elasticsearch logger
+func New(info logger.Info) (logger.Logger, error) {
+	if err := ValidateLogOpt(info.Config); err != nil {
+		return nil, err
+	}
+	cfg, err := batch.ParseConfig(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := batch.NewWriter(name, info.ContainerID+"-"+name, cfg, newSender(info))
+	if err != nil {
+		return nil, err
+	}
+	return &Elasticsearch{writer: writer}, nil
+}
+
+// ValidateLogOpt validates the log opts of elasticsearch driver
+func ValidateLogOpt(cfg map[string]string) error {
+	if cfg["es-url"] == "" {
+		return fmt.Errorf("es-url is required for %s log driver", name)
+	}
+	if s, ok := cfg["es-timeout"]; ok {
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("es-timeout must be a duration")
+		}
+	}
+	return batch.ValidateOpts(name, cfg, driverOpts)
+}
+
+// Log puts the log into the batch queue
+func (e *Elasticsearch) Log(msg *logger.Message) error {
+	return e.writer.Write(batch.NewEntry(msg))
+}
+
+// Close sends the remaining logs and closes the logger
+func (e *Elasticsearch) Close() error {
+	return e.writer.Close()
+}
+
+// Name returns the name of the logger
+func (e *Elasticsearch) Name() string {
+	return name
+}
+
+type sender struct {
+	url      string
+	index    string
+	username string
+	password string
+	meta     map[string]string
+	client   *http.Client
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func newSender(info logger.Info) *sender {
+	timeout := 10 * time.Second
+	if s, ok := info.Config["es-timeout"]; ok {
+		timeout, _ = time.ParseDuration(s)
+	}
+	index := info.Config["es-index"]
+	if index == "" {
+		index = defaultIndex
+	}
+	skipVerify, _ := strconv.ParseBool(info.Config["tls-skip-verify"])
+	return &sender{
+		url:      strings.TrimRight(info.Config["es-url"], "/") + "/_bulk",
+		index:    index,
+		username: info.Config["es-username"],
+		password: info.Config["es-password"],
+		meta:     batch.ContainerMeta(info),
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+			},
+		},
+	}
+}
+
+// Send writes the logs by the bulk api.
+// The rejected documents are logged and dropped, retrying the whole batch would duplicate the accepted ones.
+func (s *sender) Send(entries []*batch.Entry) error {
+	body, err := s.bulkBody(entries)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("elasticsearch returns %d: %s", res.StatusCode, string(msg))
+	}
+	var bulkRes bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&bulkRes); err != nil {
+		logrus.Warningf("decode bulk response of elasticsearch: %v", err)
+		return nil
+	}
+	if bulkRes.Errors {
+		var failed int
+		var reason string
+		for _, item := range bulkRes.Items {
+			for _, result := range item {
+				if result.Error != nil {
+					failed++
+					reason = result.Error.Type + ": " + result.Error.Reason
+				}
+			}
+		}
+		logrus.Warningf("elasticsearch rejected %d logs, %s", failed, reason)
+	}
+	return nil
+}
+
+func (s *sender) bulkBody(entries []*batch.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		action := map[string]interface{}{
+			"index": map[string]string{"_index": s.index + "-" + entry.Timestamp.Format("2006.01.02")},
+		}
+		doc := make(map[string]interface{}, len(s.meta)+3)
+		for k, v := range s.meta {
+			doc[k] = v
+		}
+		doc["@timestamp"] = entry.Timestamp.Format(time.RFC3339Nano)
+		doc["message"] = string(entry.Line)
+		doc["source"] = entry.Source
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Close closes the idle connections
+func (s *sender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,283 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package gelf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/goodrain/rainbond/node/nodem/logger"
+	"github.com/goodrain/rainbond/node/nodem/logger/batch"
+	"github.com/sirupsen/logrus"
+)
+
+const name = "gelf"
+
+const (
+	levelError = 3
+	levelInfo  = 6
+	// the max size of an udp chunk, including the chunk header
+	chunkSize = 8192
+	// the chunk header: magic bytes(2), message id(8), sequence number(1), sequence count(1)
+	chunkHeaderSize = 12
+	maxChunks       = 128
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+var driverOpts = map[string]bool{
+	"gelf-address": true,
+	"gelf-timeout": true,
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// Gelf sends the container logs in GELF 1.1 format over tcp or udp.
+// It is enabled for a component by the envs:
+// LOGGER_DRIVER_NAME_GELF=gelf
+// LOGGER_DRIVER_OPT_GELF={"gelf-address":"udp://graylog:12201"}
+type Gelf struct {
+	writer *batch.Writer
+}
+
+// New creates a gelf logger
+func New(info logger.Info) (logger.Logger, error) {
+	if err := ValidateLogOpt(info.Config); err != nil {
+		return nil, err
+	}
+	cfg, err := batch.ParseConfig(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newSender(info)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := batch.NewWriter(name, info.ContainerID+"-"+name, cfg, sender)
+	if err != nil {
+		return nil, err
+	}
+	return &Gelf{writer: writer}, nil
+}
+
+// ValidateLogOpt validates the log opts of gelf driver
+func ValidateLogOpt(cfg map[string]string) error {
+	if _, _, err := parseAddress(cfg["gelf-address"]); err != nil {
+		return err
+	}
+	if s, ok := cfg["gelf-timeout"]; ok {
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("gelf-timeout must be a duration")
+		}
+	}
+	return batch.ValidateOpts(name, cfg, driverOpts)
+}
+
+// Log puts the log into the batch queue
+func (g *Gelf) Log(msg *logger.Message) error {
+	return g.writer.Write(batch.NewEntry(msg))
+}
+
+// Close sends the remaining logs and closes the logger
+func (g *Gelf) Close() error {
+	return g.writer.Close()
+}
+
+// Name returns the name of the logger
+func (g *Gelf) Name() string {
+	return name
+}
+
+// parseAddress parses the address in format tcp://host:port or udp://host:port
+func parseAddress(address string) (network, host string, err error) {
+	if address == "" {
+		return "", "", fmt.Errorf("gelf-address is required for %s log driver", name)
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "tcp" && u.Scheme != "udp" {
+		return "", "", fmt.Errorf("unsupported gelf-address scheme '%s', only tcp and udp are supported", u.Scheme)
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return "", "", fmt.Errorf("invalid gelf-address '%s': %v", address, err)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+type sender struct {
+	network string
+	address string
+	timeout time.Duration
+	conn    net.Conn
+	host    string
+	extra   map[string]interface{}
+}
+
+func newSender(info logger.Info) (*sender, error) {
+	network, address, err := parseAddress(info.Config["gelf-address"])
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{
+		network: network,
+		address: address,
+		timeout: 10 * time.Second,
+		extra:   make(map[string]interface{}),
+	}
+	if t, ok := info.Config["gelf-timeout"]; ok {
+		s.timeout, _ = time.ParseDuration(t)
+	}
+	for k, v := range batch.ContainerMeta(info) {
+		if k == "host" {
+			s.host = v
+			continue
+		}
+		s.extra["_"+k] = v
+	}
+	return s, nil
+}
+
+// Send writes the logs to the gelf input, the connection is rebuilt on the next send if it is broken
+func (s *sender) Send(entries []*batch.Entry) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	var err error
+	if s.network == "tcp" {
+		err = s.sendTCP(entries)
+	} else {
+		err = s.sendUDP(entries)
+	}
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// sendTCP writes the messages delimited by null bytes
+func (s *sender) sendTCP(entries []*batch.Entry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		msg, err := s.message(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(msg)
+		buf.WriteByte(0)
+	}
+	_, err := s.conn.Write(buf.Bytes())
+	return err
+}
+
+// sendUDP writes a datagram per message, the large messages are chunked
+func (s *sender) sendUDP(entries []*batch.Entry) error {
+	for _, entry := range entries {
+		msg, err := s.message(entry)
+		if err != nil {
+			return err
+		}
+		chunks, err := chunk(msg)
+		if err != nil {
+			logrus.Warningf("drop gelf message: %v", err)
+			continue
+		}
+		for _, c := range chunks {
+			if _, err := s.conn.Write(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sender) message(entry *batch.Entry) ([]byte, error) {
+	level := levelInfo
+	if entry.Source == "stderr" {
+		level = levelError
+	}
+	msg := make(map[string]interface{}, len(s.extra)+6)
+	for k, v := range s.extra {
+		msg[k] = v
+	}
+	msg["version"] = "1.1"
+	msg["host"] = s.host
+	msg["short_message"] = string(entry.Line)
+	msg["timestamp"] = float64(entry.Timestamp.UnixNano()) / float64(time.Second)
+	msg["level"] = level
+	msg["_source"] = entry.Source
+	return json.Marshal(msg)
+}
+
+// chunk splits the message into the udp chunks
+func chunk(msg []byte) ([][]byte, error) {
+	if len(msg) <= chunkSize {
+		return [][]byte{msg}, nil
+	}
+	payloadSize := chunkSize - chunkHeaderSize
+	count := (len(msg) + payloadSize - 1) / payloadSize
+	if count > maxChunks {
+		return nil, fmt.Errorf("message of %d bytes needs more than %d chunks", len(msg), maxChunks)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payloadSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+		c := make([]byte, 0, chunkHeaderSize+end-i*payloadSize)
+		c = append(c, chunkMagic...)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, msg[i*payloadSize:end]...)
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
+
+// Close closes the connection
+func (s *sender) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package loki
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/node/nodem/logger"
+	"github.com/goodrain/rainbond/node/nodem/logger/batch"
+	"github.com/sirupsen/logrus"
+)
+
+const name = "loki"
+
+var driverOpts = map[string]bool{
+	"loki-url":             true,
+	"loki-tenant-id":       true,
+	"loki-external-labels": true,
+	"loki-timeout":         true,
+	"tls-skip-verify":      true,
+}
+
+// the metadata used as loki labels, the others are dropped to keep the cardinality low
+var labelKeys = []string{"tenant_id", "service_id", "service_alias", "container_name", "host"}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// Loki pushes the container logs to the loki push api.
+// It is enabled for a component by the envs:
+// LOGGER_DRIVER_NAME_LOKI=loki
+// LOGGER_DRIVER_OPT_LOKI={"loki-url":"http://loki:3100/loki/api/v1/push"}
+type Loki struct {
+	writer *batch.Writer
+}
+
+// New creates a loki logger
+func New(info logger.Info) (logger.Logger, error) {
+	if err := ValidateLogOpt(info.Config); err != nil {
+		return nil, err
+	}
+	cfg, err := batch.ParseConfig(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newSender(info)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := batch.NewWriter(name, info.ContainerID+"-"+name, cfg, sender)
+	if err != nil {
+		return nil, err
+	}
+	return &Loki{writer: writer}, nil
+}
+
+// ValidateLogOpt validates the log opts of loki driver
+func ValidateLogOpt(cfg map[string]string) error {
+	if cfg["loki-url"] == "" {
+		return fmt.Errorf("loki-url is required for %s log driver", name)
+	}
+	if _, err := parseLabels(cfg["loki-external-labels"]); err != nil {
+		return err
+	}
+	if s, ok := cfg["loki-timeout"]; ok {
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("loki-timeout must be a duration")
+		}
+	}
+	return batch.ValidateOpts(name, cfg, driverOpts)
+}
+
+// Log puts the log into the batch queue
+func (l *Loki) Log(msg *logger.Message) error {
+	return l.writer.Write(batch.NewEntry(msg))
+}
+
+// Close sends the remaining logs and closes the logger
+func (l *Loki) Close() error {
+	return l.writer.Close()
+}
+
+// Name returns the name of the logger
+func (l *Loki) Name() string {
+	return name
+}
+
+type sender struct {
+	url      string
+	tenantID string
+	labels   map[string]string
+	client   *http.Client
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+func newSender(info logger.Info) (*sender, error) {
+	labels, err := parseLabels(info.Config["loki-external-labels"])
+	if err != nil {
+		return nil, err
+	}
+	meta := batch.ContainerMeta(info)
+	for _, key := range labelKeys {
+		if value, ok := meta[key]; ok {
+			labels[key] = value
+		}
+	}
+	timeout := 10 * time.Second
+	if s, ok := info.Config["loki-timeout"]; ok {
+		timeout, _ = time.ParseDuration(s)
+	}
+	skipVerify, _ := strconv.ParseBool(info.Config["tls-skip-verify"])
+	return &sender{
+		url:      info.Config["loki-url"],
+		tenantID: info.Config["loki-tenant-id"],
+		labels:   labels,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+			},
+		},
+	}, nil
+}
+
+// Send pushes the logs as streams grouped by the log source
+func (s *sender) Send(entries []*batch.Entry) error {
+	body, err := json.Marshal(s.pushRequest(entries))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenantID)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("loki returns %d: %s", res.StatusCode, string(msg))
+	}
+	return nil
+}
+
+func (s *sender) pushRequest(entries []*batch.Entry) *pushRequest {
+	var streams = make(map[string]*stream)
+	var req pushRequest
+	for _, entry := range entries {
+		st, ok := streams[entry.Source]
+		if !ok {
+			st = &stream{Stream: map[string]string{"source": entry.Source}}
+			for k, v := range s.labels {
+				st.Stream[k] = v
+			}
+			streams[entry.Source] = st
+			req.Streams = append(req.Streams, st)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), string(entry.Line)})
+	}
+	return &req
+}
+
+// Close closes the idle connections
+func (s *sender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// parseLabels parses the labels in format k1=v1,k2=v2
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if s == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid loki-external-labels '%s', the format is k1=v1,k2=v2", s)
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return labels, nil
+}
@@ -66,6 +66,11 @@ func (c *ContainerLogManage) Start() error {
 			logrus.Errorf(err.Error())
 		}
 	}()
+	if c.conf.ContainerRuntime == option.ContainerRuntimeCRI {
+		go c.listAndWatchPodLogs()
+		logrus.Infof("start container log manage for cri runtime success")
+		return nil
+	}
 	go c.handleLogger()
 	go c.listAndWatchContainer(errchan)
 	go c.loollist()
@@ -309,6 +314,7 @@ type ContainerLog struct {
 	LogCopier *Copier
 	LogDriver []Logger
 	reader    *LogFile
+	parser    *LogParser
 	since     time.Time
 	stoped    *bool
 }
@@ -323,7 +329,13 @@ func (container *ContainerLog) StartLogging() error {
 		}
 		return fmt.Errorf("failed to initialize logging driver: %v", err)
 	}
+	parser, err := NewLogParser(container.Config.Env)
+	if err != nil {
+		logrus.Warnf("the log parsing of container %s is disabled: %v", container.Name, err)
+	}
+	container.parser = parser
 	copier := NewCopier(container.reader, loggers, container.since)
+	copier.parser = parser
 	container.LogCopier = copier
 	copier.Run()
 	container.LogDriver = loggers
@@ -408,6 +420,7 @@ func (container *ContainerLog) startLogger() ([]Logger, error) {
 func (container *ContainerLog) Restart() {
 	if *container.stoped {
 		copier := NewCopier(container.reader, container.LogDriver, container.since)
+		copier.parser = container.parser
 		container.LogCopier = copier
 		copier.Run()
 	}
@@ -0,0 +1,294 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the envs to config the log parsing of a component
+const (
+	// the regular expression which matches the first line of a log, the other lines are joined to the previous log
+	multilinePatternEnv = "LOGGER_MULTILINE_PATTERN"
+	// the max lines of a multiline log, default is 500
+	multilineMaxLinesEnv = "LOGGER_MULTILINE_MAX_LINES"
+	// a multiline log is finished if no line comes in the timeout, default is 1s
+	multilineTimeoutEnv = "LOGGER_MULTILINE_TIMEOUT"
+	// the format of the structured log, json or logfmt
+	parseFormatEnv = "LOGGER_PARSE_FORMAT"
+)
+
+const (
+	defaultMultilineMaxLines = 500
+	defaultMultilineTimeout  = time.Second
+)
+
+var (
+	levelKeys   = []string{"level", "lvl", "severity", "loglevel"}
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp"}
+	messageKeys = []string{"msg", "message"}
+)
+
+// LogParser joins the multiline logs and parses the structured logs of a container.
+// It is not safe for concurrent use.
+type LogParser struct {
+	pattern  *regexp.Regexp
+	maxLines int
+	timeout  time.Duration
+	format   string
+	pending  map[string]*pendingMessage
+}
+
+type pendingMessage struct {
+	msg     *Message
+	lines   int
+	updated time.Time
+}
+
+// NewLogParser creates a log parser from the container envs, it returns nil if the parsing is not configured.
+func NewLogParser(envs []string) (*LogParser, error) {
+	var envMap = make(map[string]string)
+	for _, env := range envs {
+		if kv := strings.SplitN(env, "=", 2); len(kv) == 2 {
+			envMap[kv[0]] = kv[1]
+		}
+	}
+	p := &LogParser{
+		maxLines: defaultMultilineMaxLines,
+		timeout:  defaultMultilineTimeout,
+		format:   envMap[parseFormatEnv],
+		pending:  make(map[string]*pendingMessage),
+	}
+	if p.format != "" && p.format != "json" && p.format != "logfmt" {
+		return nil, fmt.Errorf("unsupported log format %s, only json and logfmt are supported", p.format)
+	}
+	if pattern := envMap[multilinePatternEnv]; pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multiline pattern %s: %v", pattern, err)
+		}
+		p.pattern = re
+	}
+	if s, ok := envMap[multilineMaxLinesEnv]; ok {
+		maxLines, err := strconv.Atoi(s)
+		if err != nil || maxLines <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number", multilineMaxLinesEnv)
+		}
+		p.maxLines = maxLines
+	}
+	if s, ok := envMap[multilineTimeoutEnv]; ok {
+		timeout, err := time.ParseDuration(s)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("%s must be a positive duration", multilineTimeoutEnv)
+		}
+		p.timeout = timeout
+	}
+	if p.pattern == nil && p.format == "" {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// Parse parses the log, and returns the finished logs.
+// A multiline log is kept until the next log starts, or it is flushed after the timeout.
+func (p *LogParser) Parse(msg *Message) []*Message {
+	if p.pattern == nil {
+		return []*Message{p.finish(msg)}
+	}
+	pending := p.pending[msg.Source]
+	if pending != nil && pending.lines < p.maxLines && !p.pattern.Match(bytes.TrimRight(msg.Line, "\r\n")) {
+		pending.msg.Line = append(pending.msg.Line, msg.Line...)
+		pending.lines++
+		pending.updated = time.Now()
+		return nil
+	}
+	var finished []*Message
+	if pending != nil {
+		finished = append(finished, p.finish(pending.msg))
+	}
+	p.pending[msg.Source] = &pendingMessage{msg: msg, lines: 1, updated: time.Now()}
+	return finished
+}
+
+// Flush returns the multiline logs which are not updated in the timeout, or all of them if force is true.
+func (p *LogParser) Flush(force bool) []*Message {
+	var finished []*Message
+	for source, pending := range p.pending {
+		if force || time.Since(pending.updated) >= p.timeout {
+			finished = append(finished, p.finish(pending.msg))
+			delete(p.pending, source)
+		}
+	}
+	return finished
+}
+
+// finish parses the fields of the structured log. The attrs are only set if the log is structured,
+// so that the log drivers send the multiline and plain logs as the raw log.
+func (p *LogParser) finish(msg *Message) *Message {
+	var fields map[string]string
+	var ok bool
+	switch p.format {
+	case "json":
+		fields, ok = parseJSONLog(msg.Line)
+	case "logfmt":
+		fields, ok = parseLogfmt(strings.TrimSpace(string(msg.Line)))
+	}
+	if !ok {
+		return msg
+	}
+	if msg.Attrs == nil {
+		msg.Attrs = LogAttributes{}
+	}
+	for k, v := range fields {
+		switch {
+		case containsKey(levelKeys, k):
+			msg.Attrs["level"] = normalizeLevel(v)
+		case containsKey(messageKeys, k):
+			msg.Line = []byte(v + "\n")
+		case containsKey(timeKeys, k):
+			if t, ok := parseLogTime(v); ok {
+				msg.Timestamp = t
+				continue
+			}
+			msg.Attrs[k] = v
+		default:
+			msg.Attrs[k] = v
+		}
+	}
+	return msg
+}
+
+func parseJSONLog(line []byte) (map[string]string, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, false
+	}
+	fields := make(map[string]string, len(values))
+	for k, v := range values {
+		switch value := v.(type) {
+		case string:
+			fields[k] = value
+		case json.Number, bool:
+			fields[k] = fmt.Sprint(value)
+		case nil:
+			fields[k] = ""
+		default:
+			data, _ := json.Marshal(value)
+			fields[k] = string(data)
+		}
+	}
+	return fields, true
+}
+
+// parseLogfmt parses the line in format key1=value1 key2="value 2",
+// the line is not a logfmt log if it contains any word which is not a key value pair.
+func parseLogfmt(line string) (map[string]string, bool) {
+	fields := make(map[string]string)
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		if i == start || i >= len(line) || line[i] != '=' {
+			return nil, false
+		}
+		key := line[start:i]
+		i++
+		if i < len(line) && line[i] == '"' {
+			var value strings.Builder
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					value.WriteByte(line[i])
+					continue
+				}
+				if line[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				value.WriteByte(line[i])
+			}
+			if !closed {
+				return nil, false
+			}
+			fields[key] = value.String()
+			continue
+		}
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		fields[key] = line[start:i]
+	}
+	return fields, len(fields) > 0
+}
+
+func parseLogTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return time.Time{}, false
+	}
+	// the unix time in milliseconds
+	if f > 1e12 {
+		f = f / 1e3
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+func normalizeLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "warning":
+		return "warn"
+	case "err":
+		return "error"
+	}
+	return level
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogParserMultiline(t *testing.T) {
+	p, err := NewLogParser([]string{multilinePatternEnv + `=^\d{4}-\d{2}-\d{2}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"2020-01-01 12:00:00 ERROR failed\n",
+		"java.lang.NullPointerException\n",
+		"\tat com.example.Main.main(Main.java:10)\n",
+		"2020-01-01 12:00:01 INFO ok\n",
+	}
+	var finished []*Message
+	for _, line := range lines {
+		finished = append(finished, p.Parse(&Message{Line: []byte(line), Source: "stdout"})...)
+	}
+	if len(finished) != 1 || string(finished[0].Line) != lines[0]+lines[1]+lines[2] {
+		t.Fatalf("unexpected multiline logs %v", finished)
+	}
+	if finished[0].Attrs != nil {
+		t.Errorf("the multiline log should be sent as the raw log, got attrs %v", finished[0].Attrs)
+	}
+	if finished = p.Flush(false); len(finished) != 0 {
+		t.Errorf("the last log should wait for the timeout")
+	}
+	if finished = p.Flush(true); len(finished) != 1 || string(finished[0].Line) != lines[3] {
+		t.Errorf("unexpected flushed logs %v", finished)
+	}
+}
+
+func TestLogParserStructured(t *testing.T) {
+	p, err := NewLogParser([]string{parseFormatEnv + "=json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := p.Parse(&Message{Line: []byte(`{"level":"WARNING","msg":"slow request","ts":1577880000.5,"cost":1200,"path":"/api"}` + "\n")})[0]
+	if msg.Attrs["level"] != "warn" || msg.Attrs["cost"] != "1200" || msg.Attrs["path"] != "/api" {
+		t.Errorf("unexpected attrs %v", msg.Attrs)
+	}
+	if string(msg.Line) != "slow request\n" || !msg.Timestamp.Equal(time.Unix(1577880000, 5e8)) {
+		t.Errorf("unexpected message %s at %s", msg.Line, msg.Timestamp)
+	}
+
+	p, _ = NewLogParser([]string{parseFormatEnv + "=logfmt"})
+	msg = p.Parse(&Message{Line: []byte(`level=error msg="connect \"db\" failed" retry=3` + "\n")})[0]
+	if msg.Attrs["level"] != "error" || msg.Attrs["retry"] != "3" || string(msg.Line) != "connect \"db\" failed\n" {
+		t.Errorf("unexpected logfmt log %s %v", msg.Line, msg.Attrs)
+	}
+	msg = p.Parse(&Message{Line: []byte("plain text a=b\n")})[0]
+	if msg.Attrs != nil {
+		t.Errorf("plain text should not be parsed, got %v", msg.Attrs)
+	}
+}
@@ -288,11 +288,50 @@ func (s *StreamLog) Log(msg *logger.Message) error {
 	buf := bytes.NewBuffer(nil)
 	buf.WriteString(s.containerID[0:12] + ",")
 	buf.WriteString(s.serviceID)
+	if msg.Attrs != nil {
+		record, err := json.Marshal(newStructuredRecord(msg))
+		if err == nil {
+			buf.WriteByte(structuredLogMarker)
+			buf.Write(record)
+			s.cache(buf.String())
+			return nil
+		}
+		logrus.Debugf("encode structured log failure %s", err.Error())
+	}
 	buf.Write(msg.Line)
 	s.cache(buf.String())
 	return nil
 }
 
+//structuredLogMarker the structured log record is sent after the marker, which is decoded by eventlog
+const structuredLogMarker = 0x1e
+
+//structuredRecord the log parsed by the node, the multiline log is sent as one record
+type structuredRecord struct {
+	Time   string            `json:"time"`
+	Level  string            `json:"level,omitempty"`
+	Msg    string            `json:"msg"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func newStructuredRecord(msg *logger.Message) *structuredRecord {
+	record := &structuredRecord{
+		Time: msg.Timestamp.Format(time.RFC3339Nano),
+		Msg:  strings.TrimRight(string(msg.Line), "\r\n"),
+	}
+	for k, v := range msg.Attrs {
+		if k == "level" {
+			record.Level = v
+			continue
+		}
+		if record.Fields == nil {
+			record.Fields = make(map[string]string, len(msg.Attrs))
+		}
+		record.Fields[k] = v
+	}
+	return record
+}
+
 func isConnectionClosed(err error) bool {
 	if err == errClosed || err == errNoConnect {
 		return true
@@ -0,0 +1,320 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goodrain/rainbond/node/nodem/logger"
+	"github.com/goodrain/rainbond/node/nodem/logger/batch"
+	"github.com/sirupsen/logrus"
+)
+
+const name = "syslog"
+
+const (
+	severityError = 3
+	severityInfo  = 6
+	// the private enterprise number used as the structured data id
+	sdID = "rainbond@53595"
+	// the timestamp format of RFC5424, at most 6 digits of fraction
+	rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var driverOpts = map[string]bool{
+	"syslog-address":         true,
+	"syslog-facility":        true,
+	"syslog-tls-ca-cert":     true,
+	"syslog-tls-cert":        true,
+	"syslog-tls-key":         true,
+	"syslog-tls-skip-verify": true,
+	"syslog-timeout":         true,
+}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// Syslog sends the container logs in RFC5424 format over tcp or tls, the messages are framed by octet counting(RFC6587).
+// It is enabled for a component by the envs:
+// LOGGER_DRIVER_NAME_SYSLOG=syslog
+// LOGGER_DRIVER_OPT_SYSLOG={"syslog-address":"tcp+tls://syslog:6514"}
+type Syslog struct {
+	writer *batch.Writer
+}
+
+// New creates a syslog logger
+func New(info logger.Info) (logger.Logger, error) {
+	if err := ValidateLogOpt(info.Config); err != nil {
+		return nil, err
+	}
+	cfg, err := batch.ParseConfig(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newSender(info)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := batch.NewWriter(name, info.ContainerID+"-"+name, cfg, sender)
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{writer: writer}, nil
+}
+
+// ValidateLogOpt validates the log opts of syslog driver
+func ValidateLogOpt(cfg map[string]string) error {
+	if _, _, err := parseAddress(cfg["syslog-address"]); err != nil {
+		return err
+	}
+	if s, ok := cfg["syslog-facility"]; ok {
+		if _, ok := facilities[s]; !ok {
+			return fmt.Errorf("unknown syslog-facility '%s'", s)
+		}
+	}
+	if s, ok := cfg["syslog-timeout"]; ok {
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("syslog-timeout must be a duration")
+		}
+	}
+	return batch.ValidateOpts(name, cfg, driverOpts)
+}
+
+// Log puts the log into the batch queue
+func (s *Syslog) Log(msg *logger.Message) error {
+	return s.writer.Write(batch.NewEntry(msg))
+}
+
+// Close sends the remaining logs and closes the logger
+func (s *Syslog) Close() error {
+	return s.writer.Close()
+}
+
+// Name returns the name of the logger
+func (s *Syslog) Name() string {
+	return name
+}
+
+// parseAddress parses the address in format tcp://host:port or tcp+tls://host:port
+func parseAddress(address string) (string, bool, error) {
+	if address == "" {
+		return "", false, fmt.Errorf("syslog-address is required for %s log driver", name)
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", false, err
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return "", false, fmt.Errorf("invalid syslog-address '%s': %v", address, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		return u.Host, false, nil
+	case "tcp+tls":
+		return u.Host, true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported syslog-address scheme '%s', only tcp and tcp+tls are supported", u.Scheme)
+	}
+}
+
+type sender struct {
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	conn      net.Conn
+	facility  int
+	hostname  string
+	appName   string
+	procID    string
+	sd        string
+}
+
+func newSender(info logger.Info) (*sender, error) {
+	address, useTLS, err := parseAddress(info.Config["syslog-address"])
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{
+		address:  address,
+		timeout:  10 * time.Second,
+		facility: facilities["user"],
+		hostname: "-",
+		appName:  "-",
+		procID:   "-",
+	}
+	if f, ok := info.Config["syslog-facility"]; ok {
+		s.facility = facilities[f]
+	}
+	if t, ok := info.Config["syslog-timeout"]; ok {
+		s.timeout, _ = time.ParseDuration(t)
+	}
+	if useTLS {
+		if s.tlsConfig, err = newTLSConfig(info.Config); err != nil {
+			return nil, err
+		}
+	}
+	meta := batch.ContainerMeta(info)
+	if host := meta["host"]; host != "" {
+		s.hostname = host
+	}
+	if alias := meta["service_alias"]; alias != "" {
+		s.appName = truncate(alias, 48)
+	}
+	if id := meta["container_id"]; id != "" {
+		s.procID = id
+	}
+	s.sd = structuredData(meta)
+	return s, nil
+}
+
+func newTLSConfig(cfg map[string]string) (*tls.Config, error) {
+	skipVerify, _ := strconv.ParseBool(cfg["syslog-tls-skip-verify"])
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipVerify}
+	if ca := cfg["syslog-tls-ca-cert"]; ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("read syslog-tls-ca-cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", ca)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cert, key := cfg["syslog-tls-cert"], cfg["syslog-tls-key"]; cert != "" && key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("load syslog client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	return tlsConfig, nil
+}
+
+// Send writes the logs to the syslog server, the connection is rebuilt on the next send if it is broken
+func (s *sender) Send(entries []*batch.Entry) error {
+	if s.conn == nil {
+		dialer := &net.Dialer{Timeout: s.timeout}
+		var err error
+		if s.tlsConfig != nil {
+			s.conn, err = tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+		} else {
+			s.conn, err = dialer.Dial("tcp", s.address)
+		}
+		if err != nil {
+			s.conn = nil
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		msg := s.format(entry)
+		buf.WriteString(strconv.Itoa(len(msg)))
+		buf.WriteByte(' ')
+		buf.Write(msg)
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format formats the log as a RFC5424 message
+func (s *sender) format(entry *batch.Entry) []byte {
+	severity := severityInfo
+	if entry.Source == "stderr" {
+		severity = severityError
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s - %s ", s.facility*8+severity,
+		entry.Timestamp.Format(rfc5424Time), s.hostname, s.appName, s.procID, s.sd)
+	buf.Write(entry.Line)
+	return buf.Bytes()
+}
+
+// Close closes the connection
+func (s *sender) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// structuredData formats the metadata as the structured data element
+func structuredData(meta map[string]string) string {
+	if len(meta) == 0 {
+		return "-"
+	}
+	var keys []string
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString("[" + sdID)
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, ` %s="%s"`, paramName(k), escaper.Replace(meta[k]))
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// paramName replaces the characters which are not allowed in the SD-NAME
+func paramName(name string) string {
+	return truncate(strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name), 32)
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goodrain/rainbond/node/nodem/logger/batch"
+)
+
+func TestFormat(t *testing.T) {
+	s := &sender{
+		facility: facilities["local0"],
+		hostname: "node1",
+		appName:  "gr123456",
+		procID:   "0123456789ab",
+		sd:       structuredData(map[string]string{"tenant_id": "t1", "service_id": `a"]\`}),
+	}
+	entry := &batch.Entry{
+		Line:      []byte("hello"),
+		Source:    "stderr",
+		Timestamp: time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC),
+	}
+	expected := `<131>1 2020-01-02T03:04:05.123456Z node1 gr123456 0123456789ab - [rainbond@53595 service_id="a\"\]\\" tenant_id="t1"] hello`
+	if got := string(s.format(entry)); got != expected {
+		t.Errorf("expected %s, but got %s", expected, got)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	if _, useTLS, err := parseAddress("tcp+tls://127.0.0.1:6514"); err != nil || !useTLS {
+		t.Errorf("expected tls address, got %v", err)
+	}
+	if _, _, err := parseAddress("udp://127.0.0.1:514"); err == nil {
+		t.Errorf("udp should not be supported")
+	}
+}
@@ -43,6 +43,8 @@ const (
 	regErrF     = "A change of configuration created inconsistent metrics for " +
 		"%q. You have to restart the statsd_exporter, and you should " +
 		"consider the effects on your monitoring setup. Error: %s"
+	// the stats of the mesh sidecars are dropped unless they are mapped
+	ignoredUnmappedPrefix = "envoy."
 )
 
 var (
@@ -281,6 +283,9 @@ func (b *Exporter) Listen(e <-chan Events) {
 				}
 			} else {
 				eventsUnmapped.Inc()
+				if strings.HasPrefix(event.MetricName(), ignoredUnmappedPrefix) {
+					continue
+				}
 				metricName = escapeMetricName(event.MetricName())
 			}
 
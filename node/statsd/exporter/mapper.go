@@ -197,7 +197,19 @@ func InitMapping() (*MetricMapper, error) {
 		Name:   "app_client_requesttime",
 		Labels: prometheus.Labels{"service_id": "$1", "port": "$2", "protocol": "$3", "client": "$4"},
 	}
-	n.Mappings = append(n.Mappings, m1, m2, m3, m4, m5, m6)
+	// the requests rejected by the local rate limit of the mesh sidecar,
+	// source is the calling component or "all" for the limit of the port
+	m7 := metricMapping{
+		Match:  "envoy.*.*.*.http_local_rate_limit.rate_limited",
+		Name:   "app_local_rate_limited",
+		Labels: prometheus.Labels{"service_id": "$1", "port": "$2", "source": "$3"},
+	}
+	m8 := metricMapping{
+		Match:  "envoy.local_rate_limit.*.*.*.rate_limited",
+		Name:   "app_local_rate_limited",
+		Labels: prometheus.Labels{"service_id": "$1", "port": "$2", "source": "$3"},
+	}
+	n.Mappings = append(n.Mappings, m1, m2, m3, m4, m5, m6, m7, m8)
 	if n.Defaults.Buckets == nil || len(n.Defaults.Buckets) == 0 {
 		n.Defaults.Buckets = prometheus.DefBuckets
 	}
@@ -0,0 +1,135 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	rd "math/rand"
+	"net/url"
+	"time"
+)
+
+//CA is a certificate authority kept in memory, it issues the workload certificates
+//of the components in the built-in service mesh.
+type CA struct {
+	Cert    *x509.Certificate
+	Key     *rsa.PrivateKey
+	certPEM []byte
+}
+
+//NewCA creates a self-signed CA
+func NewCA(info CertInformation) (*CA, error) {
+	info.IsCA = true
+	crt := newCertificate(info)
+	crt.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := x509.CreateCertificate(rand.Reader, crt, crt, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCA(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: buf}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+//ParseCA parses the CA from pem encoded certificate and private key
+func ParseCA(certPEM, keyPEM []byte) (*CA, error) {
+	p, _ := pem.Decode(certPEM)
+	if p == nil {
+		return nil, fmt.Errorf("no pem data found in ca certificate")
+	}
+	crt, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !crt.IsCA {
+		return nil, fmt.Errorf("certificate %s is not a ca", crt.Subject.CommonName)
+	}
+	p, _ = pem.Decode(keyPEM)
+	if p == nil {
+		return nil, fmt.Errorf("no pem data found in ca private key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(p.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Cert: crt, Key: key, certPEM: certPEM}, nil
+}
+
+//CertPEM returns the pem encoded certificate of the CA
+func (c *CA) CertPEM() []byte {
+	return c.certPEM
+}
+
+//KeyPEM returns the pem encoded private key of the CA
+func (c *CA) KeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(c.Key)})
+}
+
+//SPIFFEID returns the SPIFFE style identity of a workload,
+//eg. spiffe://rainbond/ns/<namespace>/sa/<service alias>
+func SPIFFEID(trustDomain, namespace, name string) string {
+	return fmt.Sprintf("spiffe://%s/ns/%s/sa/%s", trustDomain, namespace, name)
+}
+
+//IssueSPIFFECert issues a workload certificate that carries the SPIFFE id as its URI SAN.
+//It returns the pem encoded certificate and private key and the expiration time of the certificate.
+func (c *CA) IssueSPIFFECert(spiffeID string, ttl time.Duration) (certPEM, keyPEM []byte, notAfter time.Time, err error) {
+	uri, err := url.Parse(spiffeID)
+	if err != nil || uri.Scheme != "spiffe" {
+		return nil, nil, notAfter, fmt.Errorf("invalid spiffe id %s", spiffeID)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, notAfter, err
+	}
+	now := time.Now()
+	notAfter = now.Add(ttl)
+	if notAfter.After(c.Cert.NotAfter) {
+		notAfter = c.Cert.NotAfter
+	}
+	crt := &x509.Certificate{
+		SerialNumber: big.NewInt(rd.Int63()),
+		Subject: pkix.Name{
+			Organization: c.Cert.Subject.Organization,
+		},
+		URIs: []*url.URL{uri},
+		// tolerate clock skew between nodes
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	buf, err := x509.CreateCertificate(rand.Reader, crt, c.Cert, &key.PublicKey, c.Key)
+	if err != nil {
+		return nil, nil, notAfter, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: buf})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, notAfter, nil
+}
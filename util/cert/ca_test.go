@@ -0,0 +1,65 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestIssueSPIFFECert(t *testing.T) {
+	ca, err := NewCA(CreateCertInformation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the ca must survive a round trip through its pem encoding
+	ca, err = ParseCA(ca.CertPEM(), ca.KeyPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := SPIFFEID("rainbond", "tenant", "gr123456")
+	certPEM, keyPEM, notAfter, err := ca.IssueSPIFFECert(id, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyPEM) == 0 {
+		t.Fatal("empty private key")
+	}
+	p, _ := pem.Decode(certPEM)
+	crt, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crt.URIs) != 1 || crt.URIs[0].String() != id {
+		t.Errorf("expected uri san %s, got %v", id, crt.URIs)
+	}
+	if !crt.NotAfter.Equal(notAfter.Truncate(time.Second)) {
+		t.Errorf("expected not after %s, got %s", notAfter, crt.NotAfter)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	if _, err := crt.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("verify certificate failure %s", err.Error())
+	}
+	if _, _, _, err := ca.IssueSPIFFECert("https://rainbond/ns/tenant", time.Hour); err == nil {
+		t.Error("expected error for a non spiffe id")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -97,6 +98,16 @@ func CreateMasterLock(etcdEndpoints []string, election string, prop string, ttl
 		cancel()
 		return nil, fmt.Errorf("create etcd client error,%s", err.Error())
 	}
+	return newMasterLock(ctx, cancel, client, etcdEndpoints, election, prop, ttl)
+}
+
+//NewMasterLock create master lock with the etcd client, the client is not closed when the lock is stopped
+func NewMasterLock(client *clientv3.Client, election string, prop string, ttl int64) (MasterInterface, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return newMasterLock(ctx, cancel, client, client.Endpoints(), election, prop, ttl)
+}
+
+func newMasterLock(ctx context.Context, cancel context.CancelFunc, client *clientv3.Client, etcdEndpoints []string, election string, prop string, ttl int64) (MasterInterface, error) {
 	lease, err := client.Lease.Grant(ctx, ttl)
 	if err != nil {
 		cancel()
@@ -134,10 +145,14 @@ func (m *masterLock) campaign() error {
 		m.eventchan <- MasterEvent{Type: MasterError, Error: err}
 		return err
 	}
+	observe := m.election.Observe(ctx)
 slect:
 	for {
 		select {
-		case res := <-m.election.Observe(ctx):
+		case res, ok := <-observe:
+			if !ok {
+				return ctx.Err()
+			}
 			if len(res.Kvs) > 0 {
 				if string(res.Kvs[0].Value) == m.prop {
 					logrus.Infof("current node is be elected master")
@@ -161,7 +176,7 @@ slect:
 	}
 }
 func (m *masterLock) resign() error {
-	ctx, cancel := context.WithCancel(m.ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	return m.election.Resign(ctx)
 }
@@ -171,8 +186,11 @@ func (m *masterLock) Start() {
 
 func (m *masterLock) Stop() {
 	m.once.Do(func() {
-		m.cancel()
+		// resign and revoke the lease before cancelling, so that the other candidates
+		// are elected at once rather than after the ttl
 		m.resign()
+		m.session.Close()
+		m.cancel()
 	})
 }
 
@@ -181,5 +199,11 @@ func (m *masterLock) EventsChan() <-chan MasterEvent {
 }
 
 func (m *masterLock) GetHolder() string {
-	return ""
+	ctx, cancel := context.WithTimeout(m.ctx, time.Second*3)
+	defer cancel()
+	res, err := m.election.Leader(ctx)
+	if err != nil || len(res.Kvs) == 0 {
+		return ""
+	}
+	return string(res.Kvs[0].Value)
 }
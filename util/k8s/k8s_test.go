@@ -1,18 +1,22 @@
 package k8s
 
 import (
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"os"
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 func TestNewRestConfig(t *testing.T) {
-	os.Setenv("KUBERNETES_SERVICE_HOST", "192.168.2.200")
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		t.Skip("the rest config requires a kubernetes cluster")
+	}
 	restConfig, err := NewRestConfig("")
 	if err != nil {
 		t.Fatal("create restconfig error: ", err.Error())
 	}
-	clientset, err := NewClientsetWithRestConfig(restConfig)
+	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		t.Fatal("create clientset error: ", err.Error())
 	}
@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TopologyKey returns the node label of the topology domain, zone and node are the shortcuts
+// of the well-known zone and hostname labels. Other values are used as the node label directly.
+func TopologyKey(s string) string {
+	switch s {
+	case "zone":
+		return corev1.LabelZoneFailureDomain
+	case "node":
+		return corev1.LabelHostname
+	}
+	return s
+}
+
+// ParseTopologySpread parses the value of the topology-spread component label,
+// the format is <topologyKey>[:<maxSkew>[:<whenUnsatisfiable>]], such as zone, node:2 or zone:1:DoNotSchedule.
+// The maxSkew is 1 and the whenUnsatisfiable is ScheduleAnyway by default.
+// The label selector is left to the caller.
+func ParseTopologySpread(value string) (*corev1.TopologySpreadConstraint, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid topology spread %q", value)
+	}
+	constraint := &corev1.TopologySpreadConstraint{
+		TopologyKey:       TopologyKey(parts[0]),
+		MaxSkew:           1,
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+	}
+	if len(parts) > 1 {
+		skew, err := strconv.Atoi(parts[1])
+		if err != nil || skew < 1 {
+			return nil, fmt.Errorf("invalid max skew %q of topology spread, it should be a positive integer", parts[1])
+		}
+		constraint.MaxSkew = int32(skew)
+	}
+	if len(parts) > 2 {
+		switch action := corev1.UnsatisfiableConstraintAction(parts[2]); action {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+			constraint.WhenUnsatisfiable = action
+		default:
+			return nil, fmt.Errorf("invalid whenUnsatisfiable %q of topology spread, it should be %s or %s", parts[2], corev1.DoNotSchedule, corev1.ScheduleAnyway)
+		}
+	}
+	return constraint, nil
+}
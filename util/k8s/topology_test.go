@@ -0,0 +1,38 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseTopologySpread(t *testing.T) {
+	tests := []struct {
+		value   string
+		key     string
+		skew    int32
+		action  corev1.UnsatisfiableConstraintAction
+		wantErr bool
+	}{
+		{value: "zone", key: corev1.LabelZoneFailureDomain, skew: 1, action: corev1.ScheduleAnyway},
+		{value: "node:2", key: corev1.LabelHostname, skew: 2, action: corev1.ScheduleAnyway},
+		{value: "topology.kubernetes.io/zone:1:DoNotSchedule", key: "topology.kubernetes.io/zone", skew: 1, action: corev1.DoNotSchedule},
+		{value: "", wantErr: true},
+		{value: "zone:0", wantErr: true},
+		{value: "zone:1:Never", wantErr: true},
+		{value: "zone:1:DoNotSchedule:1", wantErr: true},
+	}
+	for _, tc := range tests {
+		c, err := ParseTopologySpread(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: unexpected error %v", tc.value, err)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		if c.TopologyKey != tc.key || c.MaxSkew != tc.skew || c.WhenUnsatisfiable != tc.action {
+			t.Errorf("%q: unexpected constraint %+v", tc.value, c)
+		}
+	}
+}
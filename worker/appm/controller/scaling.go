@@ -24,8 +24,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/worker/appm/conversion"
+	"github.com/goodrain/rainbond/worker/appm/f"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
 	"github.com/sirupsen/logrus"
 	types "k8s.io/apimachinery/pkg/types"
@@ -82,9 +85,27 @@ func (s *scalingController) scalingOne(service v1.AppService) error {
 			return err
 		}
 	}
+	s.scalingPodDisruptionBudget(service)
 	return s.WaitingReady(service)
 }
 
+//scalingPodDisruptionBudget creates or deletes the pod disruption budget according to the new replicas
+func (s *scalingController) scalingPodDisruptionBudget(service v1.AppService) {
+	if err := conversion.TenantServicePodDisruptionBudget(&service, db.GetManager()); err != nil {
+		logrus.Errorf("conv pod disruption budget of service %s failure: %s", service.ServiceAlias, err.Error())
+		return
+	}
+	if pdb := service.GetPodDisruptionBudget(); pdb != nil {
+		if err := f.EnsurePodDisruptionBudget(s.manager.client, pdb); err != nil {
+			logrus.Errorf("create pod disruption budget %s failure: %s", pdb.Name, err.Error())
+		}
+		return
+	}
+	if err := f.DeletePodDisruptionBudget(s.manager.client, &service); err != nil {
+		logrus.Errorf("delete pod disruption budget failure: %s", err.Error())
+	}
+}
+
 //WaitingReady wait app start or upgrade ready
 func (s *scalingController) WaitingReady(app v1.AppService) error {
 	storeAppService := s.manager.store.GetAppService(app.ServiceID)
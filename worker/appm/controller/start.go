@@ -25,8 +25,10 @@ import (
 
 	"github.com/goodrain/rainbond/worker/appm/store"
 
+	"github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/worker/appm/f"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -100,6 +102,15 @@ func (s *startController) startOne(app v1.AppService) error {
 			return fmt.Errorf("create or check namespace failure %s", err.Error())
 		}
 	}
+	//create network policies before the pods are created
+	if err := f.EnsureDefaultDenyNetworkPolicy(s.manager.client, app.TenantID); err != nil {
+		logrus.Errorf("create default deny network policy in namespace %s failure: %s", app.TenantID, err.Error())
+	}
+	if np := app.GetNetworkPolicy(); np != nil {
+		if err := f.EnsureNetworkPolicy(s.manager.client, np); err != nil {
+			logrus.Errorf("create network policy %s failure: %s", np.Name, err.Error())
+		}
+	}
 	//step 1: create configmap
 	if configs := app.GetConfigMaps(); configs != nil {
 		for _, config := range configs {
@@ -170,6 +181,11 @@ func (s *startController) startOne(app v1.AppService) error {
 			}
 		}
 	}
+	if pdb := app.GetPodDisruptionBudget(); pdb != nil {
+		if err := f.EnsurePodDisruptionBudget(s.manager.client, pdb); err != nil {
+			logrus.Errorf("create pod disruption budget %s failure: %s", pdb.Name, err.Error())
+		}
+	}
 
 	//step 7: create CR resource
 	if crd, _ := s.manager.store.GetCrd(store.ServiceMonitor); crd != nil {
@@ -191,7 +207,29 @@ func (s *startController) startOne(app v1.AppService) error {
 		}
 	}
 
-	//step 8: waiting endpoint ready
+	//step 8: create istio resources
+	if app.GovernanceMode == model.GovernanceModeIstioServiceMesh {
+		if err := f.EnsureIstioInjection(s.manager.client, app.TenantID); err != nil {
+			logrus.Errorf("label namespace %s for istio injection failure: %s", app.TenantID, err.Error())
+		}
+		if crd, _ := s.manager.store.GetCrd(store.IstioVirtualService); crd != nil {
+			client, err := s.manager.store.GetDynamicClient()
+			if err != nil {
+				logrus.Errorf("create dynamic client failure %s", err.Error())
+			}
+			if client != nil {
+				err := f.UpgradeIstioResources(client, &app, app.GetIstioResources(true), func(msg string, err error) error {
+					logrus.Warning(msg)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("create istio resources failure: %v", err)
+				}
+			}
+		}
+	}
+
+	//step 9: waiting endpoint ready
 	app.Logger.Info("Create all app model success, will waiting app ready", event.GetLoggerOption("running"))
 	return s.WaitingReady(app)
 }
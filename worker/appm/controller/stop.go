@@ -25,6 +25,7 @@ import (
 
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/util"
+	"github.com/goodrain/rainbond/worker/appm/f"
 	"github.com/goodrain/rainbond/worker/appm/store"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
 	"github.com/sirupsen/logrus"
@@ -173,6 +174,24 @@ func (s *stopController) stopOne(app v1.AppService) error {
 		}
 	}
 
+	if crd, _ := s.manager.store.GetCrd(store.IstioVirtualService); crd != nil {
+		client, err := s.manager.store.GetDynamicClient()
+		if err != nil {
+			logrus.Errorf("create dynamic client failure %s", err.Error())
+		}
+		if client != nil {
+			if err := f.DeleteIstioResources(client, &app); err != nil {
+				logrus.Errorf("delete istio resources failure: %s", err.Error())
+			}
+		}
+	}
+	if err := f.DeleteNetworkPolicy(s.manager.client, &app); err != nil {
+		logrus.Errorf("delete network policy failure: %s", err.Error())
+	}
+	if err := f.DeletePodDisruptionBudget(s.manager.client, &app); err != nil {
+		logrus.Errorf("delete pod disruption budget failure: %s", err.Error())
+	}
+
 	//step 9: waiting endpoint ready
 	app.Logger.Info("Delete all app model success, will waiting app closed", event.GetLoggerOption("running"))
 	return s.WaitingReady(app)
@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/goodrain/rainbond/db/model"
 	"github.com/goodrain/rainbond/event"
 	"github.com/goodrain/rainbond/util"
 	"github.com/goodrain/rainbond/worker/appm/f"
@@ -186,6 +187,21 @@ func (s *upgradeController) upgradeOne(app v1.AppService) error {
 			return fmt.Errorf("create or check namespace failure %s", err.Error())
 		}
 	}
+	if err := f.EnsureDefaultDenyNetworkPolicy(s.manager.client, app.TenantID); err != nil {
+		logrus.Errorf("create default deny network policy in namespace %s failure: %s", app.TenantID, err.Error())
+	}
+	if np := app.GetNetworkPolicy(); np != nil {
+		if err := f.EnsureNetworkPolicy(s.manager.client, np); err != nil {
+			logrus.Errorf("upgrade network policy %s failure: %s", np.Name, err.Error())
+		}
+	}
+	if pdb := app.GetPodDisruptionBudget(); pdb != nil {
+		if err := f.EnsurePodDisruptionBudget(s.manager.client, pdb); err != nil {
+			logrus.Errorf("upgrade pod disruption budget %s failure: %s", pdb.Name, err.Error())
+		}
+	} else if err := f.DeletePodDisruptionBudget(s.manager.client, &app); err != nil {
+		logrus.Errorf("delete pod disruption budget failure: %s", err.Error())
+	}
 	s.upgradeConfigMap(app)
 	if deployment := app.GetDeployment(); deployment != nil {
 		_, err = s.manager.client.AppsV1().Deployments(deployment.Namespace).Patch(deployment.Name, types.MergePatchType, app.UpgradePatch["deployment"])
@@ -239,6 +255,29 @@ func (s *upgradeController) upgradeOne(app v1.AppService) error {
 		}
 	}
 
+	// the istio resources are also cleaned up when the governance mode is switched away from istio
+	if crd, _ := s.manager.store.GetCrd(store.IstioVirtualService); crd != nil {
+		client, err := s.manager.store.GetDynamicClient()
+		if err != nil {
+			logrus.Errorf("create dynamic client failure %s", err.Error())
+		}
+		if client != nil {
+			err := f.UpgradeIstioResources(client, &app, app.GetIstioResources(true), func(msg string, err error) error {
+				logrus.Warning(msg)
+				return err
+			})
+			if err != nil {
+				app.Logger.Error(fmt.Sprintf("upgrade istio resources of %s failure %s", app.ServiceAlias, err.Error()), event.GetLoggerOption("failure"))
+				return fmt.Errorf("upgrade istio resources of %s failure %s", app.ServiceAlias, err.Error())
+			}
+		}
+	}
+	if app.GovernanceMode == model.GovernanceModeIstioServiceMesh {
+		if err := f.EnsureIstioInjection(s.manager.client, app.TenantID); err != nil {
+			logrus.Errorf("label namespace %s for istio injection failure: %s", app.TenantID, err.Error())
+		}
+	}
+
 	return s.WaitingReady(app)
 }
 
@@ -45,6 +45,12 @@ func init() {
 	RegistConversion("TenantServiceAutoscaler", TenantServiceAutoscaler)
 	//step7 conv service monitor
 	RegistConversion("TenantServiceMonitor", TenantServiceMonitor)
+	//step8 conv service dependencies to istio traffic resources
+	RegistConversion("TenantServiceIstio", TenantServiceIstio)
+	//step9 conv service dependencies to network policy
+	RegistConversion("TenantServiceNetworkPolicy", TenantServiceNetworkPolicy)
+	//step10 conv service replicas to pod disruption budget
+	RegistConversion("TenantServicePodDisruptionBudget", TenantServicePodDisruptionBudget)
 }
 
 //Conversion conversion function
@@ -87,6 +93,8 @@ func InitAppService(dbmanager db.Manager, serviceID string, configs map[string]s
 	}
 	if app != nil {
 		appService.AppServiceBase.GovernanceMode = app.GovernanceMode
+		appService.AppServiceBase.MTLSMode = app.MTLSMode
+		appService.AppServiceBase.Tracing = app.GetTracingConfig()
 	}
 
 	for _, c := range conversionList {
@@ -118,6 +126,8 @@ func InitCacheAppService(dbm db.Manager, serviceID, creatorID string) (*v1.AppSe
 	}
 	if app != nil {
 		appService.AppServiceBase.GovernanceMode = app.GovernanceMode
+		appService.AppServiceBase.MTLSMode = app.MTLSMode
+		appService.AppServiceBase.Tracing = app.GetTracingConfig()
 	}
 
 	if err := TenantServiceBase(appService, dbm); err != nil {
@@ -179,6 +179,14 @@ func (a *AppServiceBuild) Build() (*v1.K8sResources, error) {
 	}
 	if crt {
 		services, _ = a.CreateUpstreamPluginMappingService(services, pp)
+		// the inner services point to the inbound mesh listeners, which serve mutual TLS
+		if a.appService.GovernanceMode == model.GovernanceModeBuildInServiceMesh && a.appService.MTLSMode != "" {
+			for _, service := range services {
+				if service.Labels["service_type"] == "inner" {
+					service.Annotations["mtls_mode"] = a.appService.MTLSMode
+				}
+			}
+		}
 	}
 
 	return &v1.K8sResources{
@@ -289,6 +297,23 @@ func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, plu
 	if rule.Cookie != "" {
 		annos[parser.GetAnnotationWithPrefix("cookie")] = rule.Cookie
 	}
+	// backend protocol
+	if rule.BackendProtocol != "" {
+		annos[parser.GetAnnotationWithPrefix("backend-protocol")] = rule.BackendProtocol
+		if rule.ProxySSLVerify {
+			annos[parser.GetAnnotationWithPrefix("proxy-ssl-verify")] = "true"
+		}
+	}
+	// error pages and maintenance mode
+	if rule.ErrorPages != "" {
+		annos[parser.GetAnnotationWithPrefix("error-pages")] = rule.ErrorPages
+	}
+	if rule.Maintenance {
+		annos[parser.GetAnnotationWithPrefix("maintenance")] = "true"
+		if rule.MaintenanceRetryAfter > 0 {
+			annos[parser.GetAnnotationWithPrefix("maintenance-retry-after")] = fmt.Sprintf("%d", rule.MaintenanceRetryAfter)
+		}
+	}
 	// certificate
 	if rule.CertificateID != "" {
 		cert, err := a.dbmanager.CertificateDao().GetCertificateByID(rule.CertificateID)
@@ -342,7 +367,28 @@ func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, plu
 				break
 			}
 			annos[parser.GetAnnotationWithPrefix("lb-type")] = extension.Value
-
+		case string(model.ACME):
+			// the certificate is managed by the acme manager of worker master,
+			// the gateway keeps answering the http-01 challenges of the domain on the http port
+			annos[parser.GetAnnotationWithPrefix("acme")] = "true"
+		case string(model.CORS):
+			annos[parser.GetAnnotationWithPrefix("enable-cors")] = extension.Value
+		case string(model.CORSAllowOrigin), string(model.CORSAllowMethods), string(model.CORSAllowHeaders),
+			string(model.CORSExposeHeaders), string(model.CORSAllowCredentials), string(model.CORSMaxAge):
+			annos[parser.GetAnnotationWithPrefix(extension.Key)] = extension.Value
+		case string(model.SetResponseHeader):
+			kv := strings.SplitN(extension.Value, ":", 2)
+			if len(kv) < 2 || strings.TrimSpace(kv[0]) == "" {
+				logrus.Warningf("invalid extension value for set-response-header: %s", extension.Value)
+				break
+			}
+			annos[parser.GetAnnotationWithPrefix("set-response-header-"+strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+		case string(model.RemoveResponseHeader):
+			key := parser.GetAnnotationWithPrefix("remove-response-headers")
+			if annos[key] != "" {
+				annos[key] += ","
+			}
+			annos[key] += extension.Value
 		default:
 			logrus.Warnf("Unexpected RuleExtension Key: %s", extension.Key)
 		}
@@ -451,13 +497,21 @@ func (a *AppServiceBuild) createServiceAnnotations() map[string]string {
 
 func (a *AppServiceBuild) createKubernetesNativeService(port *model.TenantServicesPort) *corev1.Service {
 	svc := a.createInnerService(port)
-	svc.Name = port.K8sServiceName
-	if svc.Name == "" {
-		svc.Name = fmt.Sprintf("%s-%d", a.service.ServiceAlias, port.ContainerPort)
+	svc.Name = kubernetesNativeServiceName(a.service.ServiceAlias, port)
+	if a.appService.GovernanceMode == model.GovernanceModeIstioServiceMesh {
+		// istio selects the protocol of a service port by its name prefix
+		svc.Spec.Ports[0].Name = fmt.Sprintf("%s-%d", istioPortProtocol(port.Protocol), port.ContainerPort)
 	}
 	return svc
 }
 
+func kubernetesNativeServiceName(serviceAlias string, port *model.TenantServicesPort) string {
+	if port.K8sServiceName != "" {
+		return port.K8sServiceName
+	}
+	return fmt.Sprintf("%s-%d", serviceAlias, port.ContainerPort)
+}
+
 func (a *AppServiceBuild) createInnerService(port *model.TenantServicesPort) *corev1.Service {
 	var service corev1.Service
 	service.Name = port.K8sServiceName
@@ -0,0 +1,334 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	api_model "github.com/goodrain/rainbond/api/model"
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/model"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// IstioSidecarInjectKey is the pod label and annotation that controls istio sidecar injection
+	IstioSidecarInjectKey = "sidecar.istio.io/inject"
+	// IstioNamespaceInjectionKey is the namespace label that enables istio sidecar injection
+	IstioNamespaceInjectionKey = "istio-injection"
+	// IstioNetworkingAPIVersion the api version of istio networking resources
+	IstioNetworkingAPIVersion = "networking.istio.io/v1beta1"
+	// IstioSystemNamespace the namespace of the istio control plane
+	IstioSystemNamespace = "istio-system"
+)
+
+//TenantServiceIstio conv service dependencies and net rules to istio traffic resources
+//only works when the governance mode of the application is ISTIO_SERVICE_MESH
+func TenantServiceIstio(as *v1.AppService, dbmanager db.Manager) error {
+	if as.GovernanceMode != model.GovernanceModeIstioServiceMesh {
+		return nil
+	}
+	ports, err := dbmanager.TenantServicesPortDao().GetPortsByServiceID(as.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("get service ports failure %s", err.Error())
+	}
+	callerOptions, err := getCallerPluginOptions(as.ServiceID, dbmanager)
+	if err != nil {
+		return err
+	}
+	for _, port := range ports {
+		if port.IsInnerService == nil || !*port.IsInnerService || port.Protocol == "udp" {
+			continue
+		}
+		host := istioServiceHost(kubernetesNativeServiceName(as.ServiceAlias, port), as.TenantID)
+		options := callerOptions[port.ContainerPort]
+		as.SetIstioResource(createIstioDestinationRule(as, port, host, options))
+		as.SetIstioResource(createIstioVirtualService(as, port, host, options))
+	}
+	sidecar, err := createIstioSidecar(as, dbmanager)
+	if err != nil {
+		return err
+	}
+	as.SetIstioResource(sidecar)
+	return nil
+}
+
+//istioCallerOptions the net rule options that one caller defined for a port of the current service
+type istioCallerOptions struct {
+	ServiceID string
+	Options   envoyv2.RainbondPluginOptions
+}
+
+//getCallerPluginOptions reads the downstream options that the callers of the service have set
+//through their net plugin configs, grouped by the container port of the service.
+func getCallerPluginOptions(serviceID string, dbmanager db.Manager) (map[int][]istioCallerOptions, error) {
+	relations, err := dbmanager.TenantServiceRelationDao().GetTenantServiceRelationsByDependServiceID(serviceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("get service reverse dependencies failure %s", err.Error())
+	}
+	var result = make(map[int][]istioCallerOptions)
+	for _, relation := range relations {
+		configs, err := dbmanager.TenantPluginVersionConfigDao().GetPluginConfigs(relation.ServiceID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			logrus.Warningf("get plugin configs of service %s failure %s", relation.ServiceID, err.Error())
+			continue
+		}
+		for _, config := range configs {
+			var spec api_model.ResourceSpec
+			if err := json.Unmarshal([]byte(config.ConfigStr), &spec); err != nil {
+				continue
+			}
+			for _, bs := range spec.BaseServices {
+				if bs.DependServiceID != serviceID || len(bs.Options) == 0 {
+					continue
+				}
+				result[bs.Port] = append(result[bs.Port], istioCallerOptions{
+					ServiceID: relation.ServiceID,
+					Options:   envoyv2.GetOptionValues(bs.Options),
+				})
+			}
+		}
+	}
+	for port := range result {
+		sort.Slice(result[port], func(i, j int) bool {
+			return result[port][i].ServiceID < result[port][j].ServiceID
+		})
+	}
+	return result, nil
+}
+
+func createIstioDestinationRule(as *v1.AppService, port *model.TenantServicesPort, host string, callers []istioCallerOptions) *unstructured.Unstructured {
+	options := envoyv2.GetOptionValues(nil)
+	// the destination rule is shared by all callers, so the strictest limits win
+	for i, caller := range callers {
+		o := caller.Options
+		if i == 0 {
+			options = o
+			continue
+		}
+		options.MaxConnections = minInt(options.MaxConnections, o.MaxConnections)
+		options.MaxPendingRequests = minInt(options.MaxPendingRequests, o.MaxPendingRequests)
+		options.MaxRequests = minInt(options.MaxRequests, o.MaxRequests)
+		options.ConsecutiveErrors = minInt(options.ConsecutiveErrors, o.ConsecutiveErrors)
+	}
+	connectionPool := map[string]interface{}{
+		"tcp": map[string]interface{}{
+			"maxConnections": int64(options.MaxConnections),
+			"connectTimeout": fmt.Sprintf("%ds", options.ConnectionTimeout),
+		},
+	}
+	if isHTTPProtocol(port.Protocol) {
+		httpPool := map[string]interface{}{
+			"http1MaxPendingRequests": int64(options.MaxPendingRequests),
+			"http2MaxRequests":        int64(options.MaxRequests),
+		}
+		if options.MaxRequestsPerConnection != nil {
+			httpPool["maxRequestsPerConnection"] = int64(*options.MaxRequestsPerConnection)
+		}
+		connectionPool["http"] = httpPool
+	}
+	spec := map[string]interface{}{
+		"host": host,
+		"trafficPolicy": map[string]interface{}{
+			"connectionPool": connectionPool,
+			"outlierDetection": map[string]interface{}{
+				"consecutive5xxErrors": int64(options.ConsecutiveErrors),
+				"interval":             fmt.Sprintf("%ds", options.Interval),
+				"baseEjectionTime":     fmt.Sprintf("%dms", options.BaseEjectionTimeMS),
+				"maxEjectionPercent":   int64(options.MaxEjectionPercent),
+			},
+		},
+	}
+	name := fmt.Sprintf("%s-%d", as.ServiceAlias, port.ContainerPort)
+	return newIstioResource(as, "DestinationRule", name, spec)
+}
+
+func createIstioVirtualService(as *v1.AppService, port *model.TenantServicesPort, host string, callers []istioCallerOptions) *unstructured.Unstructured {
+	destination := map[string]interface{}{
+		"host": host,
+		"port": map[string]interface{}{"number": int64(istioServicePort(port))},
+	}
+	spec := map[string]interface{}{
+		"hosts": []interface{}{host},
+	}
+	if !isHTTPProtocol(port.Protocol) {
+		spec["tcp"] = []interface{}{
+			map[string]interface{}{
+				"route": []interface{}{map[string]interface{}{"destination": destination}},
+			},
+		}
+		return newIstioResource(as, "VirtualService", fmt.Sprintf("%s-%d", as.ServiceAlias, port.ContainerPort), spec)
+	}
+	var routes []interface{}
+	for _, caller := range callers {
+		o := caller.Options
+		match := map[string]interface{}{
+			"sourceLabels": map[string]interface{}{"service_id": caller.ServiceID},
+			"uri":          map[string]interface{}{"prefix": o.Prefix},
+		}
+		if len(o.Headers) > 0 {
+			headers := make(map[string]interface{})
+			for _, h := range o.Headers {
+				if h.Name == "" {
+					continue
+				}
+				headers[h.Name] = map[string]interface{}{"exact": h.Value}
+			}
+			if len(headers) > 0 {
+				match["headers"] = headers
+			}
+		}
+		routes = append(routes, map[string]interface{}{
+			"name":  caller.ServiceID,
+			"match": []interface{}{match},
+			"route": []interface{}{map[string]interface{}{"destination": destination}},
+			"retries": map[string]interface{}{
+				"attempts": int64(o.MaxActiveRetries),
+			},
+		})
+	}
+	// the default route for callers without any net rule
+	routes = append(routes, map[string]interface{}{
+		"name":  "default",
+		"route": []interface{}{map[string]interface{}{"destination": destination}},
+	})
+	spec["http"] = routes
+	return newIstioResource(as, "VirtualService", fmt.Sprintf("%s-%d", as.ServiceAlias, port.ContainerPort), spec)
+}
+
+//createIstioSidecar limits the egress of the service to its dependencies, and the traffic to the hosts
+//out of the registry is blocked, so that the dependencies declared in rainbond are still enforced.
+func createIstioSidecar(as *v1.AppService, dbmanager db.Manager) (*unstructured.Unstructured, error) {
+	relations, err := dbmanager.TenantServiceRelationDao().GetTenantServiceRelations(as.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("get service dependencies failure %s", err.Error())
+	}
+	hosts := []interface{}{IstioSystemNamespace + "/*"}
+	for _, relation := range relations {
+		depService, err := dbmanager.TenantServiceDao().GetServiceByID(relation.DependServiceID)
+		if err != nil {
+			logrus.Warningf("get dependency service %s failure %s", relation.DependServiceID, err.Error())
+			continue
+		}
+		ports, err := dbmanager.TenantServicesPortDao().GetPortsByServiceID(relation.DependServiceID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			logrus.Warningf("get dependency service %s ports failure %s", relation.DependServiceID, err.Error())
+			continue
+		}
+		governanceMode := model.GovernanceModeBuildInServiceMesh
+		app, err := dbmanager.ApplicationDao().GetByServiceID(relation.DependServiceID)
+		if err != nil && err != bcode.ErrApplicationNotFound {
+			logrus.Warningf("get app of dependency service %s failure %s", relation.DependServiceID, err.Error())
+		}
+		if app != nil {
+			governanceMode = app.GovernanceMode
+		}
+		for _, port := range ports {
+			if port.IsInnerService == nil || !*port.IsInnerService {
+				continue
+			}
+			var serviceName string
+			if governanceMode == model.GovernanceModeBuildInServiceMesh {
+				serviceName = port.K8sServiceName
+				if serviceName == "" {
+					serviceName = fmt.Sprintf("service-%d-%d", port.ID, port.ContainerPort)
+				}
+			} else {
+				serviceName = kubernetesNativeServiceName(depService.ServiceAlias, port)
+			}
+			hosts = append(hosts, fmt.Sprintf("%s/%s", depService.TenantID, istioServiceHost(serviceName, depService.TenantID)))
+		}
+	}
+	spec := map[string]interface{}{
+		"workloadSelector": map[string]interface{}{
+			"labels": map[string]interface{}{"service_id": as.ServiceID},
+		},
+		"egress": []interface{}{
+			map[string]interface{}{"hosts": hosts},
+		},
+		"outboundTrafficPolicy": map[string]interface{}{"mode": "REGISTRY_ONLY"},
+	}
+	return newIstioResource(as, "Sidecar", as.ServiceAlias, spec), nil
+}
+
+func newIstioResource(as *v1.AppService, kind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	labels := make(map[string]interface{})
+	for k, v := range as.GetCommonLabels() {
+		labels[k] = v
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": IstioNetworkingAPIVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": as.TenantID,
+				"labels":    labels,
+			},
+			"spec": spec,
+		},
+	}
+}
+
+func istioServiceHost(serviceName, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace)
+}
+
+func istioServicePort(port *model.TenantServicesPort) int {
+	if port.MappingPort != 0 {
+		return port.MappingPort
+	}
+	return port.ContainerPort
+}
+
+//istioPortProtocol returns the istio protocol name of the rainbond port protocol
+func istioPortProtocol(protocol string) string {
+	switch protocol {
+	case "http":
+		return "http"
+	case "https":
+		return "https"
+	case "grpc":
+		return "grpc"
+	case "mysql":
+		return "mysql"
+	case "udp":
+		return "udp"
+	default:
+		return "tcp"
+	}
+}
+
+func isHTTPProtocol(protocol string) bool {
+	return protocol == "http" || protocol == "grpc"
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package conversion
+
+import (
+	"testing"
+
+	"github.com/goodrain/rainbond/db/model"
+	envoyv2 "github.com/goodrain/rainbond/node/core/envoy/v2"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCreateIstioVirtualService(t *testing.T) {
+	as := &v1.AppService{
+		AppServiceBase: v1.AppServiceBase{
+			TenantID:       "bab18e6b1c8640979b91f8dfdd211226",
+			ServiceID:      "45197f4936cf45efa2ac4831ce42025a",
+			ServiceAlias:   "gr42025a",
+			GovernanceMode: model.GovernanceModeIstioServiceMesh,
+		},
+	}
+	port := &model.TenantServicesPort{ContainerPort: 8080, MappingPort: 80, Protocol: "http"}
+	host := istioServiceHost(kubernetesNativeServiceName(as.ServiceAlias, port), as.TenantID)
+	if host != "gr42025a-8080.bab18e6b1c8640979b91f8dfdd211226.svc.cluster.local" {
+		t.Fatalf("unexpected host %s", host)
+	}
+	callers := []istioCallerOptions{
+		{
+			ServiceID: "caller",
+			Options: envoyv2.GetOptionValues(map[string]interface{}{
+				envoyv2.KeyPrefix:           "/api",
+				envoyv2.KeyMaxActiveRetries: "5",
+			}),
+		},
+	}
+	vs := createIstioVirtualService(as, port, host, callers)
+	routes, _, _ := unstructured.NestedSlice(vs.Object, "spec", "http")
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	attempts, _, _ := unstructured.NestedInt64(routes[0].(map[string]interface{}), "retries", "attempts")
+	if attempts != 5 {
+		t.Errorf("expected 5 retry attempts, got %d", attempts)
+	}
+	if name, _, _ := unstructured.NestedString(routes[1].(map[string]interface{}), "name"); name != "default" {
+		t.Errorf("the last route should be the default route, got %s", name)
+	}
+
+	port.Protocol = "tcp"
+	vs = createIstioVirtualService(as, port, host, callers)
+	if _, ok, _ := unstructured.NestedSlice(vs.Object, "spec", "tcp"); !ok {
+		t.Errorf("tcp port should be routed by tcp routes")
+	}
+}
+
+func TestIstioSidecarInjection(t *testing.T) {
+	tests := map[string]string{
+		model.GovernanceModeIstioServiceMesh:        "true",
+		model.GovernanceModeBuildInServiceMesh:      "false",
+		model.GovernanceModeKubernetesNativeService: "",
+	}
+	for mode, want := range tests {
+		as := &v1.AppService{AppServiceBase: v1.AppServiceBase{GovernanceMode: mode, Replicas: 2}}
+		if got := createPodAnnotations(as)[IstioSidecarInjectKey]; got != want {
+			t.Errorf("%s: expected inject %q, got %q", mode, want, got)
+		}
+	}
+}
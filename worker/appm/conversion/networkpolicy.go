@@ -0,0 +1,121 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conversion
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goodrain/rainbond/api/util/bcode"
+	"github.com/goodrain/rainbond/db"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/jinzhu/gorm"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// NetworkIsolationLabelKey is the pod label selected by the default-deny network policy of the tenant namespace
+	NetworkIsolationLabelKey = "network_isolation"
+	// PlatformNamespaceLabelKey is the label of the namespace where the rainbond components(such as the gateway) run
+	PlatformNamespaceLabelKey = "rainbond_platform"
+	// DefaultDenyNetworkPolicyName the name of the default-deny network policy of the tenant namespace
+	DefaultDenyNetworkPolicyName = "rbd-default-deny"
+)
+
+//TenantServiceNetworkPolicy creates the network policy that allows the ingress traffic
+//from the components which depend on this component, the component itself and the platform namespace.
+//If the network isolation of the application is disabled, all ingress traffic is allowed.
+func TenantServiceNetworkPolicy(as *v1.AppService, dbmanager db.Manager) error {
+	app, err := dbmanager.ApplicationDao().GetByServiceID(as.ServiceID)
+	if err != nil && err != bcode.ErrApplicationNotFound {
+		return fmt.Errorf("get app of service %s failure %s", as.ServiceID, err.Error())
+	}
+	if app != nil && app.DisableNetworkIsolation {
+		as.SetNetworkPolicy(createNetworkPolicy(as, nil, true))
+		return nil
+	}
+	relations, err := dbmanager.TenantServiceRelationDao().GetTenantServiceRelationsByDependServiceID(as.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("get service dependents failure %s", err.Error())
+	}
+	var callers []string
+	for _, relation := range relations {
+		callers = append(callers, relation.ServiceID)
+	}
+	as.SetNetworkPolicy(createNetworkPolicy(as, callers, false))
+	return nil
+}
+
+func createNetworkPolicy(as *v1.AppService, callers []string, allowAll bool) *networkingv1.NetworkPolicy {
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      as.ServiceAlias,
+			Namespace: as.TenantID,
+			Labels:    as.GetCommonLabels(),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			// only the labeled pods are isolated, the same as the default-deny network policy,
+			// which also allows the host network traffic to them
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"service_id": as.ServiceID, NetworkIsolationLabelKey: "true"},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	if allowAll {
+		np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{}}
+		return np
+	}
+	sort.Strings(callers)
+	peers := []networkingv1.NetworkPolicyPeer{
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"service_id": as.ServiceID},
+			},
+		},
+		PlatformNetworkPolicyPeer(),
+	}
+	if len(callers) > 0 {
+		// the dependents may be in other tenants
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "service_id",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   callers,
+					},
+				},
+			},
+		})
+	}
+	np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: peers}}
+	return np
+}
+
+//PlatformNetworkPolicyPeer returns the peer that selects all pods in the platform namespace
+func PlatformNetworkPolicyPeer() networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{PlatformNamespaceLabelKey: "true"},
+		},
+	}
+}
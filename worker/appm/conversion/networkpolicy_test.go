@@ -0,0 +1,57 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package conversion
+
+import (
+	"testing"
+
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+)
+
+func TestCreateNetworkPolicy(t *testing.T) {
+	as := &v1.AppService{
+		AppServiceBase: v1.AppServiceBase{
+			TenantID:     "bab18e6b1c8640979b91f8dfdd211226",
+			ServiceID:    "45197f4936cf45efa2ac4831ce42025a",
+			ServiceAlias: "gr42025a",
+		},
+	}
+	np := createNetworkPolicy(as, []string{"caller2", "caller1"}, false)
+	if len(np.Spec.Ingress) != 1 {
+		t.Fatalf("expected 1 ingress rule, got %d", len(np.Spec.Ingress))
+	}
+	peers := np.Spec.Ingress[0].From
+	if len(peers) != 3 {
+		t.Fatalf("expected 3 peers, got %d", len(peers))
+	}
+	callers := peers[2].PodSelector.MatchExpressions[0].Values
+	if callers[0] != "caller1" || callers[1] != "caller2" {
+		t.Errorf("unexpected callers: %v", callers)
+	}
+
+	np = createNetworkPolicy(as, nil, false)
+	if len(np.Spec.Ingress[0].From) != 2 {
+		t.Errorf("expected 2 peers without callers, got %d", len(np.Spec.Ingress[0].From))
+	}
+
+	np = createNetworkPolicy(as, []string{"caller1"}, true)
+	if len(np.Spec.Ingress) != 1 || len(np.Spec.Ingress[0].From) != 0 {
+		t.Errorf("expected allowing all ingress traffic")
+	}
+}
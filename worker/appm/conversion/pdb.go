@@ -0,0 +1,128 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package conversion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	pdbMinAvailable   = "min-available"
+	pdbMaxUnavailable = "max-unavailable"
+	pdbDisabled       = "disabled"
+	pdbDefaultBudget  = "max-unavailable=1"
+)
+
+//TenantServicePodDisruptionBudget creates the pod disruption budget which keeps part of the replicas
+//available while the nodes are drained or upgraded. Only the components with more than one replica have it.
+//The budget is set by the component label pod-disruption-budget(such as min-available=2, max-unavailable=25% or disabled),
+//or by the extension env ES_PDB_MIN_AVAILABLE or ES_PDB_MAX_UNAVAILABLE. It is max-unavailable=1 by default.
+func TenantServicePodDisruptionBudget(as *v1.AppService, dbmanager db.Manager) error {
+	as.SetPodDisruptionBudget(nil)
+	if as.ServiceKind == model.ServiceKindThirdParty || as.Replicas <= 1 {
+		return nil
+	}
+	labels, err := dbmanager.TenantServiceLabelDao().GetTenantServiceLabel(as.ServiceID)
+	if err != nil {
+		return fmt.Errorf("get labels of service %s failure %s", as.ServiceID, err.Error())
+	}
+	budget := podDisruptionBudgetValue(as, labels)
+	if budget == pdbDisabled {
+		return nil
+	}
+	pdb, err := createPodDisruptionBudget(as, budget)
+	if err != nil {
+		logrus.Warningf("service %s: %s, use the default pod disruption budget %s", as.ServiceAlias, err.Error(), pdbDefaultBudget)
+		pdb, _ = createPodDisruptionBudget(as, pdbDefaultBudget)
+	}
+	as.SetPodDisruptionBudget(pdb)
+	return nil
+}
+
+//podDisruptionBudgetValue returns the budget of the component, the component label takes precedence over the extension env
+func podDisruptionBudgetValue(as *v1.AppService, labels []*model.TenantServiceLable) string {
+	for _, l := range labels {
+		if l.LabelKey == model.LabelKeyPodDisruptionBudget && l.LabelValue != "" {
+			return strings.TrimSpace(l.LabelValue)
+		}
+	}
+	if v, ok := as.ExtensionSet["pdb_min_available"]; ok && v != "" {
+		return pdbMinAvailable + "=" + strings.TrimSpace(v)
+	}
+	if v, ok := as.ExtensionSet["pdb_max_unavailable"]; ok && v != "" {
+		return pdbMaxUnavailable + "=" + strings.TrimSpace(v)
+	}
+	return pdbDefaultBudget
+}
+
+func createPodDisruptionBudget(as *v1.AppService, budget string) (*policyv1beta1.PodDisruptionBudget, error) {
+	kv := strings.SplitN(budget, "=", 2)
+	if len(kv) != 2 {
+		return nil, fmt.Errorf("invalid pod disruption budget %q", budget)
+	}
+	value, err := parseBudgetValue(strings.TrimSpace(kv[1]))
+	if err != nil {
+		return nil, err
+	}
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      as.ServiceAlias,
+			Namespace: as.TenantID,
+			Labels: as.GetCommonLabels(map[string]string{
+				"version": as.DeployVersion,
+			}),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"service_id": as.ServiceID},
+			},
+		},
+	}
+	switch strings.TrimSpace(kv[0]) {
+	case pdbMinAvailable:
+		pdb.Spec.MinAvailable = &value
+	case pdbMaxUnavailable:
+		pdb.Spec.MaxUnavailable = &value
+	default:
+		return nil, fmt.Errorf("invalid pod disruption budget %q", budget)
+	}
+	return pdb, nil
+}
+
+//parseBudgetValue parses a non-negative number or a percentage not greater than 100%
+func parseBudgetValue(s string) (intstr.IntOrString, error) {
+	num := strings.TrimSuffix(s, "%")
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 0 || (num != s && n > 100) {
+		return intstr.IntOrString{}, fmt.Errorf("invalid pod disruption budget value %q", s)
+	}
+	if num != s {
+		return intstr.FromString(s), nil
+	}
+	return intstr.FromInt(n), nil
+}
@@ -0,0 +1,122 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package conversion
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	"github.com/goodrain/rainbond/db/model"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestTenantServicePodDisruptionBudget(t *testing.T) {
+	tests := []struct {
+		name           string
+		replicas       int
+		labels         []*model.TenantServiceLable
+		extensions     map[string]string
+		minAvailable   *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+	}{
+		{name: "single replica", replicas: 1},
+		{name: "default", replicas: 3, maxUnavailable: intPtr(intstr.FromInt(1))},
+		{
+			name:     "label",
+			replicas: 3,
+			labels: []*model.TenantServiceLable{
+				{LabelKey: model.LabelKeyNodeSelector, LabelValue: "linux"},
+				{LabelKey: model.LabelKeyPodDisruptionBudget, LabelValue: "min-available=50%"},
+			},
+			extensions:   map[string]string{"pdb_max_unavailable": "2"},
+			minAvailable: intPtr(intstr.FromString("50%")),
+		},
+		{
+			name:         "extension",
+			replicas:     3,
+			extensions:   map[string]string{"pdb_min_available": "2"},
+			minAvailable: intPtr(intstr.FromInt(2)),
+		},
+		{
+			name:     "disabled",
+			replicas: 3,
+			labels:   []*model.TenantServiceLable{{LabelKey: model.LabelKeyPodDisruptionBudget, LabelValue: "disabled"}},
+		},
+		{
+			name:           "invalid",
+			replicas:       3,
+			labels:         []*model.TenantServiceLable{{LabelKey: model.LabelKeyPodDisruptionBudget, LabelValue: "min-available=150%"}},
+			maxUnavailable: intPtr(intstr.FromInt(1)),
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			as := &v1.AppService{
+				AppServiceBase: v1.AppServiceBase{
+					TenantID:     "bab18e6b1c8640979b91f8dfdd211226",
+					ServiceID:    "45197f4936cf45efa2ac4831ce42025a",
+					ServiceAlias: "gr42025a",
+					Replicas:     tc.replicas,
+					ExtensionSet: tc.extensions,
+				},
+			}
+			dbm := db.NewMockManager(ctrl)
+			labelDao := dao.NewMockTenantServiceLabelDao(ctrl)
+			labelDao.EXPECT().GetTenantServiceLabel(as.ServiceID).Return(tc.labels, nil).AnyTimes()
+			dbm.EXPECT().TenantServiceLabelDao().Return(labelDao).AnyTimes()
+
+			if err := TenantServicePodDisruptionBudget(as, dbm); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pdb := as.GetPodDisruptionBudget()
+			if tc.minAvailable == nil && tc.maxUnavailable == nil {
+				if pdb != nil {
+					t.Fatalf("expected no pod disruption budget, got %+v", pdb.Spec)
+				}
+				return
+			}
+			if pdb == nil {
+				t.Fatal("expected a pod disruption budget")
+			}
+			if pdb.Spec.Selector.MatchLabels["service_id"] != as.ServiceID {
+				t.Errorf("unexpected selector: %v", pdb.Spec.Selector)
+			}
+			if !equalIntOrString(pdb.Spec.MinAvailable, tc.minAvailable) || !equalIntOrString(pdb.Spec.MaxUnavailable, tc.maxUnavailable) {
+				t.Errorf("unexpected budget: min available %v, max unavailable %v", pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable)
+			}
+		})
+	}
+}
+
+func intPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func equalIntOrString(a, b *intstr.IntOrString) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
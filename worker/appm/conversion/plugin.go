@@ -21,6 +21,7 @@ package conversion
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -119,6 +120,11 @@ func conversionServicePlugin(as *typesv1.AppService, dbmanager db.Manager) ([]v1
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("get plugin model info failure %s", err.Error())
 		}
+		//traffic is governed by the istio sidecar, the built-in net plugins are not injected
+		if as.GovernanceMode == model.GovernanceModeIstioServiceMesh && isNetPlugin(pluginModel) {
+			logrus.Debugf("service %s is governed by istio, skip net plugin %s", as.ServiceAlias, pluginR.PluginID)
+			continue
+		}
 		if pluginModel == model.InBoundAndOutBoundNetPlugin || pluginModel == model.InBoundNetPlugin {
 			inBoundPlugin = pluginR
 		}
@@ -126,6 +132,9 @@ func conversionServicePlugin(as *typesv1.AppService, dbmanager db.Manager) ([]v1
 			netPlugin = true
 			meshPluginID = pluginR.PluginID
 		}
+		if isNetPlugin(pluginModel) {
+			pc.Env = append(pc.Env, createMeshTracingEnvs(as)...)
+		}
 		if pluginModel == model.InitPlugin {
 			initContainers = append(initContainers, pc)
 		} else {
@@ -175,6 +184,8 @@ func createTCPDefaultPluginContainer(as *typesv1.AppService, pluginID string, en
 	envs = append(envs, xdsHostIPEnv(xdsHost))
 	envs = append(envs, v1.EnvVar{Name: "API_HOST_PORT", Value: apiHostPort})
 	envs = append(envs, v1.EnvVar{Name: "XDS_HOST_PORT", Value: xdsHostPort})
+	envs = appendXDSVersionEnv(envs)
+	envs = append(envs, createMeshTracingEnvs(as)...)
 
 	return v1.Container{
 		Name:      "default-tcpmesh-" + as.ServiceID[len(as.ServiceID)-20:],
@@ -231,12 +242,14 @@ func ApplyPluginConfig(as *typesv1.AppService, servicePluginRelation *model.Tena
 					"plugin_id":     servicePluginRelation.PluginID,
 					"service_alias": as.ServiceAlias,
 				}),
+				Annotations: createMeshConfigAnnotations(as),
 			},
 			Data: map[string]string{
 				"plugin-config": configStr,
 				"plugin-model":  servicePluginRelation.PluginModel,
 			},
 		}
+		setMeshTracingConfig(as, cm)
 		as.SetConfigMap(cm)
 	}
 }
@@ -286,16 +299,56 @@ func applyDefaultMeshPluginConfig(as *typesv1.AppService, dbmanager db.Manager)
 				"plugin_id":     pluginID,
 				"service_alias": as.ServiceAlias,
 			}),
+			Annotations: createMeshConfigAnnotations(as),
 		},
 		Data: map[string]string{
 			"plugin-config": string(resJSON),
 			"plugin-model":  model.OutBoundNetPlugin,
 		},
 	}
+	setMeshTracingConfig(as, cm)
 	as.SetConfigMap(cm)
 	return pluginID, nil
 }
 
+//createMeshConfigAnnotations passes the mutual TLS mode of the application to the envoy discover server
+func createMeshConfigAnnotations(as *typesv1.AppService) map[string]string {
+	if as.GovernanceMode != model.GovernanceModeBuildInServiceMesh || as.MTLSMode == "" {
+		return nil
+	}
+	return map[string]string{"mtls_mode": as.MTLSMode}
+}
+
+//setMeshTracingConfig passes the tracing config of the application to the envoy discover server
+func setMeshTracingConfig(as *typesv1.AppService, cm *v1.ConfigMap) {
+	if as.GovernanceMode != model.GovernanceModeBuildInServiceMesh || as.Tracing == nil {
+		return
+	}
+	tracing, err := json.Marshal(as.Tracing)
+	if err != nil {
+		logrus.Warningf("marshal tracing config of service %s failure %s", as.ServiceAlias, err.Error())
+		return
+	}
+	cm.Data["tracing-config"] = string(tracing)
+}
+
+//createMeshTracingEnvs tells the envoy of the mesh plugin where the tracing collector is,
+//the collector cluster and the tracing driver are static in the envoy bootstrap
+func createMeshTracingEnvs(as *typesv1.AppService) []v1.EnvVar {
+	if as.GovernanceMode != model.GovernanceModeBuildInServiceMesh || as.Tracing == nil {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(as.Tracing.Collector)
+	if err != nil {
+		logrus.Warningf("invalid tracing collector %s of service %s", as.Tracing.Collector, as.ServiceAlias)
+		return nil
+	}
+	return []v1.EnvVar{
+		v1.EnvVar{Name: "TRACING_COLLECTOR_HOST", Value: host},
+		v1.EnvVar{Name: "TRACING_COLLECTOR_PORT", Value: port},
+	}
+}
+
 func getPluginModel(pluginID, tenantID string, dbmanager db.Manager) (string, error) {
 	plugin, err := dbmanager.TenantPluginDao().GetPluginByID(pluginID, tenantID)
 	if err != nil {
@@ -330,6 +383,21 @@ func getXDSHostIPAndPort() (string, string, string) {
 	return xdsHost, xdsHostPort, apiHostPort
 }
 
+//appendXDSVersionEnv sets the xds version of the mesh sidecar, the env of the component takes precedence
+//over the platform default, so that the components can be migrated to xds v3 one by one
+func appendXDSVersionEnv(envs []v1.EnvVar) []v1.EnvVar {
+	version := os.Getenv("XDS_VERSION")
+	if version == "" {
+		return envs
+	}
+	for _, env := range envs {
+		if env.Name == "XDS_VERSION" {
+			return envs
+		}
+	}
+	return append(envs, v1.EnvVar{Name: "XDS_VERSION", Value: version})
+}
+
 //container envs
 func createPluginEnvs(pluginID, tenantID, serviceAlias string, mainEnvs []v1.EnvVar, versionID, serviceID string, dbmanager db.Manager) (*[]v1.EnvVar, error) {
 	versionEnvs, err := dbmanager.TenantPluginVersionENVDao().GetVersionEnvByServiceID(serviceID, pluginID)
@@ -348,6 +416,7 @@ func createPluginEnvs(pluginID, tenantID, serviceAlias string, mainEnvs []v1.Env
 	envs = append(envs, xdsHostIPEnv(xdsHost))
 	envs = append(envs, v1.EnvVar{Name: "API_HOST_PORT", Value: apiHostPort})
 	envs = append(envs, v1.EnvVar{Name: "XDS_HOST_PORT", Value: xdsHostPort})
+	envs = appendXDSVersionEnv(envs)
 	discoverURL := fmt.Sprintf(
 		"http://%s:6100/v1/resources/%s/%s/%s",
 		"${XDS_HOST_IP}",
@@ -371,6 +440,12 @@ func createPluginEnvs(pluginID, tenantID, serviceAlias string, mainEnvs []v1.Env
 	return &envs, nil
 }
 
+func isNetPlugin(pluginModel string) bool {
+	return pluginModel == model.InBoundNetPlugin ||
+		pluginModel == model.OutBoundNetPlugin ||
+		pluginModel == model.InBoundAndOutBoundNetPlugin
+}
+
 func pluginWeight(pluginModel string) int {
 	switch pluginModel {
 	case model.InBoundNetPlugin:
@@ -32,6 +32,7 @@ import (
 	"github.com/goodrain/rainbond/node/nodem/client"
 	"github.com/goodrain/rainbond/util"
 	"github.com/goodrain/rainbond/util/envutil"
+	k8sutil "github.com/goodrain/rainbond/util/k8s"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
 	"github.com/goodrain/rainbond/worker/appm/volume"
 	"github.com/jinzhu/gorm"
@@ -63,10 +64,7 @@ func TenantServiceVersion(as *v1.AppService, dbmanager db.Manager) error {
 	tolerations := createToleration(nodeSelector)
 	podtmpSpec := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels: as.GetCommonLabels(map[string]string{
-				"name":    as.ServiceAlias,
-				"version": as.DeployVersion,
-			}),
+			Labels:      createPodLabels(as),
 			Annotations: createPodAnnotations(as),
 			Name:        as.ServiceID + "-pod-spec",
 		},
@@ -107,6 +105,7 @@ func TenantServiceVersion(as *v1.AppService, dbmanager db.Manager) error {
 	if as.GetDeployment() != nil {
 		podtmpSpec.Spec.TerminationGracePeriodSeconds = &terminationGracePeriodSeconds
 	}
+	podtmpSpec.Spec.TopologySpreadConstraints = createTopologySpreadConstraints(as, dbmanager)
 	//set to deployment or statefulset
 	as.SetPodTemplate(podtmpSpec)
 	return nil
@@ -637,6 +636,7 @@ func createAffinity(as *v1.AppService, dbmanager db.Manager) *corev1.Affinity {
 	nsr := make([]corev1.NodeSelectorRequirement, 0)
 	podAffinity := make([]corev1.PodAffinityTerm, 0)
 	podAntAffinity := make([]corev1.PodAffinityTerm, 0)
+	podZoneAffinity := make([]corev1.WeightedPodAffinityTerm, 0)
 	osWindowsSelect := false
 	labels, err := dbmanager.TenantServiceLabelDao().GetTenantServiceAffinityLabel(as.ServiceID)
 	if err == nil && labels != nil && len(labels) > 0 {
@@ -689,6 +689,18 @@ func createAffinity(as *v1.AppService, dbmanager db.Manager) *corev1.Affinity {
 						Namespaces: []string{as.TenantID},
 					})
 			}
+			if l.LabelKey == dbmodel.LabelKeyServiceZoneAffinity {
+				podZoneAffinity = append(podZoneAffinity, corev1.WeightedPodAffinityTerm{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: metav1.SetAsLabelSelector(map[string]string{
+							"name": l.LabelValue,
+						}),
+						Namespaces:  []string{as.TenantID},
+						TopologyKey: k8sutil.TopologyKey("zone"),
+					},
+				})
+			}
 		}
 	}
 	if !osWindowsSelect {
@@ -707,9 +719,13 @@ func createAffinity(as *v1.AppService, dbmanager db.Manager) *corev1.Affinity {
 			},
 		}
 	}
-	if len(podAffinity) > 0 {
-		affinity.PodAffinity = &corev1.PodAffinity{
-			RequiredDuringSchedulingIgnoredDuringExecution: podAffinity,
+	if len(podAffinity) > 0 || len(podZoneAffinity) > 0 {
+		affinity.PodAffinity = &corev1.PodAffinity{}
+		if len(podAffinity) > 0 {
+			affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution = podAffinity
+		}
+		if len(podZoneAffinity) > 0 {
+			affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = podZoneAffinity
 		}
 	}
 	if len(podAntAffinity) > 0 {
@@ -720,11 +736,66 @@ func createAffinity(as *v1.AppService, dbmanager db.Manager) *corev1.Affinity {
 	return &affinity
 }
 
+//createTopologySpreadConstraints spreads the replicas of the component across the topology domains,
+//such as zones and nodes, according to the topology-spread labels of the component.
+//It takes effect when the EvenPodsSpread feature gate of the cluster is enabled.
+func createTopologySpreadConstraints(as *v1.AppService, dbmanager db.Manager) []corev1.TopologySpreadConstraint {
+	labels, err := dbmanager.TenantServiceLabelDao().GetTenantServiceAffinityLabel(as.ServiceID)
+	if err != nil {
+		logrus.Warningf("get topology spread labels of service %s failure %s", as.ServiceID, err.Error())
+		return nil
+	}
+	var constraints []corev1.TopologySpreadConstraint
+	var keys = make(map[string]bool)
+	for _, l := range labels {
+		if l.LabelKey != dbmodel.LabelKeyTopologySpread {
+			continue
+		}
+		constraint, err := k8sutil.ParseTopologySpread(l.LabelValue)
+		if err != nil {
+			logrus.Warningf("service %s: %s, ignore it", as.ServiceAlias, err.Error())
+			continue
+		}
+		// only one constraint for a topology key
+		if keys[constraint.TopologyKey] {
+			continue
+		}
+		keys[constraint.TopologyKey] = true
+		constraint.LabelSelector = metav1.SetAsLabelSelector(map[string]string{"service_id": as.ServiceID})
+		constraints = append(constraints, *constraint)
+	}
+	return constraints
+}
+
+func createPodLabels(as *v1.AppService) map[string]string {
+	labels := map[string]string{
+		"name":    as.ServiceAlias,
+		"version": as.DeployVersion,
+		// selected by the default-deny network policy of the tenant namespace
+		NetworkIsolationLabelKey: "true",
+	}
+	if as.GovernanceMode == model.GovernanceModeIstioServiceMesh {
+		labels[IstioSidecarInjectKey] = "true"
+		// istio uses app and version labels for telemetry and subset routing
+		labels["app"] = as.ServiceAlias
+	}
+	return as.GetCommonLabels(labels)
+}
+
 func createPodAnnotations(as *v1.AppService) map[string]string {
 	var annotations = make(map[string]string)
 	if as.Replicas <= 1 {
 		annotations["rainbond.com/tolerate-unready-endpoints"] = "true"
 	}
+	// the tenant namespace is labeled for istio injection as soon as one of its applications
+	// is governed by istio, so the components of the built-in service mesh, which have their own
+	// envoy sidecar, must opt out explicitly.
+	switch as.GovernanceMode {
+	case model.GovernanceModeIstioServiceMesh:
+		annotations[IstioSidecarInjectKey] = "true"
+	case model.GovernanceModeBuildInServiceMesh:
+		annotations[IstioSidecarInjectKey] = "false"
+	}
 	if as.Replicas == 1 && as.ExtensionSet["pod_ip"] != "" {
 		logrus.Infof("custom set pod ip for calico, service %s, ip: %s", as.ServiceID, as.ExtensionSet["pod_ip"])
 		annotations["cni.projectcalico.org/ipAddrs"] = fmt.Sprintf("[\"%s\"]", as.ExtensionSet["pod_ip"])
@@ -97,3 +97,50 @@ func TestFoobar(t *testing.T) {
 	cpuRequest, cpuLimit := int64(memory)/128*30, int64(memory)/128*80
 	t.Errorf("request: %d; limit: %d", cpuRequest, cpuLimit)
 }
+
+func TestCreateTopologySpreadAndZoneAffinity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	as := &v1.AppService{
+		AppServiceBase: v1.AppServiceBase{
+			TenantID:     "bab18e6b1c8640979b91f8dfdd211226",
+			ServiceID:    "45197f4936cf45efa2ac4831ce42025a",
+			ServiceAlias: "gr42025a",
+		},
+	}
+	labels := []*model.TenantServiceLable{
+		{LabelKey: model.LabelKeyTopologySpread, LabelValue: "zone:1:DoNotSchedule"},
+		{LabelKey: model.LabelKeyTopologySpread, LabelValue: "zone:2"},
+		{LabelKey: model.LabelKeyTopologySpread, LabelValue: "node:0"},
+		{LabelKey: model.LabelKeyTopologySpread, LabelValue: "node"},
+		{LabelKey: model.LabelKeyServiceZoneAffinity, LabelValue: "grdb0001"},
+	}
+	dbmanager := db.NewMockManager(ctrl)
+	labelDao := dao.NewMockTenantServiceLabelDao(ctrl)
+	labelDao.EXPECT().GetTenantServiceAffinityLabel(as.ServiceID).Return(labels, nil).AnyTimes()
+	dbmanager.EXPECT().TenantServiceLabelDao().Return(labelDao).AnyTimes()
+
+	constraints := createTopologySpreadConstraints(as, dbmanager)
+	if len(constraints) != 2 {
+		t.Fatalf("expected 2 topology spread constraints, got %d", len(constraints))
+	}
+	if c := constraints[0]; c.TopologyKey != corev1.LabelZoneFailureDomain || c.MaxSkew != 1 || c.WhenUnsatisfiable != corev1.DoNotSchedule {
+		t.Errorf("unexpected zone constraint: %+v", c)
+	}
+	if c := constraints[1]; c.TopologyKey != corev1.LabelHostname || c.WhenUnsatisfiable != corev1.ScheduleAnyway {
+		t.Errorf("unexpected node constraint: %+v", c)
+	}
+	if constraints[0].LabelSelector.MatchLabels["service_id"] != as.ServiceID {
+		t.Errorf("unexpected label selector: %v", constraints[0].LabelSelector)
+	}
+
+	affinity := createAffinity(as, dbmanager)
+	if affinity.PodAffinity == nil || len(affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Fatalf("expected a preferred zone affinity, got %+v", affinity.PodAffinity)
+	}
+	term := affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+	if term.TopologyKey != corev1.LabelZoneFailureDomain || term.LabelSelector.MatchLabels["name"] != "grdb0001" {
+		t.Errorf("unexpected zone affinity: %+v", term)
+	}
+}
@@ -0,0 +1,128 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package f
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond/worker/appm/conversion"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+//istioResourceKinds the istio resources that rainbond manages
+var istioResourceKinds = map[string]schema.GroupVersionResource{
+	"VirtualService":  {Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"},
+	"DestinationRule": {Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"},
+	"Sidecar":         {Group: "networking.istio.io", Version: "v1beta1", Resource: "sidecars"},
+}
+
+// EnsureIstioInjection labels the namespace for istio sidecar injection.
+func EnsureIstioInjection(clientset kubernetes.Interface, namespace string) error {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if ns.Labels[conversion.IstioNamespaceInjectionKey] == "enabled" {
+		return nil
+	}
+	if ns.Labels == nil {
+		ns.Labels = make(map[string]string)
+	}
+	ns.Labels[conversion.IstioNamespaceInjectionKey] = "enabled"
+	_, err = clientset.CoreV1().Namespaces().Update(ns)
+	return err
+}
+
+// UpgradeIstioResources creates or updates the istio resources of the component,
+// and deletes the ones which are no longer needed.
+func UpgradeIstioResources(
+	client dynamic.Interface,
+	as *v1.AppService,
+	new []*unstructured.Unstructured,
+	handleErr func(msg string, err error) error) error {
+	var newMap = make(map[string]*unstructured.Unstructured, len(new))
+	for _, n := range new {
+		newMap[n.GetKind()+"/"+n.GetName()] = n
+	}
+	for kind, gvr := range istioResourceKinds {
+		olds, err := client.Resource(gvr).Namespace(as.TenantID).List(metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("service_id=%s", as.ServiceID),
+		})
+		if err != nil {
+			if err := handleErr(fmt.Sprintf("error listing istio %s of service %s: %v", kind, as.ServiceID, err), err); err != nil {
+				return err
+			}
+			continue
+		}
+		var oldMap = make(map[string]unstructured.Unstructured, len(olds.Items))
+		for _, o := range olds.Items {
+			oldMap[o.GetName()] = o
+		}
+		for key, n := range newMap {
+			if n.GetKind() != kind {
+				continue
+			}
+			if o, ok := oldMap[n.GetName()]; ok {
+				n.SetResourceVersion(o.GetResourceVersion())
+				if _, err := client.Resource(gvr).Namespace(n.GetNamespace()).Update(n, metav1.UpdateOptions{}); err != nil {
+					if err := handleErr(fmt.Sprintf("error updating istio %s %s: %v", kind, n.GetName(), err), err); err != nil {
+						return err
+					}
+					continue
+				}
+				delete(oldMap, n.GetName())
+				logrus.Debugf("ServiceID: %s; successfully update istio %s: %s", as.ServiceID, kind, n.GetName())
+			} else {
+				if _, err := client.Resource(gvr).Namespace(n.GetNamespace()).Create(n, metav1.CreateOptions{}); err != nil && !k8sErrors.IsAlreadyExists(err) {
+					if err := handleErr(fmt.Sprintf("error creating istio %s %s: %v", kind, n.GetName(), err), err); err != nil {
+						return err
+					}
+					continue
+				}
+				logrus.Debugf("ServiceID: %s; successfully create istio %s: %s", as.ServiceID, kind, n.GetName())
+			}
+			delete(newMap, key)
+		}
+		for name := range oldMap {
+			if err := client.Resource(gvr).Namespace(as.TenantID).Delete(name, &metav1.DeleteOptions{}); err != nil && !k8sErrors.IsNotFound(err) {
+				if err := handleErr(fmt.Sprintf("error deleting istio %s %s: %v", kind, name, err), err); err != nil {
+					return err
+				}
+				continue
+			}
+			logrus.Debugf("ServiceID: %s; successfully delete istio %s: %s", as.ServiceID, kind, name)
+		}
+	}
+	return nil
+}
+
+// DeleteIstioResources deletes all istio resources of the component.
+func DeleteIstioResources(client dynamic.Interface, as *v1.AppService) error {
+	return UpgradeIstioResources(client, as, nil, func(msg string, err error) error {
+		logrus.Warning(msg)
+		return nil
+	})
+}
@@ -0,0 +1,180 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package f
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+
+	"github.com/goodrain/rainbond/worker/appm/conversion"
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// calico annotates the nodes with the tunnel addresses, the host network traffic to the pods
+// on the other nodes comes from the tunnel addresses in IPIP or VXLAN mode.
+var nodeTunnelAddrAnnotations = []string{"projectcalico.org/IPv4IPIPTunnelAddr", "projectcalico.org/IPv4VXLANTunnelAddr"}
+
+// HostNetworkAddresses returns the sorted CIDRs of the addresses that the host network traffic of the nodes comes from,
+// such as the traffic from the gateway, which runs with the host network.
+func HostNetworkAddresses(nodes []*corev1.Node) []string {
+	var set = make(map[string]struct{})
+	add := func(addr string) {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return
+		}
+		if ip.To4() != nil {
+			set[fmt.Sprintf("%s/32", ip.String())] = struct{}{}
+			return
+		}
+		set[fmt.Sprintf("%s/128", ip.String())] = struct{}{}
+	}
+	for _, node := range nodes {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP {
+				add(addr.Address)
+			}
+		}
+		for _, key := range nodeTunnelAddrAnnotations {
+			if addr, ok := node.Annotations[key]; ok {
+				add(addr)
+			}
+		}
+	}
+	var cidrs []string
+	for cidr := range set {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+	return cidrs
+}
+
+func listHostNetworkAddresses(clientset kubernetes.Interface) ([]string, error) {
+	list, err := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*corev1.Node
+	for i := range list.Items {
+		nodes = append(nodes, &list.Items[i])
+	}
+	return HostNetworkAddresses(nodes), nil
+}
+
+func createDefaultDenyNetworkPolicy(namespace string, hostCIDRs []string) *networkingv1.NetworkPolicy {
+	peers := []networkingv1.NetworkPolicyPeer{conversion.PlatformNetworkPolicyPeer()}
+	for _, cidr := range hostCIDRs {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      conversion.DefaultDenyNetworkPolicyName,
+			Namespace: namespace,
+			Labels:    map[string]string{"creator": "Rainbond"},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{conversion.NetworkIsolationLabelKey: "true"},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+		},
+	}
+}
+
+// EnsureDefaultDenyNetworkPolicy creates or updates the network policy that denies all ingress traffic
+// to the isolated pods of the tenant namespace, except the traffic from the platform namespace and
+// the host network of the nodes.
+// The pods created before the network isolation is introduced are not selected until they are upgraded.
+func EnsureDefaultDenyNetworkPolicy(clientset kubernetes.Interface, namespace string) error {
+	hostCIDRs, err := listHostNetworkAddresses(clientset)
+	if err != nil {
+		return fmt.Errorf("list host network addresses: %v", err)
+	}
+	return ensureDefaultDenyNetworkPolicy(clientset, namespace, hostCIDRs)
+}
+
+func ensureDefaultDenyNetworkPolicy(clientset kubernetes.Interface, namespace string, hostCIDRs []string) error {
+	np := createDefaultDenyNetworkPolicy(namespace, hostCIDRs)
+	old, err := clientset.NetworkingV1().NetworkPolicies(namespace).Get(np.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = clientset.NetworkingV1().NetworkPolicies(namespace).Create(np)
+		if err != nil && !k8sErrors.IsAlreadyExists(err) {
+			return err
+		}
+		return nil
+	}
+	if reflect.DeepEqual(old.Spec, np.Spec) {
+		return nil
+	}
+	np.ResourceVersion = old.ResourceVersion
+	_, err = clientset.NetworkingV1().NetworkPolicies(namespace).Update(np)
+	return err
+}
+
+// SyncDefaultDenyNetworkPolicies updates the host network peers of the default-deny network policies in all namespaces
+func SyncDefaultDenyNetworkPolicies(clientset kubernetes.Interface, hostCIDRs []string) error {
+	list, err := clientset.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: "metadata.name=" + conversion.DefaultDenyNetworkPolicyName,
+	})
+	if err != nil {
+		return err
+	}
+	for _, np := range list.Items {
+		if err := ensureDefaultDenyNetworkPolicy(clientset, np.Namespace, hostCIDRs); err != nil {
+			logrus.Errorf("update default deny network policy in namespace %s failure: %s", np.Namespace, err.Error())
+		}
+	}
+	return nil
+}
+
+// EnsureNetworkPolicy creates or updates the network policy of the component
+func EnsureNetworkPolicy(clientset kubernetes.Interface, np *networkingv1.NetworkPolicy) error {
+	old, err := clientset.NetworkingV1().NetworkPolicies(np.Namespace).Get(np.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = clientset.NetworkingV1().NetworkPolicies(np.Namespace).Create(np)
+		return err
+	}
+	np.ResourceVersion = old.ResourceVersion
+	_, err = clientset.NetworkingV1().NetworkPolicies(np.Namespace).Update(np)
+	return err
+}
+
+// DeleteNetworkPolicy deletes the network policy of the component
+func DeleteNetworkPolicy(clientset kubernetes.Interface, as *v1.AppService) error {
+	err := clientset.NetworkingV1().NetworkPolicies(as.TenantID).Delete(as.ServiceAlias, &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package f
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHostNetworkAddresses(t *testing.T) {
+	nodes := []*corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"projectcalico.org/IPv4IPIPTunnelAddr": "10.244.1.0"},
+			},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.168.0.2"},
+				{Type: corev1.NodeHostName, Address: "node1"},
+			}},
+		},
+		{
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+				{Type: corev1.NodeInternalIP, Address: "fd00::1"},
+			}},
+		},
+	}
+	want := []string{"10.244.1.0/32", "192.168.0.1/32", "192.168.0.2/32", "fd00::1/128"}
+	if got := HostNetworkAddresses(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	np := createDefaultDenyNetworkPolicy("ns", want)
+	if peers := np.Spec.Ingress[0].From; len(peers) != 5 || peers[1].IPBlock.CIDR != "10.244.1.0/32" {
+		t.Errorf("unexpected peers: %v", peers)
+	}
+}
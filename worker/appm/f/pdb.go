@@ -0,0 +1,59 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package f
+
+import (
+	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EnsurePodDisruptionBudget creates or updates the pod disruption budget of the component.
+// The budget is recreated if it can not be updated, pod disruption budgets are immutable before kubernetes 1.15.
+func EnsurePodDisruptionBudget(clientset kubernetes.Interface, pdb *policyv1beta1.PodDisruptionBudget) error {
+	old, err := clientset.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Get(pdb.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = clientset.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Create(pdb)
+		return err
+	}
+	pdb.ResourceVersion = old.ResourceVersion
+	_, err = clientset.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Update(pdb)
+	if err == nil || !k8sErrors.IsInvalid(err) {
+		return err
+	}
+	if err := clientset.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Delete(pdb.Name, &metav1.DeleteOptions{}); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	pdb.ResourceVersion = ""
+	_, err = clientset.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace).Create(pdb)
+	return err
+}
+
+// DeletePodDisruptionBudget deletes the pod disruption budget of the component
+func DeletePodDisruptionBudget(clientset kubernetes.Interface, as *v1.AppService) error {
+	err := clientset.PolicyV1beta1().PodDisruptionBudgets(as.TenantID).Delete(as.ServiceAlias, &metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
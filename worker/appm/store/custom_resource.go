@@ -7,11 +7,15 @@ import (
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
 	"github.com/sirupsen/logrus"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/client-go/dynamic"
 )
 
 //ServiceMonitor service monitor custom resource
 const ServiceMonitor = "servicemonitors.monitoring.coreos.com"
 
+//IstioVirtualService istio virtual service custom resource
+const IstioVirtualService = "virtualservices.networking.istio.io"
+
 func (a *appRuntimeStore) GetCrds() (ret []*apiextensions.CustomResourceDefinition, err error) {
 	return a.listers.CRD.List(nil)
 }
@@ -32,6 +36,19 @@ func (a *appRuntimeStore) GetServiceMonitorClient() (*versioned.Clientset, error
 	return c, nil
 }
 
+//GetDynamicClient get dynamic client for the custom resources without typed client, such as istio resources
+func (a *appRuntimeStore) GetDynamicClient() (dynamic.Interface, error) {
+	if c := a.crClients["Dynamic"]; c != nil {
+		return c.(dynamic.Interface), nil
+	}
+	c, err := dynamic.NewForConfig(a.kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	a.crClients["Dynamic"] = c
+	return c, nil
+}
+
 func (a *appRuntimeStore) initCustomResourceInformer(stopch chan struct{}) {
 	if cr, _ := a.GetCrd(ServiceMonitor); cr != nil {
 		smc, err := a.GetServiceMonitorClient()
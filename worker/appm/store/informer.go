@@ -39,6 +39,7 @@ type Informer struct {
 	Claims                  cache.SharedIndexInformer
 	Events                  cache.SharedIndexInformer
 	HorizontalPodAutoscaler cache.SharedIndexInformer
+	PodDisruptionBudget     cache.SharedIndexInformer
 	CRD                     cache.SharedIndexInformer
 	CRS                     map[string]cache.SharedIndexInformer
 }
@@ -66,6 +67,7 @@ func (i *Informer) Start(stop chan struct{}) {
 	go i.StorageClass.Run(stop)
 	go i.Events.Run(stop)
 	go i.HorizontalPodAutoscaler.Run(stop)
+	go i.PodDisruptionBudget.Run(stop)
 	go i.Claims.Run(stop)
 	go i.CRD.Run(stop)
 }
@@ -75,7 +77,7 @@ func (i *Informer) Ready() bool {
 	if i.Namespace.HasSynced() && i.Ingress.HasSynced() && i.Service.HasSynced() && i.Secret.HasSynced() &&
 		i.StatefulSet.HasSynced() && i.Deployment.HasSynced() && i.Pod.HasSynced() &&
 		i.ConfigMap.HasSynced() && i.Nodes.HasSynced() && i.Events.HasSynced() &&
-		i.HorizontalPodAutoscaler.HasSynced() && i.StorageClass.HasSynced() && i.Claims.HasSynced() && i.CRD.HasSynced() {
+		i.HorizontalPodAutoscaler.HasSynced() && i.PodDisruptionBudget.HasSynced() && i.StorageClass.HasSynced() && i.Claims.HasSynced() && i.CRD.HasSynced() {
 		return true
 	}
 	return false
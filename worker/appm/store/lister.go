@@ -24,6 +24,7 @@ import (
 	autoscalingv2 "k8s.io/client-go/listers/autoscaling/v2beta2"
 	corev1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/listers/extensions/v1beta1"
+	policyv1beta1 "k8s.io/client-go/listers/policy/v1beta1"
 	storagev1 "k8s.io/client-go/listers/storage/v1"
 )
 
@@ -41,5 +42,6 @@ type Lister struct {
 	StorageClass            storagev1.StorageClassLister
 	Claims                  corev1.PersistentVolumeClaimLister
 	HorizontalPodAutoscaler autoscalingv2.HorizontalPodAutoscalerLister
+	PodDisruptionBudget     policyv1beta1.PodDisruptionBudgetLister
 	CRD                     crdlisters.CustomResourceDefinitionLister
 }
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"os"
+	"reflect"
 	"sync"
 	"time"
 
@@ -49,12 +50,14 @@ import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	internalinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/internalversion"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	listcorev1 "k8s.io/client-go/listers/core/v1"
@@ -92,6 +95,7 @@ type Storer interface {
 	GetCrds() ([]*apiextensions.CustomResourceDefinition, error)
 	GetCrd(name string) (*apiextensions.CustomResourceDefinition, error)
 	GetServiceMonitorClient() (*versioned.Clientset, error)
+	GetDynamicClient() (dynamic.Interface, error)
 	GetAppStatus(appID string) (pb.AppStatus_Status, error)
 	GetAppResources(appID string) (int64, int64, error)
 }
@@ -144,6 +148,8 @@ type appRuntimeStore struct {
 	volumeTypeListeners    map[string]chan<- *model.TenantServiceVolumeType
 	volumeTypeListenerLock sync.Mutex
 	resourceCache          *ResourceCache
+	// the addresses that the host network traffic comes from, which are allowed by the default-deny network policies
+	hostNetworkAddresses []string
 }
 
 //NewStore new app runtime store
@@ -228,6 +234,9 @@ func NewStore(
 	store.informers.HorizontalPodAutoscaler = infFactory.Autoscaling().V2beta2().HorizontalPodAutoscalers().Informer()
 	store.listers.HorizontalPodAutoscaler = infFactory.Autoscaling().V2beta2().HorizontalPodAutoscalers().Lister()
 
+	store.informers.PodDisruptionBudget = infFactory.Policy().V1beta1().PodDisruptionBudgets().Informer()
+	store.listers.PodDisruptionBudget = infFactory.Policy().V1beta1().PodDisruptionBudgets().Lister()
+
 	isThirdParty := func(ep *corev1.Endpoints) bool {
 		return ep.Labels["service-kind"] == model.ServiceKindThirdParty.String()
 	}
@@ -325,6 +334,7 @@ func NewStore(
 	store.informers.Claims.AddEventHandlerWithResyncPeriod(store, time.Second*10)
 	store.informers.Events.AddEventHandlerWithResyncPeriod(store.evtEventHandler(), time.Second*10)
 	store.informers.HorizontalPodAutoscaler.AddEventHandlerWithResyncPeriod(store, time.Second*10)
+	store.informers.PodDisruptionBudget.AddEventHandlerWithResyncPeriod(store, time.Second*10)
 	return store
 }
 
@@ -418,10 +428,30 @@ func (a *appRuntimeStore) init() error {
 			return err
 		}
 	}
+	// label the platform namespace, so that the network policies of components can allow the traffic from it
+	if err := a.labelPlatformNamespace(); err != nil {
+		logrus.Warningf("label platform namespace %s failure: %v", a.conf.RBDNamespace, err)
+	}
 	// init third-party service
 	return a.initStorageclass()
 }
 
+func (a *appRuntimeStore) labelPlatformNamespace() error {
+	ns, err := a.conf.KubeClient.CoreV1().Namespaces().Get(a.conf.RBDNamespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if ns.Labels[conversion.PlatformNamespaceLabelKey] == "true" {
+		return nil
+	}
+	if ns.Labels == nil {
+		ns.Labels = make(map[string]string)
+	}
+	ns.Labels[conversion.PlatformNamespaceLabelKey] = "true"
+	_, err = a.conf.KubeClient.CoreV1().Namespaces().Update(ns)
+	return err
+}
+
 func (a *appRuntimeStore) Start() error {
 	if err := a.init(); err != nil {
 		return err
@@ -506,6 +536,10 @@ func (a *appRuntimeStore) checkReplicasetWhetherDelete(app *v1.AppService, rs *a
 }
 
 func (a *appRuntimeStore) OnAdd(obj interface{}) {
+	if _, ok := obj.(*corev1.Node); ok {
+		a.syncHostNetworkAddresses()
+		return
+	}
 	if deployment, ok := obj.(*appsv1.Deployment); ok {
 		serviceID := deployment.Labels["service_id"]
 		version := deployment.Labels["version"]
@@ -628,6 +662,21 @@ func (a *appRuntimeStore) OnAdd(obj interface{}) {
 			return
 		}
 	}
+	if pdb, ok := obj.(*policyv1beta1.PodDisruptionBudget); ok {
+		serviceID := pdb.Labels["service_id"]
+		version := pdb.Labels["version"]
+		createrID := pdb.Labels["creater_id"]
+		if serviceID != "" && version != "" && createrID != "" {
+			appservice, err := a.getAppService(serviceID, version, createrID, true)
+			if err == conversion.ErrServiceNotFound {
+				a.conf.KubeClient.PolicyV1beta1().PodDisruptionBudgets(pdb.GetNamespace()).Delete(pdb.GetName(), &metav1.DeleteOptions{})
+			}
+			if appservice != nil {
+				appservice.SetPodDisruptionBudget(pdb)
+			}
+			return
+		}
+	}
 	if sc, ok := obj.(*storagev1.StorageClass); ok {
 		vt := workerutil.TransStorageClass2RBDVolumeType(sc)
 		for _, ch := range a.volumeTypeListeners {
@@ -711,12 +760,37 @@ func (a *appRuntimeStore) getAppService(serviceID, version, createrID string, cr
 func (a *appRuntimeStore) OnUpdate(oldObj, newObj interface{}) {
 	a.OnAdd(newObj)
 }
+
+//syncHostNetworkAddresses updates the default-deny network policies when the addresses of the nodes change
+func (a *appRuntimeStore) syncHostNetworkAddresses() {
+	if !a.informers.Nodes.HasSynced() {
+		return
+	}
+	nodes, err := a.listers.Nodes.List(labels.Everything())
+	if err != nil {
+		logrus.Errorf("list nodes failure: %s", err.Error())
+		return
+	}
+	addresses := f.HostNetworkAddresses(nodes)
+	if reflect.DeepEqual(addresses, a.hostNetworkAddresses) {
+		return
+	}
+	if err := f.SyncDefaultDenyNetworkPolicies(a.clientset, addresses); err != nil {
+		logrus.Errorf("sync default deny network policies failure: %s", err.Error())
+		return
+	}
+	a.hostNetworkAddresses = addresses
+}
 func (a *appRuntimeStore) OnDelete(objs interface{}) {
 	a.OnDeletes(objs)
 }
 func (a *appRuntimeStore) OnDeletes(objs ...interface{}) {
 	for i := range objs {
 		obj := objs[i]
+		if _, ok := obj.(*corev1.Node); ok {
+			a.syncHostNetworkAddresses()
+			continue
+		}
 		if deployment, ok := obj.(*appsv1.Deployment); ok {
 			serviceID := deployment.Labels["service_id"]
 			version := deployment.Labels["version"]
@@ -837,6 +911,21 @@ func (a *appRuntimeStore) OnDeletes(objs ...interface{}) {
 				}
 			}
 		}
+		if pdb, ok := obj.(*policyv1beta1.PodDisruptionBudget); ok {
+			serviceID := pdb.Labels["service_id"]
+			version := pdb.Labels["version"]
+			createrID := pdb.Labels["creater_id"]
+			if serviceID != "" && version != "" && createrID != "" {
+				appservice, _ := a.getAppService(serviceID, version, createrID, false)
+				if appservice != nil {
+					appservice.DelPodDisruptionBudget(pdb)
+					if appservice.IsClosed() {
+						a.DeleteAppService(appservice)
+					}
+					return
+				}
+			}
+		}
 		if sc, ok := obj.(*storagev1.StorageClass); ok {
 			if err := a.dbmanager.VolumeTypeDao().DeleteModelByVolumeTypes(sc.GetName()); err != nil {
 				logrus.Errorf("delete volumeType from db error: %s", err.Error())
@@ -29,7 +29,10 @@ import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/goodrain/rainbond/builder"
 	"github.com/goodrain/rainbond/db/model"
@@ -99,6 +102,10 @@ type AppServiceBase struct {
 	Dependces      []string
 	ExtensionSet   map[string]string
 	GovernanceMode string
+	// MTLSMode is the mutual TLS mode of the built-in service mesh
+	MTLSMode string
+	// Tracing is the tracing config of the built-in service mesh, nil if disabled
+	Tracing *model.TracingConfig
 }
 
 //AppService a service of rainbond app state in kubernetes
@@ -132,6 +139,11 @@ type AppService struct {
 	UpgradePatch     map[string][]byte
 	CustomParams     map[string]string
 	envVarSecrets    []*corev1.Secret
+	// istio traffic resources, only for the ISTIO_SERVICE_MESH governance mode
+	istioResources []*unstructured.Unstructured
+	networkPolicy  *networkingv1.NetworkPolicy
+	// pod disruption budget, only for the components with more than one replica
+	podDisruptionBudget *policyv1beta1.PodDisruptionBudget
 }
 
 //CacheKey app cache key
@@ -732,6 +744,52 @@ func (a *AppService) GetServiceMonitors(canCopy bool) []*monitorv1.ServiceMonito
 	return a.serviceMonitor
 }
 
+// SetIstioResource sets a istio traffic resource(VirtualService, DestinationRule or Sidecar)
+func (a *AppService) SetIstioResource(r *unstructured.Unstructured) {
+	for i, old := range a.istioResources {
+		if old.GetKind() == r.GetKind() && old.GetName() == r.GetName() {
+			a.istioResources[i] = r
+			return
+		}
+	}
+	a.istioResources = append(a.istioResources, r)
+}
+
+// GetIstioResources returns the istio traffic resources of the component
+func (a *AppService) GetIstioResources(canCopy bool) []*unstructured.Unstructured {
+	if canCopy {
+		return append(a.istioResources[:0:0], a.istioResources...)
+	}
+	return a.istioResources
+}
+
+// SetNetworkPolicy sets the network policy which controls the ingress traffic of the component
+func (a *AppService) SetNetworkPolicy(np *networkingv1.NetworkPolicy) {
+	a.networkPolicy = np
+}
+
+// GetNetworkPolicy returns the network policy of the component
+func (a *AppService) GetNetworkPolicy() *networkingv1.NetworkPolicy {
+	return a.networkPolicy
+}
+
+// SetPodDisruptionBudget sets the pod disruption budget of the component
+func (a *AppService) SetPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) {
+	a.podDisruptionBudget = pdb
+}
+
+// GetPodDisruptionBudget returns the pod disruption budget of the component
+func (a *AppService) GetPodDisruptionBudget() *policyv1beta1.PodDisruptionBudget {
+	return a.podDisruptionBudget
+}
+
+// DelPodDisruptionBudget removes the pod disruption budget if it is the one of the component
+func (a *AppService) DelPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) {
+	if a.podDisruptionBudget != nil && a.podDisruptionBudget.GetName() == pdb.GetName() {
+		a.podDisruptionBudget = nil
+	}
+}
+
 // GetHPAs -
 func (a *AppService) GetHPAs() []*autoscalingv2.HorizontalPodAutoscaler {
 	return a.hpas
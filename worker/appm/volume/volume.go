@@ -38,6 +38,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+//DefaultVolumeCapacity the capacity(GB) of the volume claim if the capacity is not specified
+var DefaultVolumeCapacity int64 = 500
+
 // Volume volume function interface
 type Volume interface {
 	CreateVolume(define *Define) error       // use serviceVolume
@@ -100,8 +103,8 @@ func prepare() {
 func newVolumeClaim(name, volumePath, accessMode, storageClassName string, capacity int64, labels, annotations map[string]string) *corev1.PersistentVolumeClaim {
 	logrus.Debugf("volume annotaion is %+v", annotations)
 	if capacity == 0 {
-		logrus.Warnf("claim[%s] capacity is 0, set 500G default", name)
-		capacity = 500
+		logrus.Warnf("claim[%s] capacity is 0, set %dG default", name, DefaultVolumeCapacity)
+		capacity = DefaultVolumeCapacity
 	}
 	resourceStorage, _ := resource.ParseQuantity(fmt.Sprintf("%dGi", capacity)) // 统一单位使用G
 	return &corev1.PersistentVolumeClaim{
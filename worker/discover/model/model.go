@@ -165,6 +165,13 @@ func NewTaskBody(taskType string, body []byte) TaskBody {
 			return nil
 		}
 		return b
+	case "refresh_network_policy":
+		b := &RefreshNetworkPolicyTaskBody{}
+		err := ffjson.Unmarshal(body, &b)
+		if err != nil {
+			return nil
+		}
+		return b
 	default:
 		return DefaultTaskBody{}
 	}
@@ -197,6 +204,8 @@ func CreateTaskBody(taskType string) TaskBody {
 		return DeleteTenantTaskBody{}
 	case "refreshhpa":
 		return RefreshHPATaskBody{}
+	case "refresh_network_policy":
+		return RefreshNetworkPolicyTaskBody{}
 	default:
 		return DefaultTaskBody{}
 	}
@@ -358,5 +367,10 @@ type RefreshHPATaskBody struct {
 	EventID   string `json:"eventID"`
 }
 
+// RefreshNetworkPolicyTaskBody the body of the task which regenerates the network policy of the component
+type RefreshNetworkPolicyTaskBody struct {
+	ServiceID string `json:"service_id"`
+}
+
 //DefaultTaskBody 默认操作任务主体
 type DefaultTaskBody map[string]interface{}
@@ -112,6 +112,9 @@ func (g *GarbageCollector) DelKubernetesObjects(serviceGCReq model.ServiceGCTask
 	if err := g.clientset.AutoscalingV2beta2().HorizontalPodAutoscalers(serviceGCReq.TenantID).DeleteCollection(deleteOpts, listOpts); err != nil {
 		logrus.Warningf("[DelKubernetesObjects] delete hpas(%s): %v", serviceGCReq.ServiceID, err)
 	}
+	if err := g.clientset.NetworkingV1().NetworkPolicies(serviceGCReq.TenantID).DeleteCollection(deleteOpts, listOpts); err != nil {
+		logrus.Warningf("[DelKubernetesObjects] delete network policies(%s): %v", serviceGCReq.ServiceID, err)
+	}
 	// kubernetes does not support api for deleting collection of service
 	// read: https://github.com/kubernetes/kubernetes/issues/68468#issuecomment-419981870
 	serviceList, err := g.clientset.CoreV1().Services(serviceGCReq.TenantID).List(listOpts)
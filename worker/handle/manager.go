@@ -37,6 +37,7 @@ import (
 	"github.com/goodrain/rainbond/util"
 	"github.com/goodrain/rainbond/worker/appm/controller"
 	"github.com/goodrain/rainbond/worker/appm/conversion"
+	"github.com/goodrain/rainbond/worker/appm/f"
 	"github.com/goodrain/rainbond/worker/appm/store"
 	v1 "github.com/goodrain/rainbond/worker/appm/types/v1"
 	"github.com/goodrain/rainbond/worker/discover/model"
@@ -130,6 +131,9 @@ func (m *Manager) AnalystToExec(task *model.Task) error {
 	case "refreshhpa":
 		logrus.Info("start a 'refreshhpa' task worker")
 		return m.ExecRefreshHPATask(task)
+	case "refresh_network_policy":
+		logrus.Info("start a 'refresh_network_policy' task worker")
+		return m.ExecRefreshNetworkPolicyTask(task)
 	default:
 		logrus.Warning("task can not execute because no type is identified")
 		return nil
@@ -577,3 +581,30 @@ func (m *Manager) ExecRefreshHPATask(task *model.Task) error {
 	logrus.Infof("rule id: %s; successfully refresh hpa", body.RuleID)
 	return nil
 }
+
+// ExecRefreshNetworkPolicyTask executes a 'refresh_network_policy' task.
+func (m *Manager) ExecRefreshNetworkPolicyTask(task *model.Task) error {
+	body, ok := task.Body.(*model.RefreshNetworkPolicyTaskBody)
+	if !ok {
+		logrus.Errorf("exec task 'refresh_network_policy'; wrong type: %v", reflect.TypeOf(task))
+		return fmt.Errorf("exec task 'refresh_network_policy': wrong input")
+	}
+	oldAppService := m.store.GetAppService(body.ServiceID)
+	if oldAppService == nil || oldAppService.IsClosed() {
+		logrus.Debugf("service %s is closed, no need to refresh network policy", body.ServiceID)
+		return nil
+	}
+	newAppService, err := conversion.InitAppService(m.dbmanager, body.ServiceID, nil,
+		"ServiceSource", "TenantServiceBase", "TenantServiceNetworkPolicy")
+	if err != nil {
+		logrus.Errorf("Application init create failure:%s", err.Error())
+		return fmt.Errorf("Application init create failure")
+	}
+	if np := newAppService.GetNetworkPolicy(); np != nil {
+		if err := f.EnsureNetworkPolicy(m.cfg.KubeClient, np); err != nil {
+			return fmt.Errorf("refresh network policy: %v", err)
+		}
+	}
+	logrus.Infof("service id: %s; successfully refresh network policy", body.ServiceID)
+	return nil
+}
@@ -0,0 +1,109 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//DNSProvider creates and removes the TXT records of the dns-01 challenges
+type DNSProvider interface {
+	Present(fqdn, value string) error
+	CleanUp(fqdn, value string) error
+}
+
+//DNSProviderFactory creates a dns provider, the provider reads its config from the environment of worker
+type DNSProviderFactory func() (DNSProvider, error)
+
+var (
+	dnsProvidersLock sync.RWMutex
+	dnsProviders     = make(map[string]DNSProviderFactory)
+)
+
+//RegisterDNSProvider registers a dns provider, the name is used by the acme rule extension, eg. dns-01:exec
+func RegisterDNSProvider(name string, factory DNSProviderFactory) {
+	dnsProvidersLock.Lock()
+	defer dnsProvidersLock.Unlock()
+	dnsProviders[name] = factory
+}
+
+//NewDNSProvider creates the dns provider registered with the name
+func NewDNSProvider(name string) (DNSProvider, error) {
+	dnsProvidersLock.RLock()
+	factory, ok := dnsProviders[name]
+	dnsProvidersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("dns provider %s is not supported, supported providers: %s", name, strings.Join(dnsProviderNames(), ","))
+	}
+	return factory()
+}
+
+func dnsProviderNames() []string {
+	dnsProvidersLock.RLock()
+	defer dnsProvidersLock.RUnlock()
+	var names []string
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//challengeFQDN the name of the TXT record of the dns-01 challenge
+func challengeFQDN(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.") + "."
+}
+
+func init() {
+	RegisterDNSProvider("exec", newExecDNSProvider)
+}
+
+//execDNSProvider runs a program to manage the TXT records, it is called as
+//`program present <fqdn> <value>` and `program cleanup <fqdn> <value>`
+type execDNSProvider struct {
+	program string
+}
+
+func newExecDNSProvider() (DNSProvider, error) {
+	program := os.Getenv("ACME_DNS_EXEC_PATH")
+	if program == "" {
+		return nil, fmt.Errorf("env ACME_DNS_EXEC_PATH is required by the exec dns provider")
+	}
+	return &execDNSProvider{program: program}, nil
+}
+
+func (e *execDNSProvider) Present(fqdn, value string) error {
+	return e.run("present", fqdn, value)
+}
+
+func (e *execDNSProvider) CleanUp(fqdn, value string) error {
+	return e.run("cleanup", fqdn, value)
+}
+
+func (e *execDNSProvider) run(action, fqdn, value string) error {
+	out, err := exec.Command(e.program, action, fqdn, value).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s %s: %v, output: %s", e.program, action, fqdn, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+
+	gwacme "github.com/goodrain/rainbond/gateway/acme"
+)
+
+const (
+	//ChallengeHTTP01 the http-01 challenge, it is answered by the gateway
+	ChallengeHTTP01 = "http-01"
+	//ChallengeDNS01 the dns-01 challenge, it is fulfilled by a dns provider
+	ChallengeDNS01 = "dns-01"
+)
+
+//Solver fulfills the acme challenges of a type
+type Solver interface {
+	//Type the challenge type
+	Type() string
+	//Present makes the response of the challenge available, the response is the key authorization
+	//for http-01 and the TXT record value for dns-01
+	Present(ctx context.Context, domain, token, response string) error
+	CleanUp(ctx context.Context, domain, token, response string) error
+}
+
+//NewHTTP01Solver creates the http-01 solver, the responses are served by all the gateway nodes
+func NewHTTP01Solver(store gwacme.ChallengeStore) Solver {
+	return &http01Solver{store: store}
+}
+
+type http01Solver struct {
+	store gwacme.ChallengeStore
+}
+
+func (h *http01Solver) Type() string {
+	return ChallengeHTTP01
+}
+
+func (h *http01Solver) Present(ctx context.Context, domain, token, response string) error {
+	return h.store.Put(ctx, token, response, time.Hour)
+}
+
+func (h *http01Solver) CleanUp(ctx context.Context, domain, token, response string) error {
+	return h.store.Delete(ctx, token)
+}
+
+//NewDNS01Solver creates the dns-01 solver, it waits for the propagation of the record before the validation
+func NewDNS01Solver(provider DNSProvider, propagationWait time.Duration) Solver {
+	return &dns01Solver{provider: provider, propagationWait: propagationWait}
+}
+
+type dns01Solver struct {
+	provider        DNSProvider
+	propagationWait time.Duration
+}
+
+func (d *dns01Solver) Type() string {
+	return ChallengeDNS01
+}
+
+func (d *dns01Solver) Present(ctx context.Context, domain, token, response string) error {
+	if err := d.provider.Present(challengeFQDN(domain), response); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d.propagationWait):
+	}
+	return nil
+}
+
+func (d *dns01Solver) CleanUp(ctx context.Context, domain, token, response string) error {
+	return d.provider.CleanUp(challengeFQDN(domain), response)
+}
+
+//Certificate the certificate issued by the acme server
+type Certificate struct {
+	CertPEM  []byte
+	KeyPEM   []byte
+	NotAfter time.Time
+}
+
+//Issuer obtains certificates from an acme server
+type Issuer struct {
+	client *acme.Client
+	email  string
+
+	lock       sync.Mutex
+	registered bool
+}
+
+//NewIssuer creates an issuer, the account key identifies the acme account
+func NewIssuer(directoryURL, email string, accountKey crypto.Signer) *Issuer {
+	return &Issuer{
+		client: &acme.Client{Key: accountKey, DirectoryURL: directoryURL},
+		email:  email,
+	}
+}
+
+func (i *Issuer) register(ctx context.Context) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.registered {
+		return nil
+	}
+	account := &acme.Account{}
+	if i.email != "" {
+		account.Contact = []string{"mailto:" + i.email}
+	}
+	if _, err := i.client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return fmt.Errorf("register acme account: %v", err)
+	}
+	i.registered = true
+	return nil
+}
+
+//Obtain obtains a certificate of the domain, the challenges are fulfilled by the solver
+func (i *Issuer) Obtain(ctx context.Context, domain string, solver Solver) (*Certificate, error) {
+	if err := i.register(ctx); err != nil {
+		return nil, err
+	}
+	order, err := i.client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, fmt.Errorf("create order: %v", err)
+	}
+	for _, url := range order.AuthzURLs {
+		if err := i.authorize(ctx, url, solver); err != nil {
+			return nil, err
+		}
+	}
+	if order, err = i.client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("wait order: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate request: %v", err)
+	}
+	chain, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("finalize order: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	return &Certificate{
+		CertPEM:  certPEM,
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		NotAfter: leaf.NotAfter,
+	}, nil
+}
+
+func (i *Issuer) authorize(ctx context.Context, url string, solver Solver) error {
+	authz, err := i.client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("get authorization: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == solver.Type() {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("%s challenge is not offered for %s", solver.Type(), authz.Identifier.Value)
+	}
+	domain := authz.Identifier.Value
+	var response string
+	if solver.Type() == ChallengeDNS01 {
+		response, err = i.client.DNS01ChallengeRecord(challenge.Token)
+	} else {
+		response, err = i.client.HTTP01ChallengeResponse(challenge.Token)
+	}
+	if err != nil {
+		return err
+	}
+	if err := solver.Present(ctx, domain, challenge.Token, response); err != nil {
+		return fmt.Errorf("present %s challenge of %s: %v", solver.Type(), domain, err)
+	}
+	defer func() {
+		if err := solver.CleanUp(ctx, domain, challenge.Token, response); err != nil {
+			logrus.Warningf("clean up %s challenge of %s failure %s", solver.Type(), domain, err.Error())
+		}
+	}()
+	if _, err := i.client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("accept %s challenge of %s: %v", solver.Type(), domain, err)
+	}
+	if _, err := i.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("wait authorization of %s: %v", domain, strings.TrimSpace(err.Error()))
+	}
+	return nil
+}
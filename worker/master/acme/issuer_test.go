@@ -0,0 +1,407 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/goodrain/rainbond/db/model"
+	gwacme "github.com/goodrain/rainbond/gateway/acme"
+)
+
+// pebble is a minimal RFC 8555 server in the manner of letsencrypt/pebble,
+// it validates the challenges against the in-process gateway handler and dns records.
+type pebble struct {
+	t          *testing.T
+	server     *httptest.Server
+	accountKey crypto.Signer
+	gateway    http.Handler
+	dns        *memoryDNS
+	caKey      *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+
+	lock     sync.Mutex
+	nonce    int
+	accounts int
+	orders   []*pebbleOrder
+}
+
+type pebbleOrder struct {
+	domain  string
+	token   string
+	status  string
+	authz   string
+	certPEM []byte
+}
+
+func newPebble(t *testing.T, accountKey crypto.Signer, gateway http.Handler, dns *memoryDNS) *pebble {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Pebble Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+	p := &pebble{t: t, accountKey: accountKey, gateway: gateway, dns: dns, caKey: caKey, caCert: caCert}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serve))
+	return p
+}
+
+func (p *pebble) url(path string) string {
+	return p.server.URL + path
+}
+
+func (p *pebble) serve(w http.ResponseWriter, r *http.Request) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", p.nonce))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if r.URL.Path == "/directory" {
+		p.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   p.url("/nonce"),
+			"newAccount": p.url("/account"),
+			"newOrder":   p.url("/order"),
+			"revokeCert": p.url("/revoke"),
+		})
+		return
+	}
+	payload := p.payload(r)
+	var id int
+	switch {
+	case r.URL.Path == "/account":
+		p.accounts++
+		w.Header().Set("Location", p.url("/account/1"))
+		status := http.StatusCreated
+		if p.accounts > 1 {
+			status = http.StatusOK
+		}
+		p.writeJSON(w, status, map[string]string{"status": acme.StatusValid})
+	case r.URL.Path == "/order":
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		json.Unmarshal(payload, &req)
+		o := &pebbleOrder{domain: req.Identifiers[0].Value, token: fmt.Sprintf("token%d", len(p.orders)), status: acme.StatusPending, authz: acme.StatusPending}
+		p.orders = append(p.orders, o)
+		id = len(p.orders) - 1
+		w.Header().Set("Location", p.url(fmt.Sprintf("/order/%d", id)))
+		p.writeJSON(w, http.StatusCreated, p.order(id))
+	case p.match(r.URL.Path, "/order/%d", &id):
+		w.Header().Set("Location", p.url(fmt.Sprintf("/order/%d", id)))
+		p.writeJSON(w, http.StatusOK, p.order(id))
+	case p.match(r.URL.Path, "/authz/%d", &id):
+		o := p.orders[id]
+		var challenges []map[string]string
+		for _, typ := range []string{ChallengeHTTP01, ChallengeDNS01} {
+			challenges = append(challenges, map[string]string{
+				"type":   typ,
+				"url":    p.url(fmt.Sprintf("/chal/%d/%s", id, typ)),
+				"token":  o.token,
+				"status": o.authz,
+			})
+		}
+		p.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"identifier": map[string]string{"type": "dns", "value": o.domain},
+			"status":     o.authz,
+			"challenges": challenges,
+		})
+	case strings.HasPrefix(r.URL.Path, "/chal/"):
+		var typ string
+		fmt.Sscanf(strings.Replace(r.URL.Path, "/", " ", -1), " chal %d %s", &id, &typ)
+		o := p.orders[id]
+		if err := p.validate(o, typ); err != nil {
+			p.t.Logf("validate %s challenge of %s: %v", typ, o.domain, err)
+			o.authz = acme.StatusInvalid
+			o.status = acme.StatusInvalid
+		} else {
+			o.authz = acme.StatusValid
+			o.status = acme.StatusReady
+		}
+		p.writeJSON(w, http.StatusOK, map[string]string{"type": typ, "url": p.url(r.URL.Path), "token": o.token, "status": o.authz})
+	case p.match(r.URL.Path, "/finalize/%d", &id):
+		var req struct{ CSR string }
+		json.Unmarshal(payload, &req)
+		if err := p.issue(p.orders[id], req.CSR); err != nil {
+			p.writeJSON(w, http.StatusBadRequest, map[string]interface{}{"type": "urn:ietf:params:acme:error:badCSR", "detail": err.Error(), "status": 400})
+			return
+		}
+		w.Header().Set("Location", p.url(fmt.Sprintf("/order/%d", id)))
+		p.writeJSON(w, http.StatusOK, p.order(id))
+	case p.match(r.URL.Path, "/cert/%d", &id):
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(p.orders[id].certPEM)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *pebble) match(path, format string, id *int) bool {
+	n, err := fmt.Sscanf(path, format, id)
+	return err == nil && n == 1 && fmt.Sprintf(format, *id) == path && *id < len(p.orders)
+}
+
+// payload decodes the payload of the jws request, the signature is not verified
+func (p *pebble) payload(r *http.Request) []byte {
+	var jws struct{ Payload string }
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		p.t.Errorf("decode jws: %v", err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return payload
+}
+
+func (p *pebble) order(id int) map[string]interface{} {
+	o := p.orders[id]
+	order := map[string]interface{}{
+		"status":         o.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": o.domain}},
+		"authorizations": []string{p.url(fmt.Sprintf("/authz/%d", id))},
+		"finalize":       p.url(fmt.Sprintf("/finalize/%d", id)),
+	}
+	if o.certPEM != nil {
+		order["certificate"] = p.url(fmt.Sprintf("/cert/%d", id))
+	}
+	return order
+}
+
+func (p *pebble) validate(o *pebbleOrder, typ string) error {
+	thumbprint, err := acme.JWKThumbprint(p.accountKey.Public())
+	if err != nil {
+		return err
+	}
+	keyAuth := o.token + "." + thumbprint
+	switch typ {
+	case ChallengeHTTP01:
+		req := httptest.NewRequest(http.MethodGet, "http://"+o.domain+gwacme.HTTP01ChallengePath+o.token, nil)
+		rec := httptest.NewRecorder()
+		p.gateway.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != keyAuth {
+			return fmt.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+	case ChallengeDNS01:
+		sum := sha256.Sum256([]byte(keyAuth))
+		if value := p.dns.get(challengeFQDN(o.domain)); value != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return fmt.Errorf("unexpected TXT record %q", value)
+		}
+	}
+	return nil
+}
+
+func (p *pebble) issue(o *pebbleOrder, encoded string) error {
+	if o.status != acme.StatusReady {
+		return fmt.Errorf("order is %s", o.status)
+	}
+	der, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+	if len(csr.DNSNames) != 1 || csr.DNSNames[0] != o.domain {
+		return fmt.Errorf("csr names %v do not match %s", csr.DNSNames, o.domain)
+	}
+	leaf, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(p.orders) + 1)),
+		Subject:      pkix.Name{CommonName: o.domain},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second),
+	}, p.caCert, csr.PublicKey, p.caKey)
+	if err != nil {
+		return err
+	}
+	o.certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.caCert.Raw})...)
+	o.status = acme.StatusValid
+	return nil
+}
+
+func (p *pebble) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	if status >= 400 {
+		w.Header().Set("Content-Type", "application/problem+json")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type memoryDNS struct {
+	lock    sync.Mutex
+	records map[string]string
+}
+
+func (m *memoryDNS) Present(fqdn, value string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.records[fqdn] = value
+	return nil
+}
+
+func (m *memoryDNS) CleanUp(fqdn, value string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.records, fqdn)
+	return nil
+}
+
+func (m *memoryDNS) get(fqdn string) string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.records[fqdn]
+}
+
+func TestIssuerObtain(t *testing.T) {
+	dns := &memoryDNS{records: make(map[string]string)}
+	RegisterDNSProvider("memory", func() (DNSProvider, error) { return dns, nil })
+	store := gwacme.NewMemoryChallengeStore()
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newPebble(t, accountKey, gwacme.NewHTTP01Handler(store), dns)
+	defer server.server.Close()
+
+	m := &Manager{challenges: store}
+	tests := []struct {
+		domain    string
+		challenge string
+	}{
+		{domain: "www.example.com", challenge: ""},
+		{domain: "api.example.com", challenge: "http-01"},
+		{domain: "dns.example.com", challenge: "dns-01:memory"},
+	}
+	issuer := NewIssuer(server.url("/directory"), "admin@example.com", accountKey)
+	for _, tc := range tests {
+		t.Run(tc.domain, func(t *testing.T) {
+			solver, err := m.solver(tc.challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			cert, err := issuer.Obtain(ctx, tc.domain, solver)
+			if err != nil {
+				t.Fatalf("obtain certificate: %v", err)
+			}
+			block, _ := pem.Decode(cert.CertPEM)
+			leaf, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := leaf.VerifyHostname(tc.domain); err != nil {
+				t.Error(err)
+			}
+			if !leaf.NotAfter.Equal(cert.NotAfter) {
+				t.Errorf("expected not after %v, got %v", leaf.NotAfter, cert.NotAfter)
+			}
+			if strings.Count(string(cert.CertPEM), "BEGIN CERTIFICATE") != 2 {
+				t.Errorf("expected the certificate chain contains the issuer")
+			}
+			keyBlock, _ := pem.Decode(cert.KeyPEM)
+			key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pub, ok := leaf.PublicKey.(*ecdsa.PublicKey); !ok || pub.X.Cmp(key.X) != 0 || pub.Y.Cmp(key.Y) != 0 {
+				t.Errorf("the private key does not match the certificate")
+			}
+			// the challenge responses are removed after the validation
+			if keyAuth, _ := store.Get(ctx, "token0"); keyAuth != "" {
+				t.Errorf("expected the http-01 challenge is cleaned up")
+			}
+			if len(dns.records) != 0 {
+				t.Errorf("expected the dns-01 challenge is cleaned up")
+			}
+		})
+	}
+}
+
+func TestIssuerObtainInvalidChallenge(t *testing.T) {
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the gateway does not serve the challenge
+	server := newPebble(t, accountKey, http.NotFoundHandler(), nil)
+	defer server.server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	issuer := NewIssuer(server.url("/directory"), "", accountKey)
+	if _, err := issuer.Obtain(ctx, "www.example.com", NewHTTP01Solver(gwacme.NewMemoryChallengeStore())); err == nil {
+		t.Fatal("expected an error when the challenge is invalid")
+	}
+}
+
+func TestNeedRenew(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(10 * 24 * time.Hour)
+	later := now.Add(60 * 24 * time.Hour)
+	tests := []struct {
+		name string
+		cert *model.Certificate
+		want bool
+	}{
+		{name: "not exist", cert: nil, want: true},
+		{name: "uploaded without expiration", cert: &model.Certificate{Certificate: "crt"}, want: true},
+		{name: "expire soon", cert: &model.Certificate{Certificate: "crt", ExpiresAt: &soon}, want: true},
+		{name: "valid", cert: &model.Certificate{Certificate: "crt", ExpiresAt: &later}, want: false},
+	}
+	for _, tc := range tests {
+		if got := needRenew(tc.cert, now, 30*24*time.Hour); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
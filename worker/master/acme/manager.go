@@ -0,0 +1,307 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/model"
+	gwacme "github.com/goodrain/rainbond/gateway/acme"
+	"github.com/goodrain/rainbond/mq/client"
+)
+
+const (
+	accountSecretName = "rbd-acme-account"
+	accountKeyName    = "account.key"
+)
+
+//Config acme manager config
+type Config struct {
+	DirectoryURL string
+	Email        string
+	//RenewBefore the certificate is renewed when it expires within RenewBefore
+	RenewBefore        time.Duration
+	CheckInterval      time.Duration
+	DNSPropagationWait time.Duration
+	//Namespace the namespace of the account key secret
+	Namespace string
+}
+
+//Manager obtains certificates for the http rules with the acme extension and renews them ahead of expiration
+type Manager struct {
+	conf       Config
+	kubeClient kubernetes.Interface
+	dbmanager  db.Manager
+	challenges gwacme.ChallengeStore
+	mqclient   client.MQClient
+	issuer     *Issuer
+}
+
+//NewManager creates acme manager
+func NewManager(conf Config, kubeClient kubernetes.Interface, dbmanager db.Manager, challenges gwacme.ChallengeStore, mqclient client.MQClient) *Manager {
+	return &Manager{
+		conf:       conf,
+		kubeClient: kubeClient,
+		dbmanager:  dbmanager,
+		challenges: challenges,
+		mqclient:   mqclient,
+	}
+}
+
+//Start checks the acme certificates every CheckInterval until ctx is done
+func (m *Manager) Start(ctx context.Context) {
+	if m.conf.DirectoryURL == "" {
+		logrus.Info("acme directory url is not set, skip managing acme certificates")
+		return
+	}
+	ticker := time.NewTicker(m.conf.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.Sync(ctx); err != nil {
+			logrus.Errorf("sync acme certificates failure %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//Sync obtains or renews the certificates of all acme domains
+func (m *Manager) Sync(ctx context.Context) error {
+	if m.issuer == nil {
+		key, err := m.accountKey()
+		if err != nil {
+			return fmt.Errorf("load acme account key: %v", err)
+		}
+		m.issuer = NewIssuer(m.conf.DirectoryURL, m.conf.Email, key)
+	}
+	exts, err := m.dbmanager.RuleExtensionDao().ListByKey(string(model.ACME))
+	if err != nil {
+		return fmt.Errorf("list acme rule extensions: %v", err)
+	}
+	rules := make(map[tenantDomain][]*model.HTTPRule)
+	challenges := make(map[tenantDomain]string)
+	for _, ext := range exts {
+		rule, err := m.dbmanager.HTTPRuleDao().GetHTTPRuleByID(ext.RuleID)
+		if err != nil {
+			logrus.Warningf("get http rule %s failure %s", ext.RuleID, err.Error())
+			continue
+		}
+		if rule == nil || rule.UUID == "" || rule.Domain == "" {
+			continue
+		}
+		service, err := m.dbmanager.TenantServiceDao().GetServiceByID(rule.ServiceID)
+		if err != nil {
+			logrus.Warningf("get the component of http rule %s failure %s", rule.UUID, err.Error())
+			continue
+		}
+		key := tenantDomain{tenantID: service.TenantID, domain: rule.Domain}
+		rules[key] = append(rules[key], rule)
+		challenges[key] = ext.Value
+	}
+	var keys []tenantDomain
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].tenantID != keys[j].tenantID {
+			return keys[i].tenantID < keys[j].tenantID
+		}
+		return keys[i].domain < keys[j].domain
+	})
+	for _, key := range keys {
+		if err := m.syncDomain(ctx, key.tenantID, key.domain, challenges[key], rules[key]); err != nil {
+			logrus.Errorf("sync acme certificate of %s in tenant %s failure %s", key.domain, key.tenantID, err.Error())
+		}
+	}
+	return nil
+}
+
+//tenantDomain the acme certificate is shared by the rules of a domain in the same tenant
+type tenantDomain struct {
+	tenantID, domain string
+}
+
+func (m *Manager) syncDomain(ctx context.Context, tenantID, domain, challenge string, rules []*model.HTTPRule) error {
+	certID := certificateID(tenantID, domain)
+	cert, err := m.dbmanager.CertificateDao().GetCertificateByID(certID)
+	if err != nil {
+		return err
+	}
+	renewed := false
+	if needRenew(cert, time.Now(), m.conf.RenewBefore) {
+		solver, err := m.solver(challenge)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("obtain acme certificate of %s with %s challenge", domain, solver.Type())
+		obtained, err := m.issuer.Obtain(ctx, domain, solver)
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			cert = &model.Certificate{UUID: certID, CertificateName: "acme-" + domain}
+		}
+		cert.Certificate = string(obtained.CertPEM)
+		cert.PrivateKey = string(obtained.KeyPEM)
+		cert.Issuer = model.CertificateIssuerACME
+		cert.ExpiresAt = &obtained.NotAfter
+		if err := m.dbmanager.CertificateDao().AddOrUpdate(cert); err != nil {
+			return fmt.Errorf("save certificate: %v", err)
+		}
+		renewed = true
+	}
+	for _, rule := range rules {
+		if rule.CertificateID != certID {
+			rule.CertificateID = certID
+			if err := m.dbmanager.HTTPRuleDao().UpdateModel(rule); err != nil {
+				logrus.Errorf("bind certificate to http rule %s failure %s", rule.UUID, err.Error())
+				continue
+			}
+			m.applyRule(rule)
+			continue
+		}
+		if renewed {
+			if err := m.updateSecret(rule, cert); err != nil {
+				logrus.Warningf("update secret of http rule %s failure %s, apply rule instead", rule.UUID, err.Error())
+				m.applyRule(rule)
+			}
+		}
+	}
+	return nil
+}
+
+// updateSecret replaces the certificate in the secret of the rule, the gateway store watches the secret
+func (m *Manager) updateSecret(rule *model.HTTPRule, cert *model.Certificate) error {
+	service, err := m.dbmanager.TenantServiceDao().GetServiceByID(rule.ServiceID)
+	if err != nil {
+		return err
+	}
+	secret, err := m.kubeClient.CoreV1().Secrets(service.TenantID).Get(rule.UUID, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data["tls.crt"] = []byte(cert.Certificate)
+	secret.Data["tls.key"] = []byte(cert.PrivateKey)
+	_, err = m.kubeClient.CoreV1().Secrets(service.TenantID).Update(secret)
+	return err
+}
+
+func (m *Manager) applyRule(rule *model.HTTPRule) {
+	service, err := m.dbmanager.TenantServiceDao().GetServiceByID(rule.ServiceID)
+	if err != nil {
+		logrus.Errorf("get service %s failure %s", rule.ServiceID, err.Error())
+		return
+	}
+	err = m.mqclient.SendBuilderTopic(client.TaskStruct{
+		Topic:    client.WorkerTopic,
+		TaskType: "apply_rule",
+		TaskBody: map[string]interface{}{
+			"service_id":     rule.ServiceID,
+			"deploy_version": service.DeployVersion,
+			"action":         "update-http-rule",
+			"limit":          map[string]string{"domain": rule.Domain},
+		},
+	})
+	if err != nil {
+		logrus.Errorf("send apply rule task of %s failure %s", rule.Domain, err.Error())
+	}
+}
+
+func (m *Manager) solver(challenge string) (Solver, error) {
+	if challenge == "" || challenge == ChallengeHTTP01 {
+		return NewHTTP01Solver(m.challenges), nil
+	}
+	if strings.HasPrefix(challenge, ChallengeDNS01+":") {
+		provider, err := NewDNSProvider(strings.TrimPrefix(challenge, ChallengeDNS01+":"))
+		if err != nil {
+			return nil, err
+		}
+		return NewDNS01Solver(provider, m.conf.DNSPropagationWait), nil
+	}
+	return nil, fmt.Errorf("unsupported acme challenge %s", challenge)
+}
+
+// accountKey loads the acme account key from the secret, it is created at first time
+func (m *Manager) accountKey() (crypto.Signer, error) {
+	secrets := m.kubeClient.CoreV1().Secrets(m.conf.Namespace)
+	secret, err := secrets.Get(accountSecretName, metav1.GetOptions{})
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[accountKeyName])
+		if block == nil {
+			return nil, fmt.Errorf("secret %s has no valid %s", accountSecretName, accountKeyName)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = secrets.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: accountSecretName, Namespace: m.conf.Namespace},
+		Data: map[string][]byte{
+			accountKeyName: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func needRenew(cert *model.Certificate, now time.Time, renewBefore time.Duration) bool {
+	if cert == nil || cert.Certificate == "" || cert.ExpiresAt == nil {
+		return true
+	}
+	return cert.ExpiresAt.Sub(now) < renewBefore
+}
+
+// certificateID the acme certificate of a domain is shared by all its rules in the tenant
+func certificateID(tenantID, domain string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte("acme:"+tenantID+"/"+domain)))
+}
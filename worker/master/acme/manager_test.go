@@ -0,0 +1,126 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/goodrain/rainbond/db"
+	"github.com/goodrain/rainbond/db/dao"
+	"github.com/goodrain/rainbond/db/model"
+	gwacme "github.com/goodrain/rainbond/gateway/acme"
+	"github.com/goodrain/rainbond/mq/client"
+)
+
+type fakeMQClient struct {
+	client.MQClient
+	tasks []client.TaskStruct
+}
+
+func (f *fakeMQClient) SendBuilderTopic(t client.TaskStruct) error {
+	f.tasks = append(f.tasks, t)
+	return nil
+}
+
+type fakeTenantServiceDao struct {
+	dao.TenantServiceDao
+	service *model.TenantServices
+}
+
+func (f *fakeTenantServiceDao) GetServiceByID(serviceID string) (*model.TenantServices, error) {
+	return f.service, nil
+}
+
+func TestManagerSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain := "www.example.com"
+	certID := certificateID("tenant", domain)
+	// rule1 uses the certificate already, rule2 is added after the certificate is obtained
+	rule1 := &model.HTTPRule{UUID: "rule1", ServiceID: "sid", Domain: domain, CertificateID: certID}
+	rule2 := &model.HTTPRule{UUID: "rule2", ServiceID: "sid", Domain: domain}
+	expired := time.Now().Add(-time.Hour)
+	cert := &model.Certificate{UUID: certID, Certificate: "old", PrivateKey: "old", Issuer: model.CertificateIssuerACME, ExpiresAt: &expired}
+
+	ruleExtensionDao := dao.NewMockRuleExtensionDao(ctrl)
+	ruleExtensionDao.EXPECT().ListByKey(string(model.ACME)).Return([]*model.RuleExtension{
+		{RuleID: rule1.UUID, Key: string(model.ACME), Value: ChallengeHTTP01},
+		{RuleID: rule2.UUID, Key: string(model.ACME), Value: ChallengeHTTP01},
+	}, nil)
+	httpRuleDao := dao.NewMockHTTPRuleDao(ctrl)
+	httpRuleDao.EXPECT().GetHTTPRuleByID(rule1.UUID).Return(rule1, nil)
+	httpRuleDao.EXPECT().GetHTTPRuleByID(rule2.UUID).Return(rule2, nil)
+	httpRuleDao.EXPECT().UpdateModel(rule2).Return(nil)
+	certificateDao := dao.NewMockCertificateDao(ctrl)
+	certificateDao.EXPECT().GetCertificateByID(certID).Return(cert, nil)
+	certificateDao.EXPECT().AddOrUpdate(cert).Return(nil)
+	tenantServiceDao := &fakeTenantServiceDao{service: &model.TenantServices{TenantID: "tenant", ServiceID: "sid", DeployVersion: "v1"}}
+	dbmanager := db.NewMockManager(ctrl)
+	dbmanager.EXPECT().RuleExtensionDao().Return(ruleExtensionDao).AnyTimes()
+	dbmanager.EXPECT().HTTPRuleDao().Return(httpRuleDao).AnyTimes()
+	dbmanager.EXPECT().CertificateDao().Return(certificateDao).AnyTimes()
+	dbmanager.EXPECT().TenantServiceDao().Return(tenantServiceDao).AnyTimes()
+
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: rule1.UUID, Namespace: "tenant"},
+		Data:       map[string][]byte{"tls.crt": []byte("old"), "tls.key": []byte("old")},
+	})
+	store := gwacme.NewMemoryChallengeStore()
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newPebble(t, accountKey, gwacme.NewHTTP01Handler(store), nil)
+	defer server.server.Close()
+	mqclient := &fakeMQClient{}
+	m := NewManager(Config{RenewBefore: 30 * 24 * time.Hour, Namespace: "rbd-system"}, kubeClient, dbmanager, store, mqclient)
+	m.issuer = NewIssuer(server.url("/directory"), "", accountKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if cert.Certificate == "old" || cert.ExpiresAt == nil || !cert.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expected the certificate is renewed, got expiration %v", cert.ExpiresAt)
+	}
+	secret, err := kubeClient.CoreV1().Secrets("tenant").Get(rule1.UUID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["tls.crt"]) != cert.Certificate || string(secret.Data["tls.key"]) != cert.PrivateKey {
+		t.Errorf("expected the secret of %s is updated", rule1.UUID)
+	}
+	if rule2.CertificateID != certID {
+		t.Errorf("expected the certificate is bound to %s", rule2.UUID)
+	}
+	if len(mqclient.tasks) != 1 || mqclient.tasks[0].TaskType != "apply_rule" {
+		t.Fatalf("expected one apply_rule task, got %+v", mqclient.tasks)
+	}
+}
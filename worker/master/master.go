@@ -32,8 +32,12 @@ import (
 	"github.com/goodrain/rainbond/cmd/worker/option"
 	"github.com/goodrain/rainbond/db"
 	"github.com/goodrain/rainbond/db/model"
+	gwacme "github.com/goodrain/rainbond/gateway/acme"
+	"github.com/goodrain/rainbond/mq/client"
+	etcdutil "github.com/goodrain/rainbond/util/etcd"
 	"github.com/goodrain/rainbond/util/leader"
 	"github.com/goodrain/rainbond/worker/appm/store"
+	"github.com/goodrain/rainbond/worker/master/acme"
 	"github.com/goodrain/rainbond/worker/master/podevent"
 	"github.com/goodrain/rainbond/worker/master/volumes/provider"
 	"github.com/goodrain/rainbond/worker/master/volumes/provider/lib/controller"
@@ -57,6 +61,7 @@ type Controller struct {
 	namespaceCPURequest *prometheus.GaugeVec
 	namespaceCPULimit   *prometheus.GaugeVec
 	pc                  *controller.ProvisionController
+	acme                *acme.Manager
 	isLeader            bool
 
 	stopCh          chan struct{}
@@ -91,9 +96,20 @@ func NewMasterController(conf option.Config, store store.Storer) (*Controller, e
 	}, serverVersion.GitVersion)
 	stopCh := make(chan struct{})
 
+	// the etcd and mq clients are only used by the acme certificates
+	var acmeManager *acme.Manager
+	if conf.ACMEDirectoryURL != "" {
+		acmeManager, err = newACMEManager(ctx, conf)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
 	return &Controller{
 		conf:      conf,
 		pc:        pc,
+		acme:      acmeManager,
 		store:     store,
 		stopCh:    stopCh,
 		cancel:    cancel,
@@ -140,6 +156,34 @@ func NewMasterController(conf option.Config, store store.Storer) (*Controller, e
 	}, nil
 }
 
+func newACMEManager(ctx context.Context, conf option.Config) (*acme.Manager, error) {
+	etcdClientArgs := &etcdutil.ClientArgs{
+		Endpoints: conf.EtcdEndPoints,
+		CaFile:    conf.EtcdCaFile,
+		CertFile:  conf.EtcdCertFile,
+		KeyFile:   conf.EtcdKeyFile,
+	}
+	etcdCli, err := etcdutil.NewClient(ctx, etcdClientArgs)
+	if err != nil {
+		logrus.Errorf("create etcd client error: %v", err)
+		return nil, err
+	}
+	mqclient, err := client.NewMqClient(etcdClientArgs, conf.MQAPI)
+	if err != nil {
+		logrus.Errorf("create mq client error: %v", err)
+		etcdCli.Close()
+		return nil, err
+	}
+	return acme.NewManager(acme.Config{
+		DirectoryURL:       conf.ACMEDirectoryURL,
+		Email:              conf.ACMEEmail,
+		RenewBefore:        conf.ACMERenewBefore,
+		CheckInterval:      conf.ACMECheckInterval,
+		DNSPropagationWait: conf.ACMEDNSPropagationWait,
+		Namespace:          conf.RBDNamespace,
+	}, conf.KubeClient, db.GetManager(), gwacme.NewEtcdChallengeStore(etcdCli), mqclient), nil
+}
+
 //IsLeader is leader
 func (m *Controller) IsLeader() bool {
 	return m.isLeader
@@ -164,6 +208,10 @@ func (m *Controller) Start() error {
 		defer m.store.UnRegisterVolumeTypeListener("volumeTypeEvent")
 		go m.volumeTypeEvent.Handle()
 
+		if m.acme != nil {
+			go m.acme.Start(ctx)
+		}
+
 		select {
 		case <-ctx.Done():
 		case <-m.ctx.Done():